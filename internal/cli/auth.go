@@ -35,10 +35,51 @@ var authLogoutCmd = &cobra.Command{
 	RunE:  runAuthLogout,
 }
 
+var (
+	authResetKeepMessages bool
+	authResetYes          bool
+)
+
+var authResetCmd = &cobra.Command{
+	Use:   "reset",
+	Short: "Delete the local session without contacting WhatsApp",
+	Long: `Delete the local session database so the next 'whatsapp auth login' pairs
+from scratch. Use this when the session is corrupt and logout no longer works.
+
+With --keep-messages the message database is left intact, so history survives
+re-pairing. Without it, the message database is deleted too (asks to confirm
+unless --yes is given).
+
+The old linked device stays listed on your phone until you remove it there.
+
+Examples:
+  whatsapp auth reset --keep-messages
+  whatsapp auth reset --yes`,
+	Args: cobra.NoArgs,
+	RunE: runAuthReset,
+}
+
+var authStatusWatch time.Duration
+
+// statusWatchLogin is how long --watch waits for the first login before its
+// first check.
+const statusWatchLogin = 30 * time.Second
+
 var authStatusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Show connection status and database stats",
-	RunE:  runAuthStatus,
+	Long: `Show connection status and database stats.
+
+With --watch the connection is kept open and checked every interval until
+Ctrl-C, printing one JSON line per check (a line of text with --format human).
+If the link has dropped it is reconnected, and the line reports whether that
+worked. The watch holds the session, so other commands can't connect while it
+runs.
+
+Examples:
+  whatsapp auth status
+  whatsapp auth status --watch 1m >> uptime.jsonl`,
+	RunE: runAuthStatus,
 }
 
 func init() {
@@ -46,6 +87,12 @@ func init() {
 	authCmd.AddCommand(authLoginCmd)
 	authCmd.AddCommand(authLogoutCmd)
 	authCmd.AddCommand(authStatusCmd)
+	authCmd.AddCommand(authResetCmd)
+
+	authStatusCmd.Flags().DurationVar(&authStatusWatch, "watch", 0, "Keep checking the connection at this interval (e.g. 30s), reconnecting if it drops")
+
+	authResetCmd.Flags().BoolVar(&authResetKeepMessages, "keep-messages", false, "Keep the message database")
+	authResetCmd.Flags().BoolVarP(&authResetYes, "yes", "y", false, "Skip confirmation when deleting messages")
 }
 
 func runAuthLogin(cmd *cobra.Command, args []string) error {
@@ -65,6 +112,7 @@ func runAuthLogin(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("failed to create client: %w", err)
 	}
+	defer client.Disconnect()
 
 	// Set up signal handling
 	ctx, cancel := context.WithCancel(context.Background())
@@ -127,6 +175,7 @@ func runAuthLogout(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("failed to create client: %w", err)
 	}
+	defer client.Disconnect()
 
 	if !client.IsAuthenticated() {
 		if GetFormat() == FormatHuman {
@@ -142,9 +191,7 @@ func runAuthLogout(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	client.Disconnect()
-
-	// Remove session database
+	// Remove session database, still holding the session lock
 	sessionPath := GetStoreDir() + "/session.db"
 	if err := os.Remove(sessionPath); err != nil && !os.IsNotExist(err) {
 		OutputWarning("Failed to remove session file: %v", err)
@@ -156,7 +203,60 @@ func runAuthLogout(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+func runAuthReset(cmd *cobra.Command, args []string) error {
+	paths := sqliteFiles(GetSessionDBPath())
+	if !authResetKeepMessages {
+		if !authResetYes && !confirm("This deletes all locally synced messages. Continue?") {
+			return fmt.Errorf("aborted: pass --keep-messages to keep history, or --yes to delete it")
+		}
+		paths = append(paths, sqliteFiles(GetMessagesDBPath())...)
+	}
+
+	// Hold the session lock so a running sync isn't left using deleted files
+	if err := EnsureDirectories(); err != nil {
+		return fmt.Errorf("failed to create directories: %w", err)
+	}
+	unlock, err := whatsapp.LockSession(GetStoreDir())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	removed := []string{}
+	for _, path := range paths {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+		removed = append(removed, path)
+	}
+
+	msg := "Session reset, run 'whatsapp auth login' to pair again"
+	if len(removed) == 0 {
+		msg = "No session files found"
+	}
+
+	return OutputResult(map[string]any{
+		"removed":       removed,
+		"kept_messages": authResetKeepMessages,
+	}, msg)
+}
+
+// sqliteFiles returns a SQLite database path along with its WAL and shared-memory files.
+func sqliteFiles(dbPath string) []string {
+	return []string{dbPath, dbPath + "-wal", dbPath + "-shm"}
+}
+
 func runAuthStatus(cmd *cobra.Command, args []string) error {
+	if authStatusWatch < 0 {
+		return fmt.Errorf("--watch must be positive")
+	}
+	if authStatusWatch > 0 {
+		return runAuthStatusWatch()
+	}
+
 	status := store.ConnectionStatus{
 		Connected: false,
 		LoggedIn:  false,
@@ -173,6 +273,7 @@ func runAuthStatus(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return Output(status)
 	}
+	defer client.Disconnect()
 
 	if client.IsAuthenticated() {
 		// Try to connect to check actual status
@@ -187,8 +288,6 @@ func runAuthStatus(cmd *cobra.Command, args []string) error {
 					Device: device,
 				}
 			}
-
-			client.Disconnect()
 		}
 	}
 
@@ -202,3 +301,88 @@ func runAuthStatus(cmd *cobra.Command, args []string) error {
 
 	return Output(status)
 }
+
+// runAuthStatusWatch checks the connection every --watch interval on one
+// client, until interrupted.
+func runAuthStatusWatch() error {
+	db, err := store.Open(GetMessagesDBPath())
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.CloseQuietly()
+
+	client, err := connectClient(db)
+	if err != nil {
+		return err
+	}
+	defer client.Disconnect()
+	client.WA.WaitForConnection(statusWatchLogin)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return watchStatus(ctx, authStatusWatch, func() store.StatusCheck {
+		return checkConnection(client)
+	}, outputStatusCheck)
+}
+
+// watchStatus emits a check straight away and then every interval, until ctx
+// is cancelled or emitting fails.
+func watchStatus(ctx context.Context, interval time.Duration, check func() store.StatusCheck, emit func(store.StatusCheck) error) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := emit(check()); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkConnection reports whether client is connected and logged in,
+// reconnecting first if it isn't.
+func checkConnection(client *whatsapp.Client) store.StatusCheck {
+	check := store.StatusCheck{Connected: client.IsConnected(), LoggedIn: client.IsLoggedIn()}
+	if !check.Connected || !check.LoggedIn {
+		if err := client.Reconnect(); err != nil {
+			check.Error = err.Error()
+		} else {
+			check.Reconnected = true
+		}
+		check.Connected, check.LoggedIn = client.IsConnected(), client.IsLoggedIn()
+	}
+	check.CheckedAt = time.Now()
+	return check
+}
+
+// outputStatusCheck prints one --watch check as a JSON line, or as text for
+// --format human.
+func outputStatusCheck(check store.StatusCheck) error {
+	if GetFormat() != FormatHuman {
+		return outputJSONL(check, nil, false)
+	}
+	fmt.Println(formatStatusCheck(check))
+	return nil
+}
+
+// formatStatusCheck renders a --watch check as one line of text.
+func formatStatusCheck(check store.StatusCheck) string {
+	state := "connected"
+	switch {
+	case !check.Connected:
+		state = "disconnected"
+	case !check.LoggedIn:
+		state = "connected, not logged in"
+	}
+	if check.Reconnected {
+		state += " (reconnected)"
+	}
+	if check.Error != "" {
+		state += ": " + check.Error
+	}
+	return check.CheckedAt.Format(time.RFC3339) + " " + state
+}
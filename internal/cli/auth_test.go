@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/eddmann/whatsapp-cli/internal/store"
+)
+
+func TestWatchStatusChecksEveryIntervalUntilCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	checks := 0
+	var emitted []store.StatusCheck
+	check := func() store.StatusCheck {
+		checks++
+		// The link drops on the second check and comes back on the third
+		return store.StatusCheck{Connected: checks != 2, LoggedIn: checks != 2, Reconnected: checks == 3}
+	}
+	emit := func(c store.StatusCheck) error {
+		emitted = append(emitted, c)
+		if len(emitted) == 3 {
+			cancel()
+		}
+		return nil
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- watchStatus(ctx, 5*time.Millisecond, check, emit) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("watchStatus: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected watchStatus to return once cancelled")
+	}
+
+	// A tick racing the cancellation may add one more check
+	if len(emitted) < 3 || len(emitted) != checks {
+		t.Fatalf("expected one record per check, got %d checks and %+v", checks, emitted)
+	}
+	if !emitted[0].Connected || emitted[1].Connected || !emitted[2].Reconnected {
+		t.Fatalf("unexpected records %+v", emitted)
+	}
+}
+
+func TestWatchStatusStopsWhenOutputFails(t *testing.T) {
+	errClosed := errors.New("broken pipe")
+	checks := 0
+	err := watchStatus(context.Background(), time.Millisecond, func() store.StatusCheck {
+		checks++
+		return store.StatusCheck{}
+	}, func(store.StatusCheck) error { return errClosed })
+	if !errors.Is(err, errClosed) {
+		t.Fatalf("expected the output error, got %v", err)
+	}
+	if checks != 1 {
+		t.Fatalf("expected to stop after the first check, got %d", checks)
+	}
+}
+
+func TestStatusCheckRecord(t *testing.T) {
+	at := time.Date(2026, 4, 24, 12, 0, 0, 0, time.UTC)
+
+	line, err := json.Marshal(store.StatusCheck{CheckedAt: at, Connected: true, LoggedIn: true})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if got, want := string(line), `{"checked_at":"2026-04-24T12:00:00Z","connected":true,"logged_in":true}`; got != want {
+		t.Fatalf("expected %s, got %s", want, got)
+	}
+
+	tests := []struct {
+		check store.StatusCheck
+		want  string
+	}{
+		{store.StatusCheck{CheckedAt: at, Connected: true, LoggedIn: true}, "2026-04-24T12:00:00Z connected"},
+		{store.StatusCheck{CheckedAt: at, Connected: true, LoggedIn: true, Reconnected: true}, "2026-04-24T12:00:00Z connected (reconnected)"},
+		{store.StatusCheck{CheckedAt: at, Connected: true}, "2026-04-24T12:00:00Z connected, not logged in"},
+		{store.StatusCheck{CheckedAt: at, Error: "failed to reconnect: timeout"}, "2026-04-24T12:00:00Z disconnected: failed to reconnect: timeout"},
+	}
+	for _, tt := range tests {
+		if got := formatStatusCheck(tt.check); got != tt.want {
+			t.Errorf("expected %q, got %q", tt.want, got)
+		}
+	}
+}
@@ -12,11 +12,19 @@ import (
 const autoSyncThreshold = 24 * time.Hour
 const autoSyncTimeout = 30 * time.Second
 
+// stdoutIsTerminal reports whether output goes to an interactive terminal;
+// tests swap it to simulate piped and interactive runs.
+var stdoutIsTerminal = func() bool { return isTerminal(os.Stdout) }
+
 // shouldAutoSync checks if an auto-sync is needed based on last sync time.
+// Non-interactive runs (stdout piped or redirected) skip it unless --auto-sync is passed.
 func shouldAutoSync(db *store.DB) bool {
 	if NoAutoSync() {
 		return false
 	}
+	if !AutoSyncForced() && !stdoutIsTerminal() {
+		return false
+	}
 
 	lastSync, err := db.GetLastSyncTime()
 	if err != nil {
@@ -78,6 +86,8 @@ func maybeAutoSync(db *store.DB) error {
 		return fmt.Errorf("failed to create client: %w", err)
 	}
 
+	defer client.Disconnect()
+
 	// Check if authenticated
 	if !client.IsAuthenticated() {
 		// Not authenticated - skip auto-sync silently
@@ -88,7 +98,6 @@ func maybeAutoSync(db *store.DB) error {
 	if err := client.Connect(); err != nil {
 		return fmt.Errorf("connection failed: %w", err)
 	}
-	defer client.Disconnect()
 
 	// Perform the quick sync
 	return performQuickSync(client, db)
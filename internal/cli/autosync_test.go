@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/eddmann/whatsapp-cli/internal/store"
+)
+
+func TestShouldAutoSyncSkipsNonInteractiveRuns(t *testing.T) {
+	db, err := store.Open(filepath.Join(t.TempDir(), "messages.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.CloseQuietly()
+
+	origTerminal, origNoAutoSync, origAutoSync := stdoutIsTerminal, noAutoSync, autoSync
+	t.Cleanup(func() {
+		stdoutIsTerminal, noAutoSync, autoSync = origTerminal, origNoAutoSync, origAutoSync
+	})
+
+	tests := []struct {
+		name       string
+		terminal   bool
+		noAutoSync bool
+		autoSync   bool
+		lastSync   time.Time
+		want       bool
+	}{
+		{name: "interactive stale", terminal: true, lastSync: time.Now().Add(-48 * time.Hour), want: true},
+		{name: "interactive fresh", terminal: true, lastSync: time.Now(), want: false},
+		{name: "piped stale", terminal: false, lastSync: time.Now().Add(-48 * time.Hour), want: false},
+		{name: "piped with --auto-sync", terminal: false, autoSync: true, lastSync: time.Now().Add(-48 * time.Hour), want: true},
+		{name: "--no-auto-sync wins", terminal: true, noAutoSync: true, autoSync: true, lastSync: time.Now().Add(-48 * time.Hour), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			terminal := tt.terminal
+			stdoutIsTerminal = func() bool { return terminal }
+			noAutoSync, autoSync = tt.noAutoSync, tt.autoSync
+			if err := db.SetLastSyncTime(tt.lastSync); err != nil {
+				t.Fatalf("set last sync: %v", err)
+			}
+			if got := shouldAutoSync(db); got != tt.want {
+				t.Fatalf("shouldAutoSync() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,16 +2,31 @@ package cli
 
 import (
 	"fmt"
+	"os"
+	"reflect"
 
 	"github.com/spf13/cobra"
 
 	"github.com/eddmann/whatsapp-cli/internal/store"
+	"github.com/eddmann/whatsapp-cli/internal/whatsapp"
 )
 
 var (
-	chatsQuery  string
-	chatsGroups bool
-	chatsLimit  int
+	chatsQuery    string
+	chatsGroups   bool
+	chatsNonEmpty bool
+	chatsUnread   bool
+	chatsLimit    int
+	chatsPage     int
+	chatsSortBy   string
+	chatsReverse  bool
+
+	chatsRefreshNames bool
+
+	chatsPreviewLength int
+	chatsWithMessages  int
+
+	chatsDeleteYes bool
 )
 
 var chatsCmd = &cobra.Command{
@@ -20,6 +35,19 @@ var chatsCmd = &cobra.Command{
 	Long: `List all chats from the local database.
 
 Use --query to filter by name, --groups for groups only.
+Chats without any stored messages are listed too, such as the per-sender
+entries sync creates for group members; use --non-empty to hide them.
+unread counts messages from others since you last read the chat, here or on
+another device; 'whatsapp read' resets it. --unread-only lists just those chats.
+Use --preview-length to shorten last_message to N columns, so wide characters
+and emoji count for two.
+Chats are listed by most recent activity; use --sort-by name for alphabetical
+or --sort-by unread for the most unread first, and --reverse to flip the order.
+Use --page with --limit to step through the list; pages are offsets, so they
+only line up while nothing is synced in between.
+Use --refresh-names to connect and re-resolve chats that show a bare number.
+Use --with-messages N to include each chat's newest N messages (at most 50) as
+recent_messages, for an overview in a single call.
 Returns JIDs that can be used with other commands.`,
 	RunE: runChats,
 }
@@ -28,19 +56,177 @@ func init() {
 	rootCmd.AddCommand(chatsCmd)
 	chatsCmd.Flags().StringVar(&chatsQuery, "query", "", "Filter by chat name")
 	chatsCmd.Flags().BoolVar(&chatsGroups, "groups", false, "Show groups only")
+	chatsCmd.Flags().BoolVar(&chatsNonEmpty, "non-empty", false, "Hide chats with no stored messages")
+	chatsCmd.Flags().BoolVar(&chatsUnread, "unread-only", false, "Only chats with unread messages")
 	chatsCmd.Flags().IntVar(&chatsLimit, "limit", 50, "Maximum number of chats")
+	chatsCmd.Flags().IntVar(&chatsPage, "page", 1, "Page of --limit chats to show, from 1")
+	chatsCmd.Flags().StringVar(&chatsSortBy, "sort-by", "activity", "Sort order: name, activity, unread")
+	chatsCmd.Flags().StringVar(&chatsSortBy, "sort", "activity", "Alias for --sort-by")
+	chatsCmd.Flags().BoolVar(&chatsReverse, "reverse", false, "Reverse the sort order")
+	chatsCmd.Flags().BoolVar(&chatsRefreshNames, "refresh-names", false, "Re-resolve chat names from WhatsApp before listing (connects)")
+	chatsCmd.Flags().IntVar(&chatsPreviewLength, "preview-length", 0, "Truncate last_message to N columns (0 = full message)")
+	chatsCmd.Flags().IntVar(&chatsWithMessages, "with-messages", 0, fmt.Sprintf("Include each chat's newest N messages as recent_messages (at most %d)", maxChatRecentMessages))
+
+	chatsCmd.AddCommand(chatsDeleteCmd)
+	chatsDeleteCmd.Flags().BoolVarP(&chatsDeleteYes, "yes", "y", false, "Skip the confirmation prompt")
 }
 
-func runChats(cmd *cobra.Command, args []string) error {
+var chatsDeleteCmd = &cobra.Command{
+	Use:   "delete <jid>",
+	Short: "Remove a chat and its messages from the local database",
+	Long: `Remove a chat and all of its messages from the local database.
+
+Only the local copy is deleted: the chat stays on WhatsApp and on your other
+devices, and a later sync may store new messages in it again. Downloaded media
+files are left on disk; 'whatsapp media-gc' removes them.
+
+Examples:
+  whatsapp chats delete 1234567890@s.whatsapp.net
+  whatsapp chats delete 123456789-987654321@g.us --yes`,
+	Args: cobra.ExactArgs(1),
+	RunE: runChatsDelete,
+}
+
+// maxChatRecentMessages caps --with-messages, which multiplies the output by
+// every chat listed.
+const maxChatRecentMessages = 50
+
+// chatWithMessages is a chat with its newest messages, for --with-messages.
+type chatWithMessages struct {
+	store.Chat
+	RecentMessages []store.Message `json:"recent_messages"`
+}
+
+func runChatsDelete(cmd *cobra.Command, args []string) error {
+	jid := args[0]
+
 	return WithDB(func(db *store.DB) error {
-		chats, err := db.ListChats(store.ListChatsOptions{
-			Query:      chatsQuery,
-			OnlyGroups: chatsGroups,
-			Limit:      chatsLimit,
-		})
+		label := jid
+		if name := db.GetChatName(jid); name != "" {
+			label = fmt.Sprintf("%s (%s)", name, jid)
+		}
+		if !chatsDeleteYes && !confirm(fmt.Sprintf("Delete %s and its messages from the local database?", label)) {
+			return fmt.Errorf("aborted: pass --yes to delete without asking")
+		}
+
+		removed, err := db.DeleteChat(jid)
 		if err != nil {
-			return fmt.Errorf("failed to list chats: %w", err)
+			return fmt.Errorf("failed to delete chat: %w", err)
 		}
-		return Output(chats)
+		return OutputResult(store.DeleteChatResult{
+			ChatJID:  jid,
+			Messages: removed,
+		}, fmt.Sprintf("Deleted %s and %d messages", jid, removed))
 	})
 }
+
+func runChats(cmd *cobra.Command, args []string) error {
+	if err := validatePage(chatsPage, chatsLimit); err != nil {
+		return err
+	}
+	if chatsWithMessages < 0 {
+		return fmt.Errorf("--with-messages must not be negative")
+	}
+	if chatsWithMessages > maxChatRecentMessages {
+		OutputWarning("--with-messages is capped at %d", maxChatRecentMessages)
+		chatsWithMessages = maxChatRecentMessages
+	}
+	if chatsRefreshNames {
+		return WithConnection(func(db *store.DB, client *whatsapp.Client) error {
+			updated, err := client.BackfillChatNames()
+			if err != nil {
+				return fmt.Errorf("failed to refresh chat names: %w", err)
+			}
+			fmt.Fprintf(os.Stderr, "Updated %d chat names.\n", updated)
+			return listChats(db)
+		})
+	}
+	return WithDB(listChats)
+}
+
+func listChats(db *store.DB) error {
+	chats, err := db.ListChats(store.ListChatsOptions{
+		Query:      chatsQuery,
+		OnlyGroups: chatsGroups,
+		NonEmpty:   chatsNonEmpty,
+		UnreadOnly: chatsUnread,
+		SortBy:     chatsSortBy,
+		SortDesc:   sortDesc(chatsSortBy, chatsReverse),
+		Limit:      chatsLimit,
+		Page:       chatsPage,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list chats: %w", err)
+	}
+
+	if chatsPreviewLength > 0 {
+		for i := range chats {
+			if chats[i].LastMessage != nil {
+				preview := truncateWidth(*chats[i].LastMessage, chatsPreviewLength, "\u2026")
+				chats[i].LastMessage = &preview
+			}
+		}
+	}
+
+	if chatsWithMessages > 0 {
+		withMessages := make([]chatWithMessages, len(chats))
+		for i, chat := range chats {
+			messages, err := db.ListMessages(store.ListMessagesOptions{
+				ChatJID: chat.JID,
+				Limit:   chatsWithMessages,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to list messages for %s: %w", chat.JID, err)
+			}
+			withMessages[i] = chatWithMessages{Chat: chat, RecentMessages: nonNil(messages)}
+		}
+		return outputChatsWithMessages(withMessages)
+	}
+
+	return Output(nonNil(chats))
+}
+
+// outputChatsWithMessages prints chats with their recent messages. Human
+// output lists each chat's messages under a heading and CSV/TSV/template
+// output lists the messages alone; other formats get each chat with --fields
+// applied to its chat fields, keeping recent_messages whole.
+func outputChatsWithMessages(chats []chatWithMessages) error {
+	opts := GetOutputOptions()
+	switch {
+	case (opts.Format == FormatHuman || opts.Format == FormatTable) && opts.Template == "":
+		if len(chats) == 0 {
+			return Output([]store.Chat{})
+		}
+		for i, c := range chats {
+			if i > 0 {
+				fmt.Println()
+			}
+			name := c.JID
+			if c.Name != nil && *c.Name != "" {
+				name = fmt.Sprintf("%s (%s)", *c.Name, c.JID)
+			}
+			fmt.Printf("== %s ==\n", name)
+			if err := Output(c.RecentMessages); err != nil {
+				return err
+			}
+		}
+		return nil
+	case opts.Format == FormatCSV || opts.Format == FormatTSV || opts.Format == FormatTemplate:
+		flat := []store.Message{}
+		for _, c := range chats {
+			flat = append(flat, c.RecentMessages...)
+		}
+		return Output(flat)
+	case len(opts.Fields) > 0:
+		data := make([]map[string]any, len(chats))
+		for i, c := range chats {
+			c = applyMarkupStyle(c, opts.Markup).(chatWithMessages)
+			data[i] = filterStructToMap(reflect.ValueOf(c.Chat), makeFieldSet(opts.Fields))
+			data[i]["recent_messages"] = c.RecentMessages
+		}
+		opts.Fields, opts.Markup = nil, MarkupRaw
+		return output(data, opts)
+	default:
+		return output(chats, opts)
+	}
+}
@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/eddmann/whatsapp-cli/internal/store"
+)
+
+func TestChatWithMessagesEmbedsMessagesInChat(t *testing.T) {
+	name := "Alice"
+	c := chatWithMessages{
+		Chat:           store.Chat{JID: "a@s.whatsapp.net", Name: &name},
+		RecentMessages: []store.Message{{ID: "1", ChatJID: "a@s.whatsapp.net"}},
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["jid"] != "a@s.whatsapp.net" || got["name"] != "Alice" {
+		t.Fatalf("expected the chat fields at the top level, got %v", got)
+	}
+	if _, nested := got["chat"]; nested {
+		t.Fatalf("expected no nested chat object, got %v", got)
+	}
+	messages, ok := got["recent_messages"].([]any)
+	if !ok || len(messages) != 1 {
+		t.Fatalf("expected one recent message, got %v", got["recent_messages"])
+	}
+}
+
+func TestChatWithMessagesInlinesChatFields(t *testing.T) {
+	name := "Alice"
+	c := chatWithMessages{
+		Chat:           store.Chat{JID: "a@s.whatsapp.net", Name: &name},
+		RecentMessages: []store.Message{{ID: "1", ChatJID: "a@s.whatsapp.net"}},
+	}
+
+	fields := filterFields(c, []string{"jid", "name"}).(map[string]any)
+	if len(fields) != 2 || fields["jid"] != "a@s.whatsapp.net" || *fields["name"].(*string) != "Alice" {
+		t.Fatalf("expected --fields to pick the chat fields, got %v", fields)
+	}
+
+	flat := flatten(c).(map[string]any)
+	if flat["jid"] != "a@s.whatsapp.net" || *flat["name"].(*string) != "Alice" {
+		t.Fatalf("expected flattened chat fields at the top level, got %v", flat)
+	}
+	if _, nested := flat["chat.jid"]; nested {
+		t.Fatalf("expected no chat. prefix, got %v", flat)
+	}
+
+	headers, rows := extractTableData([]chatWithMessages{c}, []string{"jid", "name"}, func(v reflect.Value) string {
+		return fmt.Sprint(derefValue(v))
+	})
+	if !reflect.DeepEqual(headers, []string{"jid", "name"}) {
+		t.Fatalf("expected jid,name columns, got %v", headers)
+	}
+	if len(rows) != 1 || !reflect.DeepEqual(rows[0], []string{"a@s.whatsapp.net", "Alice"}) {
+		t.Fatalf("expected the chat row, got %v", rows)
+	}
+}
@@ -3,64 +3,214 @@ package cli
 import (
 	"context"
 	"fmt"
+	"os"
+	"sort"
 	"strings"
 
 	"github.com/spf13/cobra"
+	"go.mau.fi/whatsmeow/types"
 
 	"github.com/eddmann/whatsapp-cli/internal/store"
 	"github.com/eddmann/whatsapp-cli/internal/whatsapp"
 )
 
-var contactsQuery string
+var (
+	contactsQuery          string
+	contactsRegisteredOnly bool
+	contactsExportOutput   string
+)
 
 var contactsCmd = &cobra.Command{
 	Use:   "contacts",
 	Short: "List contacts",
-	Long:  `List all contacts from WhatsApp.`,
-	RunE:  runContacts,
+	Long: `List all contacts from WhatsApp.
+
+With --registered-only, phone number contacts are checked against WhatsApp and
+only those with an account are listed, annotated with their canonical JID.
+Checks are cached for a week, but the first run makes a network request per 50
+numbers.
+
+Examples:
+  whatsapp contacts --query john
+  whatsapp contacts --registered-only`,
+	RunE: runContacts,
+}
+
+var contactsExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export contacts as vCard",
+	Long: `Export contacts to a vCard (.vcf) file for backup or import into another
+address book. Each card has the contact's name and phone number, tagged with its
+WhatsApp ID. Contacts known only by LID are skipped unless their phone number is
+known.
+
+Examples:
+  whatsapp contacts export -o contacts.vcf
+  whatsapp contacts export --query smith > smiths.vcf`,
+	Args: cobra.NoArgs,
+	RunE: runContactsExport,
 }
 
 func init() {
 	rootCmd.AddCommand(contactsCmd)
-	contactsCmd.Flags().StringVar(&contactsQuery, "query", "", "Filter by name")
+	contactsCmd.AddCommand(contactsExportCmd)
+	contactsCmd.PersistentFlags().StringVar(&contactsQuery, "query", "", "Filter by name")
+	contactsExportCmd.Flags().StringVarP(&contactsExportOutput, "output", "o", "", "Output file (default: stdout)")
+	contactsCmd.Flags().BoolVar(&contactsRegisteredOnly, "registered-only", false, "Only contacts whose number is on WhatsApp (checks over the network)")
 }
 
 func runContacts(cmd *cobra.Command, args []string) error {
 	return WithConnection(func(db *store.DB, client *whatsapp.Client) error {
-		contacts, err := client.WA.Store.Contacts.GetAllContacts(context.Background())
+		result, err := loadContacts(client, contactsQuery)
 		if err != nil {
-			return fmt.Errorf("failed to get contacts: %w", err)
+			return err
 		}
 
-		var result []store.Contact
-		queryLower := strings.ToLower(contactsQuery)
-
-		for jid, contact := range contacts {
-			name := contact.FullName
-			if name == "" {
-				name = contact.PushName
-			}
-			if name == "" {
-				name = contact.BusinessName
+		if contactsRegisteredOnly {
+			if result, err = filterRegistered(client, result); err != nil {
+				return err
 			}
+		}
 
-			if contactsQuery != "" {
-				if !strings.Contains(strings.ToLower(name), queryLower) &&
-					!strings.Contains(jid.User, queryLower) {
-					continue
-				}
-			}
+		return Output(result)
+	})
+}
 
-			c := store.Contact{
-				JID:   jid.String(),
-				Phone: jid.User,
-			}
-			if name != "" {
-				c.Name = &name
+// loadContacts returns the contacts in the session store whose name or number
+// contains query.
+func loadContacts(client *whatsapp.Client, query string) ([]store.Contact, error) {
+	contacts, err := client.WA.Store.Contacts.GetAllContacts(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get contacts: %w", err)
+	}
+
+	var result []store.Contact
+	queryLower := strings.ToLower(query)
+
+	for jid, contact := range contacts {
+		name := contact.FullName
+		if name == "" {
+			name = contact.PushName
+		}
+		if name == "" {
+			name = contact.BusinessName
+		}
+
+		if query != "" {
+			if !strings.Contains(strings.ToLower(name), queryLower) &&
+				!strings.Contains(jid.User, queryLower) {
+				continue
 			}
-			result = append(result, c)
 		}
 
-		return Output(result)
+		c := store.Contact{
+			JID:   jid.String(),
+			Phone: jid.User,
+		}
+		if name != "" {
+			c.Name = &name
+		}
+		result = append(result, c)
+	}
+	return result, nil
+}
+
+func runContactsExport(cmd *cobra.Command, args []string) error {
+	return WithConnection(func(db *store.DB, client *whatsapp.Client) error {
+		contacts, err := loadContacts(client, contactsQuery)
+		if err != nil {
+			return err
+		}
+
+		cards, skipped := contactVCards(db, contacts)
+		var out strings.Builder
+		for _, card := range cards {
+			out.WriteString(whatsapp.FormatVCard(card))
+		}
+		if skipped > 0 {
+			OutputWarning("skipped %d contacts without a known phone number", skipped)
+		}
+
+		if contactsExportOutput == "" {
+			fmt.Print(out.String())
+			return nil
+		}
+
+		if err := os.WriteFile(contactsExportOutput, []byte(out.String()), 0600); err != nil {
+			return fmt.Errorf("failed to write file: %w", err)
+		}
+		return OutputResult(map[string]any{
+			"contacts": len(cards),
+			"skipped":  skipped,
+			"output":   contactsExportOutput,
+		}, fmt.Sprintf("Exported %d contacts to %s", len(cards), contactsExportOutput))
 	})
 }
+
+// contactVCards converts contacts to vCards sorted by name, resolving LID
+// contacts to their phone number. Contacts with no known number are skipped.
+func contactVCards(db *store.DB, contacts []store.Contact) ([]whatsapp.VCardContact, int) {
+	var cards []whatsapp.VCardContact
+	skipped := 0
+	for _, c := range contacts {
+		phone := c.Phone
+		switch {
+		case strings.HasSuffix(c.JID, "@"+types.DefaultUserServer):
+		case strings.HasSuffix(c.JID, "@"+types.HiddenUserServer):
+			phone, _, _ = db.GetLIDMapping(c.Phone)
+			phone = strings.TrimSuffix(phone, "@"+types.DefaultUserServer)
+		default:
+			phone = ""
+		}
+		if phone == "" {
+			skipped++
+			continue
+		}
+
+		card := whatsapp.VCardContact{Phone: phone}
+		if c.Name != nil {
+			card.Name = *c.Name
+		}
+		cards = append(cards, card)
+	}
+
+	sort.Slice(cards, func(i, j int) bool {
+		a, b := strings.ToLower(cards[i].Name), strings.ToLower(cards[j].Name)
+		if a != b {
+			return a < b
+		}
+		return cards[i].Phone < cards[j].Phone
+	})
+	return cards, skipped
+}
+
+// filterRegistered keeps contacts on WhatsApp, setting their canonical JID.
+// Only phone number contacts are checked; LID contacts always have an account.
+func filterRegistered(client *whatsapp.Client, contacts []store.Contact) ([]store.Contact, error) {
+	var phones []string
+	for _, c := range contacts {
+		if strings.HasSuffix(c.JID, "@"+types.DefaultUserServer) {
+			phones = append(phones, c.Phone)
+		}
+	}
+
+	regs, err := client.CheckRegistered(phones)
+	if err != nil {
+		return nil, err
+	}
+
+	var filtered []store.Contact
+	for _, c := range contacts {
+		canonical := c.JID
+		if strings.HasSuffix(c.JID, "@"+types.DefaultUserServer) {
+			reg := regs[c.Phone]
+			if !reg.Registered {
+				continue
+			}
+			canonical = reg.JID
+		}
+		c.CanonicalJID = &canonical
+		filtered = append(filtered, c)
+	}
+	return filtered, nil
+}
@@ -44,6 +44,7 @@ func runContext(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("failed to create client: %w", err)
 	}
+	defer client.Disconnect()
 
 	// Get connection status
 	status := store.ConnectionStatus{
@@ -63,7 +64,6 @@ func runContext(cmd *cobra.Command, args []string) error {
 					Device: device,
 				}
 			}
-			client.Disconnect()
 		}
 	}
 
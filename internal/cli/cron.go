@@ -0,0 +1,142 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSpec is a parsed five-field cron expression. Each field is a bitset of
+// the values it matches.
+type cronSpec struct {
+	minute, hour, dom, month, dow uint64
+	// As in cron, a day matches either day field when both are restricted.
+	domAny, dowAny bool
+}
+
+// cronField is one field of a cron expression and its allowed range.
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = [5]cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7}, // 0 and 7 are both Sunday
+}
+
+// cronHorizon bounds the search for the next run, so expressions that can
+// never match, like "0 0 30 2 *", fail instead of looping.
+const cronHorizon = 5 * 366 * 24 * time.Hour
+
+// parseCron parses "minute hour day-of-month month day-of-week", where each
+// field is *, a number, a range a-b or a list of them, optionally with a /step.
+func parseCron(expr string) (cronSpec, error) {
+	parts := strings.Fields(expr)
+	if len(parts) != len(cronFields) {
+		return cronSpec{}, fmt.Errorf("invalid cron expression %q: want 5 fields (minute hour day-of-month month day-of-week)", expr)
+	}
+
+	var sets [5]uint64
+	for i, part := range parts {
+		set, err := parseCronField(part, cronFields[i])
+		if err != nil {
+			return cronSpec{}, fmt.Errorf("invalid cron expression %q: %w", expr, err)
+		}
+		sets[i] = set
+	}
+	if sets[4]&(1<<7) != 0 {
+		sets[4] |= 1
+	}
+
+	return cronSpec{
+		minute: sets[0],
+		hour:   sets[1],
+		dom:    sets[2],
+		month:  sets[3],
+		dow:    sets[4],
+		domAny: strings.HasPrefix(parts[2], "*"),
+		dowAny: strings.HasPrefix(parts[4], "*"),
+	}, nil
+}
+
+// parseCronField returns the set of values a single field matches.
+func parseCronField(s string, f cronField) (uint64, error) {
+	var set uint64
+	for _, item := range strings.Split(s, ",") {
+		span, step := item, 1
+		if before, after, ok := strings.Cut(item, "/"); ok {
+			n, err := strconv.Atoi(after)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("bad step in %s %q", f.name, item)
+			}
+			span, step = before, n
+		}
+
+		lo, hi := f.min, f.max
+		if span != "*" {
+			first, last, isRange := strings.Cut(span, "-")
+			var err error
+			if lo, err = strconv.Atoi(first); err != nil {
+				return 0, fmt.Errorf("bad %s %q", f.name, item)
+			}
+			switch {
+			case isRange:
+				if hi, err = strconv.Atoi(last); err != nil || hi < lo {
+					return 0, fmt.Errorf("bad %s range %q", f.name, item)
+				}
+			case step == 1:
+				hi = lo
+			}
+			// A single value with a step, like 5/15, runs from it to the maximum
+		}
+		if lo < f.min || hi > f.max {
+			return 0, fmt.Errorf("%s %q is outside %d-%d", f.name, item, f.min, f.max)
+		}
+
+		for v := lo; v <= hi; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+// next returns the first minute after t that the expression matches, in t's
+// location.
+func (s cronSpec) next(t time.Time) (time.Time, error) {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	end := t.Add(cronHorizon)
+	for t.Before(end) {
+		switch {
+		case !hasBit(s.month, int(t.Month())):
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case !hasBit(s.hour, t.Hour()):
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case !hasBit(s.minute, t.Minute()):
+			t = t.Add(time.Minute)
+		default:
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cron expression never matches")
+}
+
+// dayMatches reports whether t's date matches the day-of-month and
+// day-of-week fields.
+func (s cronSpec) dayMatches(t time.Time) bool {
+	dom, dow := hasBit(s.dom, t.Day()), hasBit(s.dow, int(t.Weekday()))
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+func hasBit(set uint64, v int) bool {
+	return set&(1<<v) != 0
+}
@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/eddmann/whatsapp-cli/internal/store"
+)
+
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Local database maintenance",
+}
+
+var dbMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Apply pending schema migrations",
+	Long: `Apply any pending schema migrations to the local message database and
+report the schema version before and after.
+
+Migrations also run automatically whenever the database is opened; this
+command makes the upgrade explicit.
+
+Example:
+  whatsapp db migrate`,
+	Args: cobra.NoArgs,
+	RunE: runDBMigrate,
+}
+
+var dbStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show database size and row counts",
+	Long: `Show how much the local message database holds: the row count of each
+table, the file size (plus any write-ahead log not yet merged into it), the
+space free pages take (reclaimable with VACUUM), the approximate size of the
+search index, and the dates of the oldest and newest messages.
+
+Human, CSV and TSV output list one statistic per row; JSON keeps the structure.
+
+Examples:
+  whatsapp db stats
+  whatsapp db stats --format json`,
+	Args: cobra.NoArgs,
+	RunE: runDBStats,
+}
+
+func init() {
+	rootCmd.AddCommand(dbCmd)
+	dbCmd.AddCommand(dbMigrateCmd)
+	dbCmd.AddCommand(dbStatsCmd)
+}
+
+func runDBMigrate(cmd *cobra.Command, args []string) error {
+	if err := EnsureDirectories(); err != nil {
+		return fmt.Errorf("failed to create directories: %w", err)
+	}
+
+	db, err := store.OpenUnmigrated(GetMessagesDBPath())
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.CloseQuietly()
+
+	result, err := db.Migrate()
+	if err != nil {
+		return err
+	}
+
+	msg := fmt.Sprintf("Schema is up to date (version %d)", result.ToVersion)
+	if len(result.Applied) > 0 {
+		msg = fmt.Sprintf("Migrated schema from version %d to %d: %s",
+			result.FromVersion, result.ToVersion, strings.Join(result.Applied, ", "))
+	}
+
+	return OutputResult(result, msg)
+}
+
+// dbStatRow is one statistic in tabular db stats output.
+type dbStatRow struct {
+	Stat  string `json:"stat"`
+	Value string `json:"value"`
+}
+
+func runDBStats(cmd *cobra.Command, args []string) error {
+	return WithDB(func(db *store.DB) error {
+		stats, err := db.Stats()
+		if err != nil {
+			return fmt.Errorf("failed to read database stats: %w", err)
+		}
+
+		opts := GetOutputOptions()
+		switch {
+		case opts.Format == FormatHuman && opts.Template == "":
+			return Output(dbStatRows(stats, formatBytes))
+		case opts.Format == FormatCSV || opts.Format == FormatTSV:
+			return Output(dbStatRows(stats, func(n int64) string { return strconv.FormatInt(n, 10) }))
+		default:
+			return Output(stats)
+		}
+	})
+}
+
+// dbStatRows lists stats one per row, with sizes formatted by size.
+func dbStatRows(stats store.DatabaseStats, size func(int64) string) []dbStatRow {
+	rows := []dbStatRow{
+		{"path", stats.Path},
+		{"file_bytes", size(stats.FileBytes)},
+		{"wal_bytes", size(stats.WALBytes)},
+		{"free_bytes", size(stats.FreeBytes)},
+		{"fts_bytes", size(stats.FTSBytes)},
+	}
+	if stats.OldestMessage != nil {
+		rows = append(rows, dbStatRow{"oldest_message", stats.OldestMessage.Format(time.RFC3339)})
+	}
+	if stats.NewestMessage != nil {
+		rows = append(rows, dbStatRow{"newest_message", stats.NewestMessage.Format(time.RFC3339)})
+	}
+	for _, t := range stats.Tables {
+		rows = append(rows, dbStatRow{"rows." + t.Name, strconv.FormatInt(t.Rows, 10)})
+	}
+	return rows
+}
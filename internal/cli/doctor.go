@@ -10,7 +10,10 @@ import (
 	"github.com/eddmann/whatsapp-cli/internal/whatsapp"
 )
 
-var doctorConnect bool
+var (
+	doctorConnect bool
+	doctorFixFTS  bool
+)
 
 var doctorCmd = &cobra.Command{
 	Use:   "doctor",
@@ -20,14 +23,21 @@ var doctorCmd = &cobra.Command{
 Checks:
 - Config directory exists and is writable
 - Database is accessible
+- Search index matches the messages table (rebuilt with --repair-fts)
 - Session exists
-- Connection to WhatsApp (with --connect)`,
+- ffmpeg is installed (needed to send audio that isn't Ogg/Opus)
+- Connection to WhatsApp (with --connect)
+
+If search misses messages you know exist, the full-text index has probably
+drifted from the messages table. --repair-fts (alias --fix) rebuilds it.`,
 	RunE: runDoctor,
 }
 
 func init() {
 	rootCmd.AddCommand(doctorCmd)
 	doctorCmd.Flags().BoolVar(&doctorConnect, "connect", false, "Also test connection to WhatsApp")
+	doctorCmd.Flags().BoolVar(&doctorFixFTS, "repair-fts", false, "Rebuild the search index if it has drifted")
+	doctorCmd.Flags().BoolVar(&doctorFixFTS, "fix", false, "Alias for --repair-fts")
 }
 
 func runDoctor(cmd *cobra.Command, args []string) error {
@@ -77,6 +87,8 @@ func runDoctor(cmd *cobra.Command, args []string) error {
 	dbOK := false
 	dbStats := map[string]int{}
 
+	var ftsCheck map[string]any
+
 	db, err := store.Open(dbPath)
 	if err == nil {
 		dbOK = true
@@ -84,6 +96,7 @@ func runDoctor(cmd *cobra.Command, args []string) error {
 		msgs, _ := db.CountMessages()
 		dbStats["chats"] = chats
 		dbStats["messages"] = msgs
+		ftsCheck = checkSearchIndex(db)
 		db.CloseQuietly()
 	}
 
@@ -93,6 +106,11 @@ func runDoctor(cmd *cobra.Command, args []string) error {
 		"ok":    dbOK,
 		"stats": dbStats,
 	})
+	if ftsCheck != nil {
+		checks = append(checks, ftsCheck)
+	}
+
+	checks = append(checks, checkFFmpeg())
 
 	// Check session
 	sessionPath := GetSessionDBPath()
@@ -113,6 +131,7 @@ func runDoctor(cmd *cobra.Command, args []string) error {
 	if db, err := store.Open(GetMessagesDBPath()); err == nil {
 		if client, err := whatsapp.New(db, GetStoreDir(), false, nil); err == nil {
 			authenticated = client.IsAuthenticated()
+			client.Disconnect() // Releases the session lock for the connection test
 		}
 		db.CloseQuietly()
 	}
@@ -132,8 +151,8 @@ func runDoctor(cmd *cobra.Command, args []string) error {
 				if err := client.Connect(); err == nil {
 					connected = client.IsConnected()
 					loggedIn = client.IsLoggedIn()
-					client.Disconnect()
 				}
+				client.Disconnect()
 			}
 			db.CloseQuietly()
 		}
@@ -160,7 +179,7 @@ func runDoctor(cmd *cobra.Command, args []string) error {
 		"healthy": allOK,
 	}
 
-	if IsJSON() {
+	if IsJSON() || GetFormat() == FormatYAML {
 		return Output(result)
 	}
 
@@ -177,13 +196,18 @@ func runDoctor(cmd *cobra.Command, args []string) error {
 				ok = v
 			}
 			status := "FAIL"
-			if ok {
+			if warning, _ := check["warning"].(bool); ok && warning {
+				status = "WARN"
+			} else if ok {
 				status = "OK"
 			}
 			fmt.Printf("[%s] %s\n", status, name)
 			if path, exists := check["path"].(string); exists {
 				fmt.Printf("      Path: %s\n", path)
 			}
+			if detail, exists := check["detail"].(string); exists {
+				fmt.Printf("      %s\n", detail)
+			}
 		}
 
 		fmt.Println()
@@ -196,3 +220,61 @@ func runDoctor(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// checkFFmpeg reports whether ffmpeg is installed. Without it only audio
+// conversion is lost, so a missing ffmpeg is a warning rather than a failure.
+func checkFFmpeg() map[string]any {
+	check := map[string]any{"name": "ffmpeg available"}
+	path, err := whatsapp.FFmpegPath()
+	check["available"] = err == nil
+	check["ok"] = true
+	if err != nil {
+		check["warning"] = true
+		check["detail"] = "ffmpeg not found in PATH; install it to send audio other than Ogg/Opus"
+		return check
+	}
+	check["path"] = path
+	return check
+}
+
+// checkSearchIndex compares the search index with the messages table and, with
+// --repair-fts, rebuilds it when they differ.
+func checkSearchIndex(db *store.DB) map[string]any {
+	check := map[string]any{"name": "Search Index"}
+	if !db.HasFTS() {
+		check["available"] = false
+		check["ok"] = true
+		check["warning"] = true
+		check["detail"] = "Full-text search unavailable, search scans messages instead. Build with -tags sqlite_fts5 to enable it"
+		return check
+	}
+
+	status, err := db.CheckFTS()
+	if err != nil {
+		check["ok"] = false
+		check["detail"] = fmt.Sprintf("Could not read index: %v", err)
+		return check
+	}
+
+	if status.Drift() > 0 && doctorFixFTS {
+		if err := db.RebuildFTS(); err != nil {
+			check["detail"] = fmt.Sprintf("Rebuild failed: %v", err)
+		} else if status, err = db.CheckFTS(); err == nil {
+			check["repaired"] = true
+		}
+	}
+
+	check["messages"] = status.Messages
+	check["indexed"] = status.Indexed
+	check["drift"] = status.Drift()
+	check["ok"] = status.Drift() == 0
+	if _, exists := check["detail"]; !exists {
+		switch {
+		case check["repaired"] == true:
+			check["detail"] = fmt.Sprintf("Rebuilt index, %d messages indexed", status.Indexed)
+		case status.Drift() > 0:
+			check["detail"] = fmt.Sprintf("%d messages but %d indexed, run with --repair-fts to rebuild", status.Messages, status.Indexed)
+		}
+	}
+	return check
+}
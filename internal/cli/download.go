@@ -2,6 +2,10 @@ package cli
 
 import (
 	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/template"
 
 	"github.com/spf13/cobra"
 
@@ -9,7 +13,17 @@ import (
 	"github.com/eddmann/whatsapp-cli/internal/whatsapp"
 )
 
-var downloadChat string
+var (
+	downloadChat             string
+	downloadFilenameTemplate string
+	downloadForce            bool
+
+	downloadAllType      string
+	downloadAllAfter     string
+	downloadAllBefore    string
+	downloadAllTimeframe string
+	downloadAllProgress  bool
+)
 
 var downloadCmd = &cobra.Command{
 	Use:   "download <msg-id>",
@@ -17,29 +31,160 @@ var downloadCmd = &cobra.Command{
 	Long: `Download media (image, video, audio, document) from a message.
 
 Requires --chat to specify the chat JID.
-Files are saved to the store directory under the chat's folder.`,
+Files are saved to the store directory under the chat's folder.
+
+--filename-template names the saved file from the message instead of using the
+stored filename. Fields: {{.id}}, {{.chat}}, {{.sender}}, {{.sender_name}},
+{{.type}}, {{.timestamp}} (20060102_150405), {{.date}} (2006-01-02),
+{{.original}} (the stored filename) and {{.ext}} (its extension, with the dot).
+Path separators in the result are replaced, so files stay in the chat folder.
+
+The path is recorded as the message's local_path. Downloading the same message
+again returns that file without fetching it while it is still on disk; --force
+downloads it again, for example to save it under a new --filename-template.
+
+Examples:
+  whatsapp download ABC123 --chat 1234567890@s.whatsapp.net
+  whatsapp download ABC123 --chat 123456789-987654321@g.us --filename-template "{{.timestamp}}_{{.sender}}_{{.original}}"`,
 	Args: cobra.ExactArgs(1),
 	RunE: runDownload,
 }
 
+var downloadAllCmd = &cobra.Command{
+	Use:   "download-all <jid>",
+	Short: "Download all media in a chat",
+	Long: `Download every media message in a chat, oldest first, optionally filtered
+by type and time.
+
+Each file is saved as 'whatsapp download' would save it, so media already on
+disk is reused unless --force is given, and --filename-template works the same
+way. Progress is written to stderr and a summary to stdout; on a terminal, or
+with --progress, a bar (or, when stderr isn't a terminal, a periodic count)
+shows how many files are done. Messages synced
+without the details needed to download them are skipped, and a failed
+download doesn't stop the rest.
+
+Timeframe presets: last_hour, today, yesterday, last_3_days, this_week, last_week, this_month
+
+Examples:
+  whatsapp download-all 1234567890@s.whatsapp.net
+  whatsapp download-all 123456789-987654321@g.us --type image --timeframe this_month
+  whatsapp download-all 123456789-987654321@g.us --after 2026-01-01T00:00:00Z --filename-template "{{.date}}_{{.original}}"`,
+	Args: cobra.ExactArgs(1),
+	RunE: runDownloadAll,
+}
+
 func init() {
+	rootCmd.AddCommand(downloadAllCmd)
+	downloadAllCmd.Flags().StringVar(&downloadAllType, "type", "", "Only this media type (image, video, audio, document, sticker)")
+	downloadAllCmd.Flags().StringVar(&downloadAllAfter, "after", "", "Media sent after timestamp (RFC3339)")
+	downloadAllCmd.Flags().StringVar(&downloadAllBefore, "before", "", "Media sent before timestamp (RFC3339)")
+	downloadAllCmd.Flags().StringVar(&downloadAllTimeframe, "timeframe", "", "Timeframe preset (today, this_week, etc.)")
+	downloadAllCmd.Flags().BoolVar(&downloadForce, "force", false, "Download again even if a file is already on disk")
+	downloadAllCmd.Flags().StringVar(&downloadFilenameTemplate, "filename-template", "", "Name saved files with a template, e.g. \"{{.timestamp}}_{{.sender}}_{{.original}}\"")
+	downloadAllCmd.Flags().BoolVar(&downloadAllProgress, "progress", false, "Show files completed (default: on when stderr is a terminal)")
+	downloadAllCmd.MarkFlagsMutuallyExclusive("timeframe", "after")
+	downloadAllCmd.MarkFlagsMutuallyExclusive("timeframe", "before")
+
 	rootCmd.AddCommand(downloadCmd)
 	downloadCmd.Flags().StringVar(&downloadChat, "chat", "", "Chat JID (required)")
+	downloadCmd.Flags().BoolVar(&downloadForce, "force", false, "Download again even if the file is already on disk")
+	downloadCmd.Flags().StringVar(&downloadFilenameTemplate, "filename-template", "", "Name the saved file with a template, e.g. \"{{.timestamp}}_{{.sender}}_{{.original}}\"")
 	_ = downloadCmd.MarkFlagRequired("chat")
 }
 
 func runDownload(cmd *cobra.Command, args []string) error {
 	messageID := args[0]
 
+	var nameTemplate *template.Template
+	if downloadFilenameTemplate != "" {
+		var err error
+		if nameTemplate, err = whatsapp.ParseFilenameTemplate(downloadFilenameTemplate); err != nil {
+			return err
+		}
+	}
+
 	return WithConnection(func(db *store.DB, client *whatsapp.Client) error {
-		result, err := client.DownloadMedia(messageID, downloadChat)
+		result, err := client.DownloadMedia(messageID, downloadChat, nameTemplate, downloadForce)
 		if err != nil {
 			return fmt.Errorf("download failed: %w", err)
 		}
 
+		msg := fmt.Sprintf("Downloaded %s to %s", result.Filename, result.Path)
+		if result.Cached {
+			msg = fmt.Sprintf("Already downloaded to %s (use --force to download again)", result.Path)
+		}
 		return OutputResult(store.DownloadResult{
 			Filename: result.Filename,
 			Path:     result.Path,
-		}, fmt.Sprintf("Downloaded %s to %s", result.Filename, result.Path))
+			Cached:   result.Cached,
+		}, msg)
+	})
+}
+
+// downloadMediaTypes are the media types --type accepts.
+var downloadMediaTypes = []string{"image", "video", "audio", "document", "sticker"}
+
+func runDownloadAll(cmd *cobra.Command, args []string) error {
+	jid := args[0]
+
+	if downloadAllType != "" && !slices.Contains(downloadMediaTypes, downloadAllType) {
+		return fmt.Errorf("invalid --type %q (use %s)", downloadAllType, strings.Join(downloadMediaTypes, ", "))
+	}
+	after, before := downloadAllAfter, downloadAllBefore
+	if downloadAllTimeframe != "" {
+		var err error
+		if after, before, err = ParseTimeframe(downloadAllTimeframe); err != nil {
+			return err
+		}
+	}
+	var nameTemplate *template.Template
+	if downloadFilenameTemplate != "" {
+		var err error
+		if nameTemplate, err = whatsapp.ParseFilenameTemplate(downloadFilenameTemplate); err != nil {
+			return err
+		}
+	}
+
+	return WithConnection(func(db *store.DB, client *whatsapp.Client) error {
+		media, err := db.ListMediaMessages(jid, downloadAllType, after, before)
+		if err != nil {
+			return fmt.Errorf("failed to list media: %w", err)
+		}
+
+		bar := newProgress("Downloading", "files", len(media), progressEnabled(cmd, downloadAllProgress))
+		result := store.DownloadAllResult{ChatJID: jid}
+		for i, m := range media {
+			bar.set(i)
+			progress := fmt.Sprintf("[%d/%d] %s %s", i+1, len(media), m.MediaType, m.ID)
+			if !m.Complete {
+				result.Skipped++
+				bar.logf("%s: skipped, media info incomplete", progress)
+				continue
+			}
+
+			downloaded, err := client.DownloadMedia(m.ID, jid, nameTemplate, downloadForce)
+			switch {
+			case err != nil:
+				result.Failed++
+				result.Failures = append(result.Failures, store.DownloadFailure{MessageID: m.ID, Error: err.Error()})
+				bar.logf("%s: failed: %v", progress, err)
+			case downloaded.Cached:
+				result.Cached++
+				bar.logf("%s: already at %s", progress, downloaded.Path)
+			default:
+				result.Downloaded++
+				if info, err := os.Stat(downloaded.Path); err == nil {
+					result.Bytes += info.Size()
+				}
+				bar.logf("%s: %s", progress, downloaded.Path)
+			}
+		}
+		bar.set(len(media))
+		bar.finish()
+
+		msg := fmt.Sprintf("Downloaded %d files (%s); %d already on disk, %d skipped, %d failed",
+			result.Downloaded, formatBytes(result.Bytes), result.Cached, result.Skipped, result.Failed)
+		return OutputResult(result, msg)
 	})
 }
@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/eddmann/whatsapp-cli/internal/store"
+	"github.com/eddmann/whatsapp-cli/internal/whatsapp"
+)
+
+var (
+	editChat   string
+	editWindow time.Duration
+)
+
+var editCmd = &cobra.Command{
+	Use:   "edit <msg-id> <new-text>",
+	Short: "Edit a text message you sent",
+	Long: `Replace the text of a message you sent.
+
+Requires --chat to specify the chat JID. Only your own text messages can be
+edited, and only within WhatsApp's edit window (15 minutes), which fails with
+edit_window_expired. The stored message is updated too.
+
+Examples:
+  whatsapp edit ABC123 "Fixed typo" --chat 1234567890@s.whatsapp.net`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: runEdit,
+}
+
+func init() {
+	rootCmd.AddCommand(editCmd)
+	editCmd.Flags().StringVar(&editChat, "chat", "", "Chat JID (required)")
+	editCmd.Flags().DurationVar(&editWindow, "edit-window", whatsapp.DefaultEditWindow, "How old a message may be and still be edited")
+	_ = editCmd.MarkFlagRequired("chat")
+}
+
+func runEdit(cmd *cobra.Command, args []string) error {
+	messageID := args[0]
+	text := strings.Join(args[1:], " ")
+	if strings.TrimSpace(text) == "" {
+		return fmt.Errorf("new text can't be empty")
+	}
+
+	return WithConnection(func(db *store.DB, client *whatsapp.Client) error {
+		client.EditWindow = editWindow
+		result, err := client.EditMessage(editChat, messageID, text)
+		if err != nil {
+			return fmt.Errorf("edit failed: %w", err)
+		}
+
+		return OutputResult(store.SendResult{
+			MessageID: result.MessageID,
+			ChatJID:   result.ChatJID,
+			Timestamp: result.Timestamp,
+		}, fmt.Sprintf("Edited message %s", messageID))
+	})
+}
@@ -1,23 +1,53 @@
 package cli
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/eddmann/whatsapp-cli/internal/store"
 )
 
-var exportOutput string
+var (
+	exportOutput    string
+	exportFormat    string
+	exportWithMedia bool
+)
 
 var exportCmd = &cobra.Command{
 	Use:   "export <jid>",
 	Short: "Export chat history",
-	Long: `Export chat history to a JSON file.
+	Long: `Export chat history to a JSON file or an mbox mailbox.
+
+Exports all messages from the local database for the specified chat.
+
+--export-format mbox writes each message as an email, oldest first, for importing
+into a mail client such as Thunderbird (via ImportExportTools NG) or mutt
+(mutt -f chat.mbox). The sender is the From address, the chat name the
+Subject, and replies are threaded under the message they quote. With
+--with-media, media already downloaded with 'whatsapp download' or
+'whatsapp download-all' is attached; anything not yet downloaded is listed in
+a warning.
 
-Exports all messages from the local database for the specified chat.`,
+Examples:
+  whatsapp export 1234567890@s.whatsapp.net -o chat.json
+  whatsapp export 123456789-987654321@g.us --export-format mbox -o group.mbox
+  whatsapp download-all 123456789-987654321@g.us && whatsapp export 123456789-987654321@g.us --export-format mbox --with-media -o group.mbox`,
 	Args: cobra.ExactArgs(1),
 	RunE: runExport,
 }
@@ -25,32 +55,60 @@ Exports all messages from the local database for the specified chat.`,
 func init() {
 	rootCmd.AddCommand(exportCmd)
 	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "Output file (default: stdout)")
+	// Not --format, which still sets how the summary printed with -o is shown
+	exportCmd.Flags().StringVar(&exportFormat, "export-format", "json", "Export file format: json or mbox")
+	exportCmd.Flags().BoolVar(&exportWithMedia, "with-media", false, "Attach downloaded media to mbox messages")
 }
 
 func runExport(cmd *cobra.Command, args []string) error {
 	jid := args[0]
 
+	if exportFormat != "json" && exportFormat != "mbox" {
+		return fmt.Errorf("invalid --export-format %q: use json or mbox", exportFormat)
+	}
+	if exportWithMedia && exportFormat != "mbox" {
+		return fmt.Errorf("--with-media requires --export-format mbox")
+	}
+
 	return WithDB(func(db *store.DB) error {
-		messages, err := db.ListMessages(store.ListMessagesOptions{
+		opts := store.ListMessagesOptions{
 			ChatJID: jid,
 			Limit:   0, // No limit
-		})
+		}
+		if exportFormat == "mbox" {
+			// Mail clients expect a mailbox in the order it was received
+			opts.SortBy = "timestamp"
+		}
+		messages, err := db.ListMessages(opts)
 		if err != nil {
 			return fmt.Errorf("failed to list messages: %w", err)
 		}
 
 		chatName := db.GetChatName(jid)
 
-		exportData := map[string]any{
-			"jid":           jid,
-			"name":          chatName,
-			"message_count": len(messages),
-			"messages":      messages,
-		}
+		var data []byte
+		if exportFormat == "mbox" {
+			var buf bytes.Buffer
+			missing, err := writeMbox(&buf, chatName, messages, exportWithMedia)
+			if err != nil {
+				return fmt.Errorf("failed to write mbox: %w", err)
+			}
+			if missing > 0 {
+				OutputWarning("%d media files aren't downloaded and weren't attached; run 'whatsapp download-all %s' first", missing, jid)
+			}
+			data = buf.Bytes()
+		} else {
+			exportData := map[string]any{
+				"jid":           jid,
+				"name":          chatName,
+				"message_count": len(messages),
+				"messages":      messages,
+			}
 
-		data, err := json.MarshalIndent(exportData, "", "  ")
-		if err != nil {
-			return fmt.Errorf("failed to marshal: %w", err)
+			data, err = json.MarshalIndent(exportData, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to marshal: %w", err)
+			}
 		}
 
 		if exportOutput != "" {
@@ -64,7 +122,178 @@ func runExport(cmd *cobra.Command, args []string) error {
 			}, fmt.Sprintf("Exported %d messages to %s", len(messages), exportOutput))
 		}
 
-		fmt.Println(string(data))
-		return nil
+		if exportFormat == "json" {
+			fmt.Println(string(data))
+			return nil
+		}
+		_, err = os.Stdout.Write(data)
+		return err
+	})
+}
+
+// mboxFromLine matches body lines that mboxrd quotes with a '>' so readers
+// don't mistake them for the start of the next message.
+var mboxFromLine = regexp.MustCompile(`(?m)^(>*From )`)
+
+// writeMbox writes messages to w as an mboxrd mailbox, one email per message
+// with the chat name as the subject. With withMedia, downloaded media is
+// attached; it returns how many media messages had no downloaded file.
+func writeMbox(w io.Writer, chatName string, messages []store.Message, withMedia bool) (missing int, err error) {
+	bw := bufio.NewWriter(w)
+	for _, m := range messages {
+		var attachment string
+		if withMedia && m.MediaType != nil {
+			if m.LocalPath != nil {
+				if info, err := os.Stat(*m.LocalPath); err == nil && info.Mode().IsRegular() {
+					attachment = *m.LocalPath
+				}
+			}
+			if attachment == "" {
+				missing++
+			}
+		}
+
+		email, err := mboxEmail(chatName, m, attachment)
+		if err != nil {
+			return missing, fmt.Errorf("message %s: %w", m.ID, err)
+		}
+
+		from := mboxAddress(m).Address
+		fmt.Fprintf(bw, "From %s %s\n", from, m.Timestamp.UTC().Format(time.ANSIC))
+		bw.WriteString(mboxFromLine.ReplaceAllString(email, ">$1"))
+		bw.WriteString("\n")
+	}
+	return missing, bw.Flush()
+}
+
+// mboxEmail renders one message as an RFC 5322 email with LF line endings,
+// as mbox files use, attaching the file at attachment if it is set.
+func mboxEmail(chatName string, m store.Message, attachment string) (string, error) {
+	var b strings.Builder
+	header := func(k, v string) { fmt.Fprintf(&b, "%s: %s\n", k, v) }
+
+	header("From", mboxAddress(m).String())
+	header("Date", m.Timestamp.Format(time.RFC1123Z))
+	header("Subject", mime.QEncoding.Encode("utf-8", chatName))
+	header("Message-ID", mboxMessageID(m.ID))
+	if m.ReplyToID != nil {
+		header("In-Reply-To", mboxMessageID(*m.ReplyToID))
+		header("References", mboxMessageID(*m.ReplyToID))
+	}
+	header("MIME-Version", "1.0")
+
+	text, err := quotedPrintable(mboxBody(m))
+	if err != nil {
+		return "", err
+	}
+
+	if attachment == "" {
+		header("Content-Type", "text/plain; charset=utf-8")
+		header("Content-Transfer-Encoding", "quoted-printable")
+		b.WriteString("\n")
+		b.WriteString(text)
+		b.WriteString("\n")
+		return b.String(), nil
+	}
+
+	data, err := os.ReadFile(attachment)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", attachment, err)
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
 	})
+	if err != nil {
+		return "", err
+	}
+	io.WriteString(part, text)
+
+	name := filepath.Base(attachment)
+	// Drop any parameters, such as text/plain's charset, which may not apply
+	contentType, _, _ := strings.Cut(mime.TypeByExtension(filepath.Ext(name)), ";")
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	part, err = mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {mime.FormatMediaType(contentType, map[string]string{"name": name})},
+		"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": name})},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		io.WriteString(part, encoded[:76]+"\r\n")
+		encoded = encoded[76:]
+	}
+	io.WriteString(part, encoded)
+	if err := mw.Close(); err != nil {
+		return "", err
+	}
+
+	header("Content-Type", mime.FormatMediaType("multipart/mixed", map[string]string{"boundary": mw.Boundary()}))
+	b.WriteString("\n")
+	b.WriteString(strings.ReplaceAll(body.String(), "\r\n", "\n"))
+	return b.String(), nil
+}
+
+// mboxAddress is the From address for a message: the sender's WhatsApp JID,
+// named after their display name.
+func mboxAddress(m store.Message) *mail.Address {
+	addr := m.Sender
+	if !strings.Contains(addr, "@") {
+		addr += "@s.whatsapp.net"
+	}
+	name := m.Sender
+	switch {
+	case m.SenderName != nil:
+		name = *m.SenderName
+	case m.IsFromMe:
+		name = "Me"
+	}
+	return &mail.Address{Name: name, Address: addr}
+}
+
+// mboxMessageID turns a WhatsApp message ID into a Message-ID, so replies
+// thread and re-imports can be deduplicated.
+func mboxMessageID(id string) string {
+	return "<" + id + "@whatsapp-cli>"
+}
+
+// mboxBody is the text of a message, or a placeholder naming its media.
+func mboxBody(m store.Message) string {
+	text := ""
+	if m.Content != nil {
+		text = *m.Content
+	}
+	if m.MediaType == nil {
+		return text
+	}
+	placeholder := "[" + *m.MediaType
+	if m.Filename != nil {
+		placeholder += ": " + *m.Filename
+	}
+	placeholder += "]"
+	if text == "" {
+		return placeholder
+	}
+	return placeholder + "\n" + text
+}
+
+// quotedPrintable encodes s as quoted-printable with LF line endings.
+func quotedPrintable(s string) (string, error) {
+	var buf bytes.Buffer
+	qp := quotedprintable.NewWriter(&buf)
+	if _, err := io.WriteString(qp, s); err != nil {
+		return "", err
+	}
+	if err := qp.Close(); err != nil {
+		return "", err
+	}
+	return strings.ReplaceAll(buf.String(), "\r\n", "\n"), nil
 }
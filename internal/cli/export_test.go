@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eddmann/whatsapp-cli/internal/store"
+)
+
+func TestWriteMboxQuotesFromLinesAndThreadsReplies(t *testing.T) {
+	text := func(s string) *string { return &s }
+	name := "Alice"
+	at := time.Date(2026, 10, 1, 10, 0, 0, 0, time.UTC)
+	messages := []store.Message{
+		{ID: "m1", Sender: "447700900001", SenderName: &name, Content: text("hello\nFrom here on, café"), Timestamp: at},
+		{ID: "m2", Sender: "447700900002", IsFromMe: true, Content: text("hi"), Timestamp: at.Add(time.Minute), ReplyToID: text("m1")},
+	}
+
+	var buf bytes.Buffer
+	if _, err := writeMbox(&buf, "Book Club", messages, false); err != nil {
+		t.Fatalf("writeMbox failed: %v", err)
+	}
+	out := buf.String()
+
+	if !strings.HasPrefix(out, "From 447700900001@s.whatsapp.net Thu Oct  1 10:00:00 2026\n") {
+		t.Fatalf("unexpected envelope line in:\n%s", out)
+	}
+	if strings.Contains(out, "\nFrom here") || !strings.Contains(out, "\n>From here") {
+		t.Fatalf("expected the From line in the body to be quoted:\n%s", out)
+	}
+
+	emails := strings.Split(out, "\n\nFrom ")
+	if len(emails) != 2 {
+		t.Fatalf("expected 2 emails, got %d:\n%s", len(emails), out)
+	}
+	for i, raw := range emails {
+		_, email, _ := strings.Cut(raw, "\n") // Drop the envelope line
+		msg, err := mail.ReadMessage(strings.NewReader(email))
+		if err != nil {
+			t.Fatalf("email %d doesn't parse: %v", i, err)
+		}
+		if msg.Header.Get("Subject") != "Book Club" {
+			t.Fatalf("email %d: expected the chat name as subject, got %q", i, msg.Header.Get("Subject"))
+		}
+		if i == 1 && msg.Header.Get("In-Reply-To") != "<m1@whatsapp-cli>" {
+			t.Fatalf("expected the reply to thread under m1, got %q", msg.Header.Get("In-Reply-To"))
+		}
+	}
+	if !strings.Contains(out, `From: "Alice" <447700900001@s.whatsapp.net>`) || !strings.Contains(out, `From: "Me" <447700900002@s.whatsapp.net>`) {
+		t.Fatalf("unexpected From headers:\n%s", out)
+	}
+}
+
+func TestWriteMboxAttachesDownloadedMedia(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "photo.jpg")
+	if err := os.WriteFile(path, []byte("jpeg bytes"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	image := "image"
+	messages := []store.Message{
+		{ID: "m1", Sender: "a", MediaType: &image, LocalPath: &path, Timestamp: time.Now()},
+		{ID: "m2", Sender: "a", MediaType: &image, Timestamp: time.Now()},
+	}
+
+	var buf bytes.Buffer
+	missing, err := writeMbox(&buf, "Alice", messages, true)
+	if err != nil {
+		t.Fatalf("writeMbox failed: %v", err)
+	}
+	if missing != 1 {
+		t.Fatalf("expected 1 missing file, got %d", missing)
+	}
+
+	_, email, _ := strings.Cut(strings.Split(buf.String(), "\n\nFrom ")[0], "\n")
+	msg, err := mail.ReadMessage(strings.NewReader(email))
+	if err != nil {
+		t.Fatalf("email doesn't parse: %v", err)
+	}
+	_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatalf("bad Content-Type: %v", err)
+	}
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	if _, err := mr.NextPart(); err != nil {
+		t.Fatalf("missing text part: %v", err)
+	}
+	part, err := mr.NextPart()
+	if err != nil {
+		t.Fatalf("missing attachment: %v", err)
+	}
+	if part.FileName() != "photo.jpg" || part.Header.Get("Content-Type") != `image/jpeg; name=photo.jpg` {
+		t.Fatalf("unexpected attachment headers: %v", part.Header)
+	}
+	data, _ := io.ReadAll(part) // multipart decodes quoted-printable only, so this is base64
+	if string(data) != "anBlZyBieXRlcw==" {
+		t.Fatalf("unexpected attachment body %q", data)
+	}
+}
+
+func TestExportKeepsGlobalFormat(t *testing.T) {
+	if exportCmd.LocalFlags().Lookup("format") != nil {
+		t.Fatal("expected export not to shadow the global --format")
+	}
+	if exportCmd.InheritedFlags().Lookup("format") == nil {
+		t.Fatal("expected export to inherit the global --format")
+	}
+	if exportCmd.Flags().Lookup("export-format") == nil {
+		t.Fatal("expected an --export-format flag")
+	}
+}
@@ -3,6 +3,7 @@ package cli
 import (
 	"context"
 	"fmt"
+	"os"
 
 	"github.com/spf13/cobra"
 	"go.mau.fi/whatsmeow/types"
@@ -11,14 +12,117 @@ import (
 	"github.com/eddmann/whatsapp-cli/internal/whatsapp"
 )
 
+var groupsLocal bool
+
 var groupsCmd = &cobra.Command{
 	Use:   "groups [jid]",
 	Short: "List groups or show group info",
 	Long: `Without arguments, lists all groups.
-With a JID, shows detailed group info including members.`,
+With a JID, shows detailed group info including members.
+
+Group info is cached whenever it is fetched from WhatsApp and during history
+sync. --local reads that cache instead of connecting, so it works offline:
+without a JID it lists every cached group with its description, creator and
+member count, and with one it shows the group and its cached members. Cached
+entries include cached_at, when they were last refreshed.
+
+Examples:
+  whatsapp groups 123456789@g.us
+  whatsapp groups --local
+  whatsapp groups 123456789@g.us --local`,
 	RunE: runGroups,
 }
 
+var groupsMembersLive bool
+
+var groupsMembersCmd = &cobra.Command{
+	Use:   "members <jid>",
+	Short: "List group members from the local cache",
+	Long: `List the members of a group from the local participant cache.
+
+The cache is populated whenever group info is fetched ('whatsapp groups <jid>').
+Use --live to refresh it from WhatsApp.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runGroupsMembers,
+}
+
+var groupsCommonCmd = &cobra.Command{
+	Use:   "common <contact> <contact...>",
+	Short: "Find groups that contacts share",
+	Long: `List the groups that all of the given contacts are members of.
+
+Contacts may be phone numbers or JIDs. Only groups in the local participant
+cache are searched; it is filled whenever group info is fetched ('whatsapp
+groups <jid>' or 'whatsapp groups members <jid> --live').
+
+Examples:
+  whatsapp groups common 447700900001 447700900002
+  whatsapp groups common 447700900001@s.whatsapp.net 123456789012345@lid`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: runGroupsCommon,
+}
+
+var groupsCreateCmd = &cobra.Command{
+	Use:   "create <name> <participant...>",
+	Short: "Create a group",
+	Long: `Create a group with the given participants, as phone numbers or JIDs.
+You are added as its admin. Names are limited to 25 characters.
+
+WhatsApp creates the group even if some participants can't be added, for
+example when their privacy settings only allow invites. The result lists each
+participant's outcome, and the command fails if any weren't added.
+
+Examples:
+  whatsapp groups create "Book Club" 447700900001 447700900002
+  whatsapp groups create Family 447700900001@s.whatsapp.net`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: runGroupsCreate,
+}
+
+var groupsAddCmd = &cobra.Command{
+	Use:   "add <jid> <participant...>",
+	Short: "Add participants to a group",
+	Long: `Add participants, as phone numbers or JIDs, to a group you are an admin of.
+Reports the result for each participant; someone whose privacy settings only
+allow invites can't be added directly.
+
+Examples:
+  whatsapp groups add 123456789@g.us 447700900001 447700900002
+  whatsapp groups remove 123456789@g.us 447700900001`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return updateGroupParticipants(args[0], args[1:], whatsapp.GroupParticipantAdd)
+	},
+}
+
+var groupsRemoveCmd = &cobra.Command{
+	Use:   "remove <jid> <participant...>",
+	Short: "Remove participants from a group",
+	Long: `Remove participants, as phone numbers or JIDs, from a group you are an
+admin of. Reports the result for each participant.`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return updateGroupParticipants(args[0], args[1:], whatsapp.GroupParticipantRemove)
+	},
+}
+
+var groupsInviteReset bool
+
+var groupsInviteCmd = &cobra.Command{
+	Use:   "invite <jid>",
+	Short: "Show a group's invite link",
+	Long: `Show the invite link for a group you are an admin of. Anyone with the
+link can join the group, or ask to if it requires admin approval.
+
+--reset revokes the current link, so it stops working, and shows the new one.
+
+Examples:
+  whatsapp groups invite 123456789@g.us
+  whatsapp groups invite 123456789@g.us --reset`,
+	Args: cobra.ExactArgs(1),
+	RunE: runGroupsInvite,
+}
+
 var groupsJoinCmd = &cobra.Command{
 	Use:   "join <invite-code>",
 	Short: "Join a group via invite code",
@@ -40,14 +144,130 @@ var groupsRenameCmd = &cobra.Command{
 	RunE:  runGroupsRename,
 }
 
+var groupsTopicCmd = &cobra.Command{
+	Use:   "topic <jid> <text>",
+	Short: "Set a group's description",
+	Long: `Set a group's description (topic). Pass "" to clear it.
+
+If the group only lets admins edit its info, you must be an admin.
+
+Examples:
+  whatsapp groups topic 123456789@g.us "Meets every Thursday at 7pm"
+  whatsapp groups topic 123456789@g.us ""`,
+	Args: cobra.ExactArgs(2),
+	RunE: runGroupsTopic,
+}
+
+var groupsSetPhotoCmd = &cobra.Command{
+	Use:   "set-photo <jid> <image>",
+	Short: "Set a group's photo",
+	Long: `Set the photo of a group you are an admin of from a JPEG or PNG file.
+The image is cropped to a square around its centre and scaled down to
+640x640.
+
+Examples:
+  whatsapp groups set-photo 123456789@g.us team.jpg
+  whatsapp groups remove-photo 123456789@g.us`,
+	Args: cobra.ExactArgs(2),
+	RunE: runGroupsSetPhoto,
+}
+
+var groupsRemovePhotoCmd = &cobra.Command{
+	Use:   "remove-photo <jid>",
+	Short: "Remove a group's photo",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runGroupsRemovePhoto,
+}
+
+var (
+	groupsSettingsAnnounce bool
+	groupsSettingsLocked   bool
+)
+
+var groupsSettingsCmd = &cobra.Command{
+	Use:   "settings <jid>",
+	Short: "Show or change who can send messages and edit a group's info",
+	Long: `Show or change a group's settings. Changing them needs you to be an admin.
+
+--announce=true lets only admins send messages; --locked=true lets only admins
+edit the group's name, description and photo. A setting whose flag isn't given
+is left as it is, and with neither flag the current settings are shown.
+
+Examples:
+  whatsapp groups settings 123456789@g.us
+  whatsapp groups settings 123456789@g.us --announce=true
+  whatsapp groups settings 123456789@g.us --announce=false --locked=true`,
+	Args: cobra.ExactArgs(1),
+	RunE: runGroupsSettings,
+}
+
+var groupsRequestsCmd = &cobra.Command{
+	Use:   "requests <jid>",
+	Short: "List pending requests to join a group",
+	Long: `List the pending requests to join a group that requires admin approval.
+You must be an admin of the group.
+
+Examples:
+  whatsapp groups requests 123456789@g.us
+  whatsapp groups approve 123456789@g.us 447700900000
+  whatsapp groups reject 123456789@g.us 447700900001 447700900002`,
+	Args: cobra.ExactArgs(1),
+	RunE: runGroupsRequests,
+}
+
+var groupsApproveCmd = &cobra.Command{
+	Use:   "approve <jid> <member...>",
+	Short: "Approve pending requests to join a group",
+	Long: `Approve pending join requests. Members may be phone numbers or JIDs.
+Reports the result for each member.`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return updateGroupJoinRequests(args[0], args[1:], true)
+	},
+}
+
+var groupsRejectCmd = &cobra.Command{
+	Use:   "reject <jid> <member...>",
+	Short: "Reject pending requests to join a group",
+	Long: `Reject pending join requests. Members may be phone numbers or JIDs.
+Reports the result for each member.`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return updateGroupJoinRequests(args[0], args[1:], false)
+	},
+}
+
 func init() {
 	rootCmd.AddCommand(groupsCmd)
+	groupsCmd.AddCommand(groupsMembersCmd)
+	groupsCmd.AddCommand(groupsCommonCmd)
+	groupsCmd.AddCommand(groupsCreateCmd)
+	groupsCmd.AddCommand(groupsAddCmd)
+	groupsCmd.AddCommand(groupsRemoveCmd)
+	groupsCmd.AddCommand(groupsInviteCmd)
 	groupsCmd.AddCommand(groupsJoinCmd)
 	groupsCmd.AddCommand(groupsLeaveCmd)
 	groupsCmd.AddCommand(groupsRenameCmd)
+	groupsCmd.AddCommand(groupsTopicCmd)
+	groupsCmd.AddCommand(groupsSettingsCmd)
+	groupsCmd.AddCommand(groupsSetPhotoCmd)
+	groupsCmd.AddCommand(groupsRemovePhotoCmd)
+	groupsCmd.AddCommand(groupsRequestsCmd)
+	groupsCmd.AddCommand(groupsApproveCmd)
+	groupsCmd.AddCommand(groupsRejectCmd)
+
+	groupsCmd.Flags().BoolVar(&groupsLocal, "local", false, "Read cached group info without connecting")
+	groupsMembersCmd.Flags().BoolVar(&groupsMembersLive, "live", false, "Refresh members from WhatsApp before listing")
+	groupsInviteCmd.Flags().BoolVar(&groupsInviteReset, "reset", false, "Revoke the current link and show a new one")
+	groupsSettingsCmd.Flags().BoolVar(&groupsSettingsAnnounce, "announce", false, "Only admins can send messages (true or false)")
+	groupsSettingsCmd.Flags().BoolVar(&groupsSettingsLocked, "locked", false, "Only admins can edit the group info (true or false)")
 }
 
 func runGroups(cmd *cobra.Command, args []string) error {
+	if groupsLocal {
+		return runGroupsLocal(args)
+	}
+
 	return WithConnection(func(db *store.DB, client *whatsapp.Client) error {
 		// If JID provided, show group info
 		if len(args) > 0 {
@@ -56,66 +276,24 @@ func runGroups(cmd *cobra.Command, args []string) error {
 				return fmt.Errorf("invalid JID: %w", err)
 			}
 
-			info, err := client.WA.GetGroupInfo(context.Background(), jid)
+			info, err := client.GetGroupInfo(context.Background(), jid)
 			if err != nil {
 				return fmt.Errorf("failed to get group info: %w", err)
 			}
 
-			var participants []store.Participant
-			for _, p := range info.Participants {
-				name := ""
-				var lidStr, phoneStr *string
-
-				lookupJID := p.JID
-				if !p.PhoneNumber.IsEmpty() {
-					lookupJID = p.PhoneNumber
-				}
-
-				if contact, err := client.WA.Store.Contacts.GetContact(context.Background(), lookupJID); err == nil {
-					if contact.FullName != "" {
-						name = contact.FullName
-					} else if contact.PushName != "" {
-						name = contact.PushName
-					}
-				}
-
-				if name == "" && p.DisplayName != "" {
-					name = p.DisplayName
-				}
-
-				if !p.LID.IsEmpty() {
-					lid := p.LID.User
-					lidStr = &lid
-
-					phone := ""
-					if !p.PhoneNumber.IsEmpty() {
-						phone = p.PhoneNumber.User
-						phoneStr = &phone
-					}
-					_ = db.StoreLIDMapping(lid, phone, name)
-				}
-
-				if !p.PhoneNumber.IsEmpty() && phoneStr == nil {
-					phone := p.PhoneNumber.User
-					phoneStr = &phone
-				}
-
-				participants = append(participants, store.Participant{
-					JID:     p.JID.String(),
-					LID:     lidStr,
-					Phone:   phoneStr,
-					IsAdmin: p.IsAdmin || p.IsSuperAdmin,
-					Name:    name,
-				})
+			participants := resolveGroupParticipants(db, client, info)
+			if err := db.ReplaceGroupParticipants(info.JID.String(), participants); err != nil {
+				OutputWarning("failed to cache group participants: %v", err)
 			}
 
 			return Output(store.GroupInfo{
-				JID:          info.JID.String(),
-				Name:         info.Name,
-				Topic:        info.Topic,
-				Created:      info.GroupCreated,
-				CreatorJID:   info.OwnerJID.String(),
-				Participants: participants,
+				JID:              info.JID.String(),
+				Name:             info.Name,
+				Topic:            info.Topic,
+				Created:          info.GroupCreated,
+				CreatorJID:       info.OwnerJID.String(),
+				ParticipantCount: len(participants),
+				Participants:     participants,
 			})
 		}
 
@@ -129,6 +307,173 @@ func runGroups(cmd *cobra.Command, args []string) error {
 	})
 }
 
+// runGroupsLocal shows groups from the local cache, without connecting.
+func runGroupsLocal(args []string) error {
+	return WithDB(func(db *store.DB) error {
+		if len(args) == 0 {
+			groups, err := db.ListGroups()
+			if err != nil {
+				return fmt.Errorf("failed to list groups: %w", err)
+			}
+			return Output(nonNil(groups))
+		}
+
+		jid, err := types.ParseJID(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid JID: %w", err)
+		}
+		group, err := db.GetGroup(jid.String())
+		if err != nil {
+			return fmt.Errorf("failed to get group info: %w", err)
+		}
+		if group == nil {
+			return fmt.Errorf("no cached info for %s. Run without --local to fetch it", jid.String())
+		}
+		return Output(*group)
+	})
+}
+
+func runGroupsMembers(cmd *cobra.Command, args []string) error {
+	jid, err := types.ParseJID(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid JID: %w", err)
+	}
+
+	if groupsMembersLive {
+		return WithConnection(func(db *store.DB, client *whatsapp.Client) error {
+			info, err := client.GetGroupInfo(context.Background(), jid)
+			if err != nil {
+				return fmt.Errorf("failed to get group info: %w", err)
+			}
+
+			participants := resolveGroupParticipants(db, client, info)
+			if err := db.ReplaceGroupParticipants(info.JID.String(), participants); err != nil {
+				return fmt.Errorf("failed to cache group participants: %w", err)
+			}
+
+			return Output(participants)
+		})
+	}
+
+	return WithDB(func(db *store.DB) error {
+		participants, err := db.GetGroupParticipants(jid.String())
+		if err != nil {
+			return fmt.Errorf("failed to list group members: %w", err)
+		}
+		if len(participants) == 0 {
+			return fmt.Errorf("no cached members for %s. Run with --live to fetch them", jid.String())
+		}
+
+		return Output(participants)
+	})
+}
+
+func runGroupsCommon(cmd *cobra.Command, args []string) error {
+	return WithDB(func(db *store.DB) error {
+		cached, err := db.CountCachedGroups()
+		if err != nil {
+			return fmt.Errorf("failed to read group cache: %w", err)
+		}
+		if cached == 0 {
+			return fmt.Errorf("no group members are cached yet. Run 'whatsapp groups <jid>' for the groups to search first")
+		}
+
+		groups, err := db.GroupsContaining(args...)
+		if err != nil {
+			return fmt.Errorf("failed to find common groups: %w", err)
+		}
+		return Output(nonNil(groups))
+	})
+}
+
+// resolveGroupParticipants converts live group participants into store participants,
+// resolving display names and recording LID mappings along the way.
+func resolveGroupParticipants(db *store.DB, client *whatsapp.Client, info *types.GroupInfo) []store.Participant {
+	var participants []store.Participant
+	for _, p := range info.Participants {
+		name := ""
+		var lidStr, phoneStr *string
+
+		lookupJID := p.JID
+		if !p.PhoneNumber.IsEmpty() {
+			lookupJID = p.PhoneNumber
+		}
+
+		if contact, err := client.WA.Store.Contacts.GetContact(context.Background(), lookupJID); err == nil {
+			if contact.FullName != "" {
+				name = contact.FullName
+			} else if contact.PushName != "" {
+				name = contact.PushName
+			}
+		}
+
+		if name == "" && p.DisplayName != "" {
+			name = p.DisplayName
+		}
+
+		if !p.LID.IsEmpty() {
+			lid := p.LID.User
+			lidStr = &lid
+
+			phone := ""
+			if !p.PhoneNumber.IsEmpty() {
+				phone = p.PhoneNumber.User
+				phoneStr = &phone
+			}
+			_ = db.StoreLIDMapping(lid, phone, name)
+		}
+
+		if !p.PhoneNumber.IsEmpty() && phoneStr == nil {
+			phone := p.PhoneNumber.User
+			phoneStr = &phone
+		}
+
+		participants = append(participants, store.Participant{
+			JID:     p.JID.String(),
+			LID:     lidStr,
+			Phone:   phoneStr,
+			IsAdmin: p.IsAdmin || p.IsSuperAdmin,
+			Name:    name,
+		})
+	}
+	return participants
+}
+
+func runGroupsCreate(cmd *cobra.Command, args []string) error {
+	return WithConnection(func(db *store.DB, client *whatsapp.Client) error {
+		result, err := client.CreateGroup(args[0], args[1:])
+		if err != nil {
+			return err
+		}
+
+		failed := 0
+		for _, p := range result.Participants {
+			if !p.Success {
+				OutputWarning("couldn't add %s: %s", p.JID, p.Error)
+				failed++
+			}
+		}
+		if err := OutputResult(result, fmt.Sprintf("Created group '%s' (%s) with %d of %d participants",
+			result.Name, result.JID, len(result.Participants)-failed, len(result.Participants))); err != nil {
+			return err
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d participants couldn't be added to %s", failed, len(result.Participants), result.JID)
+		}
+		return nil
+	})
+}
+
+func runGroupsInvite(cmd *cobra.Command, args []string) error {
+	return WithConnection(func(db *store.DB, client *whatsapp.Client) error {
+		invite, err := client.GetGroupInviteLink(args[0], groupsInviteReset)
+		if err != nil {
+			return err
+		}
+		return OutputResult(invite, invite.Link)
+	})
+}
+
 func runGroupsJoin(cmd *cobra.Command, args []string) error {
 	inviteCode := args[0]
 
@@ -179,3 +524,135 @@ func runGroupsRename(cmd *cobra.Command, args []string) error {
 		}, fmt.Sprintf("Renamed group to '%s'", name))
 	})
 }
+
+func runGroupsTopic(cmd *cobra.Command, args []string) error {
+	return WithConnection(func(db *store.DB, client *whatsapp.Client) error {
+		result, err := client.SetGroupTopic(args[0], args[1])
+		if err != nil {
+			return err
+		}
+		if result.Topic == "" {
+			return OutputResult(result, fmt.Sprintf("Cleared the description of %s", result.JID))
+		}
+		return OutputResult(result, fmt.Sprintf("Set the description of %s", result.JID))
+	})
+}
+
+func runGroupsSettings(cmd *cobra.Command, args []string) error {
+	jid := args[0]
+	changeAnnounce := cmd.Flags().Changed("announce")
+	changeLocked := cmd.Flags().Changed("locked")
+
+	return WithConnection(func(db *store.DB, client *whatsapp.Client) error {
+		if changeAnnounce {
+			if err := client.SetGroupAnnounce(jid, groupsSettingsAnnounce); err != nil {
+				return err
+			}
+		}
+		if changeLocked {
+			if err := client.SetGroupLocked(jid, groupsSettingsLocked); err != nil {
+				return err
+			}
+		}
+
+		settings, err := client.GetGroupSettings(jid)
+		if err != nil {
+			return err
+		}
+		return OutputResult(settings, describeGroupSettings(settings))
+	})
+}
+
+// describeGroupSettings summarises a group's settings in a sentence.
+func describeGroupSettings(s store.GroupSettings) string {
+	send, edit := "Anyone", "anyone"
+	if s.Announce {
+		send = "Only admins"
+	}
+	if s.Locked {
+		edit = "only admins"
+	}
+	return fmt.Sprintf("%s can send messages in %s; %s can edit its info", send, s.JID, edit)
+}
+
+func runGroupsSetPhoto(cmd *cobra.Command, args []string) error {
+	if _, err := os.Stat(args[1]); err != nil {
+		return fmt.Errorf("cannot read image: %w", err)
+	}
+
+	return WithConnection(func(db *store.DB, client *whatsapp.Client) error {
+		photo, err := client.SetGroupPhoto(args[0], args[1])
+		if err != nil {
+			return err
+		}
+		return OutputResult(photo, fmt.Sprintf("Set the photo of %s (picture %s)", photo.JID, photo.PictureID))
+	})
+}
+
+func runGroupsRemovePhoto(cmd *cobra.Command, args []string) error {
+	return WithConnection(func(db *store.DB, client *whatsapp.Client) error {
+		photo, err := client.RemoveGroupPhoto(args[0])
+		if err != nil {
+			return err
+		}
+		return OutputResult(photo, fmt.Sprintf("Removed the photo of %s", photo.JID))
+	})
+}
+
+func runGroupsRequests(cmd *cobra.Command, args []string) error {
+	return WithConnection(func(db *store.DB, client *whatsapp.Client) error {
+		requests, err := client.GroupJoinRequests(args[0])
+		if err != nil {
+			return err
+		}
+		return Output(requests)
+	})
+}
+
+func updateGroupParticipants(groupJID string, participants []string, action string) error {
+	return WithConnection(func(db *store.DB, client *whatsapp.Client) error {
+		results, err := client.UpdateGroupParticipants(groupJID, participants, action)
+		if err != nil {
+			return err
+		}
+
+		if err := Output(results); err != nil {
+			return err
+		}
+
+		failed := 0
+		for _, r := range results {
+			if !r.Success {
+				failed++
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d participant changes failed", failed, len(results))
+		}
+		return nil
+	})
+}
+
+func updateGroupJoinRequests(groupJID string, members []string, approve bool) error {
+	return WithConnection(func(db *store.DB, client *whatsapp.Client) error {
+		results, err := client.UpdateGroupJoinRequests(groupJID, members, approve)
+		if err != nil {
+			return err
+		}
+
+		if err := Output(results); err != nil {
+			return err
+		}
+
+		failed := 0
+		for _, r := range results {
+			if !r.Success {
+				failed++
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d join requests failed", failed, len(results))
+		}
+		return nil
+	})
+}
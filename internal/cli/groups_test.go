@@ -0,0 +1,23 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/eddmann/whatsapp-cli/internal/store"
+)
+
+func TestDescribeGroupSettings(t *testing.T) {
+	tests := []struct {
+		settings store.GroupSettings
+		want     string
+	}{
+		{store.GroupSettings{JID: "1@g.us"}, "Anyone can send messages in 1@g.us; anyone can edit its info"},
+		{store.GroupSettings{JID: "1@g.us", Announce: true}, "Only admins can send messages in 1@g.us; anyone can edit its info"},
+		{store.GroupSettings{JID: "1@g.us", Announce: true, Locked: true}, "Only admins can send messages in 1@g.us; only admins can edit its info"},
+	}
+	for _, tt := range tests {
+		if got := describeGroupSettings(tt.settings); got != tt.want {
+			t.Errorf("describeGroupSettings(%+v) = %q, want %q", tt.settings, got, tt.want)
+		}
+	}
+}
@@ -1,8 +1,10 @@
 package cli
 
 import (
+	"bufio"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/eddmann/whatsapp-cli/internal/store"
 	"github.com/eddmann/whatsapp-cli/internal/whatsapp"
@@ -21,6 +23,10 @@ func WithDB(fn func(*store.DB) error) error {
 	}
 	defer db.CloseQuietly()
 
+	if db.FTSDropped() {
+		OutputWarning("SQLite FTS5 is not available, so the search index will fall behind. Build with: CGO_ENABLED=1 go build -tags sqlite_fts5")
+	}
+
 	// Auto-sync if needed
 	if err := maybeAutoSync(db); err != nil {
 		fmt.Fprintf(os.Stderr, "Auto-sync warning: %v\n", err)
@@ -43,24 +49,82 @@ func WithConnection(fn func(*store.DB, *whatsapp.Client) error) error {
 	}
 	defer db.CloseQuietly()
 
+	client, err := connectClient(db)
+	if err != nil {
+		return err
+	}
+	defer client.Disconnect()
+
+	// Auto-sync if needed (using existing connection)
+	if err := maybeAutoSyncWithClient(client, db); err != nil {
+		fmt.Fprintf(os.Stderr, "Auto-sync warning: %v\n", err)
+	}
+
+	return fn(db, client)
+}
+
+// connectClient creates a WhatsApp client, verifies authentication and connects.
+// The caller is responsible for disconnecting the returned client.
+func connectClient(db *store.DB) (*whatsapp.Client, error) {
 	client, err := whatsapp.New(db, GetStoreDir(), IsVerbose(), nil)
 	if err != nil {
-		return fmt.Errorf("failed to create client: %w", err)
+		return nil, fmt.Errorf("failed to create client: %w", err)
 	}
 
 	if !client.IsAuthenticated() {
-		return fmt.Errorf("not authenticated. Run 'whatsapp auth login' first")
+		client.Disconnect()
+		return nil, fmt.Errorf("not authenticated. Run 'whatsapp auth login' first")
 	}
 
 	if err := client.Connect(); err != nil {
-		return fmt.Errorf("connection failed: %w", err)
+		client.Disconnect()
+		return nil, fmt.Errorf("connection failed: %w", err)
 	}
-	defer client.Disconnect()
 
-	// Auto-sync if needed (using existing connection)
-	if err := maybeAutoSyncWithClient(client, db); err != nil {
-		fmt.Fprintf(os.Stderr, "Auto-sync warning: %v\n", err)
+	return client, nil
+}
+
+// sortDesc reports whether a --sort column is listed descending: timestamps
+// newest first and names A-Z, with --reverse flipping either.
+func sortDesc(sortBy string, reverse bool) bool {
+	desc := sortBy == "" || sortBy == "timestamp" || sortBy == "activity" || sortBy == "unread"
+	return desc != reverse
+}
+
+// validatePage checks a --page flag against its --limit. Pages start at 1,
+// and 0 is taken as the first page.
+func validatePage(page, limit int) error {
+	if page < 0 {
+		return fmt.Errorf("--page must not be negative")
 	}
+	if page > 1 && limit <= 0 {
+		return fmt.Errorf("--page requires a positive --limit")
+	}
+	return nil
+}
 
-	return fn(db, client)
+// isTerminal reports whether the file is attached to an interactive terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// confirm asks a yes/no question on stderr and reads the answer from stdin.
+// Non-interactive sessions are never confirmed; callers should offer a --yes flag.
+func confirm(prompt string) bool {
+	if !isTerminal(os.Stdin) {
+		return false
+	}
+
+	fmt.Fprintf(os.Stderr, "%s [y/N]: ", prompt)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
 }
@@ -0,0 +1,135 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"go.mau.fi/whatsmeow/types"
+
+	"github.com/eddmann/whatsapp-cli/internal/whatsapp"
+)
+
+var importOverwrite bool
+
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import data from other sources",
+}
+
+var importContactsCmd = &cobra.Command{
+	Use:   "contacts <file.vcf>",
+	Short: "Create aliases from a vCard file",
+	Long: `Create local aliases from the contacts in a vCard (.vcf) file, such as an
+address book export or the output of 'whatsapp contacts export'.
+
+Each contact's number is taken from the WhatsApp ID (waid) on its phone entry,
+or else its first number in international +format. Contacts without one are
+skipped, as are names already used for another JID. JIDs that already have an
+alias keep it unless --overwrite is given.
+
+Examples:
+  whatsapp import contacts contacts.vcf
+  whatsapp import contacts contacts.vcf --overwrite`,
+	Args: cobra.ExactArgs(1),
+	RunE: runImportContacts,
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+	importCmd.AddCommand(importContactsCmd)
+	importContactsCmd.Flags().BoolVar(&importOverwrite, "overwrite", false, "Replace existing aliases for the imported JIDs")
+}
+
+// aliasImportResult summarises an alias import.
+type aliasImportResult struct {
+	Created int               `json:"created"`
+	Updated int               `json:"updated"`
+	Skipped []aliasImportSkip `json:"skipped"`
+}
+
+// aliasImportSkip is a contact that didn't become an alias, and why.
+type aliasImportSkip struct {
+	Name   string `json:"name"`
+	Phone  string `json:"phone,omitempty"`
+	Reason string `json:"reason"`
+}
+
+func runImportContacts(cmd *cobra.Command, args []string) error {
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to read vCard file: %w", err)
+	}
+	contacts := whatsapp.ParseVCards(string(data))
+	if len(contacts) == 0 {
+		return fmt.Errorf("no contacts found in %s", args[0])
+	}
+
+	if err := EnsureDirectories(); err != nil {
+		return fmt.Errorf("failed to create directories: %w", err)
+	}
+	aliases, err := LoadAliases()
+	if err != nil {
+		return fmt.Errorf("failed to load aliases: %w", err)
+	}
+
+	result := importVCardAliases(aliases, contacts, importOverwrite)
+	if result.Created+result.Updated > 0 {
+		if err := aliases.Save(); err != nil {
+			return fmt.Errorf("failed to save aliases: %w", err)
+		}
+	}
+
+	return OutputResult(result, fmt.Sprintf("Created %d aliases, updated %d, skipped %d",
+		result.Created, result.Updated, len(result.Skipped)))
+}
+
+// importVCardAliases adds an alias for each contact with a name and number.
+func importVCardAliases(aliases Aliases, contacts []whatsapp.VCardContact, overwrite bool) aliasImportResult {
+	result := aliasImportResult{Skipped: []aliasImportSkip{}}
+
+	byJID := make(map[string]string, len(aliases))
+	for alias, jid := range aliases {
+		byJID[jid] = alias
+	}
+
+	for _, c := range contacts {
+		skip := func(reason string) {
+			result.Skipped = append(result.Skipped, aliasImportSkip{Name: c.Name, Phone: c.Phone, Reason: reason})
+		}
+
+		switch {
+		case c.Phone == "":
+			skip("no WhatsApp or international number")
+			continue
+		case c.Name == "":
+			skip("no name")
+			continue
+		}
+
+		jid := types.NewJID(c.Phone, types.DefaultUserServer).String()
+		if other, ok := aliases[c.Name]; ok && other != jid {
+			skip(fmt.Sprintf("name already used for %s", other))
+			continue
+		}
+
+		existing, hasAlias := byJID[jid]
+		switch {
+		case hasAlias && existing == c.Name:
+			skip("alias already set")
+			continue
+		case hasAlias && !overwrite:
+			skip(fmt.Sprintf("already has alias %q", existing))
+			continue
+		case hasAlias:
+			aliases.Remove(existing)
+			result.Updated++
+		default:
+			result.Created++
+		}
+
+		aliases.Set(c.Name, jid)
+		byJID[jid] = c.Name
+	}
+	return result
+}
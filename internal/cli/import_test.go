@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/eddmann/whatsapp-cli/internal/whatsapp"
+)
+
+func TestImportVCardAliases(t *testing.T) {
+	vcf := "BEGIN:VCARD\r\nVERSION:3.0\r\nFN:Jane Smith\r\nTEL;type=CELL;waid=447700900001:+44 7700 900001\r\nEND:VCARD\r\n" +
+		"BEGIN:VCARD\r\nVERSION:3.0\r\nN:Doe;John;;;\r\nitem1.TEL:+1 (555) 010-0002\r\nEND:VCARD\r\n" +
+		"BEGIN:VCARD\r\nVERSION:3.0\r\nFN:Local Only\r\nTEL:07700 900003\r\nEND:VCARD\r\n" +
+		"BEGIN:VCARD\r\nVERSION:3.0\r\nFN:Bob\r\nTEL;waid=447700900004:+447700900004\r\nEND:VCARD\r\n" +
+		"BEGIN:VCARD\r\nVERSION:3.0\r\nFN:Jane Smith\r\nTEL;waid=447700900005:+447700900005\r\nEND:VCARD\r\n"
+
+	contacts := whatsapp.ParseVCards(vcf)
+	if len(contacts) != 5 {
+		t.Fatalf("expected 5 contacts, got %+v", contacts)
+	}
+
+	aliases := Aliases{"Robert": "447700900004@s.whatsapp.net"}
+	result := importVCardAliases(aliases, contacts, false)
+
+	if result.Created != 2 || result.Updated != 0 || len(result.Skipped) != 3 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if aliases["Jane Smith"] != "447700900001@s.whatsapp.net" {
+		t.Errorf("expected Jane from waid, got %q", aliases["Jane Smith"])
+	}
+	if aliases["John Doe"] != "15550100002@s.whatsapp.net" {
+		t.Errorf("expected John from N and +number, got %q", aliases["John Doe"])
+	}
+	if aliases["Robert"] != "447700900004@s.whatsapp.net" || aliases["Bob"] != "" {
+		t.Errorf("expected existing alias kept without --overwrite, got %+v", aliases)
+	}
+
+	result = importVCardAliases(aliases, contacts, true)
+	if result.Updated != 1 || aliases["Bob"] != "447700900004@s.whatsapp.net" || aliases["Robert"] != "" {
+		t.Fatalf("expected --overwrite to replace Robert with Bob, got %+v / %+v", result, aliases)
+	}
+}
+
+func TestParseVCardsRoundTripsExport(t *testing.T) {
+	card := whatsapp.VCardContact{Name: "Smith, Jane; Work", Phone: "447700900000"}
+	got := whatsapp.ParseVCards(whatsapp.FormatVCard(card))
+	if len(got) != 1 || got[0] != card {
+		t.Fatalf("got %+v, want %+v", got, card)
+	}
+}
@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/eddmann/whatsapp-cli/internal/store"
+	"github.com/eddmann/whatsapp-cli/internal/whatsapp"
+)
+
+var (
+	locationLat     float64
+	locationLon     float64
+	locationName    string
+	locationAddress string
+	locationReplyTo string
+)
+
+var locationCmd = &cobra.Command{
+	Use:   "location <jid>",
+	Short: "Send a location pin",
+	Long: `Send a location pin to a chat.
+
+Requires --lat (-90 to 90) and --lon (-180 to 180). --name and --address are
+shown on the pin when given.
+
+Examples:
+  whatsapp location 1234567890@s.whatsapp.net --lat 51.5 --lon -0.12
+  whatsapp location 1234567890@s.whatsapp.net --lat 51.5 --lon -0.12 --name "Office" --address "1 High St"
+  whatsapp location 123456789-987654321@g.us --lat 51.5 --lon -0.12 --reply-to ABC123`,
+	Args: cobra.ExactArgs(1),
+	RunE: runLocation,
+}
+
+func init() {
+	rootCmd.AddCommand(locationCmd)
+	locationCmd.Flags().Float64Var(&locationLat, "lat", 0, "Latitude in degrees (required)")
+	locationCmd.Flags().Float64Var(&locationLon, "lon", 0, "Longitude in degrees (required)")
+	locationCmd.Flags().StringVar(&locationName, "name", "", "Place name shown on the pin")
+	locationCmd.Flags().StringVar(&locationAddress, "address", "", "Address shown on the pin")
+	locationCmd.Flags().StringVar(&locationReplyTo, "reply-to", "", "Message ID to reply to")
+	_ = locationCmd.MarkFlagRequired("lat")
+	_ = locationCmd.MarkFlagRequired("lon")
+}
+
+func runLocation(cmd *cobra.Command, args []string) error {
+	jid := args[0]
+
+	if err := whatsapp.ValidateCoordinates(locationLat, locationLon); err != nil {
+		return err
+	}
+
+	return WithConnection(func(db *store.DB, client *whatsapp.Client) error {
+		result, err := client.SendLocation(jid, locationLat, locationLon, locationName, locationAddress, locationReplyTo)
+		if err != nil {
+			return fmt.Errorf("send failed: %w", err)
+		}
+
+		return OutputResult(store.SendResult{
+			MessageID: result.MessageID,
+			ChatJID:   result.ChatJID,
+			Timestamp: result.Timestamp,
+		}, fmt.Sprintf("Sent location %s", result.MessageID))
+	})
+}
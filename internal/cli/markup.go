@@ -0,0 +1,191 @@
+package cli
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MarkupStyle controls how WhatsApp text formatting is rendered in output
+type MarkupStyle string
+
+const (
+	MarkupRaw      MarkupStyle = ""         // Leave WhatsApp markup untouched
+	MarkupPlain    MarkupStyle = "plain"    // Strip formatting markers
+	MarkupMarkdown MarkupStyle = "markdown" // Convert to standard Markdown
+)
+
+// markupFields lists the json field names that contain message text
+var markupFields = map[string]bool{
+	"content":      true,
+	"last_message": true,
+}
+
+// convertMarkup rewrites WhatsApp formatting (*bold*, _italic_, ~strike~, ```mono```)
+func convertMarkup(s string, style MarkupStyle) string {
+	switch style {
+	case MarkupPlain:
+		s = replaceDelimited(s, "```", true, func(inner string) string { return inner })
+		s = replaceDelimited(s, "*", false, func(inner string) string { return inner })
+		s = replaceDelimited(s, "_", false, func(inner string) string { return inner })
+		s = replaceDelimited(s, "~", false, func(inner string) string { return inner })
+	case MarkupMarkdown:
+		// _italic_ and ```mono``` are already valid Markdown
+		s = replaceDelimited(s, "~", false, func(inner string) string { return "~~" + inner + "~~" })
+		s = replaceDelimited(s, "*", false, func(inner string) string { return "**" + inner + "**" })
+	}
+	return s
+}
+
+// replaceDelimited replaces marker-delimited spans using WhatsApp's rules: the opening
+// marker must not follow a letter or digit and must be followed by non-space, the closing
+// marker must follow non-space and not be followed by a letter or digit.
+func replaceDelimited(s, marker string, multiline bool, wrap func(string) string) string {
+	var b strings.Builder
+	i := 0
+	for i < len(s) {
+		start := strings.Index(s[i:], marker)
+		if start < 0 {
+			break
+		}
+		start += i
+		innerStart := start + len(marker)
+
+		end := -1
+		if opensMarkup(s, start, innerStart) {
+			end = findClosingMarker(s, innerStart, marker, multiline)
+		}
+		if end < 0 {
+			b.WriteString(s[i:innerStart])
+			i = innerStart
+			continue
+		}
+
+		b.WriteString(s[i:start])
+		b.WriteString(wrap(s[innerStart:end]))
+		i = end + len(marker)
+	}
+	b.WriteString(s[i:])
+	return b.String()
+}
+
+func opensMarkup(s string, start, innerStart int) bool {
+	if start > 0 {
+		prev, _ := utf8.DecodeLastRuneInString(s[:start])
+		if isWordRune(prev) {
+			return false
+		}
+	}
+	if innerStart >= len(s) {
+		return false
+	}
+	next, _ := utf8.DecodeRuneInString(s[innerStart:])
+	return !unicode.IsSpace(next)
+}
+
+func findClosingMarker(s string, innerStart int, marker string, multiline bool) int {
+	for j := innerStart + 1; j+len(marker) <= len(s); j++ {
+		if !multiline && s[j-1] == '\n' {
+			return -1
+		}
+		if !strings.HasPrefix(s[j:], marker) {
+			continue
+		}
+		prev, _ := utf8.DecodeLastRuneInString(s[:j])
+		if unicode.IsSpace(prev) {
+			continue
+		}
+		if after := j + len(marker); after < len(s) {
+			next, _ := utf8.DecodeRuneInString(s[after:])
+			if isWordRune(next) {
+				continue
+			}
+		}
+		return j
+	}
+	return -1
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// applyMarkupStyle returns a copy of data with its message text fields
+// rewritten according to style. data itself, and anything it points to, is
+// left as it is.
+func applyMarkupStyle(data any, style MarkupStyle) any {
+	if style == MarkupRaw || data == nil {
+		return data
+	}
+	return copyWithMarkup(reflect.ValueOf(data), style).Interface()
+}
+
+// copyWithMarkup returns a deep copy of v with markup applied to the message
+// text fields of any structs in it. Maps and unexported fields are shared.
+func copyWithMarkup(v reflect.Value, style MarkupStyle) reflect.Value {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		cp := reflect.New(v.Type().Elem())
+		cp.Elem().Set(copyWithMarkup(v.Elem(), style))
+		return cp
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		cp := reflect.New(v.Type()).Elem()
+		cp.Set(copyWithMarkup(v.Elem(), style))
+		return cp
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		cp := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			cp.Index(i).Set(copyWithMarkup(v.Index(i), style))
+		}
+		return cp
+	case reflect.Array:
+		cp := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			cp.Index(i).Set(copyWithMarkup(v.Index(i), style))
+		}
+		return cp
+	case reflect.Struct:
+		cp := reflect.New(v.Type()).Elem()
+		cp.Set(v)
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !isExportedField(field) {
+				continue
+			}
+			if markupFields[getFieldName(field)] {
+				cp.Field(i).Set(markupString(v.Field(i), style))
+				continue
+			}
+			cp.Field(i).Set(copyWithMarkup(v.Field(i), style))
+		}
+		return cp
+	}
+	return v
+}
+
+// markupString returns a string or *string with markup applied, as a new
+// value. Other values are returned as they are.
+func markupString(v reflect.Value, style MarkupStyle) reflect.Value {
+	switch {
+	case v.Kind() == reflect.String:
+		cp := reflect.New(v.Type()).Elem()
+		cp.SetString(convertMarkup(v.String(), style))
+		return cp
+	case v.Kind() == reflect.Pointer && !v.IsNil() && v.Elem().Kind() == reflect.String:
+		cp := reflect.New(v.Type().Elem())
+		cp.Elem().SetString(convertMarkup(v.Elem().String(), style))
+		return cp
+	}
+	return v
+}
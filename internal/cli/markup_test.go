@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/eddmann/whatsapp-cli/internal/store"
+)
+
+func TestConvertMarkup(t *testing.T) {
+	tests := []struct {
+		in    string
+		style MarkupStyle
+		want  string
+	}{
+		{"*bold* and _italic_ ~gone~", MarkupPlain, "bold and italic gone"},
+		{"run ```make build``` now", MarkupPlain, "run make build now"},
+		{"*bold* and ~gone~", MarkupMarkdown, "**bold** and ~~gone~~"},
+		{"2*3*4 = 24", MarkupPlain, "2*3*4 = 24"},
+		{"snake_case_name", MarkupPlain, "snake_case_name"},
+		{"* not bold *", MarkupPlain, "* not bold *"},
+		{"*bold*", MarkupRaw, "*bold*"},
+	}
+
+	for _, tt := range tests {
+		if got := convertMarkup(tt.in, tt.style); got != tt.want {
+			t.Errorf("convertMarkup(%q, %q) = %q, want %q", tt.in, tt.style, got, tt.want)
+		}
+	}
+}
+
+func TestApplyMarkupStyleRewritesMessageContent(t *testing.T) {
+	content := "*hi*"
+	messages := []store.Message{{ID: "1", Content: &content}}
+
+	styled := applyMarkupStyle(messages, MarkupPlain).([]store.Message)
+
+	if got := *styled[0].Content; got != "hi" {
+		t.Fatalf("expected content to be stripped, got %q", got)
+	}
+	if messages[0].Content != &content || content != "*hi*" {
+		t.Fatalf("expected the caller's messages to be left untouched, got %q", *messages[0].Content)
+	}
+}
+
+func TestApplyMarkupStyleCopiesNestedValues(t *testing.T) {
+	content := "*hi*"
+	last := "_bye_"
+	chats := []chatWithMessages{{
+		Chat:           store.Chat{JID: "a@s.whatsapp.net", LastMessage: &last},
+		RecentMessages: []store.Message{{ID: "1", Content: &content}},
+	}}
+
+	styled := applyMarkupStyle(&chats, MarkupPlain).(*[]chatWithMessages)
+
+	got := (*styled)[0]
+	if *got.LastMessage != "bye" || *got.RecentMessages[0].Content != "hi" {
+		t.Fatalf("expected nested text stripped, got %q and %q", *got.LastMessage, *got.RecentMessages[0].Content)
+	}
+	if *chats[0].LastMessage != "_bye_" || *chats[0].RecentMessages[0].Content != "*hi*" {
+		t.Fatalf("expected the caller's chats to be left untouched, got %+v", chats[0])
+	}
+}
@@ -0,0 +1,155 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/eddmann/whatsapp-cli/internal/store"
+	"github.com/eddmann/whatsapp-cli/internal/whatsapp"
+)
+
+var mediaGCDryRun bool
+
+var mediaGCCmd = &cobra.Command{
+	Use:   "media-gc",
+	Short: "Remove downloaded media no longer referenced by any message",
+	Long: `Remove downloaded media files that don't belong to any message in the
+local database, for example after messages or chats have been pruned.
+
+Downloads are stored in one folder per chat under the store directory. A file
+is kept if a message in that chat has the same filename or was downloaded to
+it, as recorded in local_path. Use --dry-run to list
+what would be removed without deleting anything.
+
+Examples:
+  whatsapp media-gc --dry-run
+  whatsapp media-gc`,
+	Args: cobra.NoArgs,
+	RunE: runMediaGC,
+}
+
+func init() {
+	rootCmd.AddCommand(mediaGCCmd)
+	mediaGCCmd.Flags().BoolVar(&mediaGCDryRun, "dry-run", false, "List unreferenced files without removing them")
+}
+
+// orphanFile is a downloaded media file with no matching message.
+type orphanFile struct {
+	Path string
+	Size int64
+}
+
+func runMediaGC(cmd *cobra.Command, args []string) error {
+	return WithDB(func(db *store.DB) error {
+		known, err := db.MediaFilenames()
+		if err != nil {
+			return fmt.Errorf("failed to load media filenames: %w", err)
+		}
+
+		orphans, err := findOrphanMedia(GetStoreDir(), known)
+		if err != nil {
+			return fmt.Errorf("failed to scan media: %w", err)
+		}
+
+		result := store.MediaGCResult{DryRun: mediaGCDryRun, Files: []string{}}
+		for _, f := range orphans {
+			if !mediaGCDryRun {
+				if err := os.Remove(f.Path); err != nil {
+					return fmt.Errorf("failed to remove %s: %w", f.Path, err)
+				}
+			}
+			result.Files = append(result.Files, f.Path)
+			result.BytesReclaimed += f.Size
+		}
+
+		if !mediaGCDryRun {
+			removeEmptyMediaDirs(GetStoreDir())
+		}
+
+		verb := "Removed"
+		if mediaGCDryRun {
+			verb = "Would remove"
+		}
+		return OutputResult(result, fmt.Sprintf("%s %d files, %s", verb, len(result.Files), formatBytes(result.BytesReclaimed)))
+	})
+}
+
+// findOrphanMedia lists files in the per-chat download folders under storeDir
+// whose filename isn't stored for that chat in known.
+func findOrphanMedia(storeDir string, known map[string]map[string]bool) ([]orphanFile, error) {
+	byDir := make(map[string]map[string]bool, len(known))
+	for chatJID, files := range known {
+		byDir[whatsapp.MediaDirName(chatJID)] = files
+	}
+
+	entries, err := os.ReadDir(storeDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var orphans []orphanFile
+	for _, entry := range entries {
+		if !isMediaDir(entry) {
+			continue
+		}
+
+		dir := filepath.Join(storeDir, entry.Name())
+		files, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range files {
+			if !file.Type().IsRegular() || byDir[entry.Name()][file.Name()] {
+				continue
+			}
+			info, err := file.Info()
+			if err != nil {
+				return nil, err
+			}
+			orphans = append(orphans, orphanFile{Path: filepath.Join(dir, file.Name()), Size: info.Size()})
+		}
+	}
+	return orphans, nil
+}
+
+// removeEmptyMediaDirs removes per-chat download folders left empty by a collection.
+func removeEmptyMediaDirs(storeDir string) {
+	entries, err := os.ReadDir(storeDir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if isMediaDir(entry) {
+			// Remove fails on non-empty directories, which is what we want
+			_ = os.Remove(filepath.Join(storeDir, entry.Name()))
+		}
+	}
+}
+
+// isMediaDir reports whether entry is a per-chat download folder. Chat folders
+// are named after the chat JID, so they always contain an '@'.
+func isMediaDir(entry os.DirEntry) bool {
+	return entry.IsDir() && strings.Contains(entry.Name(), "@")
+}
+
+// formatBytes formats n as a human-readable size.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
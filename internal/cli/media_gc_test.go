@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindOrphanMedia(t *testing.T) {
+	storeDir := t.TempDir()
+	write := func(rel, data string) {
+		t.Helper()
+		path := filepath.Join(storeDir, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("messages.db", "db")
+	write("123@s.whatsapp.net/keep.jpg", "keep")
+	write("123@s.whatsapp.net/gone.jpg", "gone!")
+	write("456_1@s.whatsapp.net/keep.jpg", "device")
+	write("789@g.us/pruned.pdf", "pruned")
+
+	known := map[string]map[string]bool{
+		"123@s.whatsapp.net":   {"keep.jpg": true},
+		"456:1@s.whatsapp.net": {"keep.jpg": true},
+	}
+
+	orphans, err := findOrphanMedia(storeDir, known)
+	if err != nil {
+		t.Fatalf("findOrphanMedia: %v", err)
+	}
+
+	got := map[string]int64{}
+	for _, f := range orphans {
+		rel, _ := filepath.Rel(storeDir, f.Path)
+		got[rel] = f.Size
+	}
+	want := map[string]int64{
+		filepath.Join("123@s.whatsapp.net", "gone.jpg"): 5,
+		filepath.Join("789@g.us", "pruned.pdf"):         6,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for path, size := range want {
+		if got[path] != size {
+			t.Fatalf("expected %s (%d bytes), got %v", path, size, got)
+		}
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	cases := map[int64]string{
+		0:               "0 B",
+		1023:            "1023 B",
+		1536:            "1.5 KiB",
+		5 * 1024 * 1024: "5.0 MiB",
+	}
+	for n, want := range cases {
+		if got := formatBytes(n); got != want {
+			t.Errorf("formatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
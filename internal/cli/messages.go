@@ -2,6 +2,7 @@ package cli
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/spf13/cobra"
 
@@ -9,11 +10,25 @@ import (
 )
 
 var (
-	messagesLimit     int
-	messagesBefore    string
-	messagesAfter     string
-	messagesTimeframe string
-	messagesType      string
+	messagesLimit         int
+	messagesPage          int
+	messagesBefore        string
+	messagesAfter         string
+	messagesTimeframe     string
+	messagesType          string
+	messagesIncludeSystem bool
+	messagesHasMedia      bool
+	messagesForwarded     bool
+	messagesWithReplies   bool
+	messagesAfterID       string
+	messagesBeforeID      string
+	messagesThread        string
+	messagesUnreadOnly    bool
+	messagesTail          bool
+	messagesWithCursor    bool
+	messagesSort          string
+	messagesReverse       bool
+	messagesDedupe        bool
 )
 
 var messagesCmd = &cobra.Command{
@@ -23,7 +38,57 @@ var messagesCmd = &cobra.Command{
 
 Use 'whatsapp chats' to find the JID first.
 
-Timeframe presets: last_hour, today, yesterday, last_3_days, this_week, last_week, this_month`,
+System messages (group events, protocol notices) are only stored when syncing
+with 'whatsapp sync --include-system', and only listed with --include-system.
+
+Forwarded messages carry a forwarding_score, and forwarded_many_times is set
+once it reaches 4 (WhatsApp's "Forwarded many times" label). --forwarded lists
+only forwarded messages.
+
+--unread-only lists messages from others received after the last one you read,
+as recorded when 'whatsapp read' sends receipts or you read the chat on another
+device while syncing. If no read has been seen for the chat nothing is listed.
+
+--thread lists a message and every reply to it, including replies to replies,
+oldest first, so a threaded conversation can be read (or exported) in order.
+
+--before-id and --after-id page relative to a message instead, which stays
+stable while new messages arrive. The output then wraps the messages with a
+next_cursor (pass as --before-id for older messages) and a prev_cursor (pass as
+--after-id for newer ones).
+
+--json-lines-tail prints messages oldest first as JSON lines, whatever
+--format is, each with a "seq" number giving its position in the chat, so
+numbering carries on across runs, for appending to a log. Without
+--after-id it prints the newest --limit messages; with --after-id the next
+--limit messages after that one. --with-cursor ends the output with a
+{"cursor": ...} line holding the --after-id for the next run (the given cursor
+again if nothing new arrived).
+
+--sort orders by timestamp (newest first), sender or chat_name (A-Z), and
+--reverse flips it, so --reverse alone reads a chat in chronological order.
+--limit applies after sorting: with --reverse it keeps the oldest messages.
+
+--dedupe collapses each run of consecutive messages with the same sender and
+content into its first message, with a repeat_count of how many there were.
+Only adjacent repeats are collapsed, so a message sent again later still shows.
+
+--page steps through the listing --limit messages at a time, from page 1. It
+counts from the newest message, so a sync between pages shifts them; page with
+--before-id for a long chat that is still receiving messages.
+
+Timeframe presets: last_hour, today, yesterday, last_3_days, this_week, last_week, this_month
+
+Examples:
+  whatsapp messages 1234567890@s.whatsapp.net --limit 20
+  whatsapp messages 1234567890@s.whatsapp.net --limit 100 --page 3
+  whatsapp messages 123456789-987654321@g.us --forwarded
+  whatsapp messages 1234567890@s.whatsapp.net --unread-only
+  whatsapp messages 123456789-987654321@g.us --dedupe
+  whatsapp messages 1234567890@s.whatsapp.net --sort timestamp --reverse
+  whatsapp messages 123456789-987654321@g.us --thread ABC123 --with-replies
+  whatsapp messages 1234567890@s.whatsapp.net --before-id ABC123 --limit 20
+  whatsapp messages 1234567890@s.whatsapp.net --json-lines-tail --after-id ABC123 --with-cursor >> chat.log`,
 	Args: cobra.ExactArgs(1),
 	RunE: runMessages,
 }
@@ -31,15 +96,52 @@ Timeframe presets: last_hour, today, yesterday, last_3_days, this_week, last_wee
 func init() {
 	rootCmd.AddCommand(messagesCmd)
 	messagesCmd.Flags().IntVar(&messagesLimit, "limit", 50, "Maximum number of messages")
+	messagesCmd.Flags().IntVar(&messagesPage, "page", 1, "Page of --limit messages to show, from 1")
 	messagesCmd.Flags().StringVar(&messagesBefore, "before", "", "Messages before timestamp (RFC3339)")
 	messagesCmd.Flags().StringVar(&messagesAfter, "after", "", "Messages after timestamp (RFC3339)")
 	messagesCmd.Flags().StringVar(&messagesTimeframe, "timeframe", "", "Timeframe preset (today, yesterday, this_week, etc.)")
-	messagesCmd.Flags().StringVar(&messagesType, "type", "", "Filter by type (text, image, video, audio, document)")
+	messagesCmd.Flags().StringVar(&messagesType, "type", "", "Filter by type (text, image, video, audio, document, system)")
+	messagesCmd.Flags().BoolVar(&messagesHasMedia, "has-media", false, "Only messages with media of any type")
+	messagesCmd.Flags().BoolVar(&messagesHasMedia, "media-only", false, "Alias for --has-media")
+	messagesCmd.Flags().BoolVar(&messagesForwarded, "forwarded", false, "Only forwarded messages")
+	messagesCmd.Flags().BoolVar(&messagesUnreadOnly, "unread-only", false, "Only messages received after the last read receipt you sent")
+	messagesCmd.Flags().BoolVar(&messagesUnreadOnly, "since-last-read", false, "Alias for --unread-only")
+	messagesCmd.Flags().BoolVar(&messagesIncludeSystem, "include-system", false, "Include system messages (group events, protocol notices)")
+	messagesCmd.Flags().BoolVar(&messagesWithReplies, "with-replies", false, "Include a preview of the message each reply quotes")
+	messagesCmd.Flags().StringVar(&messagesBeforeID, "before-id", "", "Page to messages older than this message ID")
+	messagesCmd.Flags().StringVar(&messagesAfterID, "after-id", "", "Page to messages newer than this message ID")
+	messagesCmd.Flags().StringVar(&messagesThread, "thread", "", "List this message and all replies to it")
+	messagesCmd.Flags().StringVar(&messagesSort, "sort", "timestamp", "Sort by timestamp, sender or chat_name")
+	messagesCmd.Flags().BoolVar(&messagesReverse, "reverse", false, "Reverse the sort order (oldest first for timestamp)")
+	messagesCmd.Flags().BoolVar(&messagesDedupe, "dedupe", false, "Collapse consecutive messages with the same sender and content, with a repeat_count")
+	messagesCmd.Flags().BoolVar(&messagesTail, "json-lines-tail", false, "Print messages oldest first as JSON lines with a seq field, for appending to a log")
+	messagesCmd.Flags().BoolVar(&messagesWithCursor, "with-cursor", false, "End --json-lines-tail output with a line holding the next --after-id")
+	messagesCmd.MarkFlagsMutuallyExclusive("before-id", "after-id", "thread")
+	messagesCmd.MarkFlagsMutuallyExclusive("json-lines-tail", "before-id")
+	messagesCmd.MarkFlagsMutuallyExclusive("json-lines-tail", "thread")
+	messagesCmd.MarkFlagsMutuallyExclusive("json-lines-tail", "dedupe")
 }
 
 func runMessages(cmd *cobra.Command, args []string) error {
 	jid := args[0]
 
+	if messagesWithCursor && !messagesTail {
+		return fmt.Errorf("--with-cursor requires --json-lines-tail")
+	}
+	if messagesTail {
+		return runMessagesTail(cmd, jid)
+	}
+	if messagesBeforeID != "" || messagesAfterID != "" {
+		return runMessagesKeyset(cmd, jid)
+	}
+	if messagesThread != "" {
+		return runMessagesThread(cmd, jid)
+	}
+
+	if err := validatePage(messagesPage, messagesLimit); err != nil {
+		return err
+	}
+
 	// Parse timeframe if provided
 	after, before := messagesAfter, messagesBefore
 	if messagesTimeframe != "" {
@@ -51,16 +153,202 @@ func runMessages(cmd *cobra.Command, args []string) error {
 	}
 
 	return WithDB(func(db *store.DB) error {
+		if messagesUnreadOnly {
+			_, known, err := db.GetReadWatermark(jid)
+			if err != nil {
+				return fmt.Errorf("failed to load read watermark: %w", err)
+			}
+			if !known {
+				OutputWarning("no read receipt seen for %s yet, so nothing is known to be unread; run 'whatsapp read %s' to set one", jid, jid)
+			}
+		}
+
 		messages, err := db.ListMessages(store.ListMessagesOptions{
-			ChatJID: jid,
-			After:   after,
-			Before:  before,
-			Type:    messagesType,
-			Limit:   messagesLimit,
+			ChatJID:       jid,
+			After:         after,
+			Before:        before,
+			Type:          messagesType,
+			HasMedia:      messagesHasMedia,
+			Forwarded:     messagesForwarded,
+			UnreadOnly:    messagesUnreadOnly,
+			IncludeSystem: messagesIncludeSystem,
+			WithReplies:   messagesWithReplies,
+			SortBy:        messagesSort,
+			SortDesc:      sortDesc(messagesSort, messagesReverse),
+			Limit:         messagesLimit,
+			Page:          messagesPage,
 		})
 		if err != nil {
 			return fmt.Errorf("failed to list messages: %w", err)
 		}
+		if messagesDedupe {
+			messages = dedupeMessages(messages)
+		}
+		return Output(nonNil(messages))
+	})
+}
+
+// runMessagesThread lists the --thread message and its replies.
+func runMessagesThread(cmd *cobra.Command, jid string) error {
+	for _, name := range []string{"before", "after", "timeframe", "type", "has-media", "forwarded", "unread-only", "since-last-read", "include-system", "sort", "reverse", "page"} {
+		if cmd.Flags().Changed(name) {
+			return fmt.Errorf("--%s can't be combined with --thread", name)
+		}
+	}
+
+	return WithDB(func(db *store.DB) error {
+		messages, err := db.ListThread(jid, messagesThread, messagesWithReplies)
+		if err != nil {
+			return fmt.Errorf("failed to list thread: %w", err)
+		}
+		if messagesDedupe {
+			messages = dedupeMessages(messages)
+		}
 		return Output(messages)
 	})
 }
+
+// runMessagesKeyset lists one page of messages around --before-id/--after-id.
+func runMessagesKeyset(cmd *cobra.Command, jid string) error {
+	for _, name := range []string{"before", "after", "timeframe", "type", "has-media", "forwarded", "unread-only", "since-last-read", "include-system", "with-replies", "sort", "reverse", "page"} {
+		if cmd.Flags().Changed(name) {
+			return fmt.Errorf("--%s can't be combined with --before-id or --after-id", name)
+		}
+	}
+
+	cursor, direction := messagesBeforeID, store.PageBefore
+	if messagesAfterID != "" {
+		cursor, direction = messagesAfterID, store.PageAfter
+	}
+
+	return WithDB(func(db *store.DB) error {
+		page, err := db.ListMessagesKeyset(jid, cursor, direction, messagesLimit)
+		if err != nil {
+			return fmt.Errorf("failed to list messages: %w", err)
+		}
+		if messagesDedupe {
+			// The cursors come from the full page, so paging skips nothing
+			page.Messages = dedupeMessages(page.Messages)
+		}
+		return outputMessagePage(page)
+	})
+}
+
+// dedupeMessages collapses each run of adjacent messages with the same sender
+// and text into the first of them, counting the run in RepeatCount. Only text
+// messages are collapsed; media is kept even when captions repeat.
+func dedupeMessages(messages []store.Message) []store.Message {
+	out := make([]store.Message, 0, len(messages))
+	for _, m := range messages {
+		if n := len(out); n > 0 && sameContent(out[n-1], m) {
+			out[n-1].RepeatCount = max(out[n-1].RepeatCount, 1) + 1
+			continue
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+// sameContent reports whether a and b are the same text message from the same
+// sender.
+func sameContent(a, b store.Message) bool {
+	if a.Content == nil || b.Content == nil || *a.Content == "" {
+		return false
+	}
+	return a.Sender == b.Sender && *a.Content == *b.Content &&
+		a.MediaType == nil && b.MediaType == nil
+}
+
+// tailLine is one --json-lines-tail message, numbered by its position in the
+// chat.
+type tailLine struct {
+	Seq int `json:"seq"`
+	store.Message
+}
+
+// tailCursor is the closing --with-cursor line.
+type tailCursor struct {
+	Cursor string `json:"cursor"`
+}
+
+// runMessagesTail prints the newest page, or the page after --after-id, as
+// oldest-first JSON lines.
+func runMessagesTail(cmd *cobra.Command, jid string) error {
+	for _, name := range []string{"before", "after", "timeframe", "type", "has-media", "forwarded", "unread-only", "since-last-read", "include-system", "with-replies", "sort", "reverse", "page", "fields"} {
+		if cmd.Flags().Changed(name) {
+			return fmt.Errorf("--%s can't be combined with --json-lines-tail", name)
+		}
+	}
+
+	direction := store.PageBefore
+	if messagesAfterID != "" {
+		direction = store.PageAfter
+	}
+
+	return WithDB(func(db *store.DB) error {
+		page, err := db.ListMessagesKeyset(jid, messagesAfterID, direction, messagesLimit)
+		if err != nil {
+			return fmt.Errorf("failed to list messages: %w", err)
+		}
+
+		lines := make([]tailLine, len(page.Messages))
+		if len(lines) > 0 {
+			// Pages are newest first; the tail is oldest first
+			first, err := db.MessagePosition(jid, page.Messages[len(lines)-1].ID)
+			if err != nil {
+				return fmt.Errorf("failed to number messages: %w", err)
+			}
+			for i, m := range page.Messages {
+				lines[len(lines)-1-i] = tailLine{Seq: first + len(lines) - 1 - i, Message: m}
+			}
+		}
+		if err := outputJSONL(lines, nil, false); err != nil {
+			return err
+		}
+
+		if !messagesWithCursor {
+			return nil
+		}
+		cursor := messagesAfterID
+		if len(lines) > 0 {
+			cursor = lines[len(lines)-1].ID
+		}
+		return outputJSONL(tailCursor{Cursor: cursor}, nil, false)
+	})
+}
+
+// outputMessagePage prints a page of messages. Human, CSV/TSV and template
+// output list the messages and report the cursors on stderr; other formats get
+// the page with --fields applied to its messages.
+func outputMessagePage(page store.MessagePage) error {
+	opts := GetOutputOptions()
+	switch opts.Format {
+	case FormatHuman, FormatTable, FormatCSV, FormatTSV, FormatTemplate:
+		if opts.Template == "" {
+			if err := Output(nonNil(page.Messages)); err != nil {
+				return err
+			}
+			if page.NextCursor != "" {
+				fmt.Fprintf(os.Stderr, "older: --before-id %s\n", page.NextCursor)
+			}
+			if page.PrevCursor != "" {
+				fmt.Fprintf(os.Stderr, "newer: --after-id %s\n", page.PrevCursor)
+			}
+			return nil
+		}
+	}
+	data := messagePageOutput{
+		Messages:   filterFields(applyMarkupStyle(nonNil(page.Messages), opts.Markup), opts.Fields),
+		NextCursor: page.NextCursor,
+		PrevCursor: page.PrevCursor,
+	}
+	opts.Fields, opts.Markup = nil, MarkupRaw
+	return output(data, opts)
+}
+
+// messagePageOutput is a MessagePage whose messages have had --fields applied.
+type messagePageOutput struct {
+	Messages   any    `json:"messages"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
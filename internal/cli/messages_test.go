@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/eddmann/whatsapp-cli/internal/store"
+)
+
+func TestDedupeMessagesCollapsesAdjacentRepeats(t *testing.T) {
+	text := func(s string) *string { return &s }
+	image := "image"
+	messages := []store.Message{
+		{ID: "1", Sender: "bot", Content: text("ping")},
+		{ID: "2", Sender: "bot", Content: text("ping")},
+		{ID: "3", Sender: "bot", Content: text("ping")},
+		{ID: "4", Sender: "alice", Content: text("ping")},
+		{ID: "5", Sender: "bot", Content: text("ping")},
+		{ID: "6", Sender: "bot", Content: text("look"), MediaType: &image},
+		{ID: "7", Sender: "bot", Content: text("look"), MediaType: &image},
+	}
+
+	got := dedupeMessages(messages)
+	want := []struct {
+		id    string
+		count int
+	}{{"1", 3}, {"4", 0}, {"5", 0}, {"6", 0}, {"7", 0}}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d messages, got %+v", len(want), got)
+	}
+	for i, w := range want {
+		if got[i].ID != w.id || got[i].RepeatCount != w.count {
+			t.Fatalf("message %d: expected %s x%d, got %s x%d", i, w.id, w.count, got[i].ID, got[i].RepeatCount)
+		}
+	}
+	if messages[0].RepeatCount != 0 {
+		t.Fatal("expected the input to be left unchanged")
+	}
+}
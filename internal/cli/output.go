@@ -4,29 +4,35 @@ import (
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"reflect"
+	"slices"
 	"strings"
+	"text/template"
 	"time"
 
-	"github.com/olekukonko/tablewriter"
+	"gopkg.in/yaml.v3"
 )
 
 // Format represents the output format
 type Format string
 
 const (
-	FormatJSON  Format = "json"
-	FormatJSONL Format = "jsonl"
-	FormatCSV   Format = "csv"
-	FormatTSV   Format = "tsv"
-	FormatHuman Format = "human"
+	FormatJSON     Format = "json"
+	FormatJSONL    Format = "jsonl"
+	FormatCSV      Format = "csv"
+	FormatTSV      Format = "tsv"
+	FormatHuman    Format = "human"
+	FormatTable    Format = "table" // Alias for human
+	FormatYAML     Format = "yaml"
+	FormatTemplate Format = "template" // Each item through --template
 )
 
 // IsValid checks if a format string is valid
 func (f Format) IsValid() bool {
 	switch f {
-	case FormatJSON, FormatJSONL, FormatCSV, FormatTSV, FormatHuman:
+	case FormatJSON, FormatJSONL, FormatCSV, FormatTSV, FormatHuman, FormatTable, FormatYAML, FormatTemplate:
 		return true
 	}
 	return false
@@ -37,12 +43,35 @@ type OutputOptions struct {
 	Format   Format
 	Fields   []string // Field names to include (empty = all)
 	NoHeader bool     // Skip header row for CSV/TSV
+	Markup   MarkupStyle
+	Template string // Go template file for human output (empty = built-in rendering)
+	Flatten  bool   // Flatten nested objects into dotted keys (JSONL only)
+
+	LineTemplate string // Go template text rendered per item by --format template
+	Color        string // --color mode for human output: auto (or empty), always or never
 }
 
 // Validate checks if the options are valid
 func (o OutputOptions) Validate() error {
 	if !o.Format.IsValid() {
-		return fmt.Errorf("invalid format %q, valid formats: json, jsonl, csv, tsv, human", o.Format)
+		return fmt.Errorf("invalid format %q, valid formats: json, jsonl, yaml, csv, tsv, human, table, template", o.Format)
+	}
+	switch o.Color {
+	case "", ColorAuto, ColorAlways, ColorNever:
+	default:
+		return fmt.Errorf("invalid --color %q, use auto, always or never", o.Color)
+	}
+	if o.Format == FormatTemplate && o.LineTemplate == "" {
+		return fmt.Errorf("--format template requires --template, e.g. --template '{{.sender}}: {{.content}}'")
+	}
+	if o.LineTemplate != "" && o.Format != FormatTemplate {
+		return fmt.Errorf("--template is only supported with --format template")
+	}
+	if o.Template != "" && o.Format != FormatHuman && o.Format != FormatTable {
+		return fmt.Errorf("--human-template is only supported with --format human")
+	}
+	if o.Flatten && o.Format != FormatJSONL {
+		return fmt.Errorf("--flatten is only supported with --format jsonl")
 	}
 	return nil
 }
@@ -53,17 +82,26 @@ func output(data any, opts OutputOptions) error {
 		return err
 	}
 
+	data = applyMarkupStyle(data, opts.Markup)
+
 	switch opts.Format {
 	case FormatJSON:
 		return outputJSON(data, opts.Fields)
 	case FormatJSONL:
-		return outputJSONL(data, opts.Fields)
+		return outputJSONL(data, opts.Fields, opts.Flatten)
+	case FormatYAML:
+		return outputYAML(data, opts.Fields)
+	case FormatTemplate:
+		return outputLineTemplate(data, opts.LineTemplate, opts.Fields)
 	case FormatCSV:
 		return outputDelimited(data, ',', opts.Fields, opts.NoHeader)
 	case FormatTSV:
 		return outputDelimited(data, '\t', opts.Fields, opts.NoHeader)
-	case FormatHuman:
-		return outputHuman(data, opts.Fields)
+	case FormatHuman, FormatTable:
+		if opts.Template != "" {
+			return outputHumanTemplate(data, opts.Template, opts.Fields)
+		}
+		return outputHuman(data, opts.Fields, useColor(opts.Color, isTerminal(os.Stdout)))
 	default:
 		return outputJSON(data, opts.Fields)
 	}
@@ -77,19 +115,62 @@ func outputJSON(data any, fields []string) error {
 	return enc.Encode(data)
 }
 
-// outputJSONL prints data as JSON Lines (one JSON object per line)
-func outputJSONL(data any, fields []string) error {
+// outputYAML prints data as a YAML document.
+func outputYAML(data any, fields []string) error {
+	return writeYAML(os.Stdout, filterFields(data, fields))
+}
+
+// writeYAML writes data as YAML. It goes through JSON so keys, omitempty and
+// field order match the JSON output.
+func writeYAML(w io.Writer, data any) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return err
+	}
+	blockStyle(&doc)
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// blockStyle clears the flow and quoting styles JSON parses with, so the
+// encoder picks block style and only quotes strings that need it.
+func blockStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		blockStyle(c)
+	}
+}
+
+// outputJSONL prints data as JSON Lines (one JSON object per line), with
+// nested objects flattened into dotted keys if flat is set.
+func outputJSONL(data any, fields []string, flat bool) error {
 	v := derefValue(reflect.ValueOf(data))
 	if !v.IsValid() {
 		return nil
 	}
 
+	encode := func(enc *json.Encoder, item any) error {
+		item = filterFields(item, fields)
+		if flat {
+			item = flatten(item)
+		}
+		return enc.Encode(item)
+	}
+
 	// For slices/arrays, output each element on its own line
 	if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
 		enc := json.NewEncoder(os.Stdout)
 		for i := 0; i < v.Len(); i++ {
-			item := filterFields(v.Index(i).Interface(), fields)
-			if err := enc.Encode(item); err != nil {
+			if err := encode(enc, v.Index(i).Interface()); err != nil {
 				return fmt.Errorf("encode item %d: %w", i, err)
 			}
 		}
@@ -97,9 +178,7 @@ func outputJSONL(data any, fields []string) error {
 	}
 
 	// For single objects, output as one line
-	data = filterFields(data, fields)
-	enc := json.NewEncoder(os.Stdout)
-	return enc.Encode(data)
+	return encode(json.NewEncoder(os.Stdout), data)
 }
 
 // outputDelimited prints data as CSV or TSV using encoding/csv
@@ -129,7 +208,7 @@ func outputDelimited(data any, delimiter rune, fields []string, noHeader bool) e
 }
 
 // outputHuman prints data in human-readable format
-func outputHuman(data any, fields []string) error {
+func outputHuman(data any, fields []string, color bool) error {
 	v := derefValue(reflect.ValueOf(data))
 	if !v.IsValid() {
 		fmt.Println("(nil)")
@@ -138,48 +217,199 @@ func outputHuman(data any, fields []string) error {
 
 	switch v.Kind() {
 	case reflect.Slice, reflect.Array:
-		return outputTable(data, fields)
+		return outputTable(data, fields, color)
 	case reflect.Struct, reflect.Map:
-		return outputKeyValue(data, fields)
+		return outputKeyValue(data, fields, color)
 	default:
 		fmt.Println(data)
 		return nil
 	}
 }
 
+// humanTemplateFuncs are the helpers available to --human-template files. They
+// take any value because template data is decoded JSON, where missing fields are
+// nil and lists are []any.
+var humanTemplateFuncs = template.FuncMap{
+	"join": func(sep string, v any) string {
+		items, _ := v.([]any)
+		parts := make([]string, len(items))
+		for i, item := range items {
+			parts[i] = templateString(item)
+		}
+		return strings.Join(parts, sep)
+	},
+	"upper": func(v any) string { return strings.ToUpper(templateString(v)) },
+	"lower": func(v any) string { return strings.ToLower(templateString(v)) },
+	"truncate": func(n int, v any) string {
+		return truncateWidth(templateString(v), n, "...")
+	},
+	"indent": func(n int, v any) string {
+		pad := strings.Repeat(" ", n)
+		return pad + strings.ReplaceAll(templateString(v), "\n", "\n"+pad)
+	},
+}
+
+// templateString formats a decoded JSON value for display, with nil as "".
+func templateString(v any) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// outputHumanTemplate renders data with a user-supplied Go template.
+func outputHumanTemplate(data any, path string, fields []string) error {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read human template: %w", err)
+	}
+	tpl, err := template.New(path).Funcs(humanTemplateFuncs).Parse(string(src))
+	if err != nil {
+		return fmt.Errorf("invalid human template: %w", err)
+	}
+	return renderHumanTemplate(os.Stdout, tpl, filterFields(data, fields))
+}
+
+// outputLineTemplate renders data with an inline --template, once per item.
+func outputLineTemplate(data any, text string, fields []string) error {
+	tpl, err := template.New("template").Funcs(humanTemplateFuncs).Parse(text)
+	if err != nil {
+		return fmt.Errorf("invalid --template: %w", err)
+	}
+	return renderLineTemplate(os.Stdout, tpl, filterFields(data, fields))
+}
+
+// renderLineTemplate executes tpl against each element of data in its JSON
+// shape, or against data itself if it isn't a list, ending each with a newline.
+func renderLineTemplate(w io.Writer, tpl *template.Template, data any) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to prepare template data: %w", err)
+	}
+	var generic any
+	if err := json.Unmarshal(raw, &generic); err != nil {
+		return fmt.Errorf("failed to prepare template data: %w", err)
+	}
+
+	items, ok := generic.([]any)
+	if !ok {
+		items = []any{generic}
+	}
+	for _, item := range items {
+		if err := tpl.Execute(w, item); err != nil {
+			return fmt.Errorf("failed to render --template: %w", err)
+		}
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderHumanTemplate executes tpl against data in its JSON shape, so templates
+// use the same field names as --format json (e.g. {{.chat.name}}) and nested
+// structs can be ranged over.
+func renderHumanTemplate(w io.Writer, tpl *template.Template, data any) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to prepare template data: %w", err)
+	}
+	var generic any
+	if err := json.Unmarshal(raw, &generic); err != nil {
+		return fmt.Errorf("failed to prepare template data: %w", err)
+	}
+
+	if err := tpl.Execute(w, generic); err != nil {
+		return fmt.Errorf("failed to render human template: %w", err)
+	}
+	return nil
+}
+
 // outputTable prints a slice as a table
-func outputTable(data any, fields []string) error {
+func outputTable(data any, fields []string, color bool) error {
 	headers, rows := extractTableData(data, fields, formatHumanValue)
 	if len(rows) == 0 {
 		fmt.Println("(no results)")
 		return nil
 	}
 
+	var style func(row, col int) string
+	if color {
+		style = tableStyle(slices.Clone(headers), rows)
+	}
+
 	// Uppercase headers for human display
 	for i, h := range headers {
-		headers[i] = strings.ToUpper(h)
-	}
-
-	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader(headers)
-	table.SetAutoWrapText(false)
-	table.SetAutoFormatHeaders(true)
-	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
-	table.SetAlignment(tablewriter.ALIGN_LEFT)
-	table.SetCenterSeparator("")
-	table.SetColumnSeparator("")
-	table.SetRowSeparator("")
-	table.SetHeaderLine(false)
-	table.SetBorder(false)
-	table.SetTablePadding("  ")
-	table.SetNoWhiteSpace(true)
-	table.AppendBulk(rows)
-	table.Render()
-	return nil
+		headers[i] = strings.ToUpper(strings.ReplaceAll(h, "_", " "))
+	}
+
+	return renderStyledTable(os.Stdout, headers, rows, style)
+}
+
+// Colour modes for --color.
+const (
+	ColorAuto   = "auto"
+	ColorAlways = "always"
+	ColorNever  = "never"
+)
+
+// SGR codes used to colour human output.
+const (
+	sgrBold  = "1"
+	sgrDim   = "2"
+	sgrCyan  = "36"
+	sgrReset = "\x1b[0m"
+)
+
+// useColor reports whether human output is coloured. --color always and never
+// decide outright; auto colours only a terminal, and only if NO_COLOR is unset.
+func useColor(mode string, terminal bool) bool {
+	switch mode {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+	return terminal && os.Getenv("NO_COLOR") == ""
+}
+
+// colorize wraps s in the SGR codes, or returns it as-is if there are none.
+func colorize(s, codes string) string {
+	if codes == "" {
+		return s
+	}
+	return "\x1b[" + codes + "m" + s + sgrReset
+}
+
+// fieldStyle returns the SGR codes for a field: timestamps dim and sender
+// names bold.
+func fieldStyle(name string) string {
+	switch {
+	case name == "timestamp" || strings.HasSuffix(name, "_time") || strings.HasSuffix(name, "_at"):
+		return sgrDim
+	case name == "sender_name" || name == "last_sender":
+		return sgrBold
+	}
+	return ""
+}
+
+// tableStyle styles table cells by column with fieldStyle, and colours the
+// rows of your own messages.
+func tableStyle(headers []string, rows [][]string) func(row, col int) string {
+	fromMe := slices.IndexFunc(headers, func(h string) bool {
+		return h == "is_from_me" || h == "last_is_from_me"
+	})
+	return func(row, col int) string {
+		codes := fieldStyle(headers[col])
+		if fromMe >= 0 && rows[row][fromMe] == humanFormatterConfig.TrueValue {
+			codes = strings.TrimPrefix(codes+";"+sgrCyan, ";")
+		}
+		return codes
+	}
 }
 
 // outputKeyValue prints a struct or map as key-value pairs
-func outputKeyValue(data any, fields []string) error {
+func outputKeyValue(data any, fields []string, color bool) error {
 	v := derefValue(reflect.ValueOf(data))
 	if !v.IsValid() {
 		return nil
@@ -192,18 +422,14 @@ func outputKeyValue(data any, fields []string) error {
 		t := v.Type()
 		var pairs []struct{ name, value string }
 
-		for i := 0; i < t.NumField(); i++ {
-			field := t.Field(i)
-			if !isExportedField(field) {
-				continue
-			}
+		for _, field := range jsonFields(t) {
 			name := getFieldName(field)
 			if len(fieldSet) > 0 && !fieldSet[name] {
 				continue
 			}
 			pairs = append(pairs, struct{ name, value string }{
 				name:  name,
-				value: formatHumanValue(v.Field(i)),
+				value: formatHumanValue(fieldByIndex(v, field.Index)),
 			})
 		}
 
@@ -214,7 +440,11 @@ func outputKeyValue(data any, fields []string) error {
 			}
 		}
 		for _, p := range pairs {
-			fmt.Printf("%-*s  %s\n", maxLen, p.name+":", p.value)
+			value := p.value
+			if color {
+				value = colorize(value, fieldStyle(p.name))
+			}
+			fmt.Printf("%-*s  %s\n", maxLen, p.name+":", value)
 		}
 
 	case reflect.Map:
@@ -230,7 +460,11 @@ func outputKeyValue(data any, fields []string) error {
 			if len(fieldSet) > 0 && !fieldSet[keyStr] {
 				continue
 			}
-			fmt.Printf("%-*s  %s\n", maxLen, keyStr+":", formatHumanValue(v.MapIndex(key)))
+			value := formatHumanValue(v.MapIndex(key))
+			if color {
+				value = colorize(value, fieldStyle(keyStr))
+			}
+			fmt.Printf("%-*s  %s\n", maxLen, keyStr+":", value)
 		}
 	}
 	return nil
@@ -256,6 +490,41 @@ func isExportedField(f reflect.StructField) bool {
 	return f.PkgPath == ""
 }
 
+// jsonFields returns the exported fields of a struct type as encoding/json
+// sees them: the fields of an embedded struct without a json name are inlined,
+// with Index giving their path from t.
+func jsonFields(t reflect.Type) []reflect.StructField {
+	var fields []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		embedded := field.Type
+		if embedded.Kind() == reflect.Pointer {
+			embedded = embedded.Elem()
+		}
+		if field.Anonymous && embedded.Kind() == reflect.Struct && field.Tag.Get("json") == "" {
+			for _, inner := range jsonFields(embedded) {
+				inner.Index = append([]int{i}, inner.Index...)
+				fields = append(fields, inner)
+			}
+			continue
+		}
+		if isExportedField(field) {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// fieldByIndex returns the field of v at index, or the zero Value if it is
+// inside a nil embedded pointer.
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	field, err := v.FieldByIndexErr(index)
+	if err != nil {
+		return reflect.Value{}
+	}
+	return field
+}
+
 // getFieldName returns the json tag name or lowercased field name
 func getFieldName(f reflect.StructField) string {
 	name := f.Tag.Get("json")
@@ -311,19 +580,15 @@ func extractTableData(data any, fields []string, formatter func(reflect.Value) s
 	t := first.Type()
 	fieldSet := makeFieldSet(fields)
 	var headers []string
-	var indices []int
+	var indices [][]int
 
-	for i := 0; i < t.NumField(); i++ {
-		field := t.Field(i)
-		if !isExportedField(field) {
-			continue
-		}
+	for _, field := range jsonFields(t) {
 		name := getFieldName(field)
 		if len(fieldSet) > 0 && !fieldSet[name] {
 			continue
 		}
 		headers = append(headers, name)
-		indices = append(indices, i)
+		indices = append(indices, field.Index)
 	}
 
 	// Build rows
@@ -335,7 +600,7 @@ func extractTableData(data any, fields []string, formatter func(reflect.Value) s
 		}
 		var row []string
 		for _, idx := range indices {
-			row = append(row, formatter(elem.Field(idx)))
+			row = append(row, formatter(fieldByIndex(elem, idx)))
 		}
 		rows = append(rows, row)
 	}
@@ -382,21 +647,78 @@ func filterStructToMap(v reflect.Value, fieldSet map[string]bool) map[string]any
 	t := v.Type()
 	result := make(map[string]any)
 
-	for i := 0; i < t.NumField(); i++ {
-		field := t.Field(i)
-		if !isExportedField(field) {
-			continue
-		}
+	for _, field := range jsonFields(t) {
 		name := getFieldName(field)
 		if !fieldSet[name] {
 			continue
 		}
-		result[name] = v.Field(i).Interface()
+		if value := fieldByIndex(v, field.Index); value.IsValid() {
+			result[name] = value.Interface()
+		} else {
+			result[name] = nil
+		}
 	}
 
 	return result
 }
 
+// jsonMarshaler is kept whole by flatten, so values like time.Time encode as usual.
+var jsonMarshaler = reflect.TypeFor[json.Marshaler]()
+
+// flatten turns a struct or map into one flat map, naming nested values by
+// their dotted path (chat.jid). Every field is kept, with null for nil, so each
+// line has the same columns. Slices and other values are left as they are.
+func flatten(data any) any {
+	v := derefValue(reflect.ValueOf(data))
+	if !isFlattenable(v) {
+		return data
+	}
+	out := make(map[string]any)
+	flattenInto(out, "", v)
+	return out
+}
+
+// isFlattenable reports whether v is an object flatten should expand.
+func isFlattenable(v reflect.Value) bool {
+	if !v.IsValid() || v.Type().Implements(jsonMarshaler) || reflect.PointerTo(v.Type()).Implements(jsonMarshaler) {
+		return false
+	}
+	return v.Kind() == reflect.Struct || (v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String)
+}
+
+// flattenInto adds v's fields to out under prefix.
+func flattenInto(out map[string]any, prefix string, v reflect.Value) {
+	add := func(name string, field reflect.Value) {
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+		inner := derefValue(field)
+		switch {
+		case isFlattenable(inner):
+			flattenInto(out, key, inner)
+		case !inner.IsValid():
+			out[key] = nil
+		default:
+			out[key] = field.Interface()
+		}
+	}
+
+	if v.Kind() == reflect.Map {
+		for _, k := range v.MapKeys() {
+			add(k.String(), v.MapIndex(k))
+		}
+		return
+	}
+
+	for _, field := range jsonFields(v.Type()) {
+		if field.Tag.Get("json") == "-" {
+			continue
+		}
+		add(getFieldName(field), fieldByIndex(v, field.Index))
+	}
+}
+
 // ============================================================================
 // Value Formatters
 // ============================================================================
@@ -456,9 +778,9 @@ func formatValue(v reflect.Value, cfg valueFormatterConfig) string {
 		if t == "" {
 			return cfg.EmptyValue
 		}
-		// Truncate if configured
-		if cfg.MaxLength > 0 && len(t) > cfg.MaxLength {
-			return t[:cfg.MaxLength-3] + "..."
+		// Truncate by display width so multi-byte text isn't cut mid-rune
+		if cfg.MaxLength > 0 {
+			return truncateWidth(t, cfg.MaxLength, "...")
 		}
 		return t
 	case nil:
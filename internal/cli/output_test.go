@@ -0,0 +1,175 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/eddmann/whatsapp-cli/internal/store"
+)
+
+func TestRenderHumanTemplateUsesJSONNames(t *testing.T) {
+	tpl := template.Must(template.New("t").Funcs(humanTemplateFuncs).Parse(
+		`{{range .recent_chats}}{{upper .chat.name}}{{range .recent_messages}}
+  {{.sender}}: {{truncate 20 .content}}{{lower .media_type}}{{end}}
+{{end}}`))
+
+	name, hi, hello := "Alice", "hi", "hello"
+	data := store.ContextResult{
+		RecentChats: []store.ChatWithRecent{{
+			Chat: store.Chat{JID: "123@s.whatsapp.net", Name: &name},
+			RecentMessages: []store.Message{
+				{Sender: "Alice", Content: &hi},
+				{Sender: "me", Content: &hello},
+			},
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := renderHumanTemplate(&buf, tpl, data); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	want := "ALICE\n  Alice: hi\n  me: hello\n"
+	if buf.String() != want {
+		t.Fatalf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestWriteYAMLUsesJSONNames(t *testing.T) {
+	name, content := "Alice", "true"
+	ts := time.Date(2026, 4, 24, 12, 0, 0, 0, time.UTC)
+	data := []store.Message{
+		{ID: "m1", ChatJID: "123@s.whatsapp.net", Sender: "Alice", SenderName: &name, Content: &content, Timestamp: ts},
+	}
+
+	var buf bytes.Buffer
+	if err := writeYAML(&buf, data); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	want := `- id: m1
+  chat_jid: 123@s.whatsapp.net
+  sender: Alice
+  sender_name: Alice
+  content: "true"
+  timestamp: "2026-04-24T12:00:00Z"
+  is_from_me: false
+`
+	if buf.String() != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := writeYAML(&buf, store.TableStats{Name: "chats", Rows: 2}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if buf.String() != "name: chats\nrows: 2\n" {
+		t.Fatalf("unexpected single struct YAML %q", buf.String())
+	}
+}
+
+func TestRenderLineTemplatePerItem(t *testing.T) {
+	tpl := template.Must(template.New("t").Funcs(humanTemplateFuncs).Parse(`{{or .sender_name .sender}}: {{upper .content}}`))
+
+	name, hi, hello := "Alice", "hi", "hello"
+	data := []store.Message{
+		{Sender: "123@s.whatsapp.net", SenderName: &name, Content: &hi},
+		{Sender: "456@s.whatsapp.net", Content: &hello},
+	}
+
+	var buf bytes.Buffer
+	if err := renderLineTemplate(&buf, tpl, data); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if want := "Alice: HI\n456@s.whatsapp.net: HELLO\n"; buf.String() != want {
+		t.Fatalf("got %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := renderLineTemplate(&buf, tpl, data[0]); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if buf.String() != "Alice: HI\n" {
+		t.Fatalf("expected a single object rendered once, got %q", buf.String())
+	}
+}
+
+func TestLineTemplateNeedsTemplateFormat(t *testing.T) {
+	if err := (OutputOptions{Format: FormatTemplate}).Validate(); err == nil {
+		t.Error("expected --format template without --template to fail")
+	}
+	if err := (OutputOptions{Format: FormatJSON, LineTemplate: "{{.id}}"}).Validate(); err == nil {
+		t.Error("expected --template without --format template to fail")
+	}
+	if err := outputLineTemplate([]string{}, "{{.id", nil); err == nil || !strings.Contains(err.Error(), "invalid --template") {
+		t.Errorf("expected a parse error, got %v", err)
+	}
+}
+
+func TestHumanTemplateNeedsHumanFormat(t *testing.T) {
+	for _, format := range []Format{FormatHuman, FormatTable} {
+		if err := (OutputOptions{Format: format, Template: "view.tmpl"}).Validate(); err != nil {
+			t.Errorf("expected --human-template with --format %s to be allowed, got %v", format, err)
+		}
+	}
+	err := (OutputOptions{Format: FormatJSON, Template: "view.tmpl"}).Validate()
+	if err == nil || err.Error() != "--human-template is only supported with --format human" {
+		t.Errorf("expected --human-template with --format json to fail, got %v", err)
+	}
+}
+
+func TestUseColor(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	if !useColor(ColorAuto, true) || useColor(ColorAuto, false) {
+		t.Error("expected auto to colour only terminals")
+	}
+	if !useColor(ColorAlways, false) || useColor(ColorNever, true) {
+		t.Error("expected always and never to ignore the terminal")
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	if useColor(ColorAuto, true) {
+		t.Error("expected NO_COLOR to turn off auto colouring")
+	}
+	if !useColor(ColorAlways, true) {
+		t.Error("expected --color always to override NO_COLOR")
+	}
+}
+
+func TestFlattenUsesDottedKeys(t *testing.T) {
+	name := "Alice"
+	ts := time.Date(2026, 4, 24, 12, 0, 0, 0, time.UTC)
+	data := store.ChatWithRecent{
+		Chat:           store.Chat{JID: "123@s.whatsapp.net", Name: &name, LastMessageTime: &ts},
+		RecentMessages: []store.Message{{ID: "m1"}},
+	}
+
+	b, err := json.Marshal(flatten(data))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	if got["chat.jid"] != "123@s.whatsapp.net" || got["chat.name"] != "Alice" {
+		t.Fatalf("expected dotted chat keys, got %v", got)
+	}
+	if got["chat.last_message_time"] != "2026-04-24T12:00:00Z" {
+		t.Fatalf("expected the time kept whole, got %v", got["chat.last_message_time"])
+	}
+	if v, ok := got["chat.last_message"]; !ok || v != nil {
+		t.Fatalf("expected unset fields as null, got %v", got)
+	}
+	if _, ok := got["recent_messages"].([]any); !ok {
+		t.Fatalf("expected slices left as they are, got %v", got["recent_messages"])
+	}
+
+	fields := flatten(filterFields(data, []string{"chat"})).(map[string]any)
+	if _, ok := fields["chat.jid"]; !ok || len(fields) != len(got)-1 {
+		t.Fatalf("expected --fields output to flatten too, got %v", fields)
+	}
+}
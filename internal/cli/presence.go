@@ -0,0 +1,132 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/eddmann/whatsapp-cli/internal/store"
+	"github.com/eddmann/whatsapp-cli/internal/whatsapp"
+)
+
+var presenceDuration time.Duration
+
+// presenceRefresh is how often a held indicator is re-sent; WhatsApp clears
+// typing indicators on its own after roughly 25 seconds.
+const presenceRefresh = 10 * time.Second
+
+// keepOnlineRefresh is how often 'sync --follow --keep-online' re-sends that
+// the account is online.
+var keepOnlineRefresh = time.Minute
+
+var presenceCmd = &cobra.Command{
+	Use:   "presence <jid> <state>",
+	Short: "Show a typing or recording indicator in a chat",
+	Long: `Show a typing or recording indicator in a chat.
+
+State is one of composing (typing...), recording (recording audio...) or
+paused (clear the indicator).
+
+With --duration the indicator is held for that long and then cleared with
+paused. Ctrl-C clears it early.
+
+Examples:
+  whatsapp presence 1234567890@s.whatsapp.net composing
+  whatsapp presence 1234567890@s.whatsapp.net recording --duration 5s
+  whatsapp presence 123456789-987654321@g.us paused`,
+	Args: cobra.ExactArgs(2),
+	RunE: runPresence,
+}
+
+func init() {
+	rootCmd.AddCommand(presenceCmd)
+	presenceCmd.Flags().DurationVar(&presenceDuration, "duration", 0, "Hold the indicator this long, then send paused (e.g. 5s)")
+}
+
+func runPresence(cmd *cobra.Command, args []string) error {
+	jid, state := args[0], args[1]
+
+	if err := whatsapp.ValidateChatPresence(state); err != nil {
+		return err
+	}
+	if presenceDuration < 0 {
+		return fmt.Errorf("--duration must not be negative")
+	}
+	if presenceDuration > 0 && state == whatsapp.PresencePaused {
+		return fmt.Errorf("--duration needs composing or recording")
+	}
+
+	return WithConnection(func(db *store.DB, client *whatsapp.Client) error {
+		if err := client.SendChatPresence(jid, state); err != nil {
+			return fmt.Errorf("failed to send presence: %w", err)
+		}
+
+		if presenceDuration > 0 {
+			// Ctrl-C cuts the hold short but still clears the indicator
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+
+			if err := holdPresence(ctx, client, jid, state, presenceDuration); err != nil {
+				return err
+			}
+			if err := client.SendChatPresence(jid, whatsapp.PresencePaused); err != nil {
+				return fmt.Errorf("failed to send presence: %w", err)
+			}
+			state = whatsapp.PresencePaused
+		}
+
+		return OutputResult(store.PresenceResult{
+			ChatJID: jid,
+			State:   state,
+		}, fmt.Sprintf("Sent %s to %s", state, jid))
+	})
+}
+
+// keepOnline shows the account as online with setOnline, usually
+// Client.SetOnline, until ctx is cancelled, re-sending it every
+// keepOnlineRefresh in case a reconnect dropped it, and then marks it offline
+// again.
+func keepOnline(ctx context.Context, setOnline func(online bool) error) {
+	refresh := time.NewTicker(keepOnlineRefresh)
+	defer refresh.Stop()
+
+	for {
+		if err := setOnline(true); err != nil {
+			OutputWarning("failed to show as online: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			if err := setOnline(false); err != nil {
+				OutputWarning("failed to show as offline: %v", err)
+			}
+			return
+		case <-refresh.C:
+		}
+	}
+}
+
+// holdPresence keeps state showing for d, re-sending it before WhatsApp
+// expires it. It returns early, without error, when ctx is cancelled.
+func holdPresence(ctx context.Context, client *whatsapp.Client, jid, state string, d time.Duration) error {
+	deadline := time.NewTimer(d)
+	defer deadline.Stop()
+	refresh := time.NewTicker(presenceRefresh)
+	defer refresh.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-deadline.C:
+			return nil
+		case <-refresh.C:
+			if err := client.SendChatPresence(jid, state); err != nil {
+				return fmt.Errorf("failed to send presence: %w", err)
+			}
+		}
+	}
+}
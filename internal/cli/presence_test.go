@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestKeepOnlineRefreshesUntilCancelled(t *testing.T) {
+	orig := keepOnlineRefresh
+	keepOnlineRefresh = 5 * time.Millisecond
+	t.Cleanup(func() { keepOnlineRefresh = orig })
+
+	var mu sync.Mutex
+	var calls []bool
+	refreshed := make(chan struct{})
+	setOnline := func(online bool) error {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, online)
+		if len(calls) == 3 {
+			close(refreshed)
+		}
+		// A failure is only warned about; the next refresh tries again
+		if len(calls) == 1 {
+			return errors.New("not connected")
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		keepOnline(ctx, setOnline)
+	}()
+
+	select {
+	case <-refreshed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected online to be re-sent on each refresh")
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected keepOnline to return once cancelled")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	last := len(calls) - 1
+	if calls[last] {
+		t.Fatalf("expected to be shown offline last, got %v", calls)
+	}
+	for _, online := range calls[:last] {
+		if !online {
+			t.Fatalf("expected only online before cancelling, got %v", calls)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	// progressRedraw limits how often a terminal progress bar is redrawn.
+	progressRedraw = 100 * time.Millisecond
+	// progressLogInterval is how often progress is logged when stderr isn't a
+	// terminal.
+	progressLogInterval = 5 * time.Second
+	// progressBarWidth is the width of the bar itself, in characters.
+	progressBarWidth = 30
+)
+
+// progress reports how far a long operation has got on stderr: as a bar
+// redrawn in place on a terminal, or as a counter line every
+// progressLogInterval otherwise. A disabled progress only passes logf lines
+// through.
+type progress struct {
+	w       io.Writer
+	label   string
+	unit    string
+	total   int // 0 when the total isn't known
+	enabled bool
+	tty     bool
+
+	done  int
+	drawn bool // A bar is on the current line
+	last  time.Time
+	now   func() time.Time
+}
+
+// newProgress reports progress towards total (0 if unknown) units on stderr,
+// if enabled.
+func newProgress(label, unit string, total int, enabled bool) *progress {
+	return &progress{
+		w:       os.Stderr,
+		label:   label,
+		unit:    unit,
+		total:   total,
+		enabled: enabled,
+		tty:     isTerminal(os.Stderr),
+		now:     time.Now,
+	}
+}
+
+// progressEnabled reports whether a command's --progress flag asks for
+// progress, which it does by default when stderr is a terminal.
+func progressEnabled(cmd *cobra.Command, flag bool) bool {
+	if cmd.Flags().Changed("progress") {
+		return flag
+	}
+	return isTerminal(os.Stderr)
+}
+
+// set records that done units are complete.
+func (p *progress) set(done int) {
+	p.done = done
+	if !p.enabled {
+		return
+	}
+
+	now := p.now()
+	if p.tty {
+		if now.Sub(p.last) >= progressRedraw {
+			p.last = now
+			p.draw()
+		}
+		return
+	}
+	if p.last.IsZero() || now.Sub(p.last) >= progressLogInterval {
+		p.last = now
+		fmt.Fprintln(p.w, p.status())
+	}
+}
+
+// logf prints a line of its own, keeping any bar below it.
+func (p *progress) logf(format string, args ...any) {
+	p.clear()
+	fmt.Fprintf(p.w, format+"\n", args...)
+	if p.drawn {
+		p.draw()
+	}
+}
+
+// finish reports the final count and ends the bar's line.
+func (p *progress) finish() {
+	if !p.enabled {
+		return
+	}
+	if p.tty {
+		p.draw()
+		fmt.Fprintln(p.w)
+		p.drawn = false
+		return
+	}
+	fmt.Fprintln(p.w, p.status())
+}
+
+// draw redraws the bar in place.
+func (p *progress) draw() {
+	fmt.Fprintf(p.w, "\r\033[K%s", p.bar())
+	p.drawn = true
+}
+
+// clear erases the bar, if drawn, so a line can be printed in its place.
+func (p *progress) clear() {
+	if p.enabled && p.tty && p.drawn {
+		fmt.Fprint(p.w, "\r\033[K")
+	}
+}
+
+// bar renders the terminal progress bar.
+func (p *progress) bar() string {
+	if p.total <= 0 {
+		return p.status()
+	}
+	filled := min(p.done, p.total) * progressBarWidth / p.total
+	return fmt.Sprintf("%s [%s%s] %d/%d %s (%d%%)", p.label,
+		strings.Repeat("#", filled), strings.Repeat(".", progressBarWidth-filled),
+		p.done, p.total, p.unit, min(p.done, p.total)*100/p.total)
+}
+
+// status is a plain counter line.
+func (p *progress) status() string {
+	if p.total <= 0 {
+		return fmt.Sprintf("%s: %d %s", p.label, p.done, p.unit)
+	}
+	return fmt.Sprintf("%s: %d/%d %s", p.label, p.done, p.total, p.unit)
+}
@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testProgress(total int, tty bool) (*progress, *bytes.Buffer, *time.Time) {
+	var buf bytes.Buffer
+	clock := time.Date(2026, 10, 1, 10, 0, 0, 0, time.UTC)
+	p := &progress{w: &buf, label: "Downloading", unit: "files", total: total, enabled: true, tty: tty}
+	p.now = func() time.Time { return clock }
+	return p, &buf, &clock
+}
+
+func TestProgressLogsCounterWhenNotTerminal(t *testing.T) {
+	p, buf, clock := testProgress(4, false)
+
+	p.set(1)
+	p.set(2) // Within the interval, so not logged
+	*clock = clock.Add(progressLogInterval)
+	p.set(3)
+	p.logf("[4/4] image m4: done")
+	p.set(4)
+	p.finish()
+
+	want := "Downloading: 1/4 files\nDownloading: 3/4 files\n[4/4] image m4: done\nDownloading: 4/4 files\n"
+	if buf.String() != want {
+		t.Fatalf("unexpected output:\n%q\nwant:\n%q", buf.String(), want)
+	}
+}
+
+func TestProgressDrawsBarOnTerminal(t *testing.T) {
+	p, buf, _ := testProgress(4, true)
+
+	p.set(1)
+	p.logf("line")
+	p.set(2)
+	p.finish()
+
+	out := buf.String()
+	if !strings.HasPrefix(out, "\r\033[KDownloading [#######.......................] 1/4 files (25%)") {
+		t.Fatalf("expected the bar to be drawn first, got %q", out)
+	}
+	if !strings.Contains(out, "\r\033[Kline\n\r\033[KDownloading [#######") {
+		t.Fatalf("expected the line to be printed above the bar, got %q", out)
+	}
+	if !strings.HasSuffix(out, "2/4 files (50%)\n") {
+		t.Fatalf("expected finish to draw the final count and end the line, got %q", out)
+	}
+}
+
+func TestProgressDisabledOnlyLogs(t *testing.T) {
+	p, buf, _ := testProgress(0, true)
+	p.enabled = false
+
+	p.set(5)
+	p.logf("line")
+	p.finish()
+
+	if buf.String() != "line\n" {
+		t.Fatalf("expected only the logged line, got %q", buf.String())
+	}
+}
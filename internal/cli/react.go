@@ -21,6 +21,10 @@ var reactCmd = &cobra.Command{
 
 Requires --chat to specify the chat JID.
 
+The reaction must be a single emoji (ZWJ sequences, flags and skin tones count
+as one) or a shortcode: thumbsup, heart, joy, wow, cry, pray, thumbsdown, fire,
+tada, clap, eyes, 100, ok_hand, smile, check. Colons are optional (:heart:).
+
 Examples:
   whatsapp react ABC123 "thumbsup" --chat 1234567890@s.whatsapp.net
   whatsapp react ABC123 "" --chat 1234567890@s.whatsapp.net --remove`,
@@ -54,6 +58,12 @@ func runReact(cmd *cobra.Command, args []string) error {
 		emoji = args[1]
 	}
 
+	if !reactRemove {
+		if _, err := whatsapp.ValidateReaction(emoji); err != nil {
+			return err
+		}
+	}
+
 	return WithConnection(func(db *store.DB, client *whatsapp.Client) error {
 		result, err := client.SendReaction(reactChat, messageID, emoji, reactRemove)
 		if err != nil {
@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/eddmann/whatsapp-cli/internal/store"
+)
+
+var reactionsChat string
+
+var reactionsCmd = &cobra.Command{
+	Use:   "reactions <msg-id>",
+	Short: "List reactions to a message",
+	Long: `List who reacted to a message, and with which emoji, from the local database.
+
+Reactions are recorded as they arrive while syncing, and when sent with
+'whatsapp react'. A removed reaction is no longer listed.
+
+Requires --chat to specify the chat JID.
+
+Examples:
+  whatsapp reactions ABC123 --chat 1234567890@s.whatsapp.net
+  whatsapp reactions ABC123 --chat 123456789-987654321@g.us --format json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runReactions,
+}
+
+func init() {
+	rootCmd.AddCommand(reactionsCmd)
+	reactionsCmd.Flags().StringVar(&reactionsChat, "chat", "", "Chat JID (required)")
+	_ = reactionsCmd.MarkFlagRequired("chat")
+}
+
+func runReactions(cmd *cobra.Command, args []string) error {
+	return WithDB(func(db *store.DB) error {
+		reactions, err := db.ListReactions(reactionsChat, args[0])
+		if err != nil {
+			return fmt.Errorf("failed to list reactions: %w", err)
+		}
+		return Output(nonNil(reactions))
+	})
+}
@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/eddmann/whatsapp-cli/internal/store"
+	"github.com/eddmann/whatsapp-cli/internal/whatsapp"
+)
+
+var readLimit int
+
+var readCmd = &cobra.Command{
+	Use:   "read <jid> [msg-id...]",
+	Short: "Mark messages as read",
+	Long: `Send read receipts for messages in a chat.
+
+With no message IDs, the latest --limit messages from others in the chat (from
+the local database) are marked read. Your own messages are skipped.
+
+Examples:
+  whatsapp read 1234567890@s.whatsapp.net
+  whatsapp read 123456789-987654321@g.us ABC123 DEF456`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runRead,
+}
+
+func init() {
+	rootCmd.AddCommand(readCmd)
+	readCmd.Flags().IntVar(&readLimit, "limit", 20, "How many recent messages to mark read when no IDs are given")
+}
+
+func runRead(cmd *cobra.Command, args []string) error {
+	jid := args[0]
+	if len(args) == 1 && readLimit < 1 {
+		return fmt.Errorf("--limit must be at least 1")
+	}
+
+	return WithConnection(func(db *store.DB, client *whatsapp.Client) error {
+		result, err := client.MarkRead(jid, args[1:], readLimit)
+		if err != nil {
+			return fmt.Errorf("mark read failed: %w", err)
+		}
+
+		return OutputResult(result, fmt.Sprintf("Marked %d messages read (%d receipts)", len(result.MessageIDs), result.Receipts))
+	})
+}
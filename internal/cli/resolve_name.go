@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"github.com/eddmann/whatsapp-cli/internal/store"
+	"github.com/eddmann/whatsapp-cli/internal/whatsapp"
+)
+
+var resolveNameCmd = &cobra.Command{
+	Use:   "resolve-name <jid>",
+	Short: "Show how a JID's display name is resolved",
+	Long: `Show what each name source returns for a JID and which one is used.
+
+Names come from several places: the name stored on the chat, the group
+subject, the contact's address book, business and push names, and the name
+saved with a LID mapping. This lists each of them, along with any local
+aliases, to help explain why a chat or sender shows the name it does.
+
+The JID may also be a phone number or an alias.
+
+Examples:
+  whatsapp resolve-name 1234567890@s.whatsapp.net
+  whatsapp resolve-name 123456789012345@lid
+  whatsapp resolve-name mum`,
+	Args: cobra.ExactArgs(1),
+	RunE: runResolveName,
+}
+
+func init() {
+	rootCmd.AddCommand(resolveNameCmd)
+}
+
+func runResolveName(cmd *cobra.Command, args []string) error {
+	aliases, err := LoadAliases()
+	if err != nil {
+		return fmt.Errorf("failed to load aliases: %w", err)
+	}
+
+	return WithConnection(func(db *store.DB, client *whatsapp.Client) error {
+		res, err := client.ResolveName(aliases.Get(args[0]))
+		if err != nil {
+			return err
+		}
+		for alias, jid := range aliases {
+			if jid == res.JID {
+				res.Aliases = append(res.Aliases, alias)
+			}
+		}
+		sort.Strings(res.Aliases)
+		return Output(res)
+	})
+}
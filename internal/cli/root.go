@@ -7,6 +7,8 @@ import (
 	"time"
 
 	"github.com/spf13/cobra"
+
+	"github.com/eddmann/whatsapp-cli/internal/store"
 )
 
 var (
@@ -18,8 +20,17 @@ var (
 	noHeaderFlag bool
 	storeDir     string
 	timeout      time.Duration
+	busyTimeout  time.Duration
+	busyRetries  int
 	verbose      bool
 	noAutoSync   bool
+	autoSync     bool
+	plainFlag    bool
+	markdownFlag bool
+	humanTplFlag string
+	templateFlag string
+	colorFlag    string
+	flattenFlag  bool
 
 	// Cached resolved format
 	resolvedFormat Format
@@ -35,13 +46,24 @@ var rootCmd = &cobra.Command{
 func init() {
 	cobra.OnInitialize(initConfig, resolveFormatOnce)
 
-	rootCmd.PersistentFlags().StringVarP(&formatFlag, "format", "f", "", "Output format: json, jsonl, csv, tsv, human (default: json, or $WHATSAPP_FORMAT)")
+	rootCmd.PersistentFlags().StringVarP(&formatFlag, "format", "f", "", "Output format: json, jsonl, yaml, csv, tsv, human, table, template (default: json, or $WHATSAPP_FORMAT)")
 	rootCmd.PersistentFlags().StringVar(&fieldsFlag, "fields", "", "Comma-separated list of fields to include in output")
 	rootCmd.PersistentFlags().BoolVar(&noHeaderFlag, "no-header", false, "Skip header row in CSV/TSV output")
 	rootCmd.PersistentFlags().StringVar(&storeDir, "store", "", "Store directory (default: ~/.config/whatsapp-cli)")
 	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", 30*time.Second, "Command timeout")
+	rootCmd.PersistentFlags().DurationVar(&busyTimeout, "busy-timeout", store.BusyTimeout, "How long to wait for a database locked by another command")
+	rootCmd.PersistentFlags().IntVar(&busyRetries, "busy-retries", store.BusyRetries, "Retries with backoff after --busy-timeout on a locked database")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Verbose output")
 	rootCmd.PersistentFlags().BoolVar(&noAutoSync, "no-auto-sync", false, "Skip automatic sync check")
+	rootCmd.PersistentFlags().BoolVar(&autoSync, "auto-sync", false, "Allow automatic sync even when stdout is not a terminal")
+	rootCmd.MarkFlagsMutuallyExclusive("auto-sync", "no-auto-sync")
+	rootCmd.PersistentFlags().BoolVar(&plainFlag, "plain", false, "Strip WhatsApp formatting (*bold*, _italic_, ~strike~) from message text")
+	rootCmd.PersistentFlags().BoolVar(&markdownFlag, "markdown", false, "Convert WhatsApp formatting in message text to Markdown")
+	rootCmd.PersistentFlags().StringVar(&humanTplFlag, "human-template", "", "Go template file used to render --format human output")
+	rootCmd.PersistentFlags().StringVar(&colorFlag, "color", ColorAuto, "Colour human output: auto (terminals without NO_COLOR), always or never")
+	rootCmd.PersistentFlags().StringVar(&templateFlag, "template", "", "Go template rendered once per item for --format template, e.g. '{{.sender}}: {{.content}}'")
+	rootCmd.PersistentFlags().BoolVar(&flattenFlag, "flatten", false, "Flatten nested objects into dotted keys (jsonl only)")
+	rootCmd.MarkFlagsMutuallyExclusive("plain", "markdown")
 	rootCmd.PersistentFlags().BoolP("version", "V", false, "Show version")
 
 	rootCmd.SetVersionTemplate(fmt.Sprintf("whatsapp-cli %s\n", version))
@@ -52,6 +74,8 @@ func initConfig() {
 	if storeDir != "" {
 		SetStoreDir(storeDir)
 	}
+	store.BusyTimeout = busyTimeout
+	store.BusyRetries = busyRetries
 }
 
 // resolveFormatOnce caches the output format at startup
@@ -70,6 +94,9 @@ func resolveFormatOnce() {
 		fmt.Fprintf(os.Stderr, "warning: invalid format %q, using json\n", f)
 		resolvedFormat = FormatJSON
 	}
+	if resolvedFormat == FormatTable {
+		resolvedFormat = FormatHuman
+	}
 }
 
 // Execute runs the root command
@@ -99,12 +126,30 @@ func NoHeader() bool {
 	return noHeaderFlag
 }
 
+// GetMarkupStyle returns how message text formatting should be rendered
+func GetMarkupStyle() MarkupStyle {
+	switch {
+	case plainFlag:
+		return MarkupPlain
+	case markdownFlag:
+		return MarkupMarkdown
+	default:
+		return MarkupRaw
+	}
+}
+
 // GetOutputOptions returns the current output options
 func GetOutputOptions() OutputOptions {
 	return OutputOptions{
 		Format:   GetFormat(),
 		Fields:   GetFields(),
 		NoHeader: NoHeader(),
+		Markup:   GetMarkupStyle(),
+		Template: humanTplFlag,
+		Flatten:  flattenFlag,
+
+		LineTemplate: templateFlag,
+		Color:        colorFlag,
 	}
 }
 
@@ -147,3 +192,8 @@ func IsVerbose() bool {
 func NoAutoSync() bool {
 	return noAutoSync
 }
+
+// AutoSyncForced returns whether auto-sync was explicitly requested
+func AutoSyncForced() bool {
+	return autoSync
+}
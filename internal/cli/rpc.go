@@ -0,0 +1,347 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/eddmann/whatsapp-cli/internal/store"
+	"github.com/eddmann/whatsapp-cli/internal/whatsapp"
+)
+
+// JSON-RPC 2.0 error codes
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcServerError    = -32000
+)
+
+var rpcCmd = &cobra.Command{
+	Use:   "rpc",
+	Short: "Serve JSON-RPC over stdin/stdout",
+	Long: `Run a JSON-RPC 2.0 server speaking newline-delimited JSON over stdin/stdout.
+
+Intended for editor and agent integrations that want programmatic control
+without spawning a process per command. A single WhatsApp connection is
+opened on the first send and reused for the rest of the session.
+
+Methods:
+  ping            Returns "pong"
+  chats.list      {query, groups, non_empty, unread_only, sort_by (name, activity), limit, page}
+  messages.list   {jid, after, before, timeframe, type, limit, page}
+  search          {query, chat, from, type, timeframe, limit, page}
+  send.text       {jid, text, reply_to, quote_from}
+
+Example:
+  echo '{"jsonrpc":"2.0","id":1,"method":"chats.list","params":{"limit":5}}' | whatsapp rpc`,
+	Args: cobra.NoArgs,
+	RunE: runRPC,
+}
+
+func init() {
+	rootCmd.AddCommand(rpcCmd)
+}
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// rpcResponse is a successful response. Result is always present, as null
+// when a method has nothing to return.
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result"`
+}
+
+// rpcErrorResponse is a failed response, which has no result.
+type rpcErrorResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Error   *rpcError       `json:"error"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type rpcChatsParams struct {
+	Query    string `json:"query"`
+	Groups   bool   `json:"groups"`
+	NonEmpty bool   `json:"non_empty"`
+	Unread   bool   `json:"unread_only"`
+	SortBy   string `json:"sort_by"`
+	Limit    int    `json:"limit"`
+	Page     int    `json:"page"`
+}
+
+type rpcMessagesParams struct {
+	JID       string `json:"jid"`
+	After     string `json:"after"`
+	Before    string `json:"before"`
+	Timeframe string `json:"timeframe"`
+	Type      string `json:"type"`
+	Limit     int    `json:"limit"`
+	Page      int    `json:"page"`
+}
+
+type rpcSearchParams struct {
+	Query     string `json:"query"`
+	Chat      string `json:"chat"`
+	From      string `json:"from"`
+	Type      string `json:"type"`
+	Timeframe string `json:"timeframe"`
+	Limit     int    `json:"limit"`
+	Page      int    `json:"page"`
+}
+
+type rpcSendTextParams struct {
+	JID       string `json:"jid"`
+	Text      string `json:"text"`
+	ReplyTo   string `json:"reply_to"`
+	QuoteFrom string `json:"quote_from"`
+}
+
+// rpcServer dispatches JSON-RPC methods against a shared database and a lazily
+// connected WhatsApp client.
+type rpcServer struct {
+	db     *store.DB
+	client *whatsapp.Client
+}
+
+func runRPC(cmd *cobra.Command, args []string) error {
+	if err := EnsureDirectories(); err != nil {
+		return fmt.Errorf("failed to create directories: %w", err)
+	}
+
+	db, err := store.Open(GetMessagesDBPath())
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.CloseQuietly()
+
+	server := &rpcServer{db: db}
+	defer server.close()
+
+	return server.serve(os.Stdin, os.Stdout)
+}
+
+// serve reads one request per line and writes one response per line.
+// Notifications (requests without an id) receive no response.
+func (s *rpcServer) serve(r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	enc := json.NewEncoder(w)
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var req rpcRequest
+		if err := json.Unmarshal(line, &req); err != nil {
+			if err := enc.Encode(rpcErrorResponse{
+				JSONRPC: "2.0",
+				ID:      json.RawMessage("null"),
+				Error:   &rpcError{Code: rpcParseError, Message: fmt.Sprintf("parse error: %v", err)},
+			}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		result, rpcErr := s.dispatch(req)
+		if req.ID == nil {
+			continue
+		}
+
+		var resp any = rpcResponse{JSONRPC: "2.0", ID: req.ID, Result: result}
+		if rpcErr != nil {
+			resp = rpcErrorResponse{JSONRPC: "2.0", ID: req.ID, Error: rpcErr}
+		}
+		if err := enc.Encode(resp); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+func (s *rpcServer) dispatch(req rpcRequest) (any, *rpcError) {
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return nil, &rpcError{Code: rpcInvalidRequest, Message: "invalid request"}
+	}
+
+	switch req.Method {
+	case "ping":
+		return "pong", nil
+
+	case "chats.list":
+		var p rpcChatsParams
+		if err := decodeRPCParams(req.Params, &p); err != nil {
+			return nil, err
+		}
+		if p.Limit == 0 {
+			p.Limit = 50
+		}
+		chats, err := s.db.ListChats(store.ListChatsOptions{
+			Query:      p.Query,
+			OnlyGroups: p.Groups,
+			NonEmpty:   p.NonEmpty,
+			UnreadOnly: p.Unread,
+			SortBy:     p.SortBy,
+			SortDesc:   sortDesc(p.SortBy, false),
+			Limit:      p.Limit,
+			Page:       p.Page,
+		})
+		if err != nil {
+			return nil, rpcServerErr(err)
+		}
+		return nonNil(chats), nil
+
+	case "messages.list":
+		var p rpcMessagesParams
+		if err := decodeRPCParams(req.Params, &p); err != nil {
+			return nil, err
+		}
+		if p.JID == "" {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: "jid is required"}
+		}
+		if p.Limit == 0 {
+			p.Limit = 50
+		}
+		after, before := p.After, p.Before
+		if p.Timeframe != "" {
+			var err error
+			if after, before, err = ParseTimeframe(p.Timeframe); err != nil {
+				return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+			}
+		}
+		messages, err := s.db.ListMessages(store.ListMessagesOptions{
+			ChatJID: p.JID,
+			After:   after,
+			Before:  before,
+			Type:    p.Type,
+			Limit:   p.Limit,
+			Page:    p.Page,
+		})
+		if err != nil {
+			return nil, rpcServerErr(err)
+		}
+		return nonNil(messages), nil
+
+	case "search":
+		var p rpcSearchParams
+		if err := decodeRPCParams(req.Params, &p); err != nil {
+			return nil, err
+		}
+		if p.Query == "" {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: "query is required"}
+		}
+		if p.Limit == 0 {
+			p.Limit = 50
+		}
+		var after, before string
+		if p.Timeframe != "" {
+			var err error
+			if after, before, err = ParseTimeframe(p.Timeframe); err != nil {
+				return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+			}
+		}
+		messages, err := s.db.SearchMessages(store.SearchMessagesOptions{
+			Query:   p.Query,
+			ChatJID: p.Chat,
+			FromJID: p.From,
+			Type:    p.Type,
+			After:   after,
+			Before:  before,
+			Limit:   p.Limit,
+			Page:    p.Page,
+		})
+		if err != nil {
+			return nil, rpcServerErr(err)
+		}
+		return nonNil(messages), nil
+
+	case "send.text":
+		var p rpcSendTextParams
+		if err := decodeRPCParams(req.Params, &p); err != nil {
+			return nil, err
+		}
+		if p.JID == "" || p.Text == "" {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: "jid and text are required"}
+		}
+		client, err := s.connect()
+		if err != nil {
+			return nil, rpcServerErr(err)
+		}
+		if p.QuoteFrom != "" && p.ReplyTo == "" {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: "quote_from requires reply_to"}
+		}
+		result, err := client.SendText(p.JID, p.Text, whatsapp.SendOptions{ReplyTo: p.ReplyTo, QuoteFrom: p.QuoteFrom})
+		if err != nil {
+			return nil, rpcServerErr(fmt.Errorf("send failed: %w", err))
+		}
+		return store.SendResult{
+			MessageID: result.MessageID,
+			ChatJID:   result.ChatJID,
+			Timestamp: result.Timestamp,
+		}, nil
+	}
+
+	return nil, &rpcError{Code: rpcMethodNotFound, Message: fmt.Sprintf("method not found: %s", req.Method)}
+}
+
+// connect returns the shared client, connecting on first use.
+func (s *rpcServer) connect() (*whatsapp.Client, error) {
+	if s.client != nil {
+		return s.client, nil
+	}
+
+	client, err := connectClient(s.db)
+	if err != nil {
+		return nil, err
+	}
+	s.client = client
+	return client, nil
+}
+
+func (s *rpcServer) close() {
+	if s.client != nil {
+		s.client.Disconnect()
+	}
+}
+
+// decodeRPCParams decodes request params, treating missing params as empty.
+func decodeRPCParams(raw json.RawMessage, v any) *rpcError {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return &rpcError{Code: rpcInvalidParams, Message: fmt.Sprintf("invalid params: %v", err)}
+	}
+	return nil
+}
+
+func rpcServerErr(err error) *rpcError {
+	return &rpcError{Code: rpcServerError, Message: err.Error()}
+}
+
+// nonNil ensures empty result slices encode as [] rather than null.
+func nonNil[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
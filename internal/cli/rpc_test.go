@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/eddmann/whatsapp-cli/internal/store"
+)
+
+// rpcReply decodes either response shape, keeping the line it came from.
+type rpcReply struct {
+	ID     json.RawMessage `json:"id"`
+	Result any             `json:"result"`
+	Error  *rpcError       `json:"error"`
+	Line   string          `json:"-"`
+}
+
+// serveRPC runs the given request lines through an rpcServer and returns the
+// decoded responses.
+func serveRPC(t *testing.T, lines ...string) []rpcReply {
+	t.Helper()
+	db, err := store.Open(filepath.Join(t.TempDir(), "messages.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.CloseQuietly()
+
+	var out strings.Builder
+	server := &rpcServer{db: db}
+	if err := server.serve(strings.NewReader(strings.Join(lines, "\n")), &out); err != nil {
+		t.Fatalf("serve: %v", err)
+	}
+
+	var responses []rpcReply
+	scanner := bufio.NewScanner(strings.NewReader(out.String()))
+	for scanner.Scan() {
+		resp := rpcReply{Line: scanner.Text()}
+		if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
+			t.Fatalf("decode response %q: %v", scanner.Text(), err)
+		}
+		responses = append(responses, resp)
+	}
+	return responses
+}
+
+func TestRPCPing(t *testing.T) {
+	responses := serveRPC(t, `{"jsonrpc":"2.0","id":1,"method":"ping"}`)
+	if len(responses) != 1 {
+		t.Fatalf("expected 1 response, got %d", len(responses))
+	}
+	resp := responses[0]
+	if resp.Error != nil || resp.Result != "pong" || string(resp.ID) != "1" {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+}
+
+func TestRPCErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		code int
+	}{
+		{"parse error", `{"jsonrpc":`, rpcParseError},
+		{"missing version", `{"id":1,"method":"ping"}`, rpcInvalidRequest},
+		{"unknown method", `{"jsonrpc":"2.0","id":1,"method":"nope"}`, rpcMethodNotFound},
+		{"params of the wrong type", `{"jsonrpc":"2.0","id":1,"method":"chats.list","params":{"limit":"five"}}`, rpcInvalidParams},
+		{"missing required param", `{"jsonrpc":"2.0","id":1,"method":"messages.list","params":{}}`, rpcInvalidParams},
+		{"bad timeframe", `{"jsonrpc":"2.0","id":1,"method":"search","params":{"query":"hi","timeframe":"someday"}}`, rpcInvalidParams},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			responses := serveRPC(t, tt.line)
+			if len(responses) != 1 {
+				t.Fatalf("expected 1 response, got %d", len(responses))
+			}
+			if resp := responses[0]; resp.Error == nil || resp.Error.Code != tt.code {
+				t.Fatalf("expected error code %d, got %+v", tt.code, resp)
+			}
+			if strings.Contains(responses[0].Line, `"result"`) {
+				t.Fatalf("expected no result alongside the error, got %s", responses[0].Line)
+			}
+		})
+	}
+}
+
+func TestRPCResponseKeepsNullResult(t *testing.T) {
+	data, err := json.Marshal(rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), `{"jsonrpc":"2.0","id":1,"result":null}`; got != want {
+		t.Fatalf("expected %s, got %s", want, got)
+	}
+}
+
+func TestRPCNotificationsGetNoResponse(t *testing.T) {
+	responses := serveRPC(t,
+		`{"jsonrpc":"2.0","method":"ping"}`,
+		`{"jsonrpc":"2.0","method":"nope"}`,
+		``,
+		`{"jsonrpc":"2.0","id":"last","method":"chats.list"}`,
+	)
+	if len(responses) != 1 {
+		t.Fatalf("expected only the request with an id to be answered, got %+v", responses)
+	}
+	resp := responses[0]
+	if string(resp.ID) != `"last"` || resp.Error != nil {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+	if chats, ok := resp.Result.([]any); !ok || len(chats) != 0 {
+		t.Fatalf("expected an empty chat list, got %#v", resp.Result)
+	}
+}
@@ -0,0 +1,152 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/eddmann/whatsapp-cli/internal/store"
+)
+
+var runStopOnError bool
+
+// runCommandMethods maps script command names to RPC methods.
+var runCommandMethods = map[string]string{
+	"ping":     "ping",
+	"chats":    "chats.list",
+	"messages": "messages.list",
+	"search":   "search",
+	"send":     "send.text",
+}
+
+var runCmd = &cobra.Command{
+	Use:   "run <script.jsonl>",
+	Short: "Execute a batch of commands from a JSONL script",
+	Long: `Execute commands from a JSONL file in order, reusing a single connection.
+
+Each line is a command object with a "cmd" field plus its parameters (the same
+parameters accepted by 'whatsapp rpc'). One JSON result line is written per command.
+Use "-" to read the script from stdin.
+
+A script is not a transaction: each command takes effect as it runs, and a
+failing command doesn't undo the ones before it (sent messages stay sent).
+Use --stop-on-error to skip the rest of the script after a failure.
+
+Commands: ping, chats, messages, search, send
+
+Example script:
+  {"cmd":"send","jid":"1234567890@s.whatsapp.net","text":"Deploy started"}
+  {"cmd":"messages","jid":"1234567890@s.whatsapp.net","limit":5}`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRun,
+}
+
+func init() {
+	rootCmd.AddCommand(runCmd)
+	runCmd.Flags().BoolVar(&runStopOnError, "stop-on-error", false, "Stop at the first failing command")
+}
+
+type runCommand struct {
+	Cmd string `json:"cmd"`
+}
+
+type runResult struct {
+	Line   int    `json:"line"`
+	Cmd    string `json:"cmd"`
+	OK     bool   `json:"ok"`
+	Result any    `json:"result,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+func runRun(cmd *cobra.Command, args []string) error {
+	var script io.Reader = os.Stdin
+	if args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to open script: %w", err)
+		}
+		defer func() { _ = f.Close() }()
+		script = f
+	}
+
+	if err := EnsureDirectories(); err != nil {
+		return fmt.Errorf("failed to create directories: %w", err)
+	}
+
+	db, err := store.Open(GetMessagesDBPath())
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.CloseQuietly()
+
+	server := &rpcServer{db: db}
+	defer server.close()
+
+	return server.runScript(script, os.Stdout, runStopOnError)
+}
+
+// runScript executes each command in script, writing one result line per
+// command to w. Blank lines and lines starting with # are skipped.
+func (s *rpcServer) runScript(script io.Reader, w io.Writer, stopOnError bool) error {
+	scanner := bufio.NewScanner(script)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	enc := json.NewEncoder(w)
+
+	lineNo, executed, failed := 0, 0, 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		result := runResult{Line: lineNo}
+		var c runCommand
+		if err := json.Unmarshal([]byte(line), &c); err != nil {
+			result.Error = fmt.Sprintf("invalid command: %v", err)
+		} else {
+			result.Cmd = c.Cmd
+			method, ok := runCommandMethods[c.Cmd]
+			if !ok {
+				result.Error = fmt.Sprintf("unknown command %q", c.Cmd)
+			} else {
+				value, rpcErr := s.dispatch(rpcRequest{
+					JSONRPC: "2.0",
+					Method:  method,
+					Params:  json.RawMessage(line),
+				})
+				if rpcErr != nil {
+					result.Error = rpcErr.Message
+				} else {
+					result.OK = true
+					result.Result = value
+				}
+			}
+		}
+
+		executed++
+		if !result.OK {
+			failed++
+		}
+		if err := enc.Encode(result); err != nil {
+			return err
+		}
+
+		if !result.OK && stopOnError {
+			return fmt.Errorf("stopped at line %d: %s", lineNo, result.Error)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read script: %w", err)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d commands failed", failed, executed)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/eddmann/whatsapp-cli/internal/store"
+)
+
+// runTestScript runs script against a temporary database and returns the
+// decoded result lines along with the error runScript returned.
+func runTestScript(t *testing.T, script string, stopOnError bool) ([]runResult, error) {
+	t.Helper()
+	db, err := store.Open(filepath.Join(t.TempDir(), "messages.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.CloseQuietly()
+
+	var out strings.Builder
+	server := &rpcServer{db: db}
+	runErr := server.runScript(strings.NewReader(script), &out, stopOnError)
+
+	var results []runResult
+	dec := json.NewDecoder(strings.NewReader(out.String()))
+	for dec.More() {
+		var r runResult
+		if err := dec.Decode(&r); err != nil {
+			t.Fatalf("decode result: %v", err)
+		}
+		results = append(results, r)
+	}
+	return results, runErr
+}
+
+func TestRunScriptExecutesCommands(t *testing.T) {
+	script := strings.Join([]string{
+		`# comment`,
+		`{"cmd":"ping"}`,
+		``,
+		`{"cmd":"chats","limit":5}`,
+	}, "\n")
+
+	results, err := runTestScript(t, script, false)
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %+v", results)
+	}
+	if r := results[0]; !r.OK || r.Cmd != "ping" || r.Line != 2 || r.Result != "pong" {
+		t.Fatalf("unexpected ping result %+v", r)
+	}
+	if r := results[1]; !r.OK || r.Cmd != "chats" || r.Line != 4 {
+		t.Fatalf("unexpected chats result %+v", r)
+	}
+}
+
+func TestRunScriptReportsFailures(t *testing.T) {
+	script := strings.Join([]string{
+		`{"cmd":"nope"}`,
+		`not json`,
+		`{"cmd":"messages"}`,
+		`{"cmd":"ping"}`,
+	}, "\n")
+
+	results, err := runTestScript(t, script, false)
+	if err == nil || err.Error() != "3 of 4 commands failed" {
+		t.Fatalf("expected a failure count, got %v", err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("expected every command to run, got %+v", results)
+	}
+	for i, want := range []string{`unknown command "nope"`, "invalid command", "jid is required"} {
+		if results[i].OK || !strings.Contains(results[i].Error, want) {
+			t.Fatalf("result %d: expected error containing %q, got %+v", i, want, results[i])
+		}
+	}
+	if !results[3].OK {
+		t.Fatalf("expected ping to succeed, got %+v", results[3])
+	}
+}
+
+func TestRunScriptStopOnError(t *testing.T) {
+	script := strings.Join([]string{
+		`{"cmd":"ping"}`,
+		`{"cmd":"search"}`,
+		`{"cmd":"ping"}`,
+	}, "\n")
+
+	results, err := runTestScript(t, script, true)
+	if err == nil || !strings.Contains(err.Error(), "stopped at line 2") {
+		t.Fatalf("expected to stop at line 2, got %v", err)
+	}
+	if len(results) != 2 || !results[0].OK || results[1].OK {
+		t.Fatalf("expected ping then the failed search, got %+v", results)
+	}
+}
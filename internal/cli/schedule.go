@@ -0,0 +1,203 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/eddmann/whatsapp-cli/internal/store"
+	"github.com/eddmann/whatsapp-cli/internal/whatsapp"
+)
+
+var (
+	scheduleAt    string
+	scheduleEvery string
+)
+
+// scheduleTick is how often 'sync --follow' checks for due schedules.
+const scheduleTick = 30 * time.Second
+
+var scheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "Send text messages later, once or on a recurring schedule",
+	Long: `Queue text messages to send later, once or on a recurring schedule.
+
+Schedules are sent by 'whatsapp sync --follow', so keep it running. Runs that
+fall while it is stopped are sent once when it next starts; a recurring
+schedule is then re-armed for its next run after that.`,
+}
+
+var scheduleAddCmd = &cobra.Command{
+	Use:   "add <jid> <message>",
+	Short: "Schedule a text message",
+	Long: `Schedule a text message, either once with --at or repeatedly with --every.
+
+--every takes a cron expression in local time: minute, hour, day of month,
+month and day of week (0 or 7 is Sunday). Each field is *, a number, a range
+such as 1-5 or a list such as 1,15, with an optional /step.
+
+Examples:
+  whatsapp schedule add 123456789-987654321@g.us "Standup in 5 minutes" --every "55 8 * * 1-5"
+  whatsapp schedule add 1234567890@s.whatsapp.net "Happy birthday!" --at 2026-11-02T09:00:00Z
+  whatsapp schedule add 1234567890@s.whatsapp.net "Pay rent" --every "0 9 1 * *"`,
+	Args: cobra.ExactArgs(2),
+	RunE: runScheduleAdd,
+}
+
+var scheduleListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List scheduled messages and when each runs next",
+	Args:  cobra.NoArgs,
+	RunE:  runScheduleList,
+}
+
+var scheduleRemoveCmd = &cobra.Command{
+	Use:   "remove <id>",
+	Short: "Remove a scheduled message",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runScheduleRemove,
+}
+
+func init() {
+	rootCmd.AddCommand(scheduleCmd)
+	scheduleCmd.AddCommand(scheduleAddCmd)
+	scheduleCmd.AddCommand(scheduleListCmd)
+	scheduleCmd.AddCommand(scheduleRemoveCmd)
+
+	scheduleAddCmd.Flags().StringVar(&scheduleAt, "at", "", "Send once at this time (RFC3339)")
+	scheduleAddCmd.Flags().StringVar(&scheduleEvery, "every", "", `Send repeatedly on a cron schedule (e.g. "0 9 * * 1")`)
+	scheduleAddCmd.MarkFlagsOneRequired("at", "every")
+	scheduleAddCmd.MarkFlagsMutuallyExclusive("at", "every")
+}
+
+func runScheduleAdd(cmd *cobra.Command, args []string) error {
+	jid, text := args[0], args[1]
+	if text == "" {
+		return fmt.Errorf("message must not be empty")
+	}
+
+	now := time.Now()
+	var next time.Time
+	if scheduleEvery != "" {
+		spec, err := parseCron(scheduleEvery)
+		if err != nil {
+			return err
+		}
+		if next, err = spec.next(now); err != nil {
+			return fmt.Errorf("invalid --every %q: %w", scheduleEvery, err)
+		}
+	} else {
+		var err error
+		if next, err = time.Parse(time.RFC3339, scheduleAt); err != nil {
+			return fmt.Errorf("invalid --at %q (use RFC3339, e.g. 2026-11-02T09:00:00Z): %w", scheduleAt, err)
+		}
+		if !next.After(now) {
+			return fmt.Errorf("--at %s is in the past", scheduleAt)
+		}
+	}
+
+	return WithDB(func(db *store.DB) error {
+		s := store.Schedule{ChatJID: jid, Text: text, Every: scheduleEvery, NextRun: next.Local()}
+		id, err := db.AddSchedule(s)
+		if err != nil {
+			return fmt.Errorf("failed to add schedule: %w", err)
+		}
+		s.ID = id
+		return OutputResult(s, fmt.Sprintf("Scheduled %d for %s; keep 'whatsapp sync --follow' running to send it",
+			id, s.NextRun.Format("2006-01-02 15:04")))
+	})
+}
+
+func runScheduleList(cmd *cobra.Command, args []string) error {
+	return WithDB(func(db *store.DB) error {
+		schedules, err := db.ListSchedules()
+		if err != nil {
+			return fmt.Errorf("failed to list schedules: %w", err)
+		}
+		return Output(nonNil(schedules))
+	})
+}
+
+func runScheduleRemove(cmd *cobra.Command, args []string) error {
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid schedule ID %q", args[0])
+	}
+
+	return WithDB(func(db *store.DB) error {
+		if err := db.RemoveSchedule(id); err != nil {
+			return fmt.Errorf("failed to remove schedule: %w", err)
+		}
+		return OutputResult(map[string]any{
+			"id":      id,
+			"removed": true,
+		}, fmt.Sprintf("Removed schedule %d", id))
+	})
+}
+
+// runScheduler sends schedules as they fall due until ctx is cancelled.
+func runScheduler(ctx context.Context, db *store.DB, client *whatsapp.Client) {
+	ticker := time.NewTicker(scheduleTick)
+	defer ticker.Stop()
+
+	for {
+		sendDueSchedules(db, client, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sendDueSchedules sends every schedule due by now. One-offs are removed and
+// recurring schedules re-armed for their next run after now, so runs missed
+// while stopped are sent once rather than replayed. A send that fails while
+// disconnected is retried on the next tick; other failures are reported and
+// the run skipped.
+func sendDueSchedules(db *store.DB, client *whatsapp.Client, now time.Time) {
+	due, err := db.DueSchedules(now)
+	if err != nil {
+		OutputWarning("failed to load schedules: %v", err)
+		return
+	}
+
+	for _, s := range due {
+		_, err := client.SendText(s.ChatJID, s.Text, whatsapp.SendOptions{})
+		if errors.Is(err, whatsapp.ErrNotConnected) {
+			return
+		}
+		if err != nil {
+			OutputWarning("schedule %d: failed to send to %s: %v", s.ID, s.ChatJID, err)
+		} else {
+			fmt.Fprintf(os.Stderr, "Sent schedule %d to %s\n", s.ID, s.ChatJID)
+		}
+
+		if err := rearmSchedule(db, s, now); err != nil {
+			OutputWarning("schedule %d: %v", s.ID, err)
+		}
+	}
+}
+
+// rearmSchedule moves a schedule that has just run on to its next run, or
+// removes it if it doesn't recur.
+func rearmSchedule(db *store.DB, s store.Schedule, now time.Time) error {
+	if s.Every == "" {
+		return db.RemoveSchedule(s.ID)
+	}
+	spec, err := parseCron(s.Every)
+	if err == nil {
+		var next time.Time
+		if next, err = spec.next(now); err == nil {
+			return db.RearmSchedule(s.ID, now, next)
+		}
+	}
+	// It can't run again, so don't leave it due forever
+	_ = db.RemoveSchedule(s.ID)
+	return fmt.Errorf("removed, as %q has no next run: %w", s.Every, err)
+}
@@ -14,6 +14,12 @@ var (
 	searchType      string
 	searchTimeframe string
 	searchLimit     int
+	searchPage      int
+	searchByChat    bool
+	searchReplies   bool
+	searchSort      string
+	searchReverse   bool
+	searchContext   int
 )
 
 var searchCmd = &cobra.Command{
@@ -21,7 +27,21 @@ var searchCmd = &cobra.Command{
 	Short: "Full-text search messages",
 	Long: `Search messages using full-text search.
 
-Uses SQLite FTS5 for fast searching across all messages.
+Uses SQLite FTS5 for fast searching across all messages. If the database
+has no FTS5 index, a slower substring match is used instead.
+
+Use --by-chat to group matches into one entry per chat, ordered by each chat's
+first match (its most recent, unless sorted otherwise).
+
+Matches are listed newest first. --sort orders them by timestamp, sender or
+chat_name (A-Z) instead, and --reverse flips the order.
+
+--page shows the next --limit matches, from page 1. Pages are offsets into
+the current results, so a sync between pages can shift them.
+
+Use --context N to return each match with the N messages before and after it
+in its chat, as {match, before, after}. A message is shown once, so contexts
+that overlap drop the messages an earlier match already showed.
 
 Timeframe presets: last_hour, today, yesterday, last_3_days, this_week, last_week, this_month`,
 	Args: cobra.ExactArgs(1),
@@ -35,11 +55,27 @@ func init() {
 	searchCmd.Flags().StringVar(&searchType, "type", "", "Filter by type (text, image, video, audio, document)")
 	searchCmd.Flags().StringVar(&searchTimeframe, "timeframe", "", "Timeframe preset")
 	searchCmd.Flags().IntVar(&searchLimit, "limit", 50, "Maximum results")
+	searchCmd.Flags().IntVar(&searchPage, "page", 1, "Page of --limit matches to show, from 1")
+	searchCmd.Flags().BoolVar(&searchByChat, "by-chat", false, "Group matches by chat")
+	searchCmd.Flags().StringVar(&searchSort, "sort", "timestamp", "Sort by timestamp, sender or chat_name")
+	searchCmd.Flags().BoolVar(&searchReverse, "reverse", false, "Reverse the sort order (oldest first for timestamp)")
+	searchCmd.Flags().IntVar(&searchContext, "context", 0, "Include this many messages before and after each match")
+	searchCmd.Flags().BoolVar(&searchReplies, "with-replies", false, "Include a preview of the message each reply quotes")
 }
 
 func runSearch(cmd *cobra.Command, args []string) error {
 	query := args[0]
 
+	if err := validatePage(searchPage, searchLimit); err != nil {
+		return err
+	}
+	if searchContext < 0 {
+		return fmt.Errorf("--context must not be negative")
+	}
+	if searchContext > 0 && searchByChat {
+		return fmt.Errorf("--context cannot be combined with --by-chat")
+	}
+
 	// Parse timeframe if provided
 	var after, before string
 	if searchTimeframe != "" {
@@ -51,18 +87,183 @@ func runSearch(cmd *cobra.Command, args []string) error {
 	}
 
 	return WithDB(func(db *store.DB) error {
+		if !db.HasFTS() {
+			OutputWarning("SQLite FTS5 is not available, falling back to substring search. Build with: CGO_ENABLED=1 go build -tags sqlite_fts5")
+		}
+
 		messages, err := db.SearchMessages(store.SearchMessagesOptions{
-			Query:   query,
-			ChatJID: searchChat,
-			FromJID: searchFrom,
-			Type:    searchType,
-			After:   after,
-			Before:  before,
-			Limit:   searchLimit,
+			Query:       query,
+			ChatJID:     searchChat,
+			FromJID:     searchFrom,
+			Type:        searchType,
+			After:       after,
+			Before:      before,
+			Limit:       searchLimit,
+			Page:        searchPage,
+			WithReplies: searchReplies,
+			SortBy:      searchSort,
+			SortDesc:    sortDesc(searchSort, searchReverse),
 		})
 		if err != nil {
 			return fmt.Errorf("search failed: %w", err)
 		}
+
+		if searchByChat {
+			return outputChatMatches(groupMessagesByChat(messages))
+		}
+		if searchContext > 0 {
+			contexts, err := db.MessageContexts(messages, searchContext)
+			if err != nil {
+				return fmt.Errorf("failed to load context: %w", err)
+			}
+			return outputMessageContexts(contexts)
+		}
 		return Output(messages)
 	})
 }
+
+// groupMessagesByChat groups messages by chat, keeping the order in which each
+// chat first appears and the original order of messages within a chat.
+func groupMessagesByChat(messages []store.Message) []store.ChatMatches {
+	var groups []store.ChatMatches
+	index := make(map[string]int)
+
+	for _, m := range messages {
+		i, ok := index[m.ChatJID]
+		if !ok {
+			i = len(groups)
+			index[m.ChatJID] = i
+			groups = append(groups, store.ChatMatches{ChatJID: m.ChatJID, ChatName: m.ChatName})
+		}
+		groups[i].Matches = append(groups[i].Matches, m)
+	}
+
+	return groups
+}
+
+// outputChatMatches renders grouped results. Human output prints a section per
+// chat; CSV/TSV and template have no nesting, so they get the matches as flat
+// rows in group order.
+// Other formats keep the groups, with --fields applied to each chat's matches.
+func outputChatMatches(groups []store.ChatMatches) error {
+	opts := GetOutputOptions()
+	switch {
+	case (opts.Format == FormatHuman || opts.Format == FormatTable) && opts.Template == "":
+		if len(groups) == 0 {
+			return Output([]store.Message{})
+		}
+		for i, g := range groups {
+			if i > 0 {
+				fmt.Println()
+			}
+			name := g.ChatJID
+			if g.ChatName != nil && *g.ChatName != "" {
+				name = fmt.Sprintf("%s (%s)", *g.ChatName, g.ChatJID)
+			}
+			fmt.Printf("== %s: %d matches ==\n", name, len(g.Matches))
+			if err := Output(g.Matches); err != nil {
+				return err
+			}
+		}
+		return nil
+	case opts.Format == FormatCSV || opts.Format == FormatTSV || opts.Format == FormatTemplate:
+		var flat []store.Message
+		for _, g := range groups {
+			flat = append(flat, g.Matches...)
+		}
+		return Output(flat)
+	default:
+		data := chatMatchesWithFields(groups, opts.Fields, opts.Markup)
+		opts.Fields, opts.Markup = nil, MarkupRaw
+		return output(data, opts)
+	}
+}
+
+// chatMatchesOutput is a ChatMatches whose matches have had --fields applied.
+type chatMatchesOutput struct {
+	ChatJID  string  `json:"chat_jid"`
+	ChatName *string `json:"chat_name,omitempty"`
+	Matches  any     `json:"matches"`
+}
+
+// chatMatchesWithFields applies the markup style and field selection to each
+// group's matches, so --fields picks message fields rather than group fields.
+func chatMatchesWithFields(groups []store.ChatMatches, fields []string, markup MarkupStyle) []chatMatchesOutput {
+	out := make([]chatMatchesOutput, len(groups))
+	for i, g := range groups {
+		out[i] = chatMatchesOutput{
+			ChatJID:  g.ChatJID,
+			ChatName: g.ChatName,
+			Matches:  filterFields(applyMarkupStyle(nonNil(g.Matches), markup), fields),
+		}
+	}
+	return out
+}
+
+// outputMessageContexts renders matches with their context. Human output prints
+// a section per match with its context in chat order; CSV/TSV and template
+// have no nesting, so they get the same rows flat. Other formats keep the
+// {match, before, after} shape, with --fields applied to every message.
+func outputMessageContexts(contexts []store.MessageContext) error {
+	opts := GetOutputOptions()
+	switch {
+	case (opts.Format == FormatHuman || opts.Format == FormatTable) && opts.Template == "":
+		if len(contexts) == 0 {
+			return Output([]store.Message{})
+		}
+		for i, c := range contexts {
+			if i > 0 {
+				fmt.Println()
+			}
+			name := c.Match.ChatJID
+			if c.Match.ChatName != nil && *c.Match.ChatName != "" {
+				name = fmt.Sprintf("%s (%s)", *c.Match.ChatName, c.Match.ChatJID)
+			}
+			fmt.Printf("== Match %s in %s ==\n", c.Match.ID, name)
+			if err := Output(withContext(c)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case opts.Format == FormatCSV || opts.Format == FormatTSV || opts.Format == FormatTemplate:
+		var flat []store.Message
+		for _, c := range contexts {
+			flat = append(flat, withContext(c)...)
+		}
+		return Output(flat)
+	default:
+		data := messageContextsWithFields(contexts, opts.Fields, opts.Markup)
+		opts.Fields, opts.Markup = nil, MarkupRaw
+		return output(data, opts)
+	}
+}
+
+// withContext returns a match and its context as one oldest-first list.
+func withContext(c store.MessageContext) []store.Message {
+	messages := make([]store.Message, 0, len(c.Before)+1+len(c.After))
+	messages = append(messages, c.Before...)
+	messages = append(messages, c.Match)
+	return append(messages, c.After...)
+}
+
+// messageContextOutput is a MessageContext whose messages have had --fields
+// applied.
+type messageContextOutput struct {
+	Match  any `json:"match"`
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// messageContextsWithFields applies the markup style and field selection to
+// each match and its context, so --fields picks message fields.
+func messageContextsWithFields(contexts []store.MessageContext, fields []string, markup MarkupStyle) []messageContextOutput {
+	out := make([]messageContextOutput, len(contexts))
+	for i, c := range contexts {
+		out[i] = messageContextOutput{
+			Match:  filterFields(applyMarkupStyle(c.Match, markup), fields),
+			Before: filterFields(applyMarkupStyle(nonNil(c.Before), markup), fields),
+			After:  filterFields(applyMarkupStyle(nonNil(c.After), markup), fields),
+		}
+	}
+	return out
+}
@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/eddmann/whatsapp-cli/internal/store"
+)
+
+func TestGroupMessagesByChatPreservesOrder(t *testing.T) {
+	messages := []store.Message{
+		{ID: "3", ChatJID: "a@s.whatsapp.net"},
+		{ID: "2", ChatJID: "b@g.us"},
+		{ID: "1", ChatJID: "a@s.whatsapp.net"},
+	}
+
+	groups := groupMessagesByChat(messages)
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups, got %d", len(groups))
+	}
+	if groups[0].ChatJID != "a@s.whatsapp.net" || groups[1].ChatJID != "b@g.us" {
+		t.Fatalf("unexpected group order: %+v", groups)
+	}
+	if len(groups[0].Matches) != 2 || groups[0].Matches[0].ID != "3" || groups[0].Matches[1].ID != "1" {
+		t.Fatalf("unexpected matches for first chat: %+v", groups[0].Matches)
+	}
+}
+
+func TestChatMatchesWithFieldsFiltersMatches(t *testing.T) {
+	name := "Alice"
+	groups := []store.ChatMatches{{
+		ChatJID:  "a@s.whatsapp.net",
+		ChatName: &name,
+		Matches:  []store.Message{{ID: "1", ChatJID: "a@s.whatsapp.net", Sender: "123"}},
+	}}
+
+	out := chatMatchesWithFields(groups, []string{"id"}, MarkupRaw)
+	if len(out) != 1 || out[0].ChatJID != "a@s.whatsapp.net" || out[0].ChatName != &name {
+		t.Fatalf("expected the group to be kept, got %+v", out)
+	}
+	matches, ok := out[0].Matches.([]map[string]any)
+	if !ok || len(matches) != 1 {
+		t.Fatalf("expected filtered matches, got %#v", out[0].Matches)
+	}
+	if len(matches[0]) != 1 || matches[0]["id"] != "1" {
+		t.Fatalf("expected only the id field, got %v", matches[0])
+	}
+}
@@ -1,8 +1,13 @@
 package cli
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"os/signal"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -11,11 +16,41 @@ import (
 )
 
 var (
-	sendFile    string
-	sendCaption string
-	sendReplyTo string
+	sendFile         string
+	sendCaption      string
+	sendReplyTo      string
+	sendQuoteFrom    string
+	sendMentionsAll  bool
+	sendYes          bool
+	sendNoSplit      bool
+	sendSplitLength  int
+	sendFromTemplate string
+	sendVars         []string
+	sendToFile       string
+	sendLinkPreview  bool
+	sendLowPriority  bool
+	sendUntilAck     bool
+	sendAckDeadline  time.Duration
+	sendMaxAttempts  int
+	sendConcurrency  int
+	sendNoEphemeral  bool
+	sendMentions     []string
+	sendReconnect    bool
+	sendTo           []string
+	sendVoice        bool
+	sendCompress     int
+	sendMaxDimension int
+	sendDocTitle     string
+	sendAsDocument   bool
+	sendReact        string
+
+	sendMentionsFromText bool
+	sendButtons          []string
 )
 
+// defaultSplitLength is the character count above which text messages are split.
+const defaultSplitLength = 4096
+
 var sendCmd = &cobra.Command{
 	Use:   "send <jid> <message>",
 	Short: "Send a message",
@@ -23,20 +58,123 @@ var sendCmd = &cobra.Command{
 
 Use 'whatsapp chats' to find the JID first.
 
+Text longer than --split-length characters is sent as several messages, split
+on line or word boundaries. Use --no-split to send it as a single message.
+
+With --from-template the message is rendered from a Go text/template file, with
+{{.name}} placeholders filled from --var name=value. With --to-file the message
+is sent to every row of a CSV file that has a "jid" column; the other columns are
+template variables for that recipient (overriding --var). Every placeholder must
+have a value, and all messages are rendered before anything is sent. Recipients
+are sent to one at a time; --concurrency sends to several at once.
+
+With --to (repeatable, or comma-separated) the same message or file goes to
+every listed JID and all arguments are the message. A file is uploaded once and
+reused for every recipient. Failures are reported per recipient and don't stop
+the others.
+
+In groups, @number tokens in the text (country code, no +) mention that
+person, and --mention adds one without typing it; its token is appended. A
+number that isn't in the group still gets the message, but WhatsApp doesn't
+highlight or notify it as a mention. --mentions-from-text checks each @number
+against the group's members instead (the cached ones, or looked up if none are
+cached): members are mentioned by the JID the group knows them by, and numbers
+that aren't members are warned about and left as plain text.
+
+--reply-to quotes a message in the destination chat. Add --quote-from to quote
+a message from another chat instead; it must be in the local database.
+--react also reacts to the replied-to message with an emoji or shortcode (as
+for 'whatsapp react') once the reply is sent, saving a second command.
+
+--as-document sends an image, video or audio file as a document instead, in
+its original quality and without conversion, like "send as file" in the app.
+
+--doc-title sets the name a document is shown with and downloads as, so a
+generated file like tmp123.pdf can arrive as "Invoice.pdf". Without an
+extension the file's own is added to the download name.
+
+With --compress large JPEG and PNG images are re-encoded as JPEG before
+upload, at quality 80 or the given --compress=N, and scaled down to fit
+--max-dimension. Images that are already small are sent unchanged, as are
+other file types.
+
+Audio files are sent as voice notes, converted to speech-tuned Opus unless
+already Ogg/Opus. With --voice=false they are sent as regular audio
+attachments, encoded for music instead. WHATSAPP_OPUS_BITRATE (e.g. 64k),
+WHATSAPP_OPUS_SAMPLERATE and WHATSAPP_OPUS_APPLICATION (voip, audio or
+lowdelay) override the encoder settings.
+
+With --link-preview the first URL in the text is fetched and sent with its
+title, description and image, like the phone app does. If the page can't be
+fetched the message is sent without a preview.
+
+With --reconnect-retry a send that fails because the connection dropped is
+retried once after reconnecting, and the result reports "reconnected".
+
+In chats with disappearing messages on, messages are sent with the chat's
+timer (as last seen while syncing). Use --no-ephemeral to send a normal message.
+
+With --retry-until-delivered the message is sent again until a delivery
+receipt arrives from the recipient's phone, for alerts that must get through.
+It is resent at most --max-attempts times, spaced evenly over
+--delivery-deadline and at least 30s apart, and the result reports whether and
+when it was delivered. The command fails if it wasn't delivered in time.
+
+--button adds a reply button, given as "Text:id" (up to 3, with labels of at
+most 20 characters). Replies are synced like other messages, as the chosen
+button's text followed by its ID in brackets. Only WhatsApp Business accounts
+can send buttons, and WhatsApp may still not show them on every device; from a
+personal account the command fails rather than sending a plain message.
+
+WhatsApp has no silent sends: the recipient is notified unless they have muted
+the chat. --low-priority (alias --silent) therefore fails with an error rather
+than sending a message that still notifies.
+
 Examples:
   whatsapp send 1234567890@s.whatsapp.net "Hello!"
   whatsapp send 1234567890@s.whatsapp.net --file photo.jpg --caption "Check this out"
-  whatsapp send 1234567890@s.whatsapp.net "Reply text" --reply-to ABC123`,
+  whatsapp send 1234567890@s.whatsapp.net --file holiday.png --compress
+  whatsapp send 1234567890@s.whatsapp.net --file /tmp/tmp123.pdf --doc-title "Invoice.pdf"
+  whatsapp send 1234567890@s.whatsapp.net --file photo.jpg --as-document
+  whatsapp send 1234567890@s.whatsapp.net --file song.mp3 --voice=false
+  whatsapp send 1234567890@s.whatsapp.net "Reply text" --reply-to ABC123
+  whatsapp send 1234567890@s.whatsapp.net "On it" --reply-to ABC123 --react thumbsup
+  whatsapp send 123456789-987654321@g.us "Can you look, @447700900001?"
+  whatsapp send 123456789-987654321@g.us "Standup" --mention 447700900001 --mention 447700900002
+  whatsapp send 1234567890@s.whatsapp.net "Seen this?" --reply-to ABC123 --quote-from 123456789-987654321@g.us
+  whatsapp send 1234567890@s.whatsapp.net "Release notes: https://example.com/v2" --link-preview
+  whatsapp send 123456789-987654321@g.us "Standup in 5" --mentions-all --yes
+  whatsapp send 123456789-987654321@g.us "@447700900001 can you review?" --mentions-from-text
+  whatsapp send 1234567890@s.whatsapp.net "Prod is down" --retry-until-delivered --delivery-deadline 10m
+  whatsapp send 1234567890@s.whatsapp.net "Choose:" --button "Yes:id1" --button "No:id2"
+  whatsapp send 1234567890@s.whatsapp.net --from-template invite.txt --var name=Jane
+  whatsapp send --to-file guests.csv --from-template invite.txt --var date=Friday
+  whatsapp send --to 1234567890@s.whatsapp.net,0987654321@s.whatsapp.net "Office closed Friday"
+  whatsapp send --to 1234567890@s.whatsapp.net --to 123456789-987654321@g.us --file flyer.pdf`,
 	Args: func(cmd *cobra.Command, args []string) error {
+		toFile, _ := cmd.Flags().GetString("to-file")
+		to, _ := cmd.Flags().GetStringSlice("to")
 		file, _ := cmd.Flags().GetString("file")
-		if file != "" {
-			if len(args) < 1 {
+		template, _ := cmd.Flags().GetString("from-template")
+
+		required := 2
+		if toFile != "" || len(to) > 0 {
+			required--
+		}
+		if file != "" || template != "" {
+			required--
+		}
+		if len(args) < required {
+			switch {
+			case toFile != "":
+				return fmt.Errorf("requires a message or --from-template")
+			case len(to) > 0:
+				return fmt.Errorf("requires a message, --file or --from-template")
+			case required == 1:
 				return fmt.Errorf("requires at least 1 arg (jid)")
+			default:
+				return fmt.Errorf("requires 2 args (jid and message)")
 			}
-			return nil
-		}
-		if len(args) < 2 {
-			return fmt.Errorf("requires 2 args (jid and message)")
 		}
 		return nil
 	},
@@ -47,34 +185,571 @@ func init() {
 	rootCmd.AddCommand(sendCmd)
 	sendCmd.Flags().StringVar(&sendFile, "file", "", "Send a file (image, video, audio, document)")
 	sendCmd.Flags().StringVar(&sendCaption, "caption", "", "Caption for media file")
+	sendCmd.Flags().BoolVar(&sendAsDocument, "as-document", false, "Send the file as a document, in original quality, whatever its type")
+	sendCmd.Flags().StringVar(&sendDocTitle, "doc-title", "", "Name a document is shown and downloads as, instead of its file name")
+	sendCmd.Flags().IntVar(&sendCompress, "compress", 0, "Re-encode JPEG/PNG images as JPEG at this quality, 1-100 (80 if no value is given)")
+	sendCmd.Flags().Lookup("compress").NoOptDefVal = "80"
+	sendCmd.Flags().IntVar(&sendMaxDimension, "max-dimension", 1600, "Longest side in pixels of images re-encoded by --compress (0 for no limit)")
+	sendCmd.Flags().BoolVar(&sendVoice, "voice", true, "Send audio files as voice notes (--voice=false for a regular audio attachment)")
 	sendCmd.Flags().StringVar(&sendReplyTo, "reply-to", "", "Message ID to reply to")
+	sendCmd.Flags().StringVar(&sendReact, "react", "", "Also react to the --reply-to message with this emoji")
+	sendCmd.Flags().StringVar(&sendQuoteFrom, "quote-from", "", "Chat JID the --reply-to message is in, to quote across chats")
+	sendCmd.Flags().StringArrayVar(&sendMentions, "mention", nil, "Phone number to mention (repeatable, groups only)")
+	sendCmd.Flags().BoolVar(&sendMentionsFromText, "mentions-from-text", false, "Mention the group members named by @number tokens in the text, warning about non-members")
+	sendCmd.Flags().BoolVar(&sendMentionsAll, "mentions-all", false, "Mention every group member (groups only)")
+	sendCmd.Flags().BoolVarP(&sendYes, "yes", "y", false, "Skip confirmation prompts")
+	sendCmd.Flags().BoolVar(&sendNoSplit, "no-split", false, "Send long text as a single message")
+	sendCmd.Flags().IntVar(&sendSplitLength, "split-length", defaultSplitLength, "Split text messages longer than this many characters")
+	sendCmd.Flags().StringVar(&sendFromTemplate, "from-template", "", "Render the message from a text/template file")
+	sendCmd.Flags().StringArrayVar(&sendVars, "var", nil, "Template variable as name=value (repeatable)")
+	sendCmd.Flags().StringSliceVar(&sendTo, "to", nil, "Send to each of these JIDs (repeatable or comma-separated)")
+	sendCmd.Flags().StringVar(&sendToFile, "to-file", "", "Send to every recipient in a CSV file with a jid column")
+	sendCmd.Flags().IntVar(&sendConcurrency, "concurrency", 1, "How many --to-file recipients to send to at once")
+	sendCmd.Flags().StringArrayVar(&sendButtons, "button", nil, `Reply button as "Text:id" (repeatable, up to 3; business accounts only)`)
+	sendCmd.Flags().BoolVar(&sendLinkPreview, "link-preview", false, "Attach a preview of the first URL in the text")
+	sendCmd.Flags().BoolVar(&sendLowPriority, "low-priority", false, "Send without notifying the recipient (not supported by WhatsApp, always fails)")
+	sendCmd.Flags().BoolVar(&sendLowPriority, "silent", false, "Alias for --low-priority")
+	sendCmd.Flags().BoolVar(&sendUntilAck, "retry-until-delivered", false, "Resend until a delivery receipt arrives or --delivery-deadline passes")
+	sendCmd.Flags().DurationVar(&sendAckDeadline, "delivery-deadline", 5*time.Minute, "How long --retry-until-delivered waits for delivery")
+	sendCmd.Flags().IntVar(&sendMaxAttempts, "max-attempts", 3, "Most messages --retry-until-delivered sends, including the first")
+	sendCmd.Flags().BoolVar(&sendReconnect, "reconnect-retry", false, "Reconnect and retry once if the connection dropped")
+	sendCmd.Flags().BoolVar(&sendNoEphemeral, "no-ephemeral", false, "Send without the chat's disappearing message timer")
 }
 
 func runSend(cmd *cobra.Command, args []string) error {
+	if err := whatsapp.CheckSendOptions(whatsapp.SendOptions{LowPriority: sendLowPriority}); err != nil {
+		return err
+	}
+
+	if sendToFile != "" {
+		if sendUntilAck {
+			return fmt.Errorf("--retry-until-delivered can't be combined with --to-file")
+		}
+		if len(sendTo) > 0 {
+			return fmt.Errorf("--to can't be combined with --to-file")
+		}
+		return runSendToFile(args)
+	}
+	if len(sendTo) > 0 {
+		return runSendBroadcast(args)
+	}
+
 	jid := args[0]
-	message := ""
-	if len(args) > 1 {
-		message = strings.Join(args[1:], " ")
+	message, err := sendMessageText(args[1:])
+	if err != nil {
+		return err
+	}
+
+	if sendQuoteFrom != "" {
+		if sendReplyTo == "" {
+			return fmt.Errorf("--quote-from requires --reply-to")
+		}
+		if sendFile != "" {
+			return fmt.Errorf("--quote-from is only supported for text messages")
+		}
+	}
+
+	if sendReact != "" {
+		if sendReplyTo == "" {
+			return fmt.Errorf("--react requires --reply-to")
+		}
+		if sendUntilAck {
+			return fmt.Errorf("--react can't be combined with --retry-until-delivered")
+		}
+		if _, err := whatsapp.ValidateReaction(sendReact); err != nil {
+			return err
+		}
+	}
+
+	if sendLinkPreview && sendFile != "" {
+		return fmt.Errorf("--link-preview is only supported for text messages")
+	}
+
+	buttons, err := sendButtonOptions()
+	if err != nil {
+		return err
+	}
+	if len(buttons) > 0 && strings.TrimSpace(message) == "" {
+		return fmt.Errorf("--button needs message text")
+	}
+
+	mediaOpts, err := sendMediaOptions()
+	if err != nil {
+		return err
+	}
+
+	if sendUntilAck {
+		if sendFile != "" {
+			return fmt.Errorf("--retry-until-delivered is only supported for text messages")
+		}
+		if !sendNoSplit && len(whatsapp.SplitText(message, sendSplitLength)) > 1 {
+			return fmt.Errorf("--retry-until-delivered sends a single message; shorten it or use --no-split")
+		}
+		if sendReconnect {
+			return fmt.Errorf("--reconnect-retry can't be combined with --retry-until-delivered")
+		}
+		if sendAckDeadline <= 0 || sendMaxAttempts < 1 {
+			return fmt.Errorf("--delivery-deadline must be positive and --max-attempts at least 1")
+		}
+	}
+
+	if len(sendMentions) > 0 {
+		if !strings.HasSuffix(jid, "@g.us") {
+			return fmt.Errorf("--mention requires a group JID")
+		}
+		if sendFile != "" {
+			return fmt.Errorf("--mention is only supported for text messages")
+		}
+	}
+
+	if sendMentionsFromText {
+		if !strings.HasSuffix(jid, "@g.us") {
+			return fmt.Errorf("--mentions-from-text requires a group JID")
+		}
+		if sendFile != "" {
+			return fmt.Errorf("--mentions-from-text is only supported for text messages")
+		}
+	}
+
+	if sendMentionsAll {
+		if !strings.HasSuffix(jid, "@g.us") {
+			return fmt.Errorf("--mentions-all requires a group JID")
+		}
+		if sendFile != "" {
+			return fmt.Errorf("--mentions-all is only supported for text messages")
+		}
+		if !sendYes && !confirm(fmt.Sprintf("This will mention every member of %s. Continue?", jid)) {
+			return fmt.Errorf("aborted: --mentions-all pings every member, pass --yes to confirm")
+		}
 	}
 
 	return WithConnection(func(db *store.DB, client *whatsapp.Client) error {
-		var result *whatsapp.SendMessageResult
-		var err error
+		client.IgnoreEphemeral = sendNoEphemeral
+		if len(buttons) > 0 {
+			var result *whatsapp.SendMessageResult
+			reconnected, err := sendRetrying(client, func() (err error) {
+				result, err = client.SendButtons(jid, message, buttons)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("send failed: %w", err)
+			}
+			return OutputResult(store.SendResult{
+				MessageID:   result.MessageID,
+				ChatJID:     result.ChatJID,
+				Timestamp:   result.Timestamp,
+				Reconnected: reconnected,
+			}, fmt.Sprintf("Sent message %s with %d buttons%s", result.MessageID, len(buttons), reconnectedNote(reconnected)))
+		}
+		if sendFile != "" {
+			var result *whatsapp.SendMessageResult
+			reconnected, err := sendRetrying(client, func() (err error) {
+				result, err = client.SendMedia(jid, sendFile, sendCaption, sendReplyTo, mediaOpts)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("send failed: %w", err)
+			}
+			return outputSent(client, store.SendResult{
+				MessageID:   result.MessageID,
+				ChatJID:     result.ChatJID,
+				Timestamp:   result.Timestamp,
+				Reconnected: reconnected,
+			}, fmt.Sprintf("Sent message %s%s", result.MessageID, reconnectedNote(reconnected)))
+		}
+
+		opts := whatsapp.SendOptions{ReplyTo: sendReplyTo, QuoteFrom: sendQuoteFrom}
+		if sendMentionsAll {
+			var err error
+			opts.Mentions, err = client.GroupMentions(jid)
+			if err != nil {
+				return fmt.Errorf("failed to resolve group members: %w", err)
+			}
+		}
+		if strings.HasSuffix(jid, "@g.us") {
+			// Tokens in the text are mentioned as typed unless resolved to members
+			tokenText := message
+			if sendMentionsFromText {
+				text, members, unmatched, err := client.MentionsFromText(jid, message)
+				if err != nil {
+					return fmt.Errorf("failed to resolve mentions: %w", err)
+				}
+				for _, number := range unmatched {
+					OutputWarning("@%s isn't a member of %s, so it won't be mentioned", number, jid)
+				}
+				message, tokenText = text, ""
+				opts.Mentions = append(opts.Mentions, members...)
+			}
+			mentions, err := whatsapp.MentionJIDs(tokenText, sendMentions)
+			if err != nil {
+				return err
+			}
+			for _, m := range mentions {
+				if !slices.Contains(opts.Mentions, m) {
+					opts.Mentions = append(opts.Mentions, m)
+				}
+			}
+		}
+		if sendLinkPreview {
+			opts.LinkPreview = fetchLinkPreview(message, nil)
+		}
+
+		if sendUntilAck {
+			return sendUntilDelivered(client, jid, message, opts)
+		}
+
+		result, err := sendText(client, jid, message, opts)
+		if err != nil {
+			return err
+		}
+
+		if len(result.MessageIDs) > 1 {
+			return outputSent(client, result, fmt.Sprintf("Sent %d messages: %s%s", len(result.MessageIDs), strings.Join(result.MessageIDs, ", "), reconnectedNote(result.Reconnected)))
+		}
+		return outputSent(client, result, fmt.Sprintf("Sent message %s%s", result.MessageID, reconnectedNote(result.Reconnected)))
+	})
+}
+
+// outputSent reports a sent message, first reacting to the message it replies
+// to if --react was given. A failed reaction is reported after the message,
+// which was still sent.
+func outputSent(client *whatsapp.Client, result store.SendResult, msg string) error {
+	result, msg, reactErr := reactAfterSend(result, msg, client.SendReaction)
+	if err := OutputResult(result, msg); err != nil {
+		return err
+	}
+	return reactErr
+}
+
+// reactAfterSend sends the --react reaction to the --reply-to message,
+// recording it on result. On failure result and msg are returned unchanged
+// along with the error.
+func reactAfterSend(result store.SendResult, msg string, react func(chatJID, messageID, emoji string, remove bool) (*whatsapp.SendMessageResult, error)) (store.SendResult, string, error) {
+	if sendReact == "" {
+		return result, msg, nil
+	}
+
+	// The replied-to message is in the --quote-from chat when quoting across chats
+	chat := result.ChatJID
+	if sendQuoteFrom != "" {
+		chat = sendQuoteFrom
+	}
+	reaction, err := react(chat, sendReplyTo, sendReact, false)
+	if err != nil {
+		return result, msg, fmt.Errorf("sent message %s, but reacting to %s failed: %w", result.MessageID, sendReplyTo, err)
+	}
+
+	result.Reaction = &store.SendResult{
+		MessageID: reaction.MessageID,
+		ChatJID:   reaction.ChatJID,
+		Timestamp: reaction.Timestamp,
+	}
+	return result, fmt.Sprintf("%s and reacted to %s", msg, sendReplyTo), nil
+}
+
+// sendButtonOptions parses --button, checking it isn't combined with options
+// that only apply to plain text or media messages.
+func sendButtonOptions() ([]whatsapp.Button, error) {
+	if len(sendButtons) == 0 {
+		return nil, nil
+	}
+	switch {
+	case sendFile != "":
+		return nil, fmt.Errorf("--button is only supported for text messages")
+	case sendReplyTo != "":
+		return nil, fmt.Errorf("--button can't be combined with --reply-to")
+	case sendMentionsAll || len(sendMentions) > 0 || sendMentionsFromText:
+		return nil, fmt.Errorf("--button can't be combined with mentions")
+	case sendLinkPreview:
+		return nil, fmt.Errorf("--button can't be combined with --link-preview")
+	case sendUntilAck:
+		return nil, fmt.Errorf("--button can't be combined with --retry-until-delivered")
+	}
+
+	buttons := make([]whatsapp.Button, len(sendButtons))
+	for i, spec := range sendButtons {
+		b, err := whatsapp.ParseButton(spec)
+		if err != nil {
+			return nil, err
+		}
+		buttons[i] = b
+	}
+	if err := whatsapp.ValidateButtons(buttons); err != nil {
+		return nil, err
+	}
+	return buttons, nil
+}
+
+// sendMessageText is the message to send: args joined, or --from-template
+// rendered with --var.
+func sendMessageText(args []string) (string, error) {
+	if sendFromTemplate == "" {
+		return strings.Join(args, " "), nil
+	}
+	if sendFile != "" {
+		return "", fmt.Errorf("--from-template is only supported for text messages")
+	}
+
+	tpl, err := loadMessageTemplate(sendFromTemplate)
+	if err != nil {
+		return "", err
+	}
+	vars, err := parseTemplateVars(sendVars)
+	if err != nil {
+		return "", err
+	}
+	return renderMessageTemplate(tpl, vars)
+}
+
+// runSendBroadcast sends the same message or file to every --to recipient.
+func runSendBroadcast(args []string) error {
+	switch {
+	case sendUntilAck:
+		return fmt.Errorf("--retry-until-delivered can't be combined with --to")
+	case sendReplyTo != "" || sendQuoteFrom != "" || sendReact != "":
+		return fmt.Errorf("--reply-to and --react can't be combined with --to")
+	case sendMentionsAll || len(sendMentions) > 0 || sendMentionsFromText:
+		return fmt.Errorf("--mention, --mentions-all and --mentions-from-text can't be combined with --to")
+	case len(sendButtons) > 0:
+		return fmt.Errorf("--button can't be combined with --to")
+	case sendLinkPreview && sendFile != "":
+		return fmt.Errorf("--link-preview is only supported for text messages")
+	}
+
+	var recipients []string
+	for _, jid := range sendTo {
+		if jid = strings.TrimSpace(jid); jid != "" && !slices.Contains(recipients, jid) {
+			recipients = append(recipients, jid)
+		}
+	}
+	if len(recipients) == 0 {
+		return fmt.Errorf("--to needs at least one JID")
+	}
+
+	message, err := sendMessageText(args)
+	if err != nil {
+		return err
+	}
+
+	mediaOpts, err := sendMediaOptions()
+	if err != nil {
+		return err
+	}
 
+	return WithConnection(func(db *store.DB, client *whatsapp.Client) error {
+		client.IgnoreEphemeral = sendNoEphemeral
+
+		// Upload once; every recipient is sent the same media
+		var media *whatsapp.PreparedMedia
 		if sendFile != "" {
-			result, err = client.SendMedia(jid, sendFile, sendCaption, sendReplyTo)
-		} else {
-			result, err = client.SendText(jid, message, sendReplyTo)
+			if _, err := sendRetrying(client, func() (err error) {
+				media, err = client.PrepareMedia(sendFile, mediaOpts)
+				return err
+			}); err != nil {
+				return fmt.Errorf("send failed: %w", err)
+			}
 		}
 
+		var opts whatsapp.SendOptions
+		if sendLinkPreview {
+			opts.LinkPreview = fetchLinkPreview(message, nil)
+		}
+
+		// Ctrl-C stops sending to the remaining recipients
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		sent := whatsapp.RunBounded(ctx, recipients, sendConcurrency, func(_ context.Context, jid string) (store.SendResult, error) {
+			if media != nil {
+				return sendPreparedMedia(client, jid, media)
+			}
+
+			opts := opts
+			if strings.HasSuffix(jid, "@g.us") {
+				mentions, err := whatsapp.MentionJIDs(message, nil)
+				if err != nil {
+					return store.SendResult{}, err
+				}
+				opts.Mentions = mentions
+			}
+			return sendText(client, jid, message, opts)
+		})
+		return outputBatch(recipients, sent)
+	})
+}
+
+// sendMediaOptions returns how --file is converted and named, from --voice,
+// --compress, --as-document, --doc-title and the WHATSAPP_OPUS_* variables.
+func sendMediaOptions() (whatsapp.MediaOptions, error) {
+	if sendCompress != 0 && sendFile == "" {
+		return whatsapp.MediaOptions{}, fmt.Errorf("--compress requires --file")
+	}
+	if sendDocTitle != "" && sendFile == "" {
+		return whatsapp.MediaOptions{}, fmt.Errorf("--doc-title requires --file")
+	}
+	if sendAsDocument {
+		switch {
+		case sendFile == "":
+			return whatsapp.MediaOptions{}, fmt.Errorf("--as-document requires --file")
+		case sendCompress != 0:
+			return whatsapp.MediaOptions{}, fmt.Errorf("--as-document sends the original file; drop --compress")
+		}
+	}
+	if sendCompress < 0 || sendCompress > 100 {
+		return whatsapp.MediaOptions{}, fmt.Errorf("--compress quality must be between 1 and 100")
+	}
+	if sendMaxDimension < 0 {
+		return whatsapp.MediaOptions{}, fmt.Errorf("--max-dimension must not be negative")
+	}
+	if sendFile == "" {
+		return whatsapp.MediaOptions{}, nil
+	}
+
+	opts, err := whatsapp.DefaultMediaOptions(sendVoice)
+	if err != nil {
+		return opts, err
+	}
+	opts.ImageQuality = sendCompress
+	opts.ImageMaxDim = sendMaxDimension
+	opts.DocTitle = sendDocTitle
+	opts.AsDocument = sendAsDocument
+	return opts, nil
+}
+
+// sendPreparedMedia sends already uploaded media with --caption.
+func sendPreparedMedia(client *whatsapp.Client, jid string, media *whatsapp.PreparedMedia) (store.SendResult, error) {
+	var result *whatsapp.SendMessageResult
+	reconnected, err := sendRetrying(client, func() (err error) {
+		result, err = client.SendPreparedMedia(jid, media, sendCaption, "")
+		return err
+	})
+	if err != nil {
+		return store.SendResult{}, fmt.Errorf("send failed: %w", err)
+	}
+	return store.SendResult{
+		MessageID:   result.MessageID,
+		ChatJID:     result.ChatJID,
+		Timestamp:   result.Timestamp,
+		Reconnected: reconnected,
+	}, nil
+}
+
+// sendText sends message, splitting it into parts unless --no-split is set.
+// Only the first part quotes opts.ReplyTo. The @tokens for the mentions are
+// added before splitting and each part mentions those whose tokens it holds.
+// The link preview goes on the part with its URL.
+func sendText(client *whatsapp.Client, jid, message string, opts whatsapp.SendOptions) (store.SendResult, error) {
+	if len(opts.Mentions) > 0 {
+		message = whatsapp.AppendMentionTokens(message, opts.Mentions)
+	}
+	parts := []string{message}
+	if !sendNoSplit {
+		parts = whatsapp.SplitText(message, sendSplitLength)
+	}
+
+	var out store.SendResult
+	var ids []string
+
+	previewSent := false
+	for i, part := range parts {
+		partOpts := whatsapp.SendOptions{}
+		if i == 0 {
+			partOpts.ReplyTo = opts.ReplyTo
+			partOpts.QuoteFrom = opts.QuoteFrom
+		}
+		partOpts.Mentions = whatsapp.MentionsInText(part, opts.Mentions)
+		if p := opts.LinkPreview; p != nil && !previewSent && strings.Contains(part, p.URL) {
+			partOpts.LinkPreview = p
+			previewSent = true
+		}
+
+		var result *whatsapp.SendMessageResult
+		reconnected, err := sendRetrying(client, func() (err error) {
+			result, err = client.SendText(jid, part, partOpts)
+			return err
+		})
+		out.Reconnected = out.Reconnected || reconnected
 		if err != nil {
-			return fmt.Errorf("send failed: %w", err)
+			if i > 0 {
+				return out, fmt.Errorf("send failed after %d of %d parts (sent: %s): %w", i, len(parts), strings.Join(ids, ", "), err)
+			}
+			return out, fmt.Errorf("send failed: %w", err)
 		}
 
-		return OutputResult(store.SendResult{
-			MessageID: result.MessageID,
-			ChatJID:   result.ChatJID,
-			Timestamp: result.Timestamp,
-		}, fmt.Sprintf("Sent message %s", result.MessageID))
+		if i == 0 {
+			out.MessageID = result.MessageID
+			out.ChatJID = result.ChatJID
+			out.Timestamp = result.Timestamp
+		}
+		ids = append(ids, result.MessageID)
+	}
+
+	if len(ids) > 1 {
+		out.MessageIDs = ids
+	}
+	return out, nil
+}
+
+// sendRetrying runs send, reconnecting and retrying it once on a dropped
+// connection when --reconnect-retry is set.
+func sendRetrying(client *whatsapp.Client, send func() error) (reconnected bool, err error) {
+	if !sendReconnect {
+		return false, send()
+	}
+	return client.RetryOnDisconnect(send)
+}
+
+// reconnectedNote is appended to human output when a send needed a reconnect.
+func reconnectedNote(reconnected bool) string {
+	if reconnected {
+		return " (after reconnecting)"
+	}
+	return ""
+}
+
+// sendUntilDelivered sends message with --retry-until-delivered, failing if no
+// delivery receipt arrived before the deadline.
+func sendUntilDelivered(client *whatsapp.Client, jid, message string, opts whatsapp.SendOptions) error {
+	if len(opts.Mentions) > 0 {
+		message = whatsapp.AppendMentionTokens(message, opts.Mentions)
+	}
+
+	result, err := client.SendTextUntilDelivered(jid, message, opts, whatsapp.DeliveryRetry{
+		Deadline:    sendAckDeadline,
+		MaxAttempts: sendMaxAttempts,
 	})
+	if err != nil {
+		return fmt.Errorf("send failed: %w", err)
+	}
+
+	if !result.Delivered {
+		if err := Output(result); err != nil {
+			return err
+		}
+		return fmt.Errorf("not delivered after %d attempt(s) within %s", result.Attempts, sendAckDeadline)
+	}
+	return OutputResult(result, fmt.Sprintf("Delivered message %s after %d attempt(s)", result.MessageID, result.Attempts))
+}
+
+// fetchLinkPreview fetches a preview of the first URL in message, warning and
+// returning nil if there is none or it can't be fetched. Previews are reused
+// from cache when one is given.
+func fetchLinkPreview(message string, cache map[string]*whatsapp.LinkPreview) *whatsapp.LinkPreview {
+	url := whatsapp.FirstURL(message)
+	if url == "" {
+		OutputWarning("--link-preview: no URL found in message")
+		return nil
+	}
+	if p, ok := cache[url]; ok {
+		return p
+	}
+
+	p, err := whatsapp.FetchLinkPreview(context.Background(), url)
+	if err != nil {
+		OutputWarning("--link-preview: sending without a preview: %v", err)
+	}
+	if cache != nil {
+		cache[url] = p
+	}
+	return p
 }
@@ -0,0 +1,224 @@
+package cli
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"text/template"
+
+	"github.com/eddmann/whatsapp-cli/internal/store"
+	"github.com/eddmann/whatsapp-cli/internal/whatsapp"
+)
+
+// sendRecipient is one row of a --to-file CSV.
+type sendRecipient struct {
+	JID  string
+	Vars map[string]string
+}
+
+// batchSendResult reports the outcome of sending to one --to or --to-file
+// recipient.
+type batchSendResult struct {
+	ChatJID     string   `json:"chat_jid"`
+	MessageID   string   `json:"message_id,omitempty"`
+	Timestamp   string   `json:"timestamp,omitempty"`
+	MessageIDs  []string `json:"message_ids,omitempty"`
+	Reconnected bool     `json:"reconnected,omitempty"`
+	Error       string   `json:"error,omitempty"`
+}
+
+// loadMessageTemplate parses a message template. Missing variables are errors.
+func loadMessageTemplate(path string) (*template.Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template: %w", err)
+	}
+
+	tpl, err := template.New(path).Option("missingkey=error").Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	return tpl, nil
+}
+
+// parseTemplateVars parses name=value pairs from --var flags.
+func parseTemplateVars(pairs []string) (map[string]string, error) {
+	vars := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		name, value, ok := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid --var %q, expected name=value", pair)
+		}
+		vars[name] = value
+	}
+	return vars, nil
+}
+
+// renderMessageTemplate executes tpl with vars, failing if any placeholder has no value.
+func renderMessageTemplate(tpl *template.Template, vars map[string]string) (string, error) {
+	var b strings.Builder
+	if err := tpl.Execute(&b, vars); err != nil {
+		return "", fmt.Errorf("failed to render template: %w", err)
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+// readRecipients reads a CSV with a header row containing a "jid" column.
+// Every other column becomes a template variable for that row.
+func readRecipients(r io.Reader) ([]sendRecipient, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	jidCol := -1
+	for i, name := range header {
+		header[i] = strings.TrimSpace(name)
+		if strings.EqualFold(header[i], "jid") {
+			jidCol = i
+		}
+	}
+	if jidCol < 0 {
+		return nil, fmt.Errorf("missing jid column")
+	}
+
+	var recipients []sendRecipient
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		jid := strings.TrimSpace(row[jidCol])
+		if jid == "" {
+			return nil, fmt.Errorf("line %d: empty jid", line)
+		}
+
+		vars := make(map[string]string, len(header)-1)
+		for i, value := range row {
+			if i != jidCol {
+				vars[header[i]] = value
+			}
+		}
+		recipients = append(recipients, sendRecipient{JID: jid, Vars: vars})
+	}
+
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("no recipients")
+	}
+	return recipients, nil
+}
+
+func runSendToFile(args []string) error {
+	if sendFile != "" || sendMentionsAll || len(sendMentions) > 0 || sendMentionsFromText || len(sendButtons) > 0 || sendReplyTo != "" || sendReact != "" {
+		return fmt.Errorf("--to-file only supports plain text messages")
+	}
+
+	f, err := os.Open(sendToFile)
+	if err != nil {
+		return fmt.Errorf("failed to open recipients file: %w", err)
+	}
+	recipients, err := readRecipients(f)
+	_ = f.Close()
+	if err != nil {
+		return fmt.Errorf("invalid recipients file %s: %w", sendToFile, err)
+	}
+
+	defaults, err := parseTemplateVars(sendVars)
+	if err != nil {
+		return err
+	}
+
+	var tpl *template.Template
+	if sendFromTemplate != "" {
+		if tpl, err = loadMessageTemplate(sendFromTemplate); err != nil {
+			return err
+		}
+	}
+
+	// Render everything up front so a missing variable doesn't leave a partial send.
+	messages := make([]string, len(recipients))
+	for i, r := range recipients {
+		if tpl == nil {
+			messages[i] = strings.Join(args, " ")
+			continue
+		}
+
+		vars := make(map[string]string, len(defaults)+len(r.Vars))
+		for k, v := range defaults {
+			vars[k] = v
+		}
+		for k, v := range r.Vars {
+			vars[k] = v
+		}
+
+		if messages[i], err = renderMessageTemplate(tpl, vars); err != nil {
+			return fmt.Errorf("recipient %s: %w", r.JID, err)
+		}
+	}
+
+	return WithConnection(func(db *store.DB, client *whatsapp.Client) error {
+		client.IgnoreEphemeral = sendNoEphemeral
+		// Fetch previews first, once per URL, so the parallel sends share them
+		previews := make(map[string]*whatsapp.LinkPreview)
+		opts := make([]whatsapp.SendOptions, len(recipients))
+		if sendLinkPreview {
+			for i := range recipients {
+				opts[i].LinkPreview = fetchLinkPreview(messages[i], previews)
+			}
+		}
+
+		// Ctrl-C stops sending to the remaining recipients
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		indexes := make([]int, len(recipients))
+		jids := make([]string, len(recipients))
+		for i, r := range recipients {
+			indexes[i] = i
+			jids[i] = r.JID
+		}
+		sent := whatsapp.RunBounded(ctx, indexes, sendConcurrency, func(_ context.Context, i int) (store.SendResult, error) {
+			return sendText(client, recipients[i].JID, messages[i], opts[i])
+		})
+		return outputBatch(jids, sent)
+	})
+}
+
+// outputBatch outputs one result per recipient, failing if any send failed.
+func outputBatch(jids []string, sent []whatsapp.Result[store.SendResult]) error {
+	results := make([]batchSendResult, len(jids))
+	failed := 0
+	for i, r := range sent {
+		results[i] = batchSendResult{ChatJID: jids[i]}
+		if r.Err != nil {
+			results[i].Error = r.Err.Error()
+			failed++
+			continue
+		}
+		results[i].ChatJID = r.Value.ChatJID
+		results[i].MessageID = r.Value.MessageID
+		results[i].Timestamp = r.Value.Timestamp
+		results[i].MessageIDs = r.Value.MessageIDs
+		results[i].Reconnected = r.Value.Reconnected
+	}
+
+	if err := Output(results); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d sends failed", failed, len(jids))
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package cli
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.mau.fi/whatsmeow"
+
+	"github.com/eddmann/whatsapp-cli/internal/store"
+	"github.com/eddmann/whatsapp-cli/internal/whatsapp"
+)
+
+func TestRenderMessageTemplateRequiresAllVars(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "invite.txt")
+	if err := os.WriteFile(path, []byte("Hi {{.name}}, see you {{.date}}!\n"), 0600); err != nil {
+		t.Fatalf("write template: %v", err)
+	}
+
+	tpl, err := loadMessageTemplate(path)
+	if err != nil {
+		t.Fatalf("load template: %v", err)
+	}
+
+	got, err := renderMessageTemplate(tpl, map[string]string{"name": "Jane", "date": "Friday"})
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if got != "Hi Jane, see you Friday!" {
+		t.Fatalf("unexpected render %q", got)
+	}
+
+	if _, err := renderMessageTemplate(tpl, map[string]string{"name": "Jane"}); err == nil {
+		t.Fatal("expected error for missing variable")
+	}
+}
+
+func TestReadRecipients(t *testing.T) {
+	recipients, err := readRecipients(strings.NewReader("name,JID\nJane,447700900001@s.whatsapp.net\nBob,447700900002@s.whatsapp.net\n"))
+	if err != nil {
+		t.Fatalf("read recipients: %v", err)
+	}
+	if len(recipients) != 2 {
+		t.Fatalf("expected 2 recipients, got %d", len(recipients))
+	}
+	if recipients[1].JID != "447700900002@s.whatsapp.net" || recipients[1].Vars["name"] != "Bob" {
+		t.Fatalf("unexpected recipient %+v", recipients[1])
+	}
+
+	if _, err := readRecipients(strings.NewReader("name\nJane\n")); err == nil {
+		t.Fatal("expected error for missing jid column")
+	}
+}
+
+func TestParseTemplateVars(t *testing.T) {
+	vars, err := parseTemplateVars([]string{"name=Jane", "note=a=b"})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if vars["name"] != "Jane" || vars["note"] != "a=b" {
+		t.Fatalf("unexpected vars %v", vars)
+	}
+	if _, err := parseTemplateVars([]string{"novalue"}); err == nil {
+		t.Fatal("expected error for missing =")
+	}
+}
+
+func TestSendArgsWithTo(t *testing.T) {
+	t.Cleanup(func() {
+		// Leave --to unset for later tests, as if it was never given
+		flag := sendCmd.Flags().Lookup("to")
+		if err := flag.Value.(interface{ Replace([]string) error }).Replace(nil); err != nil {
+			t.Errorf("reset --to: %v", err)
+		}
+		flag.Changed = false
+	})
+	if err := sendCmd.Flags().Set("to", "1@s.whatsapp.net,2@s.whatsapp.net"); err != nil {
+		t.Fatalf("set --to: %v", err)
+	}
+	if len(sendTo) != 2 {
+		t.Fatalf("expected comma-separated --to to give 2 recipients, got %q", sendTo)
+	}
+
+	if err := sendCmd.Args(sendCmd, []string{"Office", "closed"}); err != nil {
+		t.Fatalf("expected every arg to be the message with --to, got %v", err)
+	}
+	if err := sendCmd.Args(sendCmd, nil); err == nil {
+		t.Fatal("expected an error without a message or file")
+	}
+}
+
+func TestSendArgsWithoutTo(t *testing.T) {
+	if sendCmd.Flags().Changed("to") || len(sendTo) != 0 {
+		t.Fatalf("expected --to unset, got %q", sendTo)
+	}
+	if err := sendCmd.Args(sendCmd, []string{"hello"}); err == nil {
+		t.Fatal("expected a recipient to be needed without --to")
+	}
+}
+
+func TestSendSilentFailsBeforeSending(t *testing.T) {
+	t.Cleanup(func() {
+		sendLowPriority = false
+		sendCmd.Flags().Lookup("silent").Changed = false
+	})
+	if err := sendCmd.Flags().Set("silent", "true"); err != nil {
+		t.Fatalf("set --silent: %v", err)
+	}
+	if !sendLowPriority {
+		t.Fatal("expected --silent to set --low-priority")
+	}
+
+	err := runSend(sendCmd, []string{"123", "hello"})
+	if !errors.Is(err, whatsapp.ErrLowPriorityUnsupported) {
+		t.Fatalf("expected ErrLowPriorityUnsupported, got %v", err)
+	}
+}
+
+func TestReactAfterSend(t *testing.T) {
+	t.Cleanup(func() { sendReact, sendReplyTo, sendQuoteFrom = "", "", "" })
+	sent := store.SendResult{MessageID: "REPLY1", ChatJID: "123@s.whatsapp.net", Timestamp: "2026-04-24T12:00:00Z"}
+
+	var reactedIn, reactedTo, emoji string
+	react := func(chatJID, messageID, e string, remove bool) (*whatsapp.SendMessageResult, error) {
+		if remove {
+			t.Fatal("expected a reaction to be added, not removed")
+		}
+		reactedIn, reactedTo, emoji = chatJID, messageID, e
+		return &whatsapp.SendMessageResult{Success: true, MessageID: "REACT1", ChatJID: chatJID, Timestamp: "2026-04-24T12:00:01Z"}, nil
+	}
+
+	// Without --react nothing more is sent
+	result, msg, err := reactAfterSend(sent, "Sent message REPLY1", func(string, string, string, bool) (*whatsapp.SendMessageResult, error) {
+		t.Fatal("expected no reaction without --react")
+		return nil, nil
+	})
+	if err != nil || result.Reaction != nil || msg != "Sent message REPLY1" {
+		t.Fatalf("unexpected result %+v %q %v", result, msg, err)
+	}
+
+	sendReact, sendReplyTo = "👍", "ORIG1"
+	result, msg, err = reactAfterSend(sent, "Sent message REPLY1", react)
+	if err != nil {
+		t.Fatalf("react: %v", err)
+	}
+	if reactedIn != sent.ChatJID || reactedTo != "ORIG1" || emoji != "👍" {
+		t.Fatalf("reacted to %s in %s with %s", reactedTo, reactedIn, emoji)
+	}
+	if result.MessageID != "REPLY1" || result.Reaction == nil || result.Reaction.MessageID != "REACT1" {
+		t.Fatalf("expected both results, got %+v", result)
+	}
+	if msg != "Sent message REPLY1 and reacted to ORIG1" {
+		t.Fatalf("unexpected message %q", msg)
+	}
+
+	// A reply quoting another chat reacts in that chat
+	sendQuoteFrom = "456@g.us"
+	if _, _, err := reactAfterSend(sent, "", react); err != nil || reactedIn != "456@g.us" {
+		t.Fatalf("expected the reaction in the --quote-from chat, got %s (%v)", reactedIn, err)
+	}
+}
+
+func TestOutputSentReportsMessageWhenReactionFails(t *testing.T) {
+	origFormat := resolvedFormat
+	t.Cleanup(func() { sendReact, sendReplyTo, resolvedFormat = "", "", origFormat })
+	sendReact, sendReplyTo, resolvedFormat = "👍", "ORIG1", FormatJSON
+
+	out, err := os.CreateTemp(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatalf("create stdout: %v", err)
+	}
+	defer func() { _ = out.Close() }()
+	orig := os.Stdout
+	os.Stdout = out
+	t.Cleanup(func() { os.Stdout = orig })
+
+	// A disconnected client can't react, but the reply was already sent
+	client := &whatsapp.Client{WA: &whatsmeow.Client{}}
+	err = outputSent(client, store.SendResult{MessageID: "REPLY1", ChatJID: "123@s.whatsapp.net"}, "Sent message REPLY1")
+	if !errors.Is(err, whatsapp.ErrNotConnected) {
+		t.Fatalf("expected the reaction error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "sent message REPLY1, but reacting to ORIG1 failed") {
+		t.Fatalf("expected the error to say the message was sent, got %v", err)
+	}
+
+	os.Stdout = orig
+	printed, err := os.ReadFile(out.Name())
+	if err != nil {
+		t.Fatalf("read stdout: %v", err)
+	}
+	if !strings.Contains(string(printed), "REPLY1") || strings.Contains(string(printed), "reaction") {
+		t.Fatalf("expected the sent message reported without a reaction, got %q", printed)
+	}
+}
@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/eddmann/whatsapp-cli/internal/store"
+	"github.com/eddmann/whatsapp-cli/internal/whatsapp"
+)
+
+var (
+	starChat   string
+	starUnstar bool
+
+	starredChat  string
+	starredLimit int
+)
+
+var starCmd = &cobra.Command{
+	Use:   "star <msg-id>",
+	Short: "Star or unstar a message",
+	Long: `Star or unstar a message.
+
+Requires --chat to specify the chat JID.
+
+Examples:
+  whatsapp star ABC123 --chat 1234567890@s.whatsapp.net
+  whatsapp star ABC123 --chat 1234567890@s.whatsapp.net --unstar`,
+	Args: cobra.ExactArgs(1),
+	RunE: runStar,
+}
+
+var starredCmd = &cobra.Command{
+	Use:   "starred",
+	Short: "List starred messages",
+	Long: `List starred messages from the local database.
+
+Use --chat to limit to a single chat.`,
+	RunE: runStarred,
+}
+
+func init() {
+	rootCmd.AddCommand(starCmd)
+	starCmd.Flags().StringVar(&starChat, "chat", "", "Chat JID (required)")
+	starCmd.Flags().BoolVar(&starUnstar, "unstar", false, "Unstar instead of star")
+	_ = starCmd.MarkFlagRequired("chat")
+
+	rootCmd.AddCommand(starredCmd)
+	starredCmd.Flags().StringVar(&starredChat, "chat", "", "Limit to specific chat JID")
+	starredCmd.Flags().IntVar(&starredLimit, "limit", 50, "Maximum number of messages")
+}
+
+func runStar(cmd *cobra.Command, args []string) error {
+	messageID := args[0]
+	starred := !starUnstar
+
+	return WithConnection(func(db *store.DB, client *whatsapp.Client) error {
+		if err := client.StarMessage(starChat, messageID, starred); err != nil {
+			return fmt.Errorf("star failed: %w", err)
+		}
+
+		action := "Starred"
+		if !starred {
+			action = "Unstarred"
+		}
+
+		return OutputResult(map[string]any{
+			"message_id": messageID,
+			"chat_jid":   starChat,
+			"starred":    starred,
+		}, fmt.Sprintf("%s message %s", action, messageID))
+	})
+}
+
+func runStarred(cmd *cobra.Command, args []string) error {
+	return WithDB(func(db *store.DB) error {
+		messages, err := db.ListMessages(store.ListMessagesOptions{
+			ChatJID: starredChat,
+			Starred: true,
+			Limit:   starredLimit,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list starred messages: %w", err)
+		}
+		return Output(messages)
+	})
+}
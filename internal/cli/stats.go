@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/eddmann/whatsapp-cli/internal/store"
+)
+
+var (
+	statsHeatmap   bool
+	statsChat      string
+	statsTimeframe string
+	statsAfter     string
+	statsBefore    string
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show message activity statistics",
+	Long: `Show statistics about stored messages.
+
+--heatmap counts messages by weekday and hour of day (local time), across all
+chats or one --chat. JSON output has a counts matrix indexed [weekday][hour],
+Sunday first; CSV/TSV has one row per weekday and hour; human output draws an
+ASCII heatmap.
+
+Timeframe presets: last_hour, today, yesterday, last_3_days, this_week, last_week, this_month
+
+Examples:
+  whatsapp stats --heatmap --format human
+  whatsapp stats --heatmap --chat 1234567890@s.whatsapp.net --timeframe this_month`,
+	Args: cobra.NoArgs,
+	RunE: runStats,
+}
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+	statsCmd.Flags().BoolVar(&statsHeatmap, "heatmap", false, "Count messages by weekday and hour")
+	statsCmd.Flags().StringVar(&statsChat, "chat", "", "Limit to specific chat JID")
+	statsCmd.Flags().StringVar(&statsTimeframe, "timeframe", "", "Timeframe preset (today, yesterday, this_week, etc.)")
+	statsCmd.Flags().StringVar(&statsAfter, "after", "", "Messages after timestamp (RFC3339)")
+	statsCmd.Flags().StringVar(&statsBefore, "before", "", "Messages before timestamp (RFC3339)")
+}
+
+func runStats(cmd *cobra.Command, args []string) error {
+	if !statsHeatmap {
+		return fmt.Errorf("choose a statistic to show: --heatmap")
+	}
+
+	after, before := statsAfter, statsBefore
+	if statsTimeframe != "" {
+		var err error
+		after, before, err = ParseTimeframe(statsTimeframe)
+		if err != nil {
+			return err
+		}
+	}
+
+	return WithDB(func(db *store.DB) error {
+		heatmap, err := db.ActivityHeatmap(store.HeatmapOptions{
+			ChatJID: statsChat,
+			After:   after,
+			Before:  before,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to count activity: %w", err)
+		}
+
+		opts := GetOutputOptions()
+		switch {
+		case opts.Format == FormatHuman && opts.Template == "":
+			renderHeatmap(os.Stdout, heatmap)
+			return nil
+		case opts.Format == FormatCSV || opts.Format == FormatTSV:
+			return Output(heatmapCells(heatmap))
+		default:
+			return Output(heatmap)
+		}
+	})
+}
+
+// heatmapCells lists every weekday and hour of a heatmap, for tabular output.
+func heatmapCells(h store.ActivityHeatmap) []store.HeatmapCell {
+	cells := make([]store.HeatmapCell, 0, 7*24)
+	for day, hours := range h.Counts {
+		for hour, count := range hours {
+			cells = append(cells, store.HeatmapCell{Day: h.Days[day], Hour: hour, Count: count})
+		}
+	}
+	return cells
+}
+
+// heatmapShades are the cell characters from no messages to the busiest hour.
+const heatmapShades = " .:-=+*#%@"
+
+// renderHeatmap draws a heatmap as a grid of weekdays by hours, shading each
+// cell relative to the busiest hour.
+func renderHeatmap(w io.Writer, h store.ActivityHeatmap) {
+	peak, peakDay, peakHour := 0, 0, 0
+	for day, hours := range h.Counts {
+		for hour, count := range hours {
+			if count > peak {
+				peak, peakDay, peakHour = count, day, hour
+			}
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("   ")
+	for hour := 0; hour < 24; hour++ {
+		fmt.Fprintf(&b, " %02d", hour)
+	}
+	b.WriteString("\n")
+	for day, hours := range h.Counts {
+		b.WriteString(h.Days[day][:3])
+		for _, count := range hours {
+			// Round up, so any activity gets at least the lightest shade.
+			shade := 0
+			if count > 0 {
+				shade = (count*(len(heatmapShades)-1) + peak - 1) / peak
+			}
+			fmt.Fprintf(&b, "  %c", heatmapShades[shade])
+		}
+		b.WriteString("\n")
+	}
+
+	if peak == 0 {
+		b.WriteString("\nNo messages\n")
+	} else {
+		fmt.Fprintf(&b, "\n%d messages, busiest %s %02d:00 (%d), scale %q\n",
+			h.Total, h.Days[peakDay], peakHour, peak, heatmapShades[1:])
+	}
+	_, _ = io.WriteString(w, b.String())
+}
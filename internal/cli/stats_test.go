@@ -0,0 +1,33 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/eddmann/whatsapp-cli/internal/store"
+)
+
+func TestRenderHeatmapShadesRelativeToPeak(t *testing.T) {
+	h := store.ActivityHeatmap{Days: []string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"}}
+	h.Counts[1][9] = 1
+	h.Counts[5][12] = 10
+	h.Total = 11
+
+	var b strings.Builder
+	renderHeatmap(&b, h)
+	lines := strings.Split(b.String(), "\n")
+
+	if !strings.HasPrefix(lines[0], "    00 01") || !strings.HasSuffix(lines[0], " 23") {
+		t.Fatalf("unexpected header %q", lines[0])
+	}
+	cell := func(line string, hour int) byte { return line[3+hour*3+2] }
+	if mon := lines[2]; !strings.HasPrefix(mon, "Mon") || cell(mon, 9) != '.' || cell(mon, 8) != ' ' {
+		t.Fatalf("expected a light cell at Monday 09:00, got %q", mon)
+	}
+	if fri := lines[6]; cell(fri, 12) != '@' {
+		t.Fatalf("expected the darkest cell at Friday 12:00, got %q", fri)
+	}
+	if !strings.Contains(b.String(), "11 messages, busiest Friday 12:00 (10)") {
+		t.Fatalf("expected a summary line, got %q", b.String())
+	}
+}
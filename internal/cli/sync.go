@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -17,6 +18,12 @@ import (
 var (
 	syncFollow        bool
 	syncDownloadMedia bool
+	syncStrict        bool
+	syncIncludeSystem bool
+	syncDumpProto     string
+	syncQuietPeriod   time.Duration
+	syncKeepOnline    bool
+	syncProgress      bool
 )
 
 var syncCmd = &cobra.Command{
@@ -25,17 +32,54 @@ var syncCmd = &cobra.Command{
 	Long: `Connect to WhatsApp and sync new messages to the local database.
 
 By default, performs a one-time sync and exits.
-Use --follow to run continuously and capture messages in real-time.`,
+Use --follow to run continuously and capture messages in real-time. While
+following, messages queued with 'whatsapp schedule add' are sent when due.
+
+--keep-online (with --follow) shows your account as online for as long as it
+runs, for always-on assistants. Contacts who can see your last seen and online
+status will see you online the whole time, and your phone may not notify you
+of new messages while it does. You are shown offline again on Ctrl+C.
+
+Chats or messages that fail to save are counted in "errors", with the first
+few messages in "error_samples". Use --strict to exit non-zero if any occur.
+
+A one-time sync finishes once WhatsApp reports the sync complete and no new
+messages have arrived for a few seconds. If those events never arrive for your
+account, --quiet-period also finishes it after that long without new messages.
+While it runs, a count of new messages saved is shown on stderr when it is a
+terminal, or every few seconds with --progress.
+
+For debugging parsing issues, --dump-proto appends the raw protobuf of every
+incoming message to a file as JSON lines. The file contains message content.
+
+Examples:
+  whatsapp sync
+  whatsapp sync --quiet-period 10s
+  whatsapp sync --follow --keep-online
+  whatsapp sync --follow --dump-proto messages.jsonl`,
 	RunE: runSync,
 }
 
 func init() {
 	rootCmd.AddCommand(syncCmd)
 	syncCmd.Flags().BoolVar(&syncFollow, "follow", false, "Run continuously, syncing messages in real-time")
+	syncCmd.Flags().BoolVar(&syncKeepOnline, "keep-online", false, "With --follow, show your account as online while running (contacts see you online)")
 	syncCmd.Flags().BoolVar(&syncDownloadMedia, "download-media", false, "Automatically download media files")
+	syncCmd.Flags().BoolVar(&syncIncludeSystem, "include-system", false, "Store system messages (group events, protocol notices) instead of skipping them")
+	syncCmd.Flags().BoolVar(&syncStrict, "strict", false, "Fail if any chats or messages could not be stored")
+	syncCmd.Flags().DurationVar(&syncQuietPeriod, "quiet-period", 0, "Also finish once no new messages have arrived for this long (e.g. 5s)")
+	syncCmd.Flags().BoolVar(&syncProgress, "progress", false, "Show new messages saved during a one-time sync (default: on when stderr is a terminal)")
+	syncCmd.Flags().StringVar(&syncDumpProto, "dump-proto", "", "Append each incoming message's raw protobuf as JSON to a file")
 }
 
 func runSync(cmd *cobra.Command, args []string) error {
+	if syncKeepOnline && !syncFollow {
+		return fmt.Errorf("--keep-online requires --follow")
+	}
+	if syncProgress && syncFollow {
+		return fmt.Errorf("--progress can't be combined with --follow")
+	}
+
 	if err := EnsureDirectories(); err != nil {
 		return fmt.Errorf("failed to create directories: %w", err)
 	}
@@ -54,6 +98,19 @@ func runSync(cmd *cobra.Command, args []string) error {
 	if !client.IsAuthenticated() {
 		return fmt.Errorf("not authenticated. Run 'whatsapp auth login' first")
 	}
+	client.IncludeSystem = syncIncludeSystem
+	if syncQuietPeriod > 0 {
+		client.SetSyncQuietPeriod(syncQuietPeriod)
+	}
+
+	if syncDumpProto != "" {
+		f, err := os.OpenFile(syncDumpProto, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to open proto dump file: %w", err)
+		}
+		defer func() { _ = f.Close() }()
+		client.ProtoDump = f
+	}
 
 	// Set up signal handling
 	ctx, cancel := context.WithCancel(context.Background())
@@ -77,20 +134,51 @@ func runSync(cmd *cobra.Command, args []string) error {
 	if syncFollow {
 		fmt.Fprintln(os.Stderr, "Connected. Syncing messages continuously. Press Ctrl+C to stop.")
 
+		var online chan struct{}
+		if syncKeepOnline {
+			online = make(chan struct{})
+			go func() {
+				defer close(online)
+				keepOnline(ctx, client.SetOnline)
+			}()
+		}
+
 		// Run until interrupted
-		<-ctx.Done()
+		runScheduler(ctx, db, client)
+		if online != nil {
+			// Wait to be shown offline before disconnecting
+			<-online
+		}
 	} else {
 		fmt.Fprintln(os.Stderr, "Connected. Performing one-time sync...")
 
+		bar := newProgress("Syncing", "new messages", 0, progressEnabled(cmd, syncProgress))
+		tick := time.NewTicker(progressRedraw)
+		defer tick.Stop()
+
 		// Wait for sync completion, timeout, or interrupt
-		syncTimeout := 2 * time.Minute
-		select {
-		case <-ctx.Done():
-			// User interrupted
-		case <-client.SyncComplete:
-			fmt.Fprintln(os.Stderr, "History sync complete.")
-		case <-time.After(syncTimeout):
-			fmt.Fprintln(os.Stderr, "Sync timeout reached.")
+		syncTimeout := time.After(2 * time.Minute)
+		status := ""
+	wait:
+		for {
+			select {
+			case <-ctx.Done():
+				// User interrupted
+				break wait
+			case <-client.SyncComplete:
+				status = "History sync complete."
+				break wait
+			case <-syncTimeout:
+				status = "Sync timeout reached."
+				break wait
+			case <-tick.C:
+				bar.set(client.SyncStats().NewMessages)
+			}
+		}
+		bar.set(client.SyncStats().NewMessages)
+		bar.finish()
+		if status != "" {
+			fmt.Fprintln(os.Stderr, status)
 		}
 	}
 
@@ -102,9 +190,34 @@ func runSync(cmd *cobra.Command, args []string) error {
 	// Output stats
 	chatCount, _ := db.CountChats("")
 	msgCount, _ := db.CountMessages()
+	stats := client.SyncStats()
+
+	result := map[string]any{
+		"chats":        chatCount,
+		"messages":     msgCount,
+		"new_messages": stats.NewMessages,
+		"images":       stats.Images,
+		"videos":       stats.Videos,
+		"audio":        stats.Audio,
+		"documents":    stats.Documents,
+		"errors":       stats.Errors,
+	}
+	if len(stats.ErrorSamples) > 0 {
+		result["error_samples"] = stats.ErrorSamples
+	}
 
-	return OutputResult(map[string]any{
-		"chats":    chatCount,
-		"messages": msgCount,
-	}, fmt.Sprintf("Synced %d chats, %d messages", chatCount, msgCount))
+	msg := fmt.Sprintf("Synced %d chats, %d messages (%d new: %d images, %d videos, %d audio, %d documents)",
+		chatCount, msgCount, stats.NewMessages, stats.Images, stats.Videos, stats.Audio, stats.Documents)
+	if stats.Errors > 0 {
+		msg += fmt.Sprintf("\n%d errors while storing data:\n  %s", stats.Errors, strings.Join(stats.ErrorSamples, "\n  "))
+	}
+
+	if err := OutputResult(result, msg); err != nil {
+		return err
+	}
+
+	if syncStrict && stats.Errors > 0 {
+		return fmt.Errorf("sync finished with %d persistence errors", stats.Errors)
+	}
+	return nil
 }
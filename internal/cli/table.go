@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// tablePadding separates columns in human table output
+const tablePadding = "  "
+
+// displayWidth returns the number of terminal columns s occupies. It builds on
+// runewidth but treats emoji presentation (VS16), skin tone modifiers and ZWJ
+// sequences as part of a single wide glyph, as terminals render them.
+func displayWidth(s string) int {
+	width, prev := 0, 0
+	joined := false
+	for _, r := range s {
+		switch {
+		case r == '\u200d':
+			joined = true
+			continue
+		case r == '\ufe0f':
+			if prev == 1 {
+				width++
+				prev = 2
+			}
+			continue
+		case r >= 0x1F3FB && r <= 0x1F3FF && prev == 2:
+			continue
+		}
+		if joined {
+			joined = false
+			continue
+		}
+		prev = runewidth.RuneWidth(r)
+		width += prev
+	}
+	return width
+}
+
+// truncateWidth shortens s to at most maxWidth columns, appending tail if cut.
+// It never splits a rune or a joined emoji sequence.
+func truncateWidth(s string, maxWidth int, tail string) string {
+	if displayWidth(s) <= maxWidth {
+		return s
+	}
+	limit := maxWidth - displayWidth(tail)
+	end := 0
+	for i, r := range s {
+		next := i + len(string(r))
+		if displayWidth(s[:next]) > limit {
+			break
+		}
+		end = next
+	}
+	return s[:end] + tail
+}
+
+// renderTable writes a borderless table whose columns are aligned by display width.
+// Cells containing newlines span multiple lines.
+func renderTable(w io.Writer, headers []string, rows [][]string) error {
+	return renderStyledTable(w, headers, rows, nil)
+}
+
+// renderStyledTable is renderTable with each body cell wrapped in the SGR codes
+// style returns for it (none if empty). Padding stays outside the codes so the
+// columns still line up.
+func renderStyledTable(w io.Writer, headers []string, rows [][]string, style func(row, col int) string) error {
+	all := rows
+	if len(headers) > 0 {
+		all = append([][]string{headers}, rows...)
+	}
+
+	var widths []int
+	for _, row := range all {
+		for i, cell := range row {
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			for _, line := range strings.Split(cell, "\n") {
+				widths[i] = max(widths[i], displayWidth(line))
+			}
+		}
+	}
+
+	for r, row := range all {
+		body := r - (len(all) - len(rows)) // Index into rows, negative for the header
+		cells := make([][]string, len(row))
+		height := 1
+		for i, cell := range row {
+			cells[i] = strings.Split(cell, "\n")
+			height = max(height, len(cells[i]))
+		}
+
+		for l := 0; l < height; l++ {
+			var b strings.Builder
+			for i, lines := range cells {
+				line := ""
+				if l < len(lines) {
+					line = lines[l]
+				}
+				if i > 0 {
+					b.WriteString(tablePadding)
+				}
+				if style != nil && body >= 0 && line != "" {
+					b.WriteString(colorize(line, style(body, i)))
+				} else {
+					b.WriteString(line)
+				}
+				b.WriteString(strings.Repeat(" ", widths[i]-displayWidth(line)))
+			}
+			if _, err := fmt.Fprintln(w, strings.TrimRight(b.String(), " ")); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
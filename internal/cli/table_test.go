@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestDisplayWidth(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+	}{
+		{"hello", 5},
+		{"日本語", 6},
+		{"caf\u00e9", 4},
+		{"\U0001F44D", 2},
+		{"\u2764\ufe0f", 2},
+		{"\U0001F44D\U0001F3FD", 2},
+		{"\U0001F468\u200d\U0001F469\u200d\U0001F467", 2},
+	}
+
+	for _, tt := range tests {
+		if got := displayWidth(tt.in); got != tt.want {
+			t.Errorf("displayWidth(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTruncateWidthKeepsRunesIntact(t *testing.T) {
+	got := truncateWidth("日本語のテキスト", 9, "...")
+	if got != "日本語..." {
+		t.Fatalf("got %q", got)
+	}
+	if got := truncateWidth("short", 10, "..."); got != "short" {
+		t.Fatalf("expected short string untouched, got %q", got)
+	}
+}
+
+func TestRenderTableAlignsWideText(t *testing.T) {
+	var buf bytes.Buffer
+	err := renderTable(&buf, []string{"NAME", "NOTE"}, [][]string{
+		{"日本語", "a"},
+		{"abc", "b"},
+	})
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %q", lines)
+	}
+	col := displayWidth(lines[0]) - displayWidth("NOTE")
+	for _, line := range lines[1:] {
+		if displayWidth(line)-1 != col {
+			t.Errorf("misaligned line %q (header column at %d)", line, col)
+		}
+	}
+}
+
+func TestRenderStyledTableKeepsAlignment(t *testing.T) {
+	headers := []string{"sender_name", "is_from_me"}
+	rows := [][]string{{"Alice", "no"}, {"me", "yes"}}
+
+	var plain, styled bytes.Buffer
+	if err := renderTable(&plain, headers, rows); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if err := renderStyledTable(&styled, headers, rows, tableStyle(headers, rows)); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+
+	ansi := regexp.MustCompile("\x1b\\[[0-9;]*m")
+	if got := ansi.ReplaceAllString(styled.String(), ""); got != plain.String() {
+		t.Fatalf("styling changed the layout:\n%s\nwant:\n%s", got, plain.String())
+	}
+	lines := strings.Split(styled.String(), "\n")
+	if lines[0] != strings.Split(plain.String(), "\n")[0] {
+		t.Errorf("expected the header left unstyled, got %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "\x1b[1mAlice") || !strings.Contains(lines[2], "\x1b[1;36mme") {
+		t.Errorf("expected bold names and your own row in cyan, got %q", lines[1:])
+	}
+}
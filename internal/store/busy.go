@@ -0,0 +1,106 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+var (
+	// BusyTimeout is how long SQLite waits for another process's lock before
+	// failing a statement with "database is locked". Set before Open.
+	BusyTimeout = 5 * time.Second
+
+	// BusyRetries is how many more times a statement is attempted after SQLite
+	// gives up waiting, backing off between attempts.
+	BusyRetries = 3
+)
+
+// busyBackoff is the delay before the first retry; it doubles on each attempt.
+var busyBackoff = 100 * time.Millisecond
+
+// isBusy reports whether err means another connection holds the database lock.
+func isBusy(err error) bool {
+	var serr sqlite3.Error
+	if errors.As(err, &serr) {
+		return serr.Code == sqlite3.ErrBusy || serr.Code == sqlite3.ErrLocked
+	}
+	return false
+}
+
+// retryBusy runs fn, retrying up to BusyRetries times while the database is locked.
+func retryBusy(fn func() error) error {
+	delay := busyBackoff
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= BusyRetries || !isBusy(err) {
+			return err
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// Exec runs a statement on the messages database, retrying while it is locked
+// by another process.
+func (d *DB) Exec(query string, args ...any) (sql.Result, error) {
+	var result sql.Result
+	err := retryBusy(func() error {
+		var err error
+		result, err = d.Messages.Exec(query, args...)
+		return err
+	})
+	return result, err
+}
+
+// Query runs a query on the messages database, retrying while it is locked
+// by another process.
+func (d *DB) Query(query string, args ...any) (*sql.Rows, error) {
+	var rows *sql.Rows
+	err := retryBusy(func() error {
+		var err error
+		rows, err = d.Messages.Query(query, args...)
+		return err
+	})
+	return rows, err
+}
+
+// Row is the result of QueryRow. The query runs when Scan is called.
+type Row struct {
+	d     *DB
+	query string
+	args  []any
+}
+
+// QueryRow runs a query expected to return at most one row on the messages
+// database, retrying while it is locked by another process.
+func (d *DB) QueryRow(query string, args ...any) *Row {
+	return &Row{d: d, query: query, args: args}
+}
+
+// Scan runs the query and copies the first row's columns into dest, returning
+// sql.ErrNoRows if there were none.
+func (r *Row) Scan(dest ...any) error {
+	return retryBusy(func() error {
+		return r.d.Messages.QueryRow(r.query, r.args...).Scan(dest...)
+	})
+}
+
+// inTx runs fn in a transaction, starting it over while the database is
+// locked by another process.
+func (d *DB) inTx(fn func(*sql.Tx) error) error {
+	return retryBusy(func() error {
+		tx, err := d.Messages.Begin()
+		if err != nil {
+			return err
+		}
+		defer func() { _ = tx.Rollback() }()
+
+		if err := fn(tx); err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+}
@@ -0,0 +1,97 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// holdWriteLock opens a second connection to path and keeps a write
+// transaction open until release is called.
+func holdWriteLock(t *testing.T, path string) (release func()) {
+	t.Helper()
+	other, err := Open(path)
+	if err != nil {
+		t.Fatalf("open second db: %v", err)
+	}
+	tx, err := other.Messages.Begin()
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	if _, err := tx.Exec(`INSERT INTO chats (jid, name) VALUES ('holder@s.whatsapp.net', 'Holder')`); err != nil {
+		t.Fatalf("write in held tx: %v", err)
+	}
+	return func() {
+		_ = tx.Commit()
+		other.CloseQuietly()
+	}
+}
+
+func setBusyConfig(t *testing.T, timeout time.Duration, retries int) {
+	t.Helper()
+	prevTimeout, prevRetries, prevBackoff := BusyTimeout, BusyRetries, busyBackoff
+	BusyTimeout, BusyRetries, busyBackoff = timeout, retries, 50*time.Millisecond
+	t.Cleanup(func() {
+		BusyTimeout, BusyRetries, busyBackoff = prevTimeout, prevRetries, prevBackoff
+	})
+}
+
+func TestExecRetriesWhileLocked(t *testing.T) {
+	setBusyConfig(t, 10*time.Millisecond, 5)
+	path := filepath.Join(t.TempDir(), "messages.db")
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.CloseQuietly()
+
+	release := holdWriteLock(t, path)
+	time.AfterFunc(150*time.Millisecond, release)
+
+	if err := db.StoreLIDMapping("123@lid", "123", "Alice"); err != nil {
+		t.Fatalf("expected write to succeed once the lock was released, got %v", err)
+	}
+}
+
+func TestExecFailsWhenRetriesExhausted(t *testing.T) {
+	setBusyConfig(t, 10*time.Millisecond, 0)
+	path := filepath.Join(t.TempDir(), "messages.db")
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.CloseQuietly()
+
+	release := holdWriteLock(t, path)
+	defer release()
+
+	err = db.StoreLIDMapping("123@lid", "123", "Alice")
+	if !isBusy(err) {
+		t.Fatalf("expected a database locked error, got %v", err)
+	}
+}
+
+func TestTransactionRetriesWhileLocked(t *testing.T) {
+	setBusyConfig(t, 10*time.Millisecond, 5)
+	path := filepath.Join(t.TempDir(), "messages.db")
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.CloseQuietly()
+
+	release := holdWriteLock(t, path)
+	time.AfterFunc(150*time.Millisecond, release)
+
+	group := "123@g.us"
+	if err := db.ReplaceGroupParticipants(group, []Participant{{JID: "456@s.whatsapp.net", Name: "Bob"}}); err != nil {
+		t.Fatalf("expected the transaction to succeed once the lock was released, got %v", err)
+	}
+	participants, err := db.GetGroupParticipants(group)
+	if err != nil {
+		t.Fatalf("get participants: %v", err)
+	}
+	if len(participants) != 1 || participants[0].Name != "Bob" {
+		t.Fatalf("unexpected participants %+v", participants)
+	}
+}
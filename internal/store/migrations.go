@@ -0,0 +1,304 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+)
+
+// migration is a single schema change. Versions must be strictly increasing.
+type migration struct {
+	version int
+	name    string
+	up      func(tx *sql.Tx) error
+}
+
+// migrations lists every schema change in the order it is applied.
+// Append new entries; never reorder or edit ones that have shipped.
+var migrations = []migration{
+	{1, "create base tables", migrateBaseTables},
+	{2, "add messages.sender_name", func(tx *sql.Tx) error {
+		return addColumn(tx, "messages", "sender_name", "TEXT")
+	}},
+	{3, "create group_participants", func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			CREATE TABLE IF NOT EXISTS group_participants (
+				group_jid TEXT,
+				jid TEXT,
+				lid TEXT,
+				phone TEXT,
+				name TEXT,
+				is_admin BOOLEAN,
+				updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (group_jid, jid)
+			);
+		`)
+		return err
+	}},
+	{4, "add messages.starred", func(tx *sql.Tx) error {
+		return addColumn(tx, "messages", "starred", "BOOLEAN DEFAULT 0")
+	}},
+	{5, "add messages.reply_to_id", func(tx *sql.Tx) error {
+		return addColumn(tx, "messages", "reply_to_id", "TEXT")
+	}},
+	{6, "create registered_numbers", func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			CREATE TABLE IF NOT EXISTS registered_numbers (
+				phone TEXT PRIMARY KEY,
+				jid TEXT,
+				is_registered BOOLEAN,
+				checked_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+		`)
+		return err
+	}},
+	{7, "create chat_settings", func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			CREATE TABLE IF NOT EXISTS chat_settings (
+				jid TEXT PRIMARY KEY,
+				ephemeral_expiration INTEGER,
+				updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+		`)
+		return err
+	}},
+	{8, "add messages.forwarding_score", func(tx *sql.Tx) error {
+		return addColumn(tx, "messages", "forwarding_score", "INTEGER DEFAULT 0")
+	}},
+	{9, "create reactions", func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			CREATE TABLE IF NOT EXISTS reactions (
+				message_id TEXT,
+				chat_jid TEXT,
+				sender TEXT,
+				emoji TEXT,
+				timestamp TIMESTAMP,
+				PRIMARY KEY (chat_jid, message_id, sender)
+			);
+		`)
+		return err
+	}},
+	{10, "add chat_settings.last_read_at", func(tx *sql.Tx) error {
+		return addColumn(tx, "chat_settings", "last_read_at", "TIMESTAMP")
+	}},
+	{11, "add chat_settings.unread_count", func(tx *sql.Tx) error {
+		return addColumn(tx, "chat_settings", "unread_count", "INTEGER DEFAULT 0")
+	}},
+	{12, "create schedules", func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			CREATE TABLE IF NOT EXISTS schedules (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				chat_jid TEXT NOT NULL,
+				text TEXT NOT NULL,
+				cron TEXT,
+				next_run TIMESTAMP NOT NULL,
+				last_run TIMESTAMP,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+		`)
+		return err
+	}},
+	{13, "add messages.local_path", func(tx *sql.Tx) error {
+		return addColumn(tx, "messages", "local_path", "TEXT")
+	}},
+	{14, "create groups", func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			CREATE TABLE IF NOT EXISTS groups (
+				jid TEXT PRIMARY KEY,
+				name TEXT,
+				topic TEXT,
+				creator_jid TEXT,
+				created TIMESTAMP,
+				participant_count INTEGER DEFAULT 0,
+				updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+		`)
+		return err
+	}},
+}
+
+// MigrationResult reports the schema version before and after Migrate.
+type MigrationResult struct {
+	FromVersion int      `json:"from_version"`
+	ToVersion   int      `json:"to_version"`
+	Applied     []string `json:"applied"`
+}
+
+// LatestSchemaVersion returns the version the schema is migrated to by Migrate.
+func LatestSchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// SchemaVersion returns the schema version recorded in the database, or 0 if none.
+func (d *DB) SchemaVersion() (int, error) {
+	if err := ensureMetadataTable(d.Messages); err != nil {
+		return 0, err
+	}
+
+	var value sql.NullString
+	err := d.QueryRow("SELECT value FROM metadata WHERE key = 'schema_version'").Scan(&value)
+	if err == sql.ErrNoRows || !value.Valid {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	version, err := strconv.Atoi(value.String)
+	if err != nil {
+		return 0, fmt.Errorf("invalid schema_version %q: %w", value.String, err)
+	}
+	return version, nil
+}
+
+// Migrate applies pending migrations in order, then sets up full-text search.
+// Each migration runs in its own transaction together with the version bump,
+// so a failure leaves the schema at the last fully applied version.
+func (d *DB) Migrate() (*MigrationResult, error) {
+	current, err := d.SchemaVersion()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	result := &MigrationResult{FromVersion: current, ToVersion: current, Applied: []string{}}
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err := applyMigration(d.Messages, m); err != nil {
+			return nil, fmt.Errorf("migration %d (%s) failed: %w", m.version, m.name, err)
+		}
+		result.ToVersion = m.version
+		result.Applied = append(result.Applied, m.name)
+	}
+
+	if err := ftsMigration(d.Messages); err != nil && !isFTSUnavailable(err) {
+		return nil, err
+	}
+	// An index created by a build with FTS5 is left in place by one without
+	// it, and so are its triggers, which would then fail every insert
+	d.hasFTS = detectFTS(d.Messages)
+	if !d.hasFTS {
+		d.dropFTSTriggers()
+	}
+
+	return result, nil
+}
+
+// dropFTSTriggers removes the search index triggers. Without FTS5 they would
+// make every insert fail, so dropping them keeps the database writable and
+// search falls back to LIKE.
+func (d *DB) dropFTSTriggers() {
+	var triggers int
+	_ = d.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name IN ('messages_ai', 'messages_ad', 'messages_au')`).Scan(&triggers)
+	for _, trigger := range []string{"messages_ai", "messages_ad", "messages_au"} {
+		_, _ = d.Exec("DROP TRIGGER IF EXISTS " + trigger)
+	}
+	d.ftsDropped = triggers > 0
+}
+
+func ensureMetadataTable(db *sql.DB) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS metadata (
+		key TEXT PRIMARY KEY,
+		value TEXT
+	)`)
+	return err
+}
+
+func applyMigration(db *sql.DB, m migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := m.up(tx); err != nil {
+		return err
+	}
+	if err := setSchemaVersion(tx, m.version); err != nil {
+		return fmt.Errorf("failed to record schema version: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+func setSchemaVersion(tx *sql.Tx, version int) error {
+	_, err := tx.Exec(
+		"INSERT INTO metadata (key, value) VALUES ('schema_version', ?) ON CONFLICT(key) DO UPDATE SET value = excluded.value",
+		strconv.Itoa(version),
+	)
+	return err
+}
+
+// hasColumn reports whether table already has the named column.
+func hasColumn(tx *sql.Tx, table, column string) (bool, error) {
+	rows, err := tx.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var (
+			cid        int
+			name       string
+			colType    string
+			notNull    bool
+			defaultVal sql.NullString
+			pk         int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultVal, &pk); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
+// addColumn adds a column to table unless it already exists.
+func addColumn(tx *sql.Tx, table, column, definition string) error {
+	exists, err := hasColumn(tx, table, column)
+	if err != nil || exists {
+		return err
+	}
+	_, err = tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
+
+func migrateBaseTables(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS chats (
+			jid TEXT PRIMARY KEY,
+			name TEXT,
+			last_message_time TIMESTAMP
+		);
+
+		CREATE TABLE IF NOT EXISTS messages (
+			id TEXT,
+			chat_jid TEXT,
+			sender TEXT,
+			content TEXT,
+			timestamp TIMESTAMP,
+			is_from_me BOOLEAN,
+			media_type TEXT,
+			filename TEXT,
+			url TEXT,
+			media_key BLOB,
+			file_sha256 BLOB,
+			file_enc_sha256 BLOB,
+			file_length INTEGER,
+			PRIMARY KEY (id, chat_jid),
+			FOREIGN KEY (chat_jid) REFERENCES chats(jid)
+		);
+
+		CREATE TABLE IF NOT EXISTS lid_mappings (
+			lid TEXT PRIMARY KEY,
+			phone TEXT,
+			name TEXT,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+	`)
+	return err
+}
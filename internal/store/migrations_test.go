@@ -0,0 +1,120 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func openUnmigratedTestDB(t *testing.T) *DB {
+	t.Helper()
+	db, err := OpenUnmigrated(filepath.Join(t.TempDir(), "messages.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(db.CloseQuietly)
+	return db
+}
+
+func TestMigrateFreshDatabase(t *testing.T) {
+	db := openUnmigratedTestDB(t)
+
+	result, err := db.Migrate()
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if result.FromVersion != 0 || result.ToVersion != LatestSchemaVersion() {
+		t.Fatalf("expected 0 -> %d, got %+v", LatestSchemaVersion(), result)
+	}
+	if len(result.Applied) != len(migrations) {
+		t.Fatalf("expected %d migrations applied, got %v", len(migrations), result.Applied)
+	}
+
+	for _, column := range []string{"sender_name", "starred"} {
+		tx, err := db.Messages.Begin()
+		if err != nil {
+			t.Fatalf("begin: %v", err)
+		}
+		exists, err := hasColumn(tx, "messages", column)
+		_ = tx.Rollback()
+		if err != nil || !exists {
+			t.Fatalf("expected messages.%s to exist (err=%v)", column, err)
+		}
+	}
+}
+
+func TestMigrateTwiceIsNoop(t *testing.T) {
+	db := openUnmigratedTestDB(t)
+
+	if _, err := db.Migrate(); err != nil {
+		t.Fatalf("first migrate: %v", err)
+	}
+
+	result, err := db.Migrate()
+	if err != nil {
+		t.Fatalf("second migrate: %v", err)
+	}
+	if result.FromVersion != LatestSchemaVersion() || len(result.Applied) != 0 {
+		t.Fatalf("expected no pending migrations, got %+v", result)
+	}
+}
+
+func TestMigrateUnversionedDatabaseWithExistingColumns(t *testing.T) {
+	db := openUnmigratedTestDB(t)
+
+	// Simulate a database created before schema versioning existed.
+	if _, err := db.Messages.Exec(`
+		CREATE TABLE chats (jid TEXT PRIMARY KEY, name TEXT, last_message_time TIMESTAMP);
+		CREATE TABLE messages (id TEXT, chat_jid TEXT, sender TEXT, sender_name TEXT, content TEXT,
+			timestamp TIMESTAMP, is_from_me BOOLEAN, media_type TEXT, filename TEXT, url TEXT,
+			media_key BLOB, file_sha256 BLOB, file_enc_sha256 BLOB, file_length INTEGER,
+			starred BOOLEAN DEFAULT 0, PRIMARY KEY (id, chat_jid));
+	`); err != nil {
+		t.Fatalf("create legacy schema: %v", err)
+	}
+
+	result, err := db.Migrate()
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if result.ToVersion != LatestSchemaVersion() {
+		t.Fatalf("expected version %d, got %+v", LatestSchemaVersion(), result)
+	}
+}
+
+func TestMigrateRollsBackFailedMigration(t *testing.T) {
+	db := openUnmigratedTestDB(t)
+	if _, err := db.Migrate(); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	original := migrations
+	t.Cleanup(func() { migrations = original })
+
+	failing := LatestSchemaVersion() + 1
+	migrations = append(append([]migration{}, original...), migration{failing, "broken", func(tx *sql.Tx) error {
+		if _, err := tx.Exec("CREATE TABLE half_applied (id TEXT)"); err != nil {
+			return err
+		}
+		return errors.New("boom")
+	}})
+
+	if _, err := db.Migrate(); err == nil {
+		t.Fatal("expected migration error")
+	}
+
+	version, err := db.SchemaVersion()
+	if err != nil {
+		t.Fatalf("schema version: %v", err)
+	}
+	if version != failing-1 {
+		t.Fatalf("expected version %d after rollback, got %d", failing-1, version)
+	}
+
+	var name string
+	err = db.Messages.QueryRow("SELECT name FROM sqlite_master WHERE name = 'half_applied'").Scan(&name)
+	if err != sql.ErrNoRows {
+		t.Fatalf("expected half_applied table to be rolled back, got %q (err=%v)", name, err)
+	}
+}
@@ -11,6 +11,7 @@ type Chat struct {
 	LastMessage     *string    `json:"last_message,omitempty"`
 	LastSender      *string    `json:"last_sender,omitempty"`
 	LastIsFromMe    *bool      `json:"last_is_from_me,omitempty"`
+	Unread          int        `json:"unread"` // Messages from others since the chat was last read
 }
 
 // Message represents a WhatsApp message.
@@ -24,27 +25,182 @@ type Message struct {
 	IsFromMe   bool      `json:"is_from_me"`
 	MediaType  *string   `json:"media_type,omitempty"`
 	Filename   *string   `json:"filename,omitempty"`
+	LocalPath  *string   `json:"local_path,omitempty"` // Where the media was downloaded to
 	ChatName   *string   `json:"chat_name,omitempty"`
+	ChatLabel  string    `json:"chat_label,omitempty"` // ChatName, or a fallback when the chat has none
+	Starred    bool      `json:"starred,omitempty"`
+	ReplyToID  *string   `json:"reply_to_id,omitempty"`
+
+	// ForwardingScore counts how many times the message was forwarded
+	// before it arrived; 0 means it wasn't forwarded.
+	ForwardingScore    int  `json:"forwarding_score,omitempty"`
+	ForwardedManyTimes bool `json:"forwarded_many_times,omitempty"`
+
+	// RepeatCount is how many identical messages in a row 'messages --dedupe'
+	// collapsed into this one; it is only set when that is more than one.
+	RepeatCount int `json:"repeat_count,omitempty"`
+
+	ReplyPreview *ReplyPreview `json:"reply_preview,omitempty"`
+}
+
+// Reaction is one person's reaction to a message.
+type Reaction struct {
+	MessageID  string    `json:"message_id"`
+	ChatJID    string    `json:"chat_jid"`
+	Sender     string    `json:"sender"`
+	SenderName *string   `json:"sender_name,omitempty"`
+	Emoji      string    `json:"emoji"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// ReplyPreview is the quoted message a reply refers to.
+type ReplyPreview struct {
+	ID         string  `json:"id"`
+	Sender     string  `json:"sender"`
+	SenderName *string `json:"sender_name,omitempty"`
+	Content    *string `json:"content,omitempty"`
+}
+
+// ChatMatches groups search results belonging to one chat.
+type ChatMatches struct {
+	ChatJID  string    `json:"chat_jid"`
+	ChatName *string   `json:"chat_name,omitempty"`
+	Matches  []Message `json:"matches"`
+}
+
+// MessageContext is a search match with the messages around it in its chat,
+// each side oldest first.
+type MessageContext struct {
+	Match  Message   `json:"match"`
+	Before []Message `json:"before"`
+	After  []Message `json:"after"`
+}
+
+// MessagePage is one page of a chat's messages, newest first, with the cursors
+// for the pages either side. A cursor is empty when there is nothing more that way.
+type MessagePage struct {
+	Messages   []Message `json:"messages"`
+	NextCursor string    `json:"next_cursor,omitempty"` // Pass as --before-id for older messages
+	PrevCursor string    `json:"prev_cursor,omitempty"` // Pass as --after-id for newer messages
 }
 
 // Contact represents a WhatsApp contact.
 type Contact struct {
-	JID   string  `json:"jid"`
-	Phone string  `json:"phone_number"`
-	Name  *string `json:"name,omitempty"`
+	JID          string  `json:"jid"`
+	Phone        string  `json:"phone_number"`
+	Name         *string `json:"name,omitempty"`
+	CanonicalJID *string `json:"canonical_jid,omitempty"` // Set by --registered-only
+}
+
+// NameResolution shows what each name source returns for a JID, for
+// debugging why a chat or sender shows the name it does.
+type NameResolution struct {
+	JID          string   `json:"jid"`
+	Aliases      []string `json:"aliases,omitempty"`       // Local aliases pointing at the JID
+	ChatName     string   `json:"chat_name,omitempty"`     // Name stored on the chat
+	LIDName      string   `json:"lid_name,omitempty"`      // Name stored with the LID mapping
+	GroupName    string   `json:"group_name,omitempty"`    // Group subject from WhatsApp
+	FullName     string   `json:"full_name,omitempty"`     // Contact's name in the address book
+	BusinessName string   `json:"business_name,omitempty"` // Verified business name
+	PushName     string   `json:"push_name,omitempty"`     // Name the contact set for themselves
+	Resolved     string   `json:"resolved"`                // Name the app shows
+	Source       string   `json:"source"`                  // Which of the above it came from
+}
+
+// Registration is the result of checking whether a phone number is on WhatsApp.
+type Registration struct {
+	Phone      string
+	JID        string // Canonical JID, empty if not registered
+	Registered bool
 }
 
 // SendResult represents the result of sending a message.
 type SendResult struct {
+	MessageID   string      `json:"message_id"`
+	ChatJID     string      `json:"chat_jid"`
+	Timestamp   string      `json:"timestamp"`
+	MessageIDs  []string    `json:"message_ids,omitempty"` // All parts when a long text was split
+	Reconnected bool        `json:"reconnected,omitempty"` // A dropped connection was re-established and the send retried
+	Reaction    *SendResult `json:"reaction,omitempty"`    // The reaction 'send --react' added to the replied-to message
+}
+
+// DeliveryResult is the outcome of sending a message until it is delivered.
+type DeliveryResult struct {
+	MessageID   string     `json:"message_id"` // The delivered attempt, or the last one sent
+	ChatJID     string     `json:"chat_jid"`
+	Timestamp   string     `json:"timestamp"`
+	Delivered   bool       `json:"delivered"`
+	DeliveredAt *time.Time `json:"delivered_at,omitempty"`
+	Attempts    int        `json:"attempts"`
+	MessageIDs  []string   `json:"message_ids"` // Every attempt, in order
+}
+
+// ReadResult is the outcome of marking messages as read.
+type ReadResult struct {
+	ChatJID    string   `json:"chat_jid"`
+	MessageIDs []string `json:"message_ids"` // Messages marked read
+	Receipts   int      `json:"receipts"`    // Read receipts sent, one per sender
+}
+
+// PresenceResult is the chat presence that was sent.
+type PresenceResult struct {
+	ChatJID string `json:"chat_jid"`
+	State   string `json:"state"`
+}
+
+// DeleteChatResult represents a chat removed from the local database.
+type DeleteChatResult struct {
+	ChatJID  string `json:"chat_jid"`
+	Messages int64  `json:"messages"` // Messages removed with it
+}
+
+// Schedule is a text message waiting to be sent, once or on a cron schedule.
+type Schedule struct {
+	ID      int64      `json:"id"`
+	ChatJID string     `json:"chat_jid"`
+	Text    string     `json:"text"`
+	Every   string     `json:"every,omitempty"` // Cron expression; empty for a one-off
+	NextRun time.Time  `json:"next_run"`
+	LastRun *time.Time `json:"last_run,omitempty"`
+}
+
+// MediaMessage is a stored message carrying media, as listed for download.
+type MediaMessage struct {
+	ID        string
+	ChatJID   string
+	MediaType string
+	Complete  bool // Has the URL, key and hashes needed to download it
+}
+
+// DownloadAllResult summarises downloading every media message in a chat.
+type DownloadAllResult struct {
+	ChatJID    string            `json:"chat_jid"`
+	Downloaded int               `json:"downloaded"`
+	Cached     int               `json:"cached"`  // Already on disk, so not fetched again
+	Skipped    int               `json:"skipped"` // Missing the media info needed to download
+	Failed     int               `json:"failed"`
+	Bytes      int64             `json:"bytes"` // Size of the files downloaded
+	Failures   []DownloadFailure `json:"failures,omitempty"`
+}
+
+// DownloadFailure is a message whose media could not be downloaded.
+type DownloadFailure struct {
 	MessageID string `json:"message_id"`
-	ChatJID   string `json:"chat_jid"`
-	Timestamp string `json:"timestamp"`
+	Error     string `json:"error"`
 }
 
 // DownloadResult represents the result of downloading media.
 type DownloadResult struct {
 	Filename string `json:"filename"`
-	Path     string `json:"path"`
+	Path     string `json:"path"`             // Recorded as the message's local_path
+	Cached   bool   `json:"cached,omitempty"` // Already downloaded, so not fetched again
+}
+
+// MediaGCResult represents the outcome of removing unreferenced media files.
+type MediaGCResult struct {
+	DryRun         bool     `json:"dry_run"`
+	Files          []string `json:"files"`
+	BytesReclaimed int64    `json:"bytes_reclaimed"`
 }
 
 // ConnectionStatus represents the WhatsApp connection status.
@@ -55,6 +211,15 @@ type ConnectionStatus struct {
 	Database  *DBStats    `json:"database,omitempty"`
 }
 
+// StatusCheck is one connection check made by auth status --watch.
+type StatusCheck struct {
+	CheckedAt   time.Time `json:"checked_at"`
+	Connected   bool      `json:"connected"`
+	LoggedIn    bool      `json:"logged_in"`
+	Reconnected bool      `json:"reconnected,omitempty"` // The link was down and came back
+	Error       string    `json:"error,omitempty"`       // Why reconnecting failed
+}
+
 // DeviceInfo contains device information.
 type DeviceInfo struct {
 	User   string `json:"user"`
@@ -67,14 +232,34 @@ type DBStats struct {
 	Messages int `json:"messages"`
 }
 
+// DatabaseStats describes what the local database holds and the space it takes.
+type DatabaseStats struct {
+	Path          string       `json:"path"`
+	FileBytes     int64        `json:"file_bytes"`
+	WALBytes      int64        `json:"wal_bytes"`  // Write-ahead log not yet checkpointed into the file
+	FreeBytes     int64        `json:"free_bytes"` // Unused pages that VACUUM would give back
+	FTSBytes      int64        `json:"fts_bytes"`  // Search index, approximate
+	Tables        []TableStats `json:"tables"`
+	OldestMessage *time.Time   `json:"oldest_message,omitempty"`
+	NewestMessage *time.Time   `json:"newest_message,omitempty"`
+}
+
+// TableStats is the row count of one table.
+type TableStats struct {
+	Name string `json:"name"`
+	Rows int64  `json:"rows"`
+}
+
 // GroupInfo represents WhatsApp group information.
 type GroupInfo struct {
-	JID          string        `json:"jid"`
-	Name         string        `json:"name"`
-	Topic        string        `json:"topic,omitempty"`
-	Created      time.Time     `json:"created"`
-	CreatorJID   string        `json:"creator_jid,omitempty"`
-	Participants []Participant `json:"participants,omitempty"`
+	JID              string        `json:"jid"`
+	Name             string        `json:"name"`
+	Topic            string        `json:"topic,omitempty"`
+	Created          time.Time     `json:"created"`
+	CreatorJID       string        `json:"creator_jid,omitempty"`
+	ParticipantCount int           `json:"participant_count"` // Set even when Participants isn't, as in listings
+	Participants     []Participant `json:"participants,omitempty"`
+	CachedAt         *time.Time    `json:"cached_at,omitempty"` // Set when read from the local cache
 }
 
 // Participant represents a group participant.
@@ -86,36 +271,139 @@ type Participant struct {
 	Name    string  `json:"name,omitempty"`
 }
 
+// GroupJoinRequest is a pending request to join a group that needs admin approval.
+type GroupJoinRequest struct {
+	JID         string    `json:"jid"`
+	Phone       string    `json:"phone,omitempty"`
+	Name        string    `json:"name,omitempty"`
+	RequestedAt time.Time `json:"requested_at"`
+}
+
+// GroupCreateResult is a newly created group and whether each requested
+// participant was added to it.
+type GroupCreateResult struct {
+	JID          string              `json:"jid"`
+	Name         string              `json:"name"`
+	Participants []GroupMemberResult `json:"participants"`
+}
+
+// GroupTopic is a group's description after changing it; empty once cleared.
+type GroupTopic struct {
+	JID   string `json:"jid"`
+	Topic string `json:"topic"`
+}
+
+// GroupSettings is who may send messages in, and edit the info of, a group.
+type GroupSettings struct {
+	JID      string `json:"jid"`
+	Announce bool   `json:"announce"` // Only admins can send messages
+	Locked   bool   `json:"locked"`   // Only admins can edit the group info
+}
+
+// GroupPhoto is a group's photo after changing it.
+type GroupPhoto struct {
+	JID       string `json:"jid"`
+	PictureID string `json:"picture_id,omitempty"` // Empty once removed
+	Removed   bool   `json:"removed,omitempty"`
+}
+
+// GroupInviteLink is a group's invite link.
+type GroupInviteLink struct {
+	JID   string `json:"jid"`
+	Link  string `json:"link"`
+	Reset bool   `json:"reset,omitempty"` // The previous link was revoked
+}
+
+// GroupMemberResult is the outcome of a membership change for one member.
+type GroupMemberResult struct {
+	JID     string `json:"jid"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
 // ListChatsOptions contains options for listing chats.
 type ListChatsOptions struct {
 	Query      string
 	OnlyGroups bool
+	NonEmpty   bool   // Skip chats with no stored (non-system) messages
+	UnreadOnly bool   // Only chats with unread messages
+	SortBy     string // name, activity or unread; empty for most recent activity first
+	SortDesc   bool   // With SortBy, sort descending
 	Limit      int
-	Page       int
+	Page       int // 1-based page of Limit results; 0 for the first
 }
 
+// ForwardedManyTimesScore is the forwarding score from which WhatsApp labels a
+// message "Forwarded many times".
+const ForwardedManyTimesScore = 4
+
+// SystemMessageType is the media_type of stored system/protocol messages
+// (group events, encryption notices, deletions), which are hidden by default.
+const SystemMessageType = "system"
+
 // ListMessagesOptions contains options for listing messages.
 type ListMessagesOptions struct {
-	After     string
-	Before    string
-	Timeframe string
-	ChatJID   string
-	Type      string
-	Limit     int
-	Page      int
+	After         string
+	Before        string
+	Timeframe     string
+	ChatJID       string
+	Type          string
+	Starred       bool
+	Forwarded     bool
+	UnreadOnly    bool // Received after the chat's read watermark; none if it is unknown
+	HasMedia      bool // Any media type; excludes text and system messages
+	IncludeSystem bool
+	WithReplies   bool
+	SortBy        string // timestamp, sender or chat_name; empty for newest first
+	SortDesc      bool   // With SortBy, sort descending
+	Limit         int
+	Page          int // 1-based page of Limit results; 0 for the first
 }
 
+// PageDirection is which side of the cursor ListMessagesKeyset pages to.
+type PageDirection string
+
+const (
+	PageBefore PageDirection = "before" // Older than the cursor
+	PageAfter  PageDirection = "after"  // Newer than the cursor
+)
+
 // SearchMessagesOptions contains options for searching messages.
 type SearchMessagesOptions struct {
-	Query     string
-	ChatJID   string
-	FromJID   string
-	After     string
-	Before    string
-	Timeframe string
-	Type      string
-	Limit     int
-	Page      int
+	Query       string
+	ChatJID     string
+	FromJID     string
+	After       string
+	Before      string
+	Timeframe   string
+	Type        string
+	WithReplies bool
+	SortBy      string // timestamp, sender or chat_name; empty for newest first
+	SortDesc    bool   // With SortBy, sort descending
+	Limit       int
+	Page        int // 1-based page of Limit results; 0 for the first
+}
+
+// HeatmapOptions contains options for ActivityHeatmap.
+type HeatmapOptions struct {
+	ChatJID string
+	After   string
+	Before  string
+}
+
+// ActivityHeatmap counts messages by weekday and hour of day, in local time.
+type ActivityHeatmap struct {
+	ChatJID string     `json:"chat_jid,omitempty"`
+	Total   int        `json:"total"`
+	Days    []string   `json:"days"`   // Row labels, Sunday first
+	Counts  [7][24]int `json:"counts"` // Counts[weekday][hour]
+}
+
+// HeatmapCell is one weekday and hour of an ActivityHeatmap.
+type HeatmapCell struct {
+	Day   string `json:"day"`
+	Hour  int    `json:"hour"`
+	Count int    `json:"count"`
 }
 
 // ContextResult represents aggregated context for LLMs.
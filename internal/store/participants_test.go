@@ -0,0 +1,164 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestReplaceGroupParticipantsReplacesCachedMembers(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "messages.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.CloseQuietly()
+
+	groupJID := "123456789-987654321@g.us"
+	lid := "233564700451061"
+	if err := db.StoreLIDMapping(lid, "447700900001", "Alice"); err != nil {
+		t.Fatalf("store lid mapping: %v", err)
+	}
+
+	if err := db.ReplaceGroupParticipants(groupJID, []Participant{
+		{JID: "stale@s.whatsapp.net"},
+	}); err != nil {
+		t.Fatalf("seed participants: %v", err)
+	}
+
+	if err := db.ReplaceGroupParticipants(groupJID, []Participant{
+		{JID: lid + "@lid", LID: &lid},
+		{JID: "447700900002@s.whatsapp.net", Name: "Bob", IsAdmin: true},
+	}); err != nil {
+		t.Fatalf("replace participants: %v", err)
+	}
+
+	participants, err := db.GetGroupParticipants(groupJID)
+	if err != nil {
+		t.Fatalf("get participants: %v", err)
+	}
+
+	if len(participants) != 2 {
+		t.Fatalf("expected 2 participants, got %d", len(participants))
+	}
+	if participants[0].Name != "Bob" || !participants[0].IsAdmin {
+		t.Fatalf("expected admin Bob first, got %+v", participants[0])
+	}
+	if participants[1].Name != "Alice" {
+		t.Fatalf("expected LID mapping name fallback, got %q", participants[1].Name)
+	}
+}
+
+func TestGetLIDMappingByPhone(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "messages.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.CloseQuietly()
+
+	if err := db.StoreLIDMapping("233564700451061", "447700900001", "Alice"); err != nil {
+		t.Fatalf("store lid mapping: %v", err)
+	}
+
+	lid, name, found := db.GetLIDMappingByPhone("447700900001")
+	if !found || lid != "233564700451061" || name != "Alice" {
+		t.Fatalf("unexpected mapping %q %q %v", lid, name, found)
+	}
+	if _, _, found := db.GetLIDMappingByPhone("447700900002"); found {
+		t.Fatal("expected no mapping for an unknown phone")
+	}
+}
+
+func TestGroupsContaining(t *testing.T) {
+	db := openTestDB(t)
+	lid := "123456789012345"
+	phone := "447700900002"
+
+	if err := db.ReplaceGroupParticipants("family@g.us", []Participant{
+		{JID: "447700900001@s.whatsapp.net"},
+		{JID: lid + "@lid", LID: &lid},
+	}); err != nil {
+		t.Fatalf("replace family: %v", err)
+	}
+	if err := db.ReplaceGroupParticipants("work@g.us", []Participant{
+		{JID: "447700900001@s.whatsapp.net"},
+		{JID: "447700900003@s.whatsapp.net"},
+	}); err != nil {
+		t.Fatalf("replace work: %v", err)
+	}
+	if err := db.StoreLIDMapping(lid, phone, ""); err != nil {
+		t.Fatalf("store mapping: %v", err)
+	}
+
+	groups, err := db.GroupsContaining("+447700900001", phone)
+	if err != nil {
+		t.Fatalf("groups containing: %v", err)
+	}
+	if len(groups) != 1 || groups[0].JID != "family@g.us" || !groups[0].IsGroup {
+		t.Fatalf("expected only the family group (matched via the LID mapping), got %+v", groups)
+	}
+
+	groups, err = db.GroupsContaining("447700900001@s.whatsapp.net", "447700900003", lid+"@lid")
+	if err != nil {
+		t.Fatalf("groups containing: %v", err)
+	}
+	if len(groups) != 0 {
+		t.Fatalf("expected no group with all three, got %+v", groups)
+	}
+
+	if count, err := db.CountCachedGroups(); err != nil || count != 2 {
+		t.Fatalf("expected 2 cached groups, got %d, %v", count, err)
+	}
+}
+
+func TestSaveGroupCachesInfo(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "messages.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.CloseQuietly()
+
+	groupJID := "123456789-987654321@g.us"
+	if group, err := db.GetGroup(groupJID); err != nil || group != nil {
+		t.Fatalf("expected no cached group, got %+v (%v)", group, err)
+	}
+
+	// History sync only fills in groups that aren't cached
+	if added, err := db.AddGroup(GroupInfo{JID: groupJID, Name: "Synced", ParticipantCount: 5}); err != nil || !added {
+		t.Fatalf("expected the synced group to be added, got %v (%v)", added, err)
+	}
+	if err := db.SaveGroup(GroupInfo{JID: groupJID, Name: "Book Club", Topic: "Thursdays", CreatorJID: "447700900001@s.whatsapp.net", ParticipantCount: 2}); err != nil {
+		t.Fatalf("save group: %v", err)
+	}
+	if added, err := db.AddGroup(GroupInfo{JID: groupJID, Name: "Stale"}); err != nil || added {
+		t.Fatalf("expected a cached group to be left alone, got %v (%v)", added, err)
+	}
+	if err := db.ReplaceGroupParticipants(groupJID, []Participant{{JID: "447700900001@s.whatsapp.net", IsAdmin: true}}); err != nil {
+		t.Fatalf("replace participants: %v", err)
+	}
+
+	group, err := db.GetGroup(groupJID)
+	if err != nil || group == nil {
+		t.Fatalf("get group: %+v (%v)", group, err)
+	}
+	if group.Name != "Book Club" || group.Topic != "Thursdays" || group.ParticipantCount != 2 || !group.Created.IsZero() || group.CachedAt == nil {
+		t.Fatalf("expected the saved info, got %+v", group)
+	}
+	if len(group.Participants) != 1 {
+		t.Fatalf("expected the cached participant, got %+v", group.Participants)
+	}
+
+	topic := "Fridays now"
+	if err := db.UpdateGroup(groupJID, nil, &topic); err != nil {
+		t.Fatalf("update group: %v", err)
+	}
+	if err := db.UpdateGroup("111-222@g.us", &topic, nil); err != nil {
+		t.Fatalf("update uncached group: %v", err)
+	}
+	if group, err := db.GetGroup(groupJID); err != nil || group.Name != "Book Club" || group.Topic != topic {
+		t.Fatalf("expected only the topic updated, got %+v (%v)", group, err)
+	}
+
+	groups, err := db.ListGroups()
+	if err != nil || len(groups) != 1 || groups[0].Participants != nil {
+		t.Fatalf("expected one group without participants, got %+v (%v)", groups, err)
+	}
+}
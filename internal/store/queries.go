@@ -3,18 +3,126 @@ package store
 import (
 	"database/sql"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 )
 
+// messageColumns is the column list scanned by scanMessages. Queries must alias
+// messages as m, chats as c and lid_mappings as l.
+const messageColumns = `m.id, m.chat_jid, m.sender,
+		       COALESCE(m.sender_name, l.name) as sender_name,
+		       m.content, m.timestamp, m.is_from_me,
+		       m.media_type, m.filename, m.local_path, c.name as chat_name,
+		       COALESCE(m.starred, 0) as starred, m.reply_to_id,
+		       COALESCE(m.forwarding_score, 0) as forwarding_score`
+
+// replyColumns and replyJoin add the quoted message (aliased r) to a messages
+// query. scanMessages expects replyColumns right after messageColumns.
+const (
+	replyColumns = `,
+		       r.id, r.sender, COALESCE(r.sender_name, rl.name), r.content`
+	replyJoin = `
+		LEFT JOIN messages r ON r.id = m.reply_to_id AND r.chat_jid = m.chat_jid
+		LEFT JOIN lid_mappings rl ON r.sender = rl.lid`
+)
+
+// selectMessages returns the select list and extra joins for a messages query.
+func selectMessages(withReplies bool) (columns, joins string) {
+	if withReplies {
+		return messageColumns + replyColumns, replyJoin
+	}
+	return messageColumns, ""
+}
+
+// notSystem excludes stored system messages based on the given media_type column.
+func notSystem(column string) string {
+	return `COALESCE(` + column + `, '') != '` + SystemMessageType + `'`
+}
+
+// chatSortColumns whitelists the ORDER BY expression for each ListChatsOptions.SortBy.
+var chatSortColumns = map[string]string{
+	"activity":  "c.last_message_time",
+	"timestamp": "c.last_message_time",
+	"name":      "COALESCE(NULLIF(c.name, ''), c.jid) COLLATE NOCASE",
+	"chat_name": "COALESCE(NULLIF(c.name, ''), c.jid) COLLATE NOCASE",
+	"unread":    "COALESCE(s.unread_count, 0)",
+}
+
+// messageSortColumns whitelists the ORDER BY expression for each message SortBy.
+var messageSortColumns = map[string]string{
+	"timestamp": "m.timestamp",
+	"sender":    "COALESCE(NULLIF(COALESCE(m.sender_name, l.name), ''), m.sender) COLLATE NOCASE",
+	"chat_name": "COALESCE(NULLIF(c.name, ''), m.chat_jid) COLLATE NOCASE",
+}
+
+// sortDirection returns the ORDER BY direction keyword.
+func sortDirection(desc bool) string {
+	if desc {
+		return " DESC"
+	}
+	return " ASC"
+}
+
+// chatOrder returns the ORDER BY clause for ListChats: most recent activity
+// first unless SortBy is set, and then for ties.
+func chatOrder(opts ListChatsOptions) (string, error) {
+	if opts.SortBy == "" {
+		return "c.last_message_time DESC NULLS LAST", nil
+	}
+	column, ok := chatSortColumns[opts.SortBy]
+	if !ok {
+		return "", fmt.Errorf("invalid sort %q (use name, activity or unread)", opts.SortBy)
+	}
+	return column + sortDirection(opts.SortDesc) + " NULLS LAST, c.last_message_time DESC NULLS LAST", nil
+}
+
+// messageOrder returns the ORDER BY clause for a message listing: newest
+// first unless sortBy is set. Ties on other columns go oldest or newest first
+// to match.
+func messageOrder(sortBy string, desc bool) (string, error) {
+	if sortBy == "" {
+		return "m.timestamp DESC", nil
+	}
+	column, ok := messageSortColumns[sortBy]
+	if !ok {
+		return "", fmt.Errorf("invalid sort %q (use timestamp, sender or chat_name)", sortBy)
+	}
+	order := column + sortDirection(desc)
+	if sortBy != "timestamp" {
+		order += ", m.timestamp" + sortDirection(desc)
+	}
+	return order, nil
+}
+
+// limitClause returns the LIMIT for a listing, offset to the given 1-based
+// page. Page 0 or 1 is the first page; without a limit there are no pages.
+func limitClause(limit, page int) string {
+	if limit <= 0 {
+		return ""
+	}
+	clause := fmt.Sprintf(" LIMIT %d", limit)
+	if page > 1 {
+		clause += fmt.Sprintf(" OFFSET %d", (page-1)*limit)
+	}
+	return clause
+}
+
 // ListChats returns chats matching the given options.
 func (d *DB) ListChats(opts ListChatsOptions) ([]Chat, error) {
+	order, err := chatOrder(opts)
+	if err != nil {
+		return nil, err
+	}
+
 	query := `
 		SELECT c.jid, c.name, c.last_message_time,
-			(SELECT content FROM messages WHERE chat_jid = c.jid ORDER BY timestamp DESC LIMIT 1) as last_message,
-			(SELECT sender FROM messages WHERE chat_jid = c.jid ORDER BY timestamp DESC LIMIT 1) as last_sender,
-			(SELECT is_from_me FROM messages WHERE chat_jid = c.jid ORDER BY timestamp DESC LIMIT 1) as last_is_from_me
+			(SELECT content FROM messages WHERE chat_jid = c.jid AND ` + notSystem("media_type") + ` ORDER BY timestamp DESC LIMIT 1) as last_message,
+			(SELECT sender FROM messages WHERE chat_jid = c.jid AND ` + notSystem("media_type") + ` ORDER BY timestamp DESC LIMIT 1) as last_sender,
+			(SELECT is_from_me FROM messages WHERE chat_jid = c.jid AND ` + notSystem("media_type") + ` ORDER BY timestamp DESC LIMIT 1) as last_is_from_me,
+			COALESCE(s.unread_count, 0)
 		FROM chats c
+		LEFT JOIN chat_settings s ON s.jid = c.jid
 		WHERE 1=1
 	`
 	var args []any
@@ -29,13 +137,19 @@ func (d *DB) ListChats(opts ListChatsOptions) ([]Chat, error) {
 		query += " AND c.jid LIKE '%@g.us'"
 	}
 
-	query += " ORDER BY c.last_message_time DESC NULLS LAST"
+	if opts.UnreadOnly {
+		query += " AND s.unread_count > 0"
+	}
 
-	if opts.Limit > 0 {
-		query += fmt.Sprintf(" LIMIT %d", opts.Limit)
+	if opts.NonEmpty {
+		query += " AND EXISTS (SELECT 1 FROM messages WHERE chat_jid = c.jid AND " + notSystem("media_type") + ")"
 	}
 
-	rows, err := d.Messages.Query(query, args...)
+	query += " ORDER BY " + order
+
+	query += limitClause(opts.Limit, opts.Page)
+
+	rows, err := d.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -49,13 +163,16 @@ func (d *DB) ListChats(opts ListChatsOptions) ([]Chat, error) {
 		var lastMsg, lastSender sql.NullString
 		var lastFromMe sql.NullBool
 
-		if err := rows.Scan(&jid, &name, &lastTime, &lastMsg, &lastSender, &lastFromMe); err != nil {
+		var unread int
+
+		if err := rows.Scan(&jid, &name, &lastTime, &lastMsg, &lastSender, &lastFromMe, &unread); err != nil {
 			continue
 		}
 
 		chat := Chat{
 			JID:     jid,
 			IsGroup: strings.HasSuffix(jid, "@g.us"),
+			Unread:  unread,
 		}
 
 		if name.Valid && name.String != "" {
@@ -83,10 +200,7 @@ func (d *DB) ListChats(opts ListChatsOptions) ([]Chat, error) {
 // OldestMessageForChat returns the earliest stored message for a chat.
 func (d *DB) OldestMessageForChat(chatJID string) (Message, error) {
 	query := `
-		SELECT m.id, m.chat_jid, m.sender,
-		       COALESCE(m.sender_name, l.name) as sender_name,
-		       m.content, m.timestamp, m.is_from_me,
-		       m.media_type, m.filename, c.name as chat_name
+		SELECT ` + messageColumns + `
 		FROM messages m
 		LEFT JOIN chats c ON m.chat_jid = c.jid
 		LEFT JOIN lid_mappings l ON m.sender = l.lid
@@ -95,7 +209,7 @@ func (d *DB) OldestMessageForChat(chatJID string) (Message, error) {
 		LIMIT 1
 	`
 
-	messages, err := d.scanMessages(query, []any{chatJID})
+	messages, err := d.scanMessages(query, []any{chatJID}, false)
 	if err != nil {
 		return Message{}, err
 	}
@@ -108,14 +222,16 @@ func (d *DB) OldestMessageForChat(chatJID string) (Message, error) {
 
 // ListMessages returns messages matching the given options.
 func (d *DB) ListMessages(opts ListMessagesOptions) ([]Message, error) {
+	order, err := messageOrder(opts.SortBy, opts.SortDesc)
+	if err != nil {
+		return nil, err
+	}
+	columns, joins := selectMessages(opts.WithReplies)
 	query := `
-		SELECT m.id, m.chat_jid, m.sender,
-		       COALESCE(m.sender_name, l.name) as sender_name,
-		       m.content, m.timestamp, m.is_from_me,
-		       m.media_type, m.filename, c.name as chat_name
+		SELECT ` + columns + `
 		FROM messages m
 		LEFT JOIN chats c ON m.chat_jid = c.jid
-		LEFT JOIN lid_mappings l ON m.sender = l.lid
+		LEFT JOIN lid_mappings l ON m.sender = l.lid` + joins + `
 		WHERE 1=1
 	`
 	var args []any
@@ -125,6 +241,23 @@ func (d *DB) ListMessages(opts ListMessagesOptions) ([]Message, error) {
 		args = append(args, opts.ChatJID)
 	}
 
+	if opts.Starred {
+		query += " AND m.starred = 1"
+	}
+
+	if opts.Forwarded {
+		query += " AND m.forwarding_score > 0"
+	}
+
+	if opts.UnreadOnly {
+		query += " AND m.is_from_me = 0 AND m.timestamp > (SELECT last_read_at FROM chat_settings WHERE jid = m.chat_jid)"
+	}
+
+	if opts.HasMedia {
+		query += " AND m.media_type IS NOT NULL AND m.media_type != '' AND m.media_type != ?"
+		args = append(args, SystemMessageType)
+	}
+
 	if opts.After != "" {
 		afterTime, err := time.Parse(time.RFC3339, opts.After)
 		if err == nil {
@@ -141,39 +274,323 @@ func (d *DB) ListMessages(opts ListMessagesOptions) ([]Message, error) {
 		}
 	}
 
+	if opts.Type != SystemMessageType && !opts.IncludeSystem {
+		query += " AND " + notSystem("m.media_type")
+	}
+
 	if opts.Type != "" {
 		switch opts.Type {
 		case "text":
 			query += " AND (m.media_type IS NULL OR m.media_type = '')"
-		case "image", "video", "audio", "document", "sticker":
+		case "image", "video", "audio", "document", "sticker", SystemMessageType:
 			query += " AND m.media_type = ?"
 			args = append(args, opts.Type)
 		}
 	}
 
-	query += " ORDER BY m.timestamp DESC"
+	query += " ORDER BY " + order
+
+	query += limitClause(opts.Limit, opts.Page)
+
+	return d.scanMessages(query, args, opts.WithReplies)
+}
+
+// ListMediaMessages returns a chat's media messages oldest first, optionally
+// of one media type and between after and before (RFC3339).
+func (d *DB) ListMediaMessages(chatJID, mediaType, after, before string) ([]MediaMessage, error) {
+	query := `SELECT id, chat_jid, media_type,
+			COALESCE(url, '') != '' AND LENGTH(media_key) > 0 AND LENGTH(file_sha256) > 0
+				AND LENGTH(file_enc_sha256) > 0 AND COALESCE(file_length, 0) > 0
+		FROM messages
+		WHERE chat_jid = ? AND media_type IS NOT NULL AND media_type != '' AND media_type != ?`
+	args := []any{chatJID, SystemMessageType}
+
+	if mediaType != "" {
+		query += " AND media_type = ?"
+		args = append(args, mediaType)
+	}
+	for _, bound := range []struct{ value, op string }{{after, ">="}, {before, "<="}} {
+		if bound.value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, bound.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time %q (use RFC3339): %w", bound.value, err)
+		}
+		query += " AND timestamp " + bound.op + " ?"
+		args = append(args, t)
+	}
+	query += " ORDER BY timestamp, rowid"
+
+	rows, err := d.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var messages []MediaMessage
+	for rows.Next() {
+		var m MediaMessage
+		if err := rows.Scan(&m.ID, &m.ChatJID, &m.MediaType, &m.Complete); err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
+
+// ListThread returns a message and every message replying to it, directly or
+// to one of its replies, oldest first.
+func (d *DB) ListThread(chatJID, messageID string, withReplies bool) ([]Message, error) {
+	columns, joins := selectMessages(withReplies)
+	query := `
+		WITH RECURSIVE thread(id) AS (
+			SELECT id FROM messages WHERE chat_jid = ? AND id = ?
+			UNION
+			SELECT r.id FROM messages r JOIN thread t ON r.reply_to_id = t.id WHERE r.chat_jid = ?
+		)
+		SELECT ` + columns + `
+		FROM messages m
+		LEFT JOIN chats c ON m.chat_jid = c.jid
+		LEFT JOIN lid_mappings l ON m.sender = l.lid` + joins + `
+		WHERE m.chat_jid = ? AND m.id IN (SELECT id FROM thread)
+		ORDER BY m.timestamp ASC, m.rowid ASC`
+
+	messages, err := d.scanMessages(query, []any{chatJID, messageID, chatJID, chatJID}, withReplies)
+	if err != nil {
+		return nil, err
+	}
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("message %s not found in %s", messageID, chatJID)
+	}
+	return messages, nil
+}
+
+// ListMessagesKeyset returns up to limit messages of a chat on one side of the
+// message cursorID, newest first. Pages are keyed on (timestamp, rowid) rather
+// than an offset, so messages arriving between calls don't shift them. System
+// messages are skipped, as in ListMessages. An empty cursorID with PageBefore
+// returns the newest page.
+func (d *DB) ListMessagesKeyset(chatJID, cursorID string, direction PageDirection, limit int) (MessagePage, error) {
+	var cmp, order string
+	switch direction {
+	case PageBefore:
+		cmp, order = "<", "DESC"
+	case PageAfter:
+		cmp, order = ">", "ASC"
+	default:
+		return MessagePage{}, fmt.Errorf("invalid page direction %q (use before or after)", direction)
+	}
+
+	query := `
+		SELECT ` + messageColumns + `
+		FROM messages m
+		LEFT JOIN chats c ON m.chat_jid = c.jid
+		LEFT JOIN lid_mappings l ON m.sender = l.lid
+		WHERE m.chat_jid = ? AND ` + notSystem("m.media_type")
+	args := []any{chatJID}
+
+	if cursorID != "" || direction != PageBefore {
+		var cursor int64
+		err := d.QueryRow(`SELECT rowid FROM messages WHERE chat_jid = ? AND id = ?`, chatJID, cursorID).Scan(&cursor)
+		if err == sql.ErrNoRows {
+			return MessagePage{}, fmt.Errorf("message %s not found in %s", cursorID, chatJID)
+		}
+		if err != nil {
+			return MessagePage{}, err
+		}
+		query += `
+		  AND (m.timestamp, m.rowid) ` + cmp + ` (SELECT timestamp, rowid FROM messages WHERE rowid = ?)`
+		args = append(args, cursor)
+	}
+	query += `
+		ORDER BY m.timestamp ` + order + `, m.rowid ` + order
+	if limit > 0 {
+		// One extra row tells us whether there is another page.
+		query += fmt.Sprintf(" LIMIT %d", limit+1)
+	}
+
+	messages, err := d.scanMessages(query, args, false)
+	if err != nil {
+		return MessagePage{}, err
+	}
+	more := limit > 0 && len(messages) > limit
+	if more {
+		messages = messages[:limit]
+	}
+	if direction == PageAfter {
+		slices.Reverse(messages)
+	}
+
+	page := MessagePage{Messages: messages}
+	if len(messages) == 0 {
+		return page, nil
+	}
+	// The cursor message itself lies on the other side of the page.
+	newest, oldest := messages[0].ID, messages[len(messages)-1].ID
+	switch {
+	case direction == PageBefore:
+		page.PrevCursor = newest
+		if more {
+			page.NextCursor = oldest
+		}
+	default:
+		page.NextCursor = oldest
+		if more {
+			page.PrevCursor = newest
+		}
+	}
+	return page, nil
+}
+
+// MessagePosition returns where a message falls in its chat, counting from 1
+// for the oldest in the order ListMessagesKeyset pages through.
+func (d *DB) MessagePosition(chatJID, id string) (int, error) {
+	var position int
+	err := d.QueryRow(`
+		SELECT COUNT(*) FROM messages m, messages target
+		WHERE target.chat_jid = ? AND target.id = ?
+		  AND m.chat_jid = target.chat_jid AND `+notSystem("m.media_type")+`
+		  AND (m.timestamp, m.rowid) <= (target.timestamp, target.rowid)
+	`, chatJID, id).Scan(&position)
+	if err != nil {
+		return 0, err
+	}
+	if position == 0 {
+		return 0, fmt.Errorf("message %s not found in %s", id, chatJID)
+	}
+	return position, nil
+}
+
+// MessageContexts returns each match with up to n messages either side of it
+// in its chat, found with keyset queries around the match. A message is only
+// listed once: context skips the other matches and anything already shown
+// around an earlier match, so overlapping contexts don't repeat.
+func (d *DB) MessageContexts(matches []Message, n int) ([]MessageContext, error) {
+	key := func(m Message) string { return m.ChatJID + "\x00" + m.ID }
+	seen := make(map[string]bool, len(matches))
+	for _, m := range matches {
+		seen[key(m)] = true
+	}
+	unseen := func(messages []Message) []Message {
+		out := []Message{}
+		for _, m := range messages {
+			if !seen[key(m)] {
+				seen[key(m)] = true
+				out = append(out, m)
+			}
+		}
+		return out
+	}
+
+	contexts := make([]MessageContext, 0, len(matches))
+	for _, match := range matches {
+		before, err := d.ListMessagesKeyset(match.ChatJID, match.ID, PageBefore, n)
+		if err != nil {
+			return nil, err
+		}
+		after, err := d.ListMessagesKeyset(match.ChatJID, match.ID, PageAfter, n)
+		if err != nil {
+			return nil, err
+		}
+		// Pages come newest first; context reads oldest first.
+		slices.Reverse(before.Messages)
+		slices.Reverse(after.Messages)
+		contexts = append(contexts, MessageContext{
+			Match:  match,
+			Before: unseen(before.Messages),
+			After:  unseen(after.Messages),
+		})
+	}
+	return contexts, nil
+}
+
+// ActivityHeatmap counts messages by weekday and hour of day in local time,
+// skipping system messages.
+func (d *DB) ActivityHeatmap(opts HeatmapOptions) (ActivityHeatmap, error) {
+	query := `
+		SELECT CAST(strftime('%w', timestamp, 'localtime') AS INTEGER) AS day,
+		       CAST(strftime('%H', timestamp, 'localtime') AS INTEGER) AS hour,
+		       COUNT(*)
+		FROM messages
+		WHERE ` + notSystem("media_type")
+	var args []any
+
+	if opts.ChatJID != "" {
+		query += " AND chat_jid = ?"
+		args = append(args, opts.ChatJID)
+	}
+	if opts.After != "" {
+		if afterTime, err := time.Parse(time.RFC3339, opts.After); err == nil {
+			query += " AND timestamp >= ?"
+			args = append(args, afterTime)
+		}
+	}
+	if opts.Before != "" {
+		if beforeTime, err := time.Parse(time.RFC3339, opts.Before); err == nil {
+			query += " AND timestamp <= ?"
+			args = append(args, beforeTime)
+		}
+	}
+	query += " GROUP BY day, hour"
+
+	heatmap := ActivityHeatmap{ChatJID: opts.ChatJID}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		heatmap.Days = append(heatmap.Days, day.String())
+	}
 
-	if opts.Limit > 0 {
-		query += fmt.Sprintf(" LIMIT %d", opts.Limit)
+	rows, err := d.Query(query, args...)
+	if err != nil {
+		return heatmap, err
 	}
+	defer func() { _ = rows.Close() }()
 
-	return d.scanMessages(query, args)
+	for rows.Next() {
+		var day, hour sql.NullInt64
+		var count int
+		if err := rows.Scan(&day, &hour, &count); err != nil {
+			return heatmap, err
+		}
+		if !day.Valid || !hour.Valid || day.Int64 < 0 || day.Int64 > 6 || hour.Int64 < 0 || hour.Int64 > 23 {
+			continue
+		}
+		heatmap.Counts[day.Int64][hour.Int64] += count
+		heatmap.Total += count
+	}
+	return heatmap, rows.Err()
 }
 
 // SearchMessages performs full-text search on messages.
+// Without FTS5 it falls back to a case-insensitive substring scan.
 func (d *DB) SearchMessages(opts SearchMessagesOptions) ([]Message, error) {
-	query := `
-		SELECT m.id, m.chat_jid, m.sender,
-		       COALESCE(m.sender_name, l.name) as sender_name,
-		       m.content, m.timestamp, m.is_from_me,
-		       m.media_type, m.filename, c.name as chat_name
+	order, err := messageOrder(opts.SortBy, opts.SortDesc)
+	if err != nil {
+		return nil, err
+	}
+	var query string
+	var args []any
+	columns, joins := selectMessages(opts.WithReplies)
+
+	if d.hasFTS {
+		query = `
+		SELECT ` + columns + `
 		FROM messages m
 		JOIN messages_fts fts ON m.rowid = fts.rowid
 		LEFT JOIN chats c ON m.chat_jid = c.jid
-		LEFT JOIN lid_mappings l ON m.sender = l.lid
+		LEFT JOIN lid_mappings l ON m.sender = l.lid` + joins + `
 		WHERE messages_fts MATCH ?
 	`
-	args := []any{opts.Query}
+		args = append(args, NormalizeText(opts.Query))
+	} else {
+		query = `
+		SELECT ` + columns + `
+		FROM messages m
+		LEFT JOIN chats c ON m.chat_jid = c.jid
+		LEFT JOIN lid_mappings l ON m.sender = l.lid` + joins + `
+		WHERE m.content LIKE ? ESCAPE '\'
+	`
+		args = append(args, "%"+escapeLike(NormalizeText(opts.Query))+"%")
+	}
 
 	if opts.ChatJID != "" {
 		query += " AND m.chat_jid = ?"
@@ -201,29 +618,102 @@ func (d *DB) SearchMessages(opts SearchMessagesOptions) ([]Message, error) {
 		}
 	}
 
+	if opts.Type != SystemMessageType {
+		query += " AND " + notSystem("m.media_type")
+	}
+
 	if opts.Type != "" {
 		switch opts.Type {
 		case "text":
 			query += " AND (m.media_type IS NULL OR m.media_type = '')"
-		case "image", "video", "audio", "document", "sticker":
+		case "image", "video", "audio", "document", "sticker", SystemMessageType:
 			query += " AND m.media_type = ?"
 			args = append(args, opts.Type)
 		}
 	}
 
-	query += " ORDER BY m.timestamp DESC"
+	query += " ORDER BY " + order
+
+	query += limitClause(opts.Limit, opts.Page)
+
+	return d.scanMessages(query, args, opts.WithReplies)
+}
+
+// escapeLike escapes LIKE wildcards so the pattern matches literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// SetMessageStarred records whether a message is starred.
+func (d *DB) SetMessageStarred(chatJID, messageID string, starred bool) error {
+	_, err := d.Exec("UPDATE messages SET starred = ? WHERE id = ? AND chat_jid = ?", starred, messageID, chatJID)
+	return err
+}
+
+// UpdateMessageContent replaces the stored text of a message, e.g. after an edit.
+func (d *DB) UpdateMessageContent(chatJID, messageID, content string) error {
+	_, err := d.Exec("UPDATE messages SET content = ? WHERE id = ? AND chat_jid = ?", content, messageID, chatJID)
+	return err
+}
+
+// DeleteMessage removes a message from the local store, e.g. once it has been
+// revoked.
+func (d *DB) DeleteMessage(chatJID, messageID string) error {
+	return d.inTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec("DELETE FROM reactions WHERE message_id = ? AND chat_jid = ?", messageID, chatJID); err != nil {
+			return err
+		}
+		_, err := tx.Exec("DELETE FROM messages WHERE id = ? AND chat_jid = ?", messageID, chatJID)
+		return err
+	})
+}
 
-	if opts.Limit > 0 {
-		query += fmt.Sprintf(" LIMIT %d", opts.Limit)
+// SetReaction records sender's reaction to a message, replacing any earlier
+// one. An empty emoji means the reaction was removed and deletes it.
+func (d *DB) SetReaction(chatJID, messageID, sender, emoji string, at time.Time) error {
+	if emoji == "" {
+		_, err := d.Exec("DELETE FROM reactions WHERE chat_jid = ? AND message_id = ? AND sender = ?", chatJID, messageID, sender)
+		return err
 	}
+	_, err := d.Exec(`INSERT INTO reactions (message_id, chat_jid, sender, emoji, timestamp) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(chat_jid, message_id, sender) DO UPDATE SET emoji = excluded.emoji, timestamp = excluded.timestamp`,
+		messageID, chatJID, sender, emoji, at)
+	return err
+}
+
+// ListReactions returns the reactions to a message, oldest first.
+func (d *DB) ListReactions(chatJID, messageID string) ([]Reaction, error) {
+	rows, err := d.Query(`
+		SELECT r.message_id, r.chat_jid, r.sender, COALESCE(l.name, c.name), r.emoji, r.timestamp
+		FROM reactions r
+		LEFT JOIN lid_mappings l ON r.sender = l.lid
+		LEFT JOIN chats c ON c.jid = r.sender || '@s.whatsapp.net'
+		WHERE r.chat_jid = ? AND r.message_id = ?
+		ORDER BY r.timestamp, r.sender`, chatJID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
 
-	return d.scanMessages(query, args)
+	var reactions []Reaction
+	for rows.Next() {
+		var r Reaction
+		var senderName sql.NullString
+		if err := rows.Scan(&r.MessageID, &r.ChatJID, &r.Sender, &senderName, &r.Emoji, &r.Timestamp); err != nil {
+			return nil, err
+		}
+		if senderName.Valid && senderName.String != "" {
+			r.SenderName = &senderName.String
+		}
+		reactions = append(reactions, r)
+	}
+	return reactions, rows.Err()
 }
 
 // GetChatName returns the name of a chat by JID.
 func (d *DB) GetChatName(jid string) string {
 	var name sql.NullString
-	_ = d.Messages.QueryRow("SELECT name FROM chats WHERE jid = ?", jid).Scan(&name)
+	_ = d.QueryRow("SELECT name FROM chats WHERE jid = ?", jid).Scan(&name)
 	if name.Valid {
 		return name.String
 	}
@@ -231,8 +721,9 @@ func (d *DB) GetChatName(jid string) string {
 }
 
 // scanMessages is a helper to scan message rows into Message structs.
-func (d *DB) scanMessages(query string, args []any) ([]Message, error) {
-	rows, err := d.Messages.Query(query, args...)
+// withReplies must match whether the query selected replyColumns.
+func (d *DB) scanMessages(query string, args []any, withReplies bool) ([]Message, error) {
+	rows, err := d.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -241,9 +732,14 @@ func (d *DB) scanMessages(query string, args []any) ([]Message, error) {
 	var messages []Message
 	for rows.Next() {
 		var m Message
-		var senderName, content, mediaType, filename, chatName sql.NullString
+		var senderName, content, mediaType, filename, localPath, chatName, replyToID sql.NullString
+		var replyID, replySender, replySenderName, replyContent sql.NullString
 
-		if err := rows.Scan(&m.ID, &m.ChatJID, &m.Sender, &senderName, &content, &m.Timestamp, &m.IsFromMe, &mediaType, &filename, &chatName); err != nil {
+		dest := []any{&m.ID, &m.ChatJID, &m.Sender, &senderName, &content, &m.Timestamp, &m.IsFromMe, &mediaType, &filename, &localPath, &chatName, &m.Starred, &replyToID, &m.ForwardingScore}
+		if withReplies {
+			dest = append(dest, &replyID, &replySender, &replySenderName, &replyContent)
+		}
+		if err := rows.Scan(dest...); err != nil {
 			continue
 		}
 
@@ -259,12 +755,80 @@ func (d *DB) scanMessages(query string, args []any) ([]Message, error) {
 		if filename.Valid && filename.String != "" {
 			m.Filename = &filename.String
 		}
+		if localPath.Valid && localPath.String != "" {
+			m.LocalPath = &localPath.String
+		}
 		if chatName.Valid {
 			m.ChatName = &chatName.String
 		}
+		if replyToID.Valid && replyToID.String != "" {
+			m.ReplyToID = &replyToID.String
+		}
+		m.ForwardedManyTimes = m.ForwardingScore >= ForwardedManyTimesScore
+		if replyID.Valid {
+			m.ReplyPreview = &ReplyPreview{ID: replyID.String, Sender: replySender.String}
+			if replySenderName.Valid && replySenderName.String != "" {
+				m.ReplyPreview.SenderName = &replySenderName.String
+			}
+			if replyContent.Valid {
+				m.ReplyPreview.Content = &replyContent.String
+			}
+		}
 
 		messages = append(messages, m)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
+	d.labelChats(messages)
 	return messages, nil
 }
+
+// labelChats sets each message's ChatLabel to its chat's name, or for chats
+// with no name to chatFallbackName, so exports always have a readable label.
+func (d *DB) labelChats(messages []Message) {
+	fallback := make(map[string]string)
+	for i := range messages {
+		m := &messages[i]
+		if m.ChatName != nil && *m.ChatName != "" {
+			m.ChatLabel = *m.ChatName
+			continue
+		}
+		label, ok := fallback[m.ChatJID]
+		if !ok {
+			label = d.chatFallbackName(m.ChatJID)
+			fallback[m.ChatJID] = label
+		}
+		m.ChatLabel = label
+	}
+}
+
+// chatFallbackName names a chat that has no name of its own: from its LID
+// mapping or cached group info, then for a direct chat the latest name the
+// other person sent under, and otherwise the JID's user part. It depends only
+// on the chat, so a chat gets the same label on every page.
+func (d *DB) chatFallbackName(chatJID string) string {
+	user, server, _ := strings.Cut(chatJID, "@")
+
+	var name sql.NullString
+	switch server {
+	case "lid":
+		_, name.String, _ = d.GetLIDMapping(user)
+	case "g.us":
+		_ = d.QueryRow("SELECT name FROM groups WHERE jid = ?", chatJID).Scan(&name)
+	default:
+		_, name.String, _ = d.GetLIDMappingByPhone(user)
+	}
+	if name.String == "" && server != "g.us" {
+		_ = d.QueryRow(`
+			SELECT sender_name FROM messages
+			WHERE chat_jid = ? AND is_from_me = 0 AND COALESCE(sender_name, '') != ''
+			ORDER BY timestamp DESC LIMIT 1
+		`, chatJID).Scan(&name)
+	}
+	if name.String != "" {
+		return name.String
+	}
+	return user
+}
@@ -0,0 +1,1018 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func openTestDB(t *testing.T) *DB {
+	t.Helper()
+	db, err := Open(filepath.Join(t.TempDir(), "messages.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(db.CloseQuietly)
+	return db
+}
+
+func insertTestMessage(t *testing.T, db *DB, id, chatJID, content string, ts time.Time) {
+	t.Helper()
+	if _, err := db.Messages.Exec(`INSERT OR IGNORE INTO chats (jid, name) VALUES (?, ?)`, chatJID, "Test Chat"); err != nil {
+		t.Fatalf("insert chat: %v", err)
+	}
+	if _, err := db.Messages.Exec(`INSERT INTO messages (id, chat_jid, sender, content, timestamp, is_from_me) VALUES (?, ?, ?, ?, ?, ?)`,
+		id, chatJID, "12345", content, ts, false); err != nil {
+		t.Fatalf("insert message %s: %v", id, err)
+	}
+}
+
+func TestListMessagesStarredFilter(t *testing.T) {
+	db := openTestDB(t)
+	chatJID := "12345@s.whatsapp.net"
+	ts := time.Date(2026, 4, 24, 12, 0, 0, 0, time.UTC)
+
+	insertTestMessage(t, db, "plain", chatJID, "plain", ts)
+	insertTestMessage(t, db, "starred", chatJID, "starred", ts.Add(time.Minute))
+
+	if err := db.SetMessageStarred(chatJID, "starred", true); err != nil {
+		t.Fatalf("set starred: %v", err)
+	}
+
+	messages, err := db.ListMessages(ListMessagesOptions{ChatJID: chatJID, Starred: true})
+	if err != nil {
+		t.Fatalf("list messages: %v", err)
+	}
+
+	if len(messages) != 1 || messages[0].ID != "starred" || !messages[0].Starred {
+		t.Fatalf("expected only the starred message, got %+v", messages)
+	}
+}
+
+func TestSearchMessagesMatchesDecomposedQuery(t *testing.T) {
+	db := openTestDB(t)
+	chatJID := "12345@s.whatsapp.net"
+
+	insertTestMessage(t, db, "cafe", chatJID, NormalizeText("meet at the caf\u00e9"), time.Date(2026, 4, 24, 12, 0, 0, 0, time.UTC))
+
+	messages, err := db.SearchMessages(SearchMessagesOptions{Query: "cafe\u0301"})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+
+	if len(messages) != 1 || messages[0].ID != "cafe" {
+		t.Fatalf("expected decomposed query to match precomposed content, got %+v", messages)
+	}
+}
+
+func TestSearchMessagesFallsBackWithoutFTS(t *testing.T) {
+	db := openTestDB(t)
+	chatJID := "12345@s.whatsapp.net"
+	ts := time.Date(2026, 4, 24, 12, 0, 0, 0, time.UTC)
+
+	if !db.HasFTS() {
+		t.Fatal("expected FTS to be available in tests")
+	}
+
+	insertTestMessage(t, db, "match", chatJID, "Deploy finished at 100% capacity", ts)
+	insertTestMessage(t, db, "other", chatJID, "nothing to see", ts.Add(time.Minute))
+
+	db.hasFTS = false
+	if _, err := db.Messages.Exec("DROP TABLE messages_fts"); err != nil {
+		t.Fatalf("drop fts: %v", err)
+	}
+
+	messages, err := db.SearchMessages(SearchMessagesOptions{Query: "deploy"})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(messages) != 1 || messages[0].ID != "match" {
+		t.Fatalf("expected substring match, got %+v", messages)
+	}
+
+	messages, err = db.SearchMessages(SearchMessagesOptions{Query: "0%"})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(messages) != 1 || messages[0].ID != "match" {
+		t.Fatalf("expected literal %% match, got %+v", messages)
+	}
+}
+
+func TestListMessagesHidesSystemMessagesByDefault(t *testing.T) {
+	db := openTestDB(t)
+	chatJID := "123-456@g.us"
+	ts := time.Date(2026, 4, 24, 12, 0, 0, 0, time.UTC)
+
+	insertTestMessage(t, db, "text", chatJID, "hello", ts)
+	insertTestMessage(t, db, "event", chatJID, "group participant add: 12345", ts.Add(time.Minute))
+	if _, err := db.Messages.Exec("UPDATE messages SET media_type = ? WHERE id = 'event'", SystemMessageType); err != nil {
+		t.Fatalf("mark system: %v", err)
+	}
+
+	messages, err := db.ListMessages(ListMessagesOptions{ChatJID: chatJID})
+	if err != nil {
+		t.Fatalf("list messages: %v", err)
+	}
+	if len(messages) != 1 || messages[0].ID != "text" {
+		t.Fatalf("expected system message hidden, got %+v", messages)
+	}
+
+	messages, err = db.ListMessages(ListMessagesOptions{ChatJID: chatJID, IncludeSystem: true})
+	if err != nil {
+		t.Fatalf("list messages: %v", err)
+	}
+	if len(messages) != 2 {
+		t.Fatalf("expected both messages with IncludeSystem, got %+v", messages)
+	}
+
+	chats, err := db.ListChats(ListChatsOptions{})
+	if err != nil {
+		t.Fatalf("list chats: %v", err)
+	}
+	if len(chats) != 1 || chats[0].LastMessage == nil || *chats[0].LastMessage != "hello" {
+		t.Fatalf("expected last_message to skip system messages, got %+v", chats)
+	}
+}
+
+func TestListMessagesWithReplies(t *testing.T) {
+	db := openTestDB(t)
+	chatJID := "12345@s.whatsapp.net"
+	ts := time.Date(2026, 4, 24, 12, 0, 0, 0, time.UTC)
+
+	insertTestMessage(t, db, "original", chatJID, "lunch?", ts)
+	insertTestMessage(t, db, "reply", chatJID, "sure", ts.Add(time.Minute))
+	if _, err := db.Messages.Exec(`UPDATE messages SET reply_to_id = 'original' WHERE id = 'reply'`); err != nil {
+		t.Fatalf("set reply_to_id: %v", err)
+	}
+
+	messages, err := db.ListMessages(ListMessagesOptions{ChatJID: chatJID, WithReplies: true})
+	if err != nil {
+		t.Fatalf("list messages: %v", err)
+	}
+	if len(messages) != 2 || messages[0].ID != "reply" {
+		t.Fatalf("expected reply first, got %+v", messages)
+	}
+	preview := messages[0].ReplyPreview
+	if preview == nil || preview.ID != "original" || preview.Content == nil || *preview.Content != "lunch?" {
+		t.Fatalf("expected preview of original, got %+v", preview)
+	}
+	if messages[1].ReplyPreview != nil {
+		t.Fatalf("expected no preview on a non-reply, got %+v", messages[1].ReplyPreview)
+	}
+
+	messages, err = db.ListMessages(ListMessagesOptions{ChatJID: chatJID})
+	if err != nil {
+		t.Fatalf("list messages: %v", err)
+	}
+	if messages[0].ReplyPreview != nil || messages[0].ReplyToID == nil || *messages[0].ReplyToID != "original" {
+		t.Fatalf("expected reply_to_id without preview, got %+v", messages[0])
+	}
+}
+
+func TestListThread(t *testing.T) {
+	db := openTestDB(t)
+	chatJID := "12345@s.whatsapp.net"
+	ts := time.Date(2026, 4, 24, 12, 0, 0, 0, time.UTC)
+
+	insertTestMessage(t, db, "root", chatJID, "lunch?", ts)
+	insertTestMessage(t, db, "reply", chatJID, "sure", ts.Add(time.Minute))
+	insertTestMessage(t, db, "other", chatJID, "unrelated", ts.Add(2*time.Minute))
+	insertTestMessage(t, db, "nested", chatJID, "where?", ts.Add(3*time.Minute))
+	insertTestMessage(t, db, "elsewhere", "999@s.whatsapp.net", "same id, other chat", ts.Add(4*time.Minute))
+	if _, err := db.Messages.Exec(`UPDATE messages SET reply_to_id = CASE id WHEN 'reply' THEN 'root' WHEN 'nested' THEN 'reply' ELSE 'root' END WHERE id IN ('reply', 'nested', 'elsewhere')`); err != nil {
+		t.Fatalf("set reply_to_id: %v", err)
+	}
+
+	messages, err := db.ListThread(chatJID, "root", false)
+	if err != nil {
+		t.Fatalf("list thread: %v", err)
+	}
+	var ids []string
+	for _, m := range messages {
+		ids = append(ids, m.ID)
+	}
+	if strings.Join(ids, ",") != "root,reply,nested" {
+		t.Fatalf("expected the thread oldest first, got %v", ids)
+	}
+
+	if _, err := db.ListThread(chatJID, "missing", false); err == nil {
+		t.Fatal("expected an error for an unknown message")
+	}
+}
+
+func TestListMessagesHasMedia(t *testing.T) {
+	db := openTestDB(t)
+	chatJID := "12345@s.whatsapp.net"
+	ts := time.Date(2026, 4, 24, 12, 0, 0, 0, time.UTC)
+
+	insertTestMessage(t, db, "text", chatJID, "hello", ts)
+	insertTestMessage(t, db, "image", chatJID, "", ts.Add(time.Minute))
+	insertTestMessage(t, db, "doc", chatJID, "", ts.Add(2*time.Minute))
+	insertTestMessage(t, db, "event", chatJID, "joined", ts.Add(3*time.Minute))
+	if _, err := db.Messages.Exec(`UPDATE messages SET media_type = CASE id WHEN 'image' THEN 'image' WHEN 'doc' THEN 'document' WHEN 'event' THEN 'system' END WHERE id IN ('image', 'doc', 'event')`); err != nil {
+		t.Fatalf("set media types: %v", err)
+	}
+
+	messages, err := db.ListMessages(ListMessagesOptions{ChatJID: chatJID, HasMedia: true, IncludeSystem: true})
+	if err != nil {
+		t.Fatalf("list messages: %v", err)
+	}
+	if len(messages) != 2 || messages[0].ID != "doc" || messages[1].ID != "image" {
+		t.Fatalf("expected only the media messages, got %+v", messages)
+	}
+}
+
+func TestListMessagesForwarded(t *testing.T) {
+	db := openTestDB(t)
+	chatJID := "12345@s.whatsapp.net"
+	ts := time.Date(2026, 4, 24, 12, 0, 0, 0, time.UTC)
+
+	insertTestMessage(t, db, "original", chatJID, "hello", ts)
+	insertTestMessage(t, db, "once", chatJID, "fwd", ts.Add(time.Minute))
+	insertTestMessage(t, db, "chain", chatJID, "fwd fwd", ts.Add(2*time.Minute))
+	if _, err := db.Messages.Exec(`UPDATE messages SET forwarding_score = CASE id WHEN 'once' THEN 1 ELSE 5 END WHERE id IN ('once', 'chain')`); err != nil {
+		t.Fatalf("set forwarding scores: %v", err)
+	}
+
+	messages, err := db.ListMessages(ListMessagesOptions{ChatJID: chatJID, Forwarded: true})
+	if err != nil {
+		t.Fatalf("list messages: %v", err)
+	}
+	if len(messages) != 2 || messages[0].ID != "chain" || messages[1].ID != "once" {
+		t.Fatalf("expected only the forwarded messages, got %+v", messages)
+	}
+	if !messages[0].ForwardedManyTimes || messages[0].ForwardingScore != 5 {
+		t.Fatalf("expected chain to be forwarded many times, got %+v", messages[0])
+	}
+	if messages[1].ForwardedManyTimes {
+		t.Fatalf("expected a single forward not to be labelled, got %+v", messages[1])
+	}
+}
+
+func TestReactions(t *testing.T) {
+	db := openTestDB(t)
+	chatJID := "123456789-987654321@g.us"
+	ts := time.Date(2026, 4, 24, 12, 0, 0, 0, time.UTC)
+	insertTestMessage(t, db, "msg", chatJID, "hello", ts)
+
+	for _, r := range []struct{ sender, emoji string }{
+		{"111", "\U0001F44D"},
+		{"222", "\u2764\uFE0F"},
+		{"111", "\U0001F602"}, // Replaces 111's first reaction
+		{"222", ""},           // Removes 222's reaction
+		{"333", "\U0001F525"},
+	} {
+		ts = ts.Add(time.Minute)
+		if err := db.SetReaction(chatJID, "msg", r.sender, r.emoji, ts); err != nil {
+			t.Fatalf("set reaction: %v", err)
+		}
+	}
+
+	reactions, err := db.ListReactions(chatJID, "msg")
+	if err != nil {
+		t.Fatalf("list reactions: %v", err)
+	}
+	if len(reactions) != 2 || reactions[0].Sender != "111" || reactions[0].Emoji != "\U0001F602" || reactions[1].Sender != "333" {
+		t.Fatalf("unexpected reactions %+v", reactions)
+	}
+
+	if err := db.DeleteMessage(chatJID, "msg"); err != nil {
+		t.Fatalf("delete message: %v", err)
+	}
+	if reactions, _ := db.ListReactions(chatJID, "msg"); len(reactions) != 0 {
+		t.Fatalf("expected reactions to be deleted with the message, got %+v", reactions)
+	}
+}
+
+func TestMessageContexts(t *testing.T) {
+	db := openTestDB(t)
+	chatJID := "12345@s.whatsapp.net"
+	ts := time.Date(2026, 4, 24, 12, 0, 0, 0, time.UTC)
+
+	for i := 1; i <= 7; i++ {
+		id := fmt.Sprintf("m%d", i)
+		insertTestMessage(t, db, id, chatJID, id, ts.Add(time.Duration(i)*time.Minute))
+	}
+	match := func(id string) Message { return Message{ID: id, ChatJID: chatJID} }
+
+	contexts, err := db.MessageContexts([]Message{match("m5"), match("m3")}, 2)
+	if err != nil {
+		t.Fatalf("message contexts: %v", err)
+	}
+	ids := func(messages []Message) string {
+		var out []string
+		for _, m := range messages {
+			out = append(out, m.ID)
+		}
+		return strings.Join(out, ",")
+	}
+	if len(contexts) != 2 {
+		t.Fatalf("expected 2 contexts, got %d", len(contexts))
+	}
+	// m3 is itself a match, so it isn't repeated as m5's context.
+	if got := ids(contexts[0].Before) + "|" + ids(contexts[0].After); got != "m4|m6,m7" {
+		t.Fatalf("unexpected context for m5: %s", got)
+	}
+	// m4 was already shown around m5.
+	if got := ids(contexts[1].Before) + "|" + ids(contexts[1].After); got != "m1,m2|" {
+		t.Fatalf("unexpected context for m3: %s", got)
+	}
+	if contexts[1].After == nil {
+		t.Fatal("expected an empty, non-nil after list")
+	}
+}
+
+func TestListMessagesKeyset(t *testing.T) {
+	db := openTestDB(t)
+	chatJID := "12345@s.whatsapp.net"
+	ts := time.Date(2026, 4, 24, 12, 0, 0, 0, time.UTC)
+
+	// m2 and m3 share a timestamp, so the rowid has to break the tie.
+	insertTestMessage(t, db, "m1", chatJID, "one", ts)
+	insertTestMessage(t, db, "m2", chatJID, "two", ts.Add(time.Minute))
+	insertTestMessage(t, db, "m3", chatJID, "three", ts.Add(time.Minute))
+	insertTestMessage(t, db, "m4", chatJID, "four", ts.Add(2*time.Minute))
+	insertTestMessage(t, db, "m5", chatJID, "five", ts.Add(3*time.Minute))
+
+	ids := func(page MessagePage) string {
+		var out []string
+		for _, m := range page.Messages {
+			out = append(out, m.ID)
+		}
+		return strings.Join(out, ",")
+	}
+
+	page, err := db.ListMessagesKeyset(chatJID, "m5", PageBefore, 2)
+	if err != nil {
+		t.Fatalf("list before: %v", err)
+	}
+	if ids(page) != "m4,m3" || page.NextCursor != "m3" || page.PrevCursor != "m4" {
+		t.Fatalf("unexpected first page %s %+v", ids(page), page)
+	}
+
+	// A message arriving mid-way doesn't shift the next page.
+	insertTestMessage(t, db, "m6", chatJID, "six", ts.Add(4*time.Minute))
+
+	page, err = db.ListMessagesKeyset(chatJID, page.NextCursor, PageBefore, 2)
+	if err != nil {
+		t.Fatalf("list before: %v", err)
+	}
+	if ids(page) != "m2,m1" || page.NextCursor != "" || page.PrevCursor != "m2" {
+		t.Fatalf("unexpected last page %s %+v", ids(page), page)
+	}
+
+	page, err = db.ListMessagesKeyset(chatJID, "m3", PageAfter, 2)
+	if err != nil {
+		t.Fatalf("list after: %v", err)
+	}
+	if ids(page) != "m5,m4" || page.NextCursor != "m4" || page.PrevCursor != "m5" {
+		t.Fatalf("unexpected newer page %s %+v", ids(page), page)
+	}
+
+	page, err = db.ListMessagesKeyset(chatJID, "", PageBefore, 2)
+	if err != nil {
+		t.Fatalf("list newest: %v", err)
+	}
+	if ids(page) != "m6,m5" || page.NextCursor != "m5" || page.PrevCursor != "m6" {
+		t.Fatalf("unexpected newest page %s %+v", ids(page), page)
+	}
+
+	if _, err := db.ListMessagesKeyset(chatJID, "missing", PageBefore, 2); err == nil {
+		t.Fatal("expected an error for an unknown cursor")
+	}
+
+	for id, want := range map[string]int{"m1": 1, "m2": 2, "m3": 3, "m6": 6} {
+		if got, err := db.MessagePosition(chatJID, id); err != nil || got != want {
+			t.Fatalf("expected %s at position %d, got %d (%v)", id, want, got, err)
+		}
+	}
+	if _, err := db.MessagePosition(chatJID, "missing"); err == nil {
+		t.Fatal("expected an error for an unknown message")
+	}
+}
+
+func TestActivityHeatmap(t *testing.T) {
+	db := openTestDB(t)
+	chatJID := "12345@s.whatsapp.net"
+	ts := time.Date(2026, 4, 24, 12, 30, 0, 0, time.UTC)
+
+	insertTestMessage(t, db, "a", chatJID, "one", ts)
+	insertTestMessage(t, db, "b", chatJID, "two", ts.Add(10*time.Minute))
+	insertTestMessage(t, db, "c", chatJID, "three", ts.Add(24*time.Hour))
+	insertTestMessage(t, db, "other", "999@s.whatsapp.net", "elsewhere", ts)
+
+	heatmap, err := db.ActivityHeatmap(HeatmapOptions{ChatJID: chatJID})
+	if err != nil {
+		t.Fatalf("heatmap: %v", err)
+	}
+	local := ts.Local()
+	next := ts.Add(24 * time.Hour).Local()
+	if heatmap.Total != 3 || heatmap.Counts[local.Weekday()][local.Hour()] != 2 || heatmap.Counts[next.Weekday()][next.Hour()] != 1 {
+		t.Fatalf("unexpected heatmap %+v", heatmap)
+	}
+	if len(heatmap.Days) != 7 || heatmap.Days[0] != "Sunday" {
+		t.Fatalf("unexpected day labels %v", heatmap.Days)
+	}
+
+	heatmap, err = db.ActivityHeatmap(HeatmapOptions{After: ts.Add(time.Hour).Format(time.RFC3339)})
+	if err != nil {
+		t.Fatalf("heatmap after: %v", err)
+	}
+	if heatmap.Total != 1 {
+		t.Fatalf("expected only the later message, got %+v", heatmap)
+	}
+}
+
+func TestRegistrationCache(t *testing.T) {
+	db := openTestDB(t)
+
+	if err := db.SaveRegistration(Registration{Phone: "447700900000", JID: "447700900000@s.whatsapp.net", Registered: true}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if err := db.SaveRegistration(Registration{Phone: "15550000000"}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	regs, err := db.GetRegistrations(time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if r := regs["447700900000"]; !r.Registered || r.JID != "447700900000@s.whatsapp.net" {
+		t.Fatalf("expected registered entry, got %+v", r)
+	}
+	if r, ok := regs["15550000000"]; !ok || r.Registered {
+		t.Fatalf("expected cached unregistered entry, got %+v (found=%v)", r, ok)
+	}
+
+	regs, err = db.GetRegistrations(time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if len(regs) != 0 {
+		t.Fatalf("expected stale entries to be ignored, got %+v", regs)
+	}
+}
+
+func TestListChatsSortByName(t *testing.T) {
+	db := openTestDB(t)
+	ts := time.Date(2026, 4, 24, 12, 0, 0, 0, time.UTC)
+
+	for i, name := range []string{"bravo", "Alpha", "charlie"} {
+		if _, err := db.Messages.Exec(`INSERT INTO chats (jid, name, last_message_time) VALUES (?, ?, ?)`,
+			name+"@s.whatsapp.net", name, ts.Add(time.Duration(i)*time.Minute)); err != nil {
+			t.Fatalf("insert chat: %v", err)
+		}
+	}
+
+	chats, err := db.ListChats(ListChatsOptions{SortBy: "name"})
+	if err != nil {
+		t.Fatalf("list chats: %v", err)
+	}
+	var names []string
+	for _, c := range chats {
+		names = append(names, *c.Name)
+	}
+	if strings.Join(names, ",") != "Alpha,bravo,charlie" {
+		t.Fatalf("expected alphabetical order, got %v", names)
+	}
+
+	chats, err = db.ListChats(ListChatsOptions{SortBy: "name", SortDesc: true})
+	if err != nil {
+		t.Fatalf("list chats: %v", err)
+	}
+	if len(chats) != 3 || *chats[0].Name != "charlie" || *chats[2].Name != "Alpha" {
+		t.Fatalf("expected reverse alphabetical order, got %v", chats)
+	}
+
+	if _, err := db.ListChats(ListChatsOptions{SortBy: "name; DROP TABLE chats"}); err == nil {
+		t.Fatal("expected an unknown sort to be rejected")
+	}
+}
+
+func TestListMessagesSort(t *testing.T) {
+	db := openTestDB(t)
+	chatJID := "12345@s.whatsapp.net"
+	ts := time.Date(2026, 4, 24, 12, 0, 0, 0, time.UTC)
+
+	insertTestMessage(t, db, "m1", chatJID, "one", ts)
+	insertTestMessage(t, db, "m2", chatJID, "two", ts.Add(time.Minute))
+	insertTestMessage(t, db, "m3", chatJID, "three", ts.Add(2*time.Minute))
+	for id, sender := range map[string]string{"m1": "carol", "m2": "alice", "m3": "Bob"} {
+		if _, err := db.Messages.Exec(`UPDATE messages SET sender_name = ? WHERE id = ?`, sender, id); err != nil {
+			t.Fatalf("set sender: %v", err)
+		}
+	}
+
+	ids := func(opts ListMessagesOptions) string {
+		t.Helper()
+		messages, err := db.ListMessages(opts)
+		if err != nil {
+			t.Fatalf("list messages: %v", err)
+		}
+		var out []string
+		for _, m := range messages {
+			out = append(out, m.ID)
+		}
+		return strings.Join(out, ",")
+	}
+
+	if got := ids(ListMessagesOptions{ChatJID: chatJID}); got != "m3,m2,m1" {
+		t.Errorf("expected newest first by default, got %s", got)
+	}
+	if got := ids(ListMessagesOptions{ChatJID: chatJID, SortBy: "timestamp"}); got != "m1,m2,m3" {
+		t.Errorf("expected oldest first, got %s", got)
+	}
+	if got := ids(ListMessagesOptions{ChatJID: chatJID, SortBy: "sender"}); got != "m2,m3,m1" {
+		t.Errorf("expected senders A-Z ignoring case, got %s", got)
+	}
+	if got := ids(ListMessagesOptions{ChatJID: chatJID, SortBy: "sender", SortDesc: true}); got != "m1,m3,m2" {
+		t.Errorf("expected senders Z-A, got %s", got)
+	}
+	if _, err := db.ListMessages(ListMessagesOptions{SortBy: "timestamp; DROP TABLE messages"}); err == nil {
+		t.Error("expected an unknown sort to be rejected")
+	}
+	if _, err := db.SearchMessages(SearchMessagesOptions{Query: "one", SortBy: "content"}); err == nil {
+		t.Error("expected search to reject an unknown sort")
+	}
+}
+
+func TestListMessagesPage(t *testing.T) {
+	db := openTestDB(t)
+	chatJID := "12345@s.whatsapp.net"
+	ts := time.Date(2026, 4, 24, 12, 0, 0, 0, time.UTC)
+
+	for i := 1; i <= 5; i++ {
+		id := fmt.Sprintf("m%d", i)
+		insertTestMessage(t, db, id, chatJID, id, ts.Add(time.Duration(i)*time.Minute))
+	}
+
+	ids := func(page int) string {
+		t.Helper()
+		messages, err := db.ListMessages(ListMessagesOptions{ChatJID: chatJID, Limit: 2, Page: page})
+		if err != nil {
+			t.Fatalf("list messages: %v", err)
+		}
+		var out []string
+		for _, m := range messages {
+			out = append(out, m.ID)
+		}
+		return strings.Join(out, ",")
+	}
+
+	for page, want := range map[int]string{0: "m5,m4", 1: "m5,m4", 2: "m3,m2", 3: "m1", 4: ""} {
+		if got := ids(page); got != want {
+			t.Errorf("page %d: expected %q, got %q", page, want, got)
+		}
+	}
+}
+
+func TestListChatsNonEmpty(t *testing.T) {
+	db := openTestDB(t)
+	ts := time.Date(2026, 4, 24, 12, 0, 0, 0, time.UTC)
+
+	insertTestMessage(t, db, "m1", "busy@s.whatsapp.net", "hello", ts)
+	if _, err := db.Messages.Exec(`INSERT INTO chats (jid, name) VALUES ('empty@s.whatsapp.net', 'Empty')`); err != nil {
+		t.Fatalf("insert chat: %v", err)
+	}
+
+	all, err := db.ListChats(ListChatsOptions{})
+	if err != nil {
+		t.Fatalf("list chats: %v", err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("expected empty chats to be listed by default, got %d chats", len(all))
+	}
+
+	nonEmpty, err := db.ListChats(ListChatsOptions{NonEmpty: true})
+	if err != nil {
+		t.Fatalf("list chats: %v", err)
+	}
+	if len(nonEmpty) != 1 || nonEmpty[0].JID != "busy@s.whatsapp.net" {
+		t.Fatalf("expected only the chat with messages, got %+v", nonEmpty)
+	}
+}
+
+func TestCheckFTSDetectsAndRepairsDrift(t *testing.T) {
+	db := openTestDB(t)
+	chatJID := "12345@s.whatsapp.net"
+	ts := time.Date(2026, 4, 24, 12, 0, 0, 0, time.UTC)
+
+	insertTestMessage(t, db, "indexed", chatJID, "quarterly report", ts)
+
+	// Simulate an older database where the insert trigger was missing
+	if _, err := db.Messages.Exec("DROP TRIGGER messages_ai"); err != nil {
+		t.Fatalf("drop trigger: %v", err)
+	}
+	insertTestMessage(t, db, "missed", chatJID, "budget forecast", ts.Add(time.Minute))
+
+	status, err := db.CheckFTS()
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if status.Messages != 2 || status.Indexed != 1 || status.Drift() != 1 {
+		t.Fatalf("expected 1 message of drift, got %+v", status)
+	}
+
+	if err := db.RebuildFTS(); err != nil {
+		t.Fatalf("rebuild: %v", err)
+	}
+	status, err = db.CheckFTS()
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if status.Drift() != 0 {
+		t.Fatalf("expected no drift after rebuild, got %+v", status)
+	}
+
+	messages, err := db.SearchMessages(SearchMessagesOptions{Query: "budget"})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(messages) != 1 || messages[0].ID != "missed" {
+		t.Fatalf("expected rebuilt index to find the missed message, got %+v", messages)
+	}
+}
+
+func TestDropFTSTriggersReportsDroppedIndex(t *testing.T) {
+	db := openTestDB(t)
+
+	db.dropFTSTriggers()
+	if !db.FTSDropped() {
+		t.Fatal("expected dropping existing triggers to be reported")
+	}
+
+	var triggers int
+	if err := db.Messages.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name LIKE 'messages_a_'`).Scan(&triggers); err != nil {
+		t.Fatalf("count triggers: %v", err)
+	}
+	if triggers != 0 {
+		t.Fatalf("expected triggers to be dropped, %d left", triggers)
+	}
+
+	db.dropFTSTriggers()
+	if db.FTSDropped() {
+		t.Fatal("expected nothing to report once the triggers are gone")
+	}
+}
+
+func TestMigrateDropsTriggersLeftWithoutFTS(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "messages.db")
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	// As a build without FTS5 sees a database indexed by one with it: the
+	// triggers are there, but the index can't be used
+	if _, err := db.Messages.Exec("DROP TABLE messages_fts"); err != nil {
+		t.Fatalf("drop fts: %v", err)
+	}
+	db.CloseQuietly()
+
+	original := ftsMigration
+	ftsMigration = func(*sql.DB) error { return nil }
+	t.Cleanup(func() { ftsMigration = original })
+
+	db, err = Open(path)
+	if err != nil {
+		t.Fatalf("reopen db: %v", err)
+	}
+	t.Cleanup(db.CloseQuietly)
+
+	if db.HasFTS() || !db.FTSDropped() {
+		t.Fatalf("expected the stale triggers to be dropped, got HasFTS=%v FTSDropped=%v", db.HasFTS(), db.FTSDropped())
+	}
+	insertTestMessage(t, db, "after", "12345@s.whatsapp.net", "still writable", time.Now())
+}
+
+func TestDatabaseStats(t *testing.T) {
+	db := openTestDB(t)
+	chatJID := "12345@s.whatsapp.net"
+	first := time.Date(2026, 4, 24, 12, 0, 0, 0, time.UTC)
+	insertTestMessage(t, db, "a", chatJID, "first", first)
+	insertTestMessage(t, db, "b", chatJID, "second", first.Add(time.Hour))
+
+	stats, err := db.Stats()
+	if err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	if stats.FileBytes == 0 || !strings.HasSuffix(stats.Path, "messages.db") {
+		t.Fatalf("expected the database file to be measured, got %+v", stats)
+	}
+
+	counts := make(map[string]int64)
+	for _, table := range stats.Tables {
+		if strings.HasPrefix(table.Name, "messages_fts") {
+			t.Fatalf("expected search index tables to be left out, got %s", table.Name)
+		}
+		counts[table.Name] = table.Rows
+	}
+	if counts["messages"] != 2 || counts["chats"] != 1 {
+		t.Fatalf("unexpected row counts %v", counts)
+	}
+
+	if stats.OldestMessage == nil || !stats.OldestMessage.Equal(first) ||
+		stats.NewestMessage == nil || !stats.NewestMessage.Equal(first.Add(time.Hour)) {
+		t.Fatalf("unexpected message range %v - %v", stats.OldestMessage, stats.NewestMessage)
+	}
+}
+
+func TestListMessagesUnreadOnly(t *testing.T) {
+	db := openTestDB(t)
+	chatJID := "12345@s.whatsapp.net"
+	ts := time.Date(2026, 4, 24, 12, 0, 0, 0, time.UTC)
+
+	insertTestMessage(t, db, "old", chatJID, "seen", ts)
+	insertTestMessage(t, db, "read", chatJID, "seen too", ts.Add(time.Minute))
+	insertTestMessage(t, db, "mine", chatJID, "my reply", ts.Add(2*time.Minute))
+	insertTestMessage(t, db, "new", chatJID, "not seen", ts.Add(3*time.Minute))
+	if _, err := db.Messages.Exec(`UPDATE messages SET is_from_me = 1 WHERE id = 'mine'`); err != nil {
+		t.Fatalf("mark own message: %v", err)
+	}
+
+	opts := ListMessagesOptions{ChatJID: chatJID, UnreadOnly: true}
+	if messages, err := db.ListMessages(opts); err != nil || len(messages) != 0 {
+		t.Fatalf("expected nothing without a watermark, got %+v, %v", messages, err)
+	}
+
+	if err := db.AdvanceReadWatermark([]string{"old", "read"}, "other@s.whatsapp.net", chatJID); err != nil {
+		t.Fatalf("advance watermark: %v", err)
+	}
+	// An older read doesn't move the watermark back
+	if err := db.AdvanceReadWatermark([]string{"old"}, chatJID); err != nil {
+		t.Fatalf("advance watermark: %v", err)
+	}
+	if at, known, err := db.GetReadWatermark(chatJID); err != nil || !known || !at.Equal(ts.Add(time.Minute)) {
+		t.Fatalf("expected watermark at the read message, got %v, %v, %v", at, known, err)
+	}
+
+	messages, err := db.ListMessages(opts)
+	if err != nil {
+		t.Fatalf("list messages: %v", err)
+	}
+	if len(messages) != 1 || messages[0].ID != "new" {
+		t.Fatalf("expected only the unread message from others, got %+v", messages)
+	}
+}
+
+func TestDeleteChat(t *testing.T) {
+	db := openTestDB(t)
+	ts := time.Date(2026, 4, 24, 12, 0, 0, 0, time.UTC)
+
+	insertTestMessage(t, db, "spam1", "spam@s.whatsapp.net", "win a prize", ts)
+	insertTestMessage(t, db, "spam2", "spam@s.whatsapp.net", "claim your prize", ts.Add(time.Minute))
+	insertTestMessage(t, db, "keep", "friend@s.whatsapp.net", "prize draw tonight?", ts)
+	if err := db.SetReaction("spam@s.whatsapp.net", "spam1", "me", "x", ts); err != nil {
+		t.Fatalf("set reaction: %v", err)
+	}
+
+	removed, err := db.DeleteChat("spam@s.whatsapp.net")
+	if err != nil {
+		t.Fatalf("delete chat: %v", err)
+	}
+	if removed != 2 {
+		t.Fatalf("expected 2 messages removed, got %d", removed)
+	}
+
+	chats, err := db.ListChats(ListChatsOptions{})
+	if err != nil {
+		t.Fatalf("list chats: %v", err)
+	}
+	if len(chats) != 1 || chats[0].JID != "friend@s.whatsapp.net" {
+		t.Fatalf("expected only the other chat to remain, got %+v", chats)
+	}
+	// The FTS index follows the deleted messages.
+	messages, err := db.SearchMessages(SearchMessagesOptions{Query: "prize"})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(messages) != 1 || messages[0].ID != "keep" {
+		t.Fatalf("expected only the kept message to match, got %+v", messages)
+	}
+	reactions, err := db.ListReactions("spam@s.whatsapp.net", "spam1")
+	if err != nil {
+		t.Fatalf("list reactions: %v", err)
+	}
+	if len(reactions) != 0 {
+		t.Fatalf("expected reactions to be removed, got %+v", reactions)
+	}
+
+	if _, err := db.DeleteChat("spam@s.whatsapp.net"); err == nil {
+		t.Fatal("expected deleting an unknown chat to fail")
+	}
+}
+
+func TestChatUnreadCount(t *testing.T) {
+	db := openTestDB(t)
+	chatJID := "12345@s.whatsapp.net"
+	ts := time.Date(2026, 4, 24, 12, 0, 0, 0, time.UTC)
+
+	insertTestMessage(t, db, "m1", chatJID, "one", ts)
+	insertTestMessage(t, db, "m2", chatJID, "two", ts.Add(time.Minute))
+	insertTestMessage(t, db, "m3", chatJID, "three", ts.Add(2*time.Minute))
+	insertTestMessage(t, db, "other", "67890@s.whatsapp.net", "hi", ts)
+	for range 3 {
+		if err := db.IncrementUnread(chatJID); err != nil {
+			t.Fatalf("increment unread: %v", err)
+		}
+	}
+
+	unread := func() map[string]int {
+		t.Helper()
+		chats, err := db.ListChats(ListChatsOptions{UnreadOnly: true})
+		if err != nil {
+			t.Fatalf("list chats: %v", err)
+		}
+		out := make(map[string]int)
+		for _, c := range chats {
+			out[c.JID] = c.Unread
+		}
+		return out
+	}
+
+	if got := unread(); len(got) != 1 || got[chatJID] != 3 {
+		t.Fatalf("expected only %s with 3 unread, got %v", chatJID, got)
+	}
+
+	for _, desc := range []bool{true, false} {
+		chats, err := db.ListChats(ListChatsOptions{SortBy: "unread", SortDesc: desc})
+		if err != nil {
+			t.Fatalf("list chats by unread: %v", err)
+		}
+		if len(chats) != 2 || (chats[0].JID == chatJID) != desc {
+			t.Fatalf("expected the unread chat first only when descending (%v), got %+v", desc, chats)
+		}
+	}
+
+	// Reading up to m2 leaves m3 unread.
+	if err := db.AdvanceReadWatermark([]string{"m1", "m2"}, chatJID); err != nil {
+		t.Fatalf("advance watermark: %v", err)
+	}
+	if got := unread(); got[chatJID] != 1 {
+		t.Fatalf("expected 1 unread after reading m2, got %v", got)
+	}
+
+	if err := db.AdvanceReadWatermark([]string{"m3"}, chatJID); err != nil {
+		t.Fatalf("advance watermark: %v", err)
+	}
+	if got := unread(); len(got) != 0 {
+		t.Fatalf("expected no unread chats, got %v", got)
+	}
+}
+
+func TestSchedules(t *testing.T) {
+	db := openTestDB(t)
+	now := time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC)
+	// Stored in another zone, but still due before the UTC one
+	berlin := time.FixedZone("CEST", 2*60*60)
+
+	weekly, err := db.AddSchedule(Schedule{ChatJID: "g@g.us", Text: "standup", Every: "0 9 * * 3", NextRun: now.In(berlin).Add(-time.Minute)})
+	if err != nil {
+		t.Fatalf("add schedule: %v", err)
+	}
+	if _, err := db.AddSchedule(Schedule{ChatJID: "a@s.whatsapp.net", Text: "later", NextRun: now.Add(time.Hour)}); err != nil {
+		t.Fatalf("add schedule: %v", err)
+	}
+
+	due, err := db.DueSchedules(now)
+	if err != nil {
+		t.Fatalf("due schedules: %v", err)
+	}
+	if len(due) != 1 || due[0].ID != weekly || due[0].Every != "0 9 * * 3" || due[0].LastRun != nil {
+		t.Fatalf("expected only the weekly schedule to be due, got %+v", due)
+	}
+
+	if err := db.RearmSchedule(weekly, now, now.AddDate(0, 0, 7)); err != nil {
+		t.Fatalf("rearm: %v", err)
+	}
+	if due, err = db.DueSchedules(now); err != nil || len(due) != 0 {
+		t.Fatalf("expected nothing due after re-arming, got %+v (err=%v)", due, err)
+	}
+
+	all, err := db.ListSchedules()
+	if err != nil {
+		t.Fatalf("list schedules: %v", err)
+	}
+	if len(all) != 2 || all[0].Text != "later" || all[1].LastRun == nil || !all[1].LastRun.Equal(now) {
+		t.Fatalf("unexpected schedules %+v", all)
+	}
+
+	if err := db.RemoveSchedule(weekly); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if err := db.RemoveSchedule(weekly); err == nil {
+		t.Fatal("expected removing a missing schedule to fail")
+	}
+}
+
+func TestListMediaMessages(t *testing.T) {
+	db := openTestDB(t)
+	chatJID := "12345@s.whatsapp.net"
+	ts := time.Date(2026, 4, 24, 12, 0, 0, 0, time.UTC)
+
+	insertTestMessage(t, db, "text", chatJID, "hello", ts)
+	insertTestMessage(t, db, "photo", chatJID, "", ts.Add(time.Minute))
+	insertTestMessage(t, db, "voice", chatJID, "", ts.Add(2*time.Minute))
+	insertTestMessage(t, db, "old", chatJID, "", ts.Add(3*time.Minute))
+	for id, mediaType := range map[string]string{"photo": "image", "voice": "audio", "old": "image"} {
+		if _, err := db.Messages.Exec(`UPDATE messages SET media_type = ? WHERE id = ?`, mediaType, id); err != nil {
+			t.Fatalf("set media type: %v", err)
+		}
+	}
+	// "old" was synced without the keys needed to download it.
+	if _, err := db.Messages.Exec(`UPDATE messages SET url = 'https://mmg.whatsapp.net/x', media_key = x'01',
+		file_sha256 = x'02', file_enc_sha256 = x'03', file_length = 4 WHERE id IN ('photo', 'voice')`); err != nil {
+		t.Fatalf("set media info: %v", err)
+	}
+
+	describe := func(media []MediaMessage) string {
+		var out []string
+		for _, m := range media {
+			out = append(out, fmt.Sprintf("%s:%s:%v", m.ID, m.MediaType, m.Complete))
+		}
+		return strings.Join(out, ",")
+	}
+
+	media, err := db.ListMediaMessages(chatJID, "", "", "")
+	if err != nil {
+		t.Fatalf("list media: %v", err)
+	}
+	if got := describe(media); got != "photo:image:true,voice:audio:true,old:image:false" {
+		t.Fatalf("unexpected media %s", got)
+	}
+
+	media, err = db.ListMediaMessages(chatJID, "image", ts.Add(2*time.Minute).Format(time.RFC3339), "")
+	if err != nil {
+		t.Fatalf("list media: %v", err)
+	}
+	if got := describe(media); got != "old:image:false" {
+		t.Fatalf("unexpected filtered media %s", got)
+	}
+
+	if _, err := db.ListMediaMessages(chatJID, "", "yesterday", ""); err == nil {
+		t.Fatal("expected an invalid time to be rejected")
+	}
+}
+
+func TestListMessagesChatLabel(t *testing.T) {
+	db := openTestDB(t)
+	ts := time.Date(2026, 4, 24, 12, 0, 0, 0, time.UTC)
+
+	insertTestMessage(t, db, "named", "111@s.whatsapp.net", "hi", ts)
+	for _, chat := range []string{"222@s.whatsapp.net", "333@s.whatsapp.net"} {
+		if _, err := db.Messages.Exec(`INSERT INTO chats (jid, name) VALUES (?, NULL)`, chat); err != nil {
+			t.Fatalf("insert chat: %v", err)
+		}
+	}
+	for _, m := range []struct {
+		id, chat, senderName string
+		fromMe               bool
+	}{
+		{"mine", "222@s.whatsapp.net", "", true},
+		{"theirs", "222@s.whatsapp.net", "Bob", false},
+		{"unknown", "333@s.whatsapp.net", "", false},
+	} {
+		if _, err := db.Messages.Exec(`INSERT INTO messages (id, chat_jid, sender, sender_name, content, timestamp, is_from_me) VALUES (?, ?, ?, NULLIF(?, ''), 'x', ?, ?)`,
+			m.id, m.chat, "222", m.senderName, ts, m.fromMe); err != nil {
+			t.Fatalf("insert message %s: %v", m.id, err)
+		}
+	}
+
+	messages, err := db.ListMessages(ListMessagesOptions{})
+	if err != nil {
+		t.Fatalf("list messages: %v", err)
+	}
+	want := map[string]string{"named": "Test Chat", "mine": "Bob", "theirs": "Bob", "unknown": "333"}
+	for _, m := range messages {
+		if m.ChatLabel != want[m.ID] {
+			t.Errorf("message %s: expected chat label %q, got %q", m.ID, want[m.ID], m.ChatLabel)
+		}
+		if m.ID != "named" && m.ChatName != nil {
+			t.Errorf("message %s: expected the raw chat name to stay unset, got %q", m.ID, *m.ChatName)
+		}
+	}
+
+	// The label comes from the chat, not the page, so a page holding only our
+	// own message is labelled the same.
+	if err := db.SetMessageStarred("222@s.whatsapp.net", "mine", true); err != nil {
+		t.Fatalf("star message: %v", err)
+	}
+	page, err := db.ListMessages(ListMessagesOptions{ChatJID: "222@s.whatsapp.net", Starred: true})
+	if err != nil || len(page) != 1 || page[0].ID != "mine" {
+		t.Fatalf("list page: %+v (%v)", page, err)
+	}
+	if page[0].ChatLabel != "Bob" {
+		t.Errorf("expected the page to be labelled Bob, got %q", page[0].ChatLabel)
+	}
+
+	if err := db.StoreLIDMapping("444", "333", "Carol"); err != nil {
+		t.Fatalf("store lid mapping: %v", err)
+	}
+	page, err = db.ListMessages(ListMessagesOptions{ChatJID: "333@s.whatsapp.net"})
+	if err != nil || len(page) != 1 || page[0].ChatLabel != "Carol" {
+		t.Fatalf("expected the LID mapping's name, got %+v (%v)", page, err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -12,17 +13,35 @@ import (
 
 // DB wraps the messages database connection.
 type DB struct {
-	Messages *sql.DB
+	Messages   *sql.DB
+	hasFTS     bool
+	ftsDropped bool
 }
 
-// Open opens the messages database at the given path.
+// Open opens the messages database at the given path and applies any pending migrations.
 func Open(dbPath string) (*DB, error) {
+	d, err := OpenUnmigrated(dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := d.Migrate(); err != nil {
+		d.CloseQuietly()
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// OpenUnmigrated opens the messages database without touching its schema.
+// Call Migrate before running queries.
+func OpenUnmigrated(dbPath string) (*DB, error) {
 	dir := dbPath[:strings.LastIndex(dbPath, "/")]
 	if err := os.MkdirAll(dir, 0700); err != nil {
 		return nil, fmt.Errorf("failed to create db dir: %w", err)
 	}
 
-	connStr := fmt.Sprintf("file:%s?_foreign_keys=on", dbPath)
+	connStr := fmt.Sprintf("file:%s?_foreign_keys=on&_busy_timeout=%d", dbPath, BusyTimeout.Milliseconds())
 	mdb, err := sql.Open("sqlite3", connStr)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open messages db: %w", err)
@@ -31,14 +50,32 @@ func Open(dbPath string) (*DB, error) {
 	// Configure for SQLite single-writer limitation
 	mdb.SetMaxOpenConns(1)
 
-	if err := migrate(mdb); err != nil {
-		_ = mdb.Close()
-		return nil, err
-	}
-
 	return &DB{Messages: mdb}, nil
 }
 
+// HasFTS reports whether the messages_fts full-text index is usable.
+// It is checked once when the database is opened.
+func (d *DB) HasFTS() bool {
+	return d.hasFTS
+}
+
+// FTSDropped reports whether opening the database removed the search index
+// triggers because this build lacks FTS5. Messages stored from then on are
+// missing from the index until a build with FTS5 opens the database again.
+func (d *DB) FTSDropped() bool {
+	return d.ftsDropped
+}
+
+func detectFTS(db *sql.DB) bool {
+	_, err := db.Exec("SELECT rowid FROM messages_fts LIMIT 0")
+	return err == nil
+}
+
+func isFTSUnavailable(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "fts5") || strings.Contains(msg, "no such module")
+}
+
 // Close closes all database connections.
 func (d *DB) Close() error {
 	if d == nil {
@@ -55,47 +92,17 @@ func (d *DB) CloseQuietly() {
 	_ = d.Close()
 }
 
-func migrate(db *sql.DB) error {
-	_, err := db.Exec(`
-		CREATE TABLE IF NOT EXISTS chats (
-			jid TEXT PRIMARY KEY,
-			name TEXT,
-			last_message_time TIMESTAMP
-		);
-
-		CREATE TABLE IF NOT EXISTS messages (
-			id TEXT,
-			chat_jid TEXT,
-			sender TEXT,
-			sender_name TEXT,
-			content TEXT,
-			timestamp TIMESTAMP,
-			is_from_me BOOLEAN,
-			media_type TEXT,
-			filename TEXT,
-			url TEXT,
-			media_key BLOB,
-			file_sha256 BLOB,
-			file_enc_sha256 BLOB,
-			file_length INTEGER,
-			PRIMARY KEY (id, chat_jid),
-			FOREIGN KEY (chat_jid) REFERENCES chats(jid)
-		);
-
-		CREATE TABLE IF NOT EXISTS lid_mappings (
-			lid TEXT PRIMARY KEY,
-			phone TEXT,
-			name TEXT,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		);
-
-		CREATE TABLE IF NOT EXISTS metadata (
-			key TEXT PRIMARY KEY,
-			value TEXT
-		);
-	`)
-	if err != nil {
-		return fmt.Errorf("failed to run migrations: %w", err)
+// ftsMigration sets up full-text search in Migrate; tests replace it to
+// simulate a build without FTS5.
+var ftsMigration = migrateFTS
+
+// migrateFTS creates the messages_fts index and the triggers keeping it in sync.
+// The index is rebuilt only when it or its insert trigger was missing, since
+// messages written meanwhile were never indexed.
+func migrateFTS(db *sql.DB) error {
+	var existing int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name IN ('messages_fts', 'messages_ai')`).Scan(&existing); err != nil {
+		return err
 	}
 
 	// Create FTS5 virtual table for full-text search
@@ -104,9 +111,6 @@ func migrate(db *sql.DB) error {
 		content='messages',
 		content_rowid='rowid'
 	);`); err != nil {
-		if strings.Contains(strings.ToLower(err.Error()), "fts5") || strings.Contains(strings.ToLower(err.Error()), "no such module") {
-			return fmt.Errorf("SQLite FTS5 is not available. Build with: CGO_ENABLED=1 go build -tags sqlite_fts5")
-		}
 		return err
 	}
 
@@ -137,24 +141,71 @@ func migrate(db *sql.DB) error {
 	}
 
 	// Rebuild index to sync with existing messages
-	_, _ = db.Exec(`INSERT INTO messages_fts(messages_fts) VALUES('rebuild')`)
-
-	// Add sender_name column if it doesn't exist (for existing databases)
-	_, _ = db.Exec(`ALTER TABLE messages ADD COLUMN sender_name TEXT`)
+	if existing < 2 {
+		_, _ = db.Exec(`INSERT INTO messages_fts(messages_fts) VALUES('rebuild')`)
+	}
 
 	return nil
 }
 
+// FTSStatus compares the messages table with what the search index holds.
+type FTSStatus struct {
+	Messages int `json:"messages"`
+	Indexed  int `json:"indexed"`
+}
+
+// Drift is the number of messages missing from (or stale in) the index.
+func (s FTSStatus) Drift() int {
+	if s.Messages > s.Indexed {
+		return s.Messages - s.Indexed
+	}
+	return s.Indexed - s.Messages
+}
+
+// FTSRowCount returns the number of rows in the search index. messages_fts is
+// an external-content table, so counting it directly would count messages;
+// the docsize shadow table has one row per indexed message.
+func (d *DB) FTSRowCount() (int, error) {
+	if !d.hasFTS {
+		return 0, fmt.Errorf("full-text search is not available")
+	}
+	var count int
+	err := d.QueryRow("SELECT COUNT(*) FROM messages_fts_docsize").Scan(&count)
+	return count, err
+}
+
+// CheckFTS counts the messages and the indexed rows so drift can be reported.
+func (d *DB) CheckFTS() (FTSStatus, error) {
+	var status FTSStatus
+	var err error
+	if status.Messages, err = d.CountMessages(); err != nil {
+		return status, err
+	}
+	if status.Indexed, err = d.FTSRowCount(); err != nil {
+		return status, err
+	}
+	return status, nil
+}
+
+// RebuildFTS rebuilds the search index from the messages table.
+func (d *DB) RebuildFTS() error {
+	if !d.hasFTS {
+		return fmt.Errorf("full-text search is not available")
+	}
+	_, err := d.Exec(`INSERT INTO messages_fts(messages_fts) VALUES('rebuild')`)
+	return err
+}
+
 // CountChats returns the total number of chats matching the query.
 func (d *DB) CountChats(query string) (int, error) {
 	var count int
 	var err error
 
 	if query == "" {
-		err = d.Messages.QueryRow("SELECT COUNT(*) FROM chats").Scan(&count)
+		err = d.QueryRow("SELECT COUNT(*) FROM chats").Scan(&count)
 	} else {
 		pattern := "%" + strings.ToLower(query) + "%"
-		err = d.Messages.QueryRow("SELECT COUNT(*) FROM chats WHERE LOWER(name) LIKE ? OR jid LIKE ?", pattern, pattern).Scan(&count)
+		err = d.QueryRow("SELECT COUNT(*) FROM chats WHERE LOWER(name) LIKE ? OR jid LIKE ?", pattern, pattern).Scan(&count)
 	}
 
 	return count, err
@@ -163,13 +214,150 @@ func (d *DB) CountChats(query string) (int, error) {
 // CountMessages returns the total number of messages.
 func (d *DB) CountMessages() (int, error) {
 	var count int
-	err := d.Messages.QueryRow("SELECT COUNT(*) FROM messages").Scan(&count)
+	err := d.QueryRow("SELECT COUNT(*) FROM messages").Scan(&count)
 	return count, err
 }
 
+// Stats reports row counts per table, file sizes and the date range of
+// messages. The search index's own tables are counted in FTSBytes instead.
+func (d *DB) Stats() (DatabaseStats, error) {
+	var stats DatabaseStats
+	if err := d.QueryRow("SELECT file FROM pragma_database_list WHERE name = 'main'").Scan(&stats.Path); err != nil {
+		return stats, fmt.Errorf("failed to locate database: %w", err)
+	}
+	if info, err := os.Stat(stats.Path); err == nil {
+		stats.FileBytes = info.Size()
+	}
+	if info, err := os.Stat(stats.Path + "-wal"); err == nil {
+		stats.WALBytes = info.Size()
+	}
+
+	var pageSize, freePages int64
+	if err := d.QueryRow("SELECT page_size, freelist_count FROM pragma_page_size, pragma_freelist_count").Scan(&pageSize, &freePages); err != nil {
+		return stats, fmt.Errorf("failed to read page counts: %w", err)
+	}
+	stats.FreeBytes = pageSize * freePages
+
+	rows, err := d.Query(`SELECT name FROM sqlite_master
+		WHERE type = 'table' AND name NOT LIKE 'sqlite_%' AND name NOT LIKE 'messages_fts%'
+		ORDER BY name`)
+	if err != nil {
+		return stats, fmt.Errorf("failed to list tables: %w", err)
+	}
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			_ = rows.Close()
+			return stats, err
+		}
+		tables = append(tables, name)
+	}
+	_ = rows.Close()
+
+	for _, name := range tables {
+		table := TableStats{Name: name}
+		if err := d.QueryRow(`SELECT COUNT(*) FROM "` + name + `"`).Scan(&table.Rows); err != nil {
+			return stats, fmt.Errorf("failed to count %s: %w", name, err)
+		}
+		stats.Tables = append(stats.Tables, table)
+	}
+
+	// The FTS5 shadow tables are ordinary tables, readable even without FTS5
+	var shadow int
+	_ = d.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name IN ('messages_fts_data', 'messages_fts_docsize')").Scan(&shadow)
+	if shadow == 2 {
+		if err := d.QueryRow(`SELECT
+			(SELECT COALESCE(SUM(LENGTH(block)), 0) FROM messages_fts_data) +
+			(SELECT COALESCE(SUM(LENGTH(sz)), 0) FROM messages_fts_docsize)`).Scan(&stats.FTSBytes); err != nil {
+			return stats, fmt.Errorf("failed to measure search index: %w", err)
+		}
+	}
+
+	// ORDER BY rather than MIN/MAX so the driver still parses the timestamp
+	for _, q := range []struct {
+		order string
+		dest  **time.Time
+	}{{"ASC", &stats.OldestMessage}, {"DESC", &stats.NewestMessage}} {
+		var t time.Time
+		err := d.QueryRow("SELECT timestamp FROM messages ORDER BY timestamp " + q.order + " LIMIT 1").Scan(&t)
+		if err == sql.ErrNoRows {
+			break
+		}
+		if err != nil {
+			return stats, fmt.Errorf("failed to read message dates: %w", err)
+		}
+		*q.dest = &t
+	}
+
+	return stats, nil
+}
+
+// MediaFilenames returns the media filenames stored for each chat, keyed by chat
+// JID: each message's own filename and the name it was downloaded as.
+func (d *DB) MediaFilenames() (map[string]map[string]bool, error) {
+	rows, err := d.Query(`SELECT chat_jid, filename FROM messages WHERE filename IS NOT NULL AND filename != ''
+		UNION SELECT chat_jid, local_path FROM messages WHERE local_path IS NOT NULL AND local_path != ''`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	files := make(map[string]map[string]bool)
+	for rows.Next() {
+		var chatJID, filename string
+		if err := rows.Scan(&chatJID, &filename); err != nil {
+			return nil, err
+		}
+		if files[chatJID] == nil {
+			files[chatJID] = make(map[string]bool)
+		}
+		files[chatJID][filepath.Base(filename)] = true
+	}
+	return files, rows.Err()
+}
+
+// SetMediaLocalPath records where a message's media was downloaded to.
+func (d *DB) SetMediaLocalPath(chatJID, messageID, path string) error {
+	_, err := d.Exec("UPDATE messages SET local_path = ? WHERE id = ? AND chat_jid = ?", path, messageID, chatJID)
+	return err
+}
+
+// GetRegistrations returns cached registration checks made after since, keyed by phone.
+func (d *DB) GetRegistrations(since time.Time) (map[string]Registration, error) {
+	rows, err := d.Query(`SELECT phone, COALESCE(jid, ''), is_registered FROM registered_numbers WHERE checked_at >= ?`, since.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	regs := make(map[string]Registration)
+	for rows.Next() {
+		var r Registration
+		if err := rows.Scan(&r.Phone, &r.JID, &r.Registered); err != nil {
+			return nil, err
+		}
+		regs[r.Phone] = r
+	}
+	return regs, rows.Err()
+}
+
+// SaveRegistration caches the result of a registration check.
+func (d *DB) SaveRegistration(r Registration) error {
+	_, err := d.Exec(`
+		INSERT INTO registered_numbers (phone, jid, is_registered, checked_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(phone) DO UPDATE SET
+			jid = excluded.jid,
+			is_registered = excluded.is_registered,
+			checked_at = excluded.checked_at
+	`, r.Phone, r.JID, r.Registered, time.Now().UTC())
+	return err
+}
+
 // StoreLIDMapping stores a LID -> phone/name mapping.
 func (d *DB) StoreLIDMapping(lid, phone, name string) error {
-	_, err := d.Messages.Exec(`
+	_, err := d.Exec(`
 		INSERT INTO lid_mappings (lid, phone, name, updated_at)
 		VALUES (?, ?, ?, CURRENT_TIMESTAMP)
 		ON CONFLICT(lid) DO UPDATE SET
@@ -183,13 +371,23 @@ func (d *DB) StoreLIDMapping(lid, phone, name string) error {
 // GetLIDMapping retrieves a LID mapping.
 func (d *DB) GetLIDMapping(lid string) (phone, name string, found bool) {
 	var p, n sql.NullString
-	err := d.Messages.QueryRow("SELECT phone, name FROM lid_mappings WHERE lid = ?", lid).Scan(&p, &n)
+	err := d.QueryRow("SELECT phone, name FROM lid_mappings WHERE lid = ?", lid).Scan(&p, &n)
 	if err != nil {
 		return "", "", false
 	}
 	return p.String, n.String, true
 }
 
+// GetLIDMappingByPhone returns the LID mapping for a phone number.
+func (d *DB) GetLIDMappingByPhone(phone string) (lid, name string, found bool) {
+	var l, n sql.NullString
+	err := d.QueryRow("SELECT lid, name FROM lid_mappings WHERE phone = ? ORDER BY updated_at DESC LIMIT 1", phone).Scan(&l, &n)
+	if err != nil {
+		return "", "", false
+	}
+	return l.String, n.String, true
+}
+
 // ResolveSenderName tries to resolve a sender identifier to a display name.
 func (d *DB) ResolveSenderName(sender string) string {
 	// First check lid_mappings
@@ -203,7 +401,7 @@ func (d *DB) ResolveSenderName(sender string) string {
 	if !strings.Contains(sender, "@") {
 		jid = sender + "@s.whatsapp.net"
 	}
-	_ = d.Messages.QueryRow("SELECT name FROM chats WHERE jid = ?", jid).Scan(&chatName)
+	_ = d.QueryRow("SELECT name FROM chats WHERE jid = ?", jid).Scan(&chatName)
 	if chatName.Valid && chatName.String != "" {
 		return chatName.String
 	}
@@ -211,10 +409,466 @@ func (d *DB) ResolveSenderName(sender string) string {
 	return ""
 }
 
+// ReplaceGroupParticipants replaces the cached participant list for a group.
+func (d *DB) ReplaceGroupParticipants(groupJID string, participants []Participant) error {
+	return d.inTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec("DELETE FROM group_participants WHERE group_jid = ?", groupJID); err != nil {
+			return err
+		}
+
+		for _, p := range participants {
+			if _, err := tx.Exec(`
+				INSERT INTO group_participants (group_jid, jid, lid, phone, name, is_admin, updated_at)
+				VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+			`, groupJID, p.JID, p.LID, p.Phone, p.Name, p.IsAdmin); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetGroupParticipants returns the cached participants for a group.
+// Names fall back to LID mappings when the cached name is empty.
+func (d *DB) GetGroupParticipants(groupJID string) ([]Participant, error) {
+	rows, err := d.Query(`
+		SELECT p.jid, p.lid, p.phone, COALESCE(NULLIF(p.name, ''), l.name, '') as name, p.is_admin
+		FROM group_participants p
+		LEFT JOIN lid_mappings l ON p.lid = l.lid
+		WHERE p.group_jid = ?
+		ORDER BY p.is_admin DESC, name, p.jid
+	`, groupJID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var participants []Participant
+	for rows.Next() {
+		var p Participant
+		var lid, phone sql.NullString
+
+		if err := rows.Scan(&p.JID, &lid, &phone, &p.Name, &p.IsAdmin); err != nil {
+			continue
+		}
+
+		if lid.Valid && lid.String != "" {
+			p.LID = &lid.String
+		}
+		if phone.Valid && phone.String != "" {
+			p.Phone = &phone.String
+		}
+
+		participants = append(participants, p)
+	}
+
+	return participants, nil
+}
+
+// GroupsContaining returns the groups in the participant cache that have every
+// one of the given members. Members may be phone numbers or phone/LID JIDs; a
+// phone number also matches a participant known only by a mapped LID.
+func (d *DB) GroupsContaining(members ...string) ([]Chat, error) {
+	if len(members) == 0 {
+		return nil, fmt.Errorf("no members given")
+	}
+
+	var selects []string
+	var args []any
+	for _, m := range members {
+		user, server, _ := strings.Cut(strings.TrimPrefix(strings.TrimSpace(m), "+"), "@")
+		if user == "" {
+			return nil, fmt.Errorf("invalid member %q", m)
+		}
+		if i := strings.Index(user, ":"); i >= 0 {
+			user = user[:i] // Drop a device suffix
+		}
+		if server == "lid" {
+			selects = append(selects, `SELECT group_jid FROM group_participants WHERE jid = ? OR lid = ?`)
+			args = append(args, user+"@lid", user)
+			continue
+		}
+		selects = append(selects, `SELECT group_jid FROM group_participants
+			WHERE jid = ? OR phone = ? OR lid IN (SELECT lid FROM lid_mappings WHERE phone = ?)`)
+		args = append(args, user+"@s.whatsapp.net", user, user)
+	}
+
+	rows, err := d.Query(`
+		SELECT g.group_jid, c.name, c.last_message_time
+		FROM (`+strings.Join(selects, " INTERSECT ")+`) g
+		LEFT JOIN chats c ON c.jid = g.group_jid
+		ORDER BY COALESCE(NULLIF(c.name, ''), g.group_jid) COLLATE NOCASE
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var groups []Chat
+	for rows.Next() {
+		g := Chat{IsGroup: true}
+		var name sql.NullString
+		var lastMessage sql.NullTime
+		if err := rows.Scan(&g.JID, &name, &lastMessage); err != nil {
+			return nil, err
+		}
+		if name.Valid && name.String != "" {
+			g.Name = &name.String
+		}
+		if lastMessage.Valid {
+			g.LastMessageTime = &lastMessage.Time
+		}
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+// CountCachedGroups returns how many groups have members in the participant cache.
+func (d *DB) CountCachedGroups() (int, error) {
+	var count int
+	err := d.QueryRow("SELECT COUNT(DISTINCT group_jid) FROM group_participants").Scan(&count)
+	return count, err
+}
+
+// groupColumns are the columns scanned by scanGroup.
+const groupColumns = "jid, COALESCE(name, ''), COALESCE(topic, ''), COALESCE(creator_jid, ''), created, participant_count, updated_at"
+
+// SaveGroup caches a group's info, replacing anything cached for it before.
+// Participants aren't stored here; see ReplaceGroupParticipants.
+func (d *DB) SaveGroup(g GroupInfo) error {
+	_, err := d.Exec(`
+		INSERT INTO groups (jid, name, topic, creator_jid, created, participant_count, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(jid) DO UPDATE SET
+			name = excluded.name,
+			topic = excluded.topic,
+			creator_jid = excluded.creator_jid,
+			created = excluded.created,
+			participant_count = excluded.participant_count,
+			updated_at = CURRENT_TIMESTAMP
+	`, g.JID, g.Name, g.Topic, g.CreatorJID, nullTime(g.Created), g.ParticipantCount)
+	return err
+}
+
+// UpdateGroup changes the cached name and topic of a group, leaving either as
+// it is when nil. A group that isn't cached is left alone.
+func (d *DB) UpdateGroup(jid string, name, topic *string) error {
+	_, err := d.Exec(`
+		UPDATE groups SET
+			name = COALESCE(?, name),
+			topic = COALESCE(?, topic),
+			updated_at = CURRENT_TIMESTAMP
+		WHERE jid = ?
+	`, name, topic, jid)
+	return err
+}
+
+// AddGroup caches a group's info unless it is already cached, so an older
+// snapshot, such as one from history sync, doesn't overwrite a fresher one.
+// It reports whether the group was added.
+func (d *DB) AddGroup(g GroupInfo) (bool, error) {
+	res, err := d.Exec(`
+		INSERT INTO groups (jid, name, topic, creator_jid, created, participant_count, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(jid) DO NOTHING
+	`, g.JID, g.Name, g.Topic, g.CreatorJID, nullTime(g.Created), g.ParticipantCount)
+	if err != nil {
+		return false, err
+	}
+	added, err := res.RowsAffected()
+	return added > 0, err
+}
+
+// GetGroup returns a group's cached info, with its cached participants, or
+// nil if it isn't cached.
+func (d *DB) GetGroup(jid string) (*GroupInfo, error) {
+	g, err := scanGroup(d.QueryRow("SELECT "+groupColumns+" FROM groups WHERE jid = ?", jid))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if g.Participants, err = d.GetGroupParticipants(jid); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// ListGroups returns the cached info of every group, by name, without
+// participants.
+func (d *DB) ListGroups() ([]GroupInfo, error) {
+	rows, err := d.Query("SELECT " + groupColumns + " FROM groups ORDER BY name COLLATE NOCASE, jid")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var groups []GroupInfo
+	for rows.Next() {
+		g, err := scanGroup(rows)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+// scanGroup reads a row of groupColumns.
+func scanGroup(row interface{ Scan(...any) error }) (GroupInfo, error) {
+	var g GroupInfo
+	var created sql.NullTime
+	var updated time.Time
+	if err := row.Scan(&g.JID, &g.Name, &g.Topic, &g.CreatorJID, &created, &g.ParticipantCount, &updated); err != nil {
+		return GroupInfo{}, err
+	}
+	if created.Valid {
+		g.Created = created.Time
+	}
+	g.CachedAt = &updated
+	return g, nil
+}
+
+// nullTime stores the zero time as NULL.
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+// DeleteChat removes a chat from the local store along with its messages,
+// their reactions and the chat's settings and cached participants. Messages
+// go first, as they reference the chat, and the FTS index follows them by
+// trigger. It returns how many messages were removed.
+func (d *DB) DeleteChat(jid string) (int64, error) {
+	var removed int64
+	err := d.inTx(func(tx *sql.Tx) error {
+		var chats int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM chats WHERE jid = ?", jid).Scan(&chats); err != nil {
+			return err
+		}
+		res, err := tx.Exec("DELETE FROM messages WHERE chat_jid = ?", jid)
+		if err != nil {
+			return err
+		}
+		if removed, err = res.RowsAffected(); err != nil {
+			return err
+		}
+		if chats == 0 && removed == 0 {
+			return fmt.Errorf("chat %s not found", jid)
+		}
+		for _, stmt := range []string{
+			"DELETE FROM reactions WHERE chat_jid = ?",
+			"DELETE FROM chat_settings WHERE jid = ?",
+			"DELETE FROM group_participants WHERE group_jid = ?",
+			"DELETE FROM groups WHERE jid = ?",
+			"DELETE FROM chats WHERE jid = ?",
+		} {
+			if _, err := tx.Exec(stmt, jid); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return removed, err
+}
+
+// SetChatEphemeral records a chat's disappearing message timer in seconds, or
+// 0 if disappearing messages are off.
+func (d *DB) SetChatEphemeral(jid string, seconds uint32) error {
+	_, err := d.Exec(`
+		INSERT INTO chat_settings (jid, ephemeral_expiration, updated_at)
+		VALUES (?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(jid) DO UPDATE SET
+			ephemeral_expiration = excluded.ephemeral_expiration,
+			updated_at = CURRENT_TIMESTAMP
+	`, jid, seconds)
+	return err
+}
+
+// GetChatEphemeral returns a chat's disappearing message timer in seconds, or
+// 0 if it is off or has not been seen.
+func (d *DB) GetChatEphemeral(jid string) (uint32, error) {
+	var seconds sql.NullInt64
+	err := d.QueryRow("SELECT ephemeral_expiration FROM chat_settings WHERE jid = ?", jid).Scan(&seconds)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return uint32(seconds.Int64), nil
+}
+
+// IncrementUnread counts a newly received message from someone else as unread.
+func (d *DB) IncrementUnread(chatJID string) error {
+	_, err := d.Exec(`
+		INSERT INTO chat_settings (jid, unread_count, updated_at)
+		VALUES (?, 1, CURRENT_TIMESTAMP)
+		ON CONFLICT(jid) DO UPDATE SET
+			unread_count = COALESCE(unread_count, 0) + 1,
+			updated_at = CURRENT_TIMESTAMP
+	`, chatJID)
+	return err
+}
+
+// SetUnread sets a chat's unread count, e.g. as reported by history sync.
+func (d *DB) SetUnread(chatJID string, count int) error {
+	_, err := d.Exec(`
+		INSERT INTO chat_settings (jid, unread_count, updated_at)
+		VALUES (?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(jid) DO UPDATE SET
+			unread_count = excluded.unread_count,
+			updated_at = CURRENT_TIMESTAMP
+	`, chatJID, count)
+	return err
+}
+
+// AdvanceReadWatermark records that a chat has been read up to the newest of
+// messageIDs, looked up in whichever of chatJIDs they are stored under. The
+// watermark is keyed by that chat and never moves backwards. The chat's unread
+// count drops to the messages from others still after it.
+func (d *DB) AdvanceReadWatermark(messageIDs []string, chatJIDs ...string) error {
+	if len(messageIDs) == 0 || len(chatJIDs) == 0 {
+		return nil
+	}
+
+	var args []any
+	for _, jid := range chatJIDs {
+		args = append(args, jid)
+	}
+	for _, id := range messageIDs {
+		args = append(args, id)
+	}
+	var chatJID string
+	var newest time.Time
+	err := d.QueryRow(`SELECT chat_jid, timestamp FROM messages
+		WHERE chat_jid IN (`+Placeholders(len(chatJIDs))+`) AND id IN (`+Placeholders(len(messageIDs))+`)
+		ORDER BY timestamp DESC LIMIT 1`, args...).Scan(&chatJID, &newest)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	current, known, err := d.GetReadWatermark(chatJID)
+	if err != nil {
+		return err
+	}
+	if known && !newest.After(current) {
+		return nil
+	}
+	var unread int
+	err = d.QueryRow(`SELECT COUNT(*) FROM messages
+		WHERE chat_jid = ? AND is_from_me = 0 AND timestamp > ? AND `+notSystem("media_type"),
+		chatJID, newest).Scan(&unread)
+	if err != nil {
+		return err
+	}
+	_, err = d.Exec(`
+		INSERT INTO chat_settings (jid, last_read_at, unread_count, updated_at)
+		VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(jid) DO UPDATE SET
+			last_read_at = excluded.last_read_at,
+			unread_count = excluded.unread_count,
+			updated_at = CURRENT_TIMESTAMP
+	`, chatJID, newest, unread)
+	return err
+}
+
+// Placeholders returns n comma-separated ? placeholders for an IN list.
+func Placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// GetReadWatermark returns the time of the newest message read in a chat, and
+// false if no read receipt for it has been seen.
+func (d *DB) GetReadWatermark(chatJID string) (time.Time, bool, error) {
+	var at sql.NullTime
+	err := d.QueryRow("SELECT last_read_at FROM chat_settings WHERE jid = ?", chatJID).Scan(&at)
+	if err == sql.ErrNoRows {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return at.Time, at.Valid, nil
+}
+
+// AddSchedule stores a message to send at s.NextRun and returns its ID.
+func (d *DB) AddSchedule(s Schedule) (int64, error) {
+	res, err := d.Exec(`INSERT INTO schedules (chat_jid, text, cron, next_run) VALUES (?, ?, ?, ?)`,
+		s.ChatJID, s.Text, s.Every, s.NextRun.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// ListSchedules returns all schedules, soonest first.
+func (d *DB) ListSchedules() ([]Schedule, error) {
+	return d.querySchedules("")
+}
+
+// DueSchedules returns the schedules whose next run is at or before now.
+func (d *DB) DueSchedules(now time.Time) ([]Schedule, error) {
+	return d.querySchedules("WHERE next_run <= ?", now.UTC())
+}
+
+// querySchedules loads schedules matching a WHERE clause, soonest first.
+// Times are stored in UTC so they compare correctly as text.
+func (d *DB) querySchedules(where string, args ...any) ([]Schedule, error) {
+	rows, err := d.Query(`SELECT id, chat_jid, text, COALESCE(cron, ''), next_run, last_run
+		FROM schedules `+where+` ORDER BY next_run, id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var schedules []Schedule
+	for rows.Next() {
+		var s Schedule
+		var lastRun sql.NullTime
+		if err := rows.Scan(&s.ID, &s.ChatJID, &s.Text, &s.Every, &s.NextRun, &lastRun); err != nil {
+			return nil, err
+		}
+		s.NextRun = s.NextRun.Local()
+		if lastRun.Valid {
+			at := lastRun.Time.Local()
+			s.LastRun = &at
+		}
+		schedules = append(schedules, s)
+	}
+	return schedules, rows.Err()
+}
+
+// RearmSchedule records that a recurring schedule ran at ranAt and sets when it
+// runs next.
+func (d *DB) RearmSchedule(id int64, ranAt, next time.Time) error {
+	_, err := d.Exec(`UPDATE schedules SET last_run = ?, next_run = ? WHERE id = ?`, ranAt.UTC(), next.UTC(), id)
+	return err
+}
+
+// RemoveSchedule deletes a schedule.
+func (d *DB) RemoveSchedule(id int64) error {
+	res, err := d.Exec(`DELETE FROM schedules WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("schedule %d not found", id)
+	}
+	return nil
+}
+
 // GetLastSyncTime returns the last sync time, or zero time if never synced.
 func (d *DB) GetLastSyncTime() (time.Time, error) {
 	var value sql.NullString
-	err := d.Messages.QueryRow("SELECT value FROM metadata WHERE key = 'last_sync_time'").Scan(&value)
+	err := d.QueryRow("SELECT value FROM metadata WHERE key = 'last_sync_time'").Scan(&value)
 	if err == sql.ErrNoRows || !value.Valid {
 		return time.Time{}, nil
 	}
@@ -226,7 +880,7 @@ func (d *DB) GetLastSyncTime() (time.Time, error) {
 
 // SetLastSyncTime stores the last sync time.
 func (d *DB) SetLastSyncTime(t time.Time) error {
-	_, err := d.Messages.Exec(
+	_, err := d.Exec(
 		"INSERT INTO metadata (key, value) VALUES ('last_sync_time', ?) ON CONFLICT(key) DO UPDATE SET value = excluded.value",
 		t.Format(time.RFC3339),
 	)
@@ -0,0 +1,9 @@
+package store
+
+import "golang.org/x/text/unicode/norm"
+
+// NormalizeText returns the NFC form of s so that visually identical text
+// (e.g. precomposed vs combining accents) compares and searches equally.
+func NormalizeText(s string) string {
+	return norm.NFC.String(s)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"strings"
@@ -25,12 +26,32 @@ type Client struct {
 	Store        *store.DB
 	Logger       *slog.Logger
 	BaseDir      string
-	SyncComplete chan struct{} // Signals when history sync is complete
-
-	syncCompleteMu    sync.Mutex
-	syncCompleteTimer *time.Timer
-	backfillMu        sync.Mutex
-	pendingBackfill   *pendingBackfillRequest
+	SyncComplete <-chan struct{} // Closed once the initial sync has settled
+
+	// IncludeSystem stores messages without text or media (group events,
+	// protocol and encryption notices) as system messages instead of dropping them.
+	IncludeSystem bool
+
+	// ProtoDump receives each incoming message's raw protobuf as a JSON line.
+	ProtoDump io.Writer
+
+	// EditWindow is how old a message may be and still be edited. Zero means
+	// DefaultEditWindow; it is configurable in case WhatsApp changes the limit.
+	EditWindow time.Duration
+
+	// IgnoreEphemeral sends without the chat's disappearing message timer.
+	// By default outgoing messages follow the timer last seen for the chat.
+	IgnoreEphemeral bool
+
+	syncState       *syncTracker
+	receipts        receiptWaiters
+	backfillMu      sync.Mutex
+	pendingBackfill *pendingBackfillRequest
+	dumpMu          sync.Mutex
+	statsMu         sync.Mutex
+	stats           SyncStats
+	lock            *sessionLock
+	reconnectMu     sync.Mutex
 }
 
 // New creates a new WhatsApp client.
@@ -71,6 +92,17 @@ func New(db *store.DB, baseDir string, verbose bool, logger *slog.Logger) (*Clie
 		return nil, fmt.Errorf("failed to create store dir: %w", err)
 	}
 
+	lock, err := acquireSessionLock(baseDir, lockWaitTimeout)
+	if err != nil {
+		return nil, err
+	}
+	ok := false
+	defer func() {
+		if !ok {
+			lock.release()
+		}
+	}()
+
 	waDBURI := fmt.Sprintf("file:%s/session.db?_foreign_keys=on", baseDir)
 	container, err := sqlstore.New(context.Background(), "sqlite3", waDBURI, dbLog)
 	if err != nil {
@@ -92,14 +124,17 @@ func New(db *store.DB, baseDir string, verbose bool, logger *slog.Logger) (*Clie
 	}
 
 	c := &Client{
-		WA:           client,
-		Store:        db,
-		Logger:       logger,
-		BaseDir:      baseDir,
-		SyncComplete: make(chan struct{}, 1),
+		WA:      client,
+		Store:   db,
+		Logger:  logger,
+		BaseDir: baseDir,
+		lock:    lock,
 	}
+	c.syncState = newSyncTracker(syncCompletionSettleDelay, client.IsConnected)
+	c.SyncComplete = c.syncState.done
 	c.registerHandlers()
 
+	ok = true
 	return c, nil
 }
 
@@ -126,18 +161,22 @@ func (c *Client) GetDeviceID() (user string, device uint16) {
 	return c.WA.Store.ID.User, c.WA.Store.ID.Device
 }
 
-// Disconnect disconnects from WhatsApp.
+// SetSyncQuietPeriod treats the initial sync as complete once no message has
+// been stored for d, even if WhatsApp never reports the sync as finished.
+// Call it before connecting.
+func (c *Client) SetSyncQuietPeriod(d time.Duration) {
+	c.syncState.setQuietPeriod(d)
+}
+
+// Disconnect disconnects from WhatsApp and releases the session lock.
 func (c *Client) Disconnect() {
-	c.syncCompleteMu.Lock()
-	if c.syncCompleteTimer != nil {
-		c.syncCompleteTimer.Stop()
-		c.syncCompleteTimer = nil
-	}
-	c.syncCompleteMu.Unlock()
+	c.syncState.stop()
 
 	if c.WA != nil && c.WA.IsConnected() {
 		c.WA.Disconnect()
 	}
+
+	c.lock.release()
 }
 
 // Logout logs out and clears the session.
@@ -159,7 +198,7 @@ func (c *Client) Logout() error {
 func (c *Client) getChatName(jid, chatJID string, _ any, sender string) string {
 	// Try to get existing name from DB
 	var existing sql.NullString
-	_ = c.Store.Messages.QueryRow("SELECT name FROM chats WHERE jid = ?", chatJID).Scan(&existing)
+	_ = c.Store.QueryRow("SELECT name FROM chats WHERE jid = ?", chatJID).Scan(&existing)
 	if existing.Valid && existing.String != "" {
 		return existing.String
 	}
@@ -240,27 +279,33 @@ func (c *Client) resolvePreferredName(jid interface{}) string {
 	if err != nil {
 		return ""
 	}
+	name, _ := c.preferredName(parsedJID)
+	return name
+}
 
+// preferredName resolves a human-friendly name for a JID, along with the
+// NameResolution field it came from.
+func (c *Client) preferredName(parsedJID types.JID) (name, source string) {
 	// Groups
 	if parsedJID.Server == "g.us" {
-		if info, err := c.WA.GetGroupInfo(context.Background(), parsedJID); err == nil && info.Name != "" {
-			return info.Name
+		if info, err := c.GetGroupInfo(context.Background(), parsedJID); err == nil && info.Name != "" {
+			return info.Name, "group_name"
 		}
-		return fmt.Sprintf("Group %s", parsedJID.User)
+		return fmt.Sprintf("Group %s", parsedJID.User), "jid"
 	}
 
 	// Contacts
 	if contact, err := c.WA.Store.Contacts.GetContact(context.Background(), parsedJID); err == nil {
 		if contact.FullName != "" {
-			return contact.FullName
+			return contact.FullName, "full_name"
 		}
 		if contact.BusinessName != "" {
-			return contact.BusinessName
+			return contact.BusinessName, "business_name"
 		}
 		if contact.PushName != "" {
-			return contact.PushName
+			return contact.PushName, "push_name"
 		}
 	}
 
-	return parsedJID.User
+	return parsedJID.User, "jid"
 }
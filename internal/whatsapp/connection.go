@@ -2,14 +2,26 @@ package whatsapp
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"time"
 
 	"github.com/mdp/qrterminal/v3"
+	"go.mau.fi/whatsmeow"
 	waHistorySync "go.mau.fi/whatsmeow/proto/waHistorySync"
+	"go.mau.fi/whatsmeow/socket"
 	"go.mau.fi/whatsmeow/types/events"
 )
 
+// ErrNotConnected is returned when a request needs the connection and it's down.
+var ErrNotConnected = errors.New("not connected")
+
+// reconnectTimeout is how long Reconnect waits for the session to log back in.
+const reconnectTimeout = 30 * time.Second
+
+// syncCompletionSettleDelay is the default quiet period after the last message
+// before the initial sync counts as settled.
 const syncCompletionSettleDelay = 5 * time.Second
 
 // registerHandlers registers event handlers for WhatsApp events.
@@ -26,43 +38,51 @@ func (c *Client) registerHandlers() {
 			// Check if sync is complete (progress == 100)
 			if v.Data != nil && v.Data.Progress != nil && *v.Data.Progress >= 100 {
 				c.Logger.Info("history sync complete")
-				c.backfillChatNames()
-				c.signalSyncCompleteAfterSettleDelay()
+				if _, err := c.BackfillChatNames(); err != nil {
+					c.Logger.Warn("backfill: chat names failed", "err", err)
+				}
+				c.syncState.historyComplete()
 			}
 		case *events.OfflineSyncCompleted:
-			c.backfillChatNames()
+			if _, err := c.BackfillChatNames(); err != nil {
+				c.Logger.Warn("backfill: chat names failed", "err", err)
+			}
+			// An empty store means a fresh login, so wait for history sync too
 			count, _ := c.Store.CountMessages()
-			if count > 0 {
-				c.signalSyncCompleteAfterSettleDelay()
+			c.syncState.offlineComplete(count == 0)
+		case *events.Receipt:
+			c.receipts.handle(v)
+			c.recordOwnRead(v)
+		case *events.GroupInfo:
+			if e := v.Ephemeral; e != nil {
+				seconds := e.DisappearingTimer
+				if !e.IsEphemeral {
+					seconds = 0
+				}
+				if err := c.Store.SetChatEphemeral(v.JID.String(), seconds); err != nil {
+					c.Logger.Warn("failed to store disappearing timer", "chat_jid", v.JID.String(), "err", err)
+				}
+			}
+			if name, topic := groupInfoChange(v); name != nil || topic != nil {
+				if err := c.Store.UpdateGroup(v.JID.String(), name, topic); err != nil {
+					c.Logger.Warn("failed to update cached group info", "chat_jid", v.JID.String(), "err", err)
+				}
+			}
+		case *events.Star:
+			if err := c.Store.SetMessageStarred(v.ChatJID.String(), v.MessageID, v.Action.GetStarred()); err != nil {
+				c.Logger.Warn("failed to store starred state", "id", v.MessageID, "chat_jid", v.ChatJID.String(), "err", err)
 			}
 		case *events.Connected:
 			c.Logger.Info("connected to WhatsApp")
+			c.syncState.connected()
+		case *events.Disconnected:
+			c.syncState.disconnected()
 		case *events.LoggedOut:
 			c.Logger.Warn("logged out of WhatsApp")
 		}
 	})
 }
 
-func (c *Client) signalSyncCompleteAfterSettleDelay() {
-	c.syncCompleteMu.Lock()
-	defer c.syncCompleteMu.Unlock()
-
-	if c.syncCompleteTimer != nil {
-		c.syncCompleteTimer.Stop()
-	}
-
-	c.syncCompleteTimer = time.AfterFunc(syncCompletionSettleDelay, func() {
-		if !c.WA.IsConnected() {
-			return
-		}
-
-		select {
-		case c.SyncComplete <- struct{}{}:
-		default:
-		}
-	})
-}
-
 // ConnectWithQR connects to WhatsApp, displaying a QR code if needed.
 func (c *Client) ConnectWithQR(ctx context.Context) error {
 	if c.WA.Store.ID == nil {
@@ -89,3 +109,48 @@ func (c *Client) ConnectWithQR(ctx context.Context) error {
 func (c *Client) Connect() error {
 	return c.WA.Connect()
 }
+
+// Reconnect drops the websocket and connects again, waiting until the session
+// is logged back in. Concurrent callers share one reconnect: a caller that finds
+// the session already back does nothing.
+func (c *Client) Reconnect() error {
+	c.reconnectMu.Lock()
+	defer c.reconnectMu.Unlock()
+	if c.WA.IsConnected() && c.WA.IsLoggedIn() {
+		return nil
+	}
+
+	c.WA.Disconnect()
+	if err := c.WA.Connect(); err != nil {
+		return fmt.Errorf("failed to reconnect: %w", err)
+	}
+	if !c.WA.WaitForConnection(reconnectTimeout) {
+		return fmt.Errorf("failed to reconnect: not logged in after %s", reconnectTimeout)
+	}
+	return nil
+}
+
+// IsDisconnectError reports whether err came from the connection being down or
+// dropping mid-request, rather than from WhatsApp rejecting the request.
+func IsDisconnectError(err error) bool {
+	var disconnected *whatsmeow.DisconnectedError
+	return errors.Is(err, ErrNotConnected) ||
+		errors.Is(err, whatsmeow.ErrNotConnected) ||
+		errors.Is(err, socket.ErrSocketClosed) ||
+		errors.As(err, &disconnected)
+}
+
+// RetryOnDisconnect runs send and, if it fails because the connection dropped,
+// reconnects once and runs it again. It reports whether it reconnected.
+func (c *Client) RetryOnDisconnect(send func() error) (reconnected bool, err error) {
+	err = send()
+	if err == nil || !IsDisconnectError(err) {
+		return false, err
+	}
+
+	c.Logger.Warn("send failed on a dropped connection, reconnecting", "err", err)
+	if rerr := c.Reconnect(); rerr != nil {
+		return false, fmt.Errorf("%w (%w)", err, rerr)
+	}
+	return true, send()
+}
@@ -0,0 +1,56 @@
+package whatsapp
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/socket"
+)
+
+func TestIsDisconnectError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"not connected", ErrNotConnected, true},
+		{"wrapped not connected", fmt.Errorf("send failed: %w", ErrNotConnected), true},
+		{"websocket not connected", whatsmeow.ErrNotConnected, true},
+		{"socket closed", socket.ErrSocketClosed, true},
+		{"dropped mid-request", &whatsmeow.DisconnectedError{Action: "message send"}, true},
+		{"rejected", whatsmeow.ErrServerReturnedError, false},
+		{"other", errors.New("invalid recipient"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsDisconnectError(tt.err); got != tt.want {
+				t.Fatalf("IsDisconnectError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryOnDisconnectOnlyRetriesDisconnects(t *testing.T) {
+	c := &Client{}
+	rejected := errors.New("invalid recipient")
+
+	calls := 0
+	reconnected, err := c.RetryOnDisconnect(func() error {
+		calls++
+		return rejected
+	})
+	if !errors.Is(err, rejected) || reconnected || calls != 1 {
+		t.Fatalf("expected one failed call without reconnecting, got calls=%d reconnected=%v err=%v", calls, reconnected, err)
+	}
+
+	calls = 0
+	reconnected, err = c.RetryOnDisconnect(func() error {
+		calls++
+		return nil
+	})
+	if err != nil || reconnected || calls != 1 {
+		t.Fatalf("expected one successful call, got calls=%d reconnected=%v err=%v", calls, reconnected, err)
+	}
+}
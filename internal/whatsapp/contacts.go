@@ -0,0 +1,79 @@
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/eddmann/whatsapp-cli/internal/store"
+)
+
+const (
+	// registrationBatchSize is how many numbers are sent per IsOnWhatsApp query.
+	registrationBatchSize = 50
+
+	// registrationCacheTTL is how long a registration check is trusted.
+	registrationCacheTTL = 7 * 24 * time.Hour
+)
+
+// CheckRegistered reports which phone numbers are on WhatsApp, keyed by phone.
+// Cached results are reused; the rest are queried in batches and cached.
+func (c *Client) CheckRegistered(phones []string) (map[string]store.Registration, error) {
+	cached, err := c.Store.GetRegistrations(time.Now().Add(-registrationCacheTTL))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read registration cache: %w", err)
+	}
+
+	result := make(map[string]store.Registration, len(phones))
+	var pending []string
+	for _, phone := range phones {
+		if r, ok := cached[phone]; ok {
+			result[phone] = r
+		} else {
+			pending = append(pending, phone)
+		}
+	}
+
+	for start := 0; start < len(pending); start += registrationBatchSize {
+		batch := pending[start:min(start+registrationBatchSize, len(pending))]
+
+		query := make([]string, len(batch))
+		for i, phone := range batch {
+			query[i] = "+" + phone
+		}
+
+		resp, err := c.WA.IsOnWhatsApp(context.Background(), query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check registration: %w", err)
+		}
+
+		found := make(map[string]store.Registration, len(resp))
+		for _, r := range resp {
+			// Key by the number we asked about; the canonical JID can differ from it
+			phone := strings.TrimPrefix(r.Query, "+")
+			if phone == "" {
+				phone = r.JID.User
+			}
+			reg := store.Registration{Phone: phone, Registered: r.IsIn}
+			if r.IsIn {
+				reg.JID = r.JID.ToNonAD().String()
+			}
+			found[phone] = reg
+		}
+
+		// Numbers missing from the response are treated as unregistered
+		for _, phone := range batch {
+			reg, ok := found[phone]
+			if !ok {
+				reg = store.Registration{Phone: phone}
+			}
+			if err := c.Store.SaveRegistration(reg); err != nil {
+				return nil, fmt.Errorf("failed to cache registration: %w", err)
+			}
+			result[phone] = reg
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,50 @@
+package whatsapp
+
+import (
+	"encoding/json"
+	"time"
+
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// protoDumpEntry is one line of a --dump-proto file.
+type protoDumpEntry struct {
+	ID        string          `json:"id"`
+	ChatJID   string          `json:"chat_jid"`
+	Sender    string          `json:"sender"`
+	Timestamp time.Time       `json:"timestamp"`
+	Message   json.RawMessage `json:"message"`
+}
+
+// dumpProto writes msg's raw protobuf as a JSON line to ProtoDump, if set.
+// Failures are logged rather than interrupting the sync.
+func (c *Client) dumpProto(msg *events.Message) {
+	if c.ProtoDump == nil {
+		return
+	}
+
+	raw, err := protojson.Marshal(msg.Message)
+	if err != nil {
+		c.Logger.Warn("failed to marshal message proto", "id", msg.Info.ID, "err", err)
+		return
+	}
+
+	line, err := json.Marshal(protoDumpEntry{
+		ID:        msg.Info.ID,
+		ChatJID:   msg.Info.Chat.String(),
+		Sender:    msg.Info.Sender.String(),
+		Timestamp: msg.Info.Timestamp,
+		Message:   raw,
+	})
+	if err != nil {
+		c.Logger.Warn("failed to encode message proto", "id", msg.Info.ID, "err", err)
+		return
+	}
+
+	c.dumpMu.Lock()
+	defer c.dumpMu.Unlock()
+	if _, err := c.ProtoDump.Write(append(line, '\n')); err != nil {
+		c.Logger.Warn("failed to write message proto", "id", msg.Info.ID, "err", err)
+	}
+}
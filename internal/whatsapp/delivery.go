@@ -0,0 +1,151 @@
+package whatsapp
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+
+	"github.com/eddmann/whatsapp-cli/internal/store"
+)
+
+// minDeliveryRetrySpacing is the shortest gap between resends, however many
+// attempts fit in the deadline, so a retrying alert can't flood the recipient.
+const minDeliveryRetrySpacing = 30 * time.Second
+
+// DeliveryRetry controls SendTextUntilDelivered.
+type DeliveryRetry struct {
+	Deadline    time.Duration // Stop waiting for a delivery receipt after this long
+	MaxAttempts int           // Most messages to send, including the first
+}
+
+// interval is the wait between attempts: the deadline spread evenly over the
+// attempts, but never less than minDeliveryRetrySpacing.
+func (r DeliveryRetry) interval() time.Duration {
+	if r.MaxAttempts <= 1 {
+		return r.Deadline
+	}
+	return max(r.Deadline/time.Duration(r.MaxAttempts), minDeliveryRetrySpacing)
+}
+
+// deliveryReceipt is a receipt for one of the messages being waited on.
+type deliveryReceipt struct {
+	MessageID string
+	At        time.Time
+}
+
+// receiptWaiters routes delivery receipts to senders waiting on them.
+type receiptWaiters struct {
+	mu      sync.Mutex
+	waiters map[types.MessageID]chan<- deliveryReceipt
+}
+
+// watch delivers the first receipt for id to ch. ch should be buffered; a
+// receipt is dropped rather than blocking the event handler.
+func (w *receiptWaiters) watch(id types.MessageID, ch chan<- deliveryReceipt) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.waiters == nil {
+		w.waiters = make(map[types.MessageID]chan<- deliveryReceipt)
+	}
+	w.waiters[id] = ch
+}
+
+func (w *receiptWaiters) unwatch(ids ...types.MessageID) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, id := range ids {
+		delete(w.waiters, id)
+	}
+}
+
+// handle passes on receipts showing a message reached the recipient's device.
+// Read and played receipts imply delivery; receipts from our own devices don't.
+func (w *receiptWaiters) handle(evt *events.Receipt) {
+	if evt.IsFromMe {
+		return
+	}
+	switch evt.Type {
+	case types.ReceiptTypeDelivered, types.ReceiptTypeRead, types.ReceiptTypePlayed:
+	default:
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, id := range evt.MessageIDs {
+		if ch, ok := w.waiters[id]; ok {
+			select {
+			case ch <- deliveryReceipt{MessageID: id, At: evt.Timestamp}:
+			default:
+			}
+		}
+	}
+}
+
+// SendTextUntilDelivered sends a text message and waits for a delivery receipt,
+// sending it again each retry interval until one arrives, retry.MaxAttempts
+// messages have been sent, or retry.Deadline passes. A receipt for any attempt
+// counts. Not being delivered is reported in the result, not as an error.
+func (c *Client) SendTextUntilDelivered(recipient, text string, opts SendOptions, retry DeliveryRetry) (*store.DeliveryResult, error) {
+	if retry.Deadline <= 0 {
+		return nil, fmt.Errorf("delivery deadline must be positive")
+	}
+	if retry.MaxAttempts < 1 {
+		retry.MaxAttempts = 1
+	}
+
+	receipts := make(chan deliveryReceipt, 1)
+	var ids []types.MessageID
+	defer func() { c.receipts.unwatch(ids...) }()
+
+	result := &store.DeliveryResult{}
+	deadline := time.Now().Add(retry.Deadline)
+	for {
+		id := c.WA.GenerateMessageID()
+		c.receipts.watch(id, receipts)
+		ids = append(ids, id)
+
+		opts.MessageID = id
+		sent, err := c.SendText(recipient, text, opts)
+		if err != nil {
+			if result.Attempts == 0 {
+				return nil, err
+			}
+			c.Logger.Warn("resend failed", "attempt", len(ids), "err", err)
+		} else {
+			if result.Attempts == 0 {
+				result.ChatJID = sent.ChatJID
+				result.Timestamp = sent.Timestamp
+			}
+			result.Attempts++
+			result.MessageIDs = append(result.MessageIDs, sent.MessageID)
+		}
+
+		wait := time.Until(deadline)
+		if len(ids) < retry.MaxAttempts {
+			wait = min(wait, retry.interval())
+		}
+
+		timer := time.NewTimer(max(wait, 0))
+		select {
+		case r := <-receipts:
+			timer.Stop()
+			at := r.At
+			result.Delivered = true
+			result.MessageID = r.MessageID
+			result.DeliveredAt = &at
+			return result, nil
+		case <-timer.C:
+		}
+
+		if !time.Now().Before(deadline) {
+			if n := len(result.MessageIDs); n > 0 {
+				result.MessageID = result.MessageIDs[n-1]
+			}
+			return result, nil
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package whatsapp
+
+import (
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+func TestReceiptWaitersHandle(t *testing.T) {
+	var w receiptWaiters
+	ch := make(chan deliveryReceipt, 1)
+	w.watch("A1", ch)
+
+	// Receipts from our own devices and unrelated types don't count
+	w.handle(&events.Receipt{MessageSource: types.MessageSource{IsFromMe: true}, MessageIDs: []types.MessageID{"A1"}})
+	w.handle(&events.Receipt{MessageIDs: []types.MessageID{"A1"}, Type: types.ReceiptTypeRetry})
+	w.handle(&events.Receipt{MessageIDs: []types.MessageID{"B2"}})
+	select {
+	case r := <-ch:
+		t.Fatalf("unexpected receipt %+v", r)
+	default:
+	}
+
+	at := time.Now()
+	w.handle(&events.Receipt{MessageIDs: []types.MessageID{"B2", "A1"}, Timestamp: at, Type: types.ReceiptTypeRead})
+	select {
+	case r := <-ch:
+		if r.MessageID != "A1" || !r.At.Equal(at) {
+			t.Fatalf("got %+v", r)
+		}
+	default:
+		t.Fatal("expected a receipt for A1")
+	}
+
+	w.unwatch("A1")
+	w.handle(&events.Receipt{MessageIDs: []types.MessageID{"A1"}})
+	if len(ch) != 0 {
+		t.Fatal("expected no receipt after unwatch")
+	}
+}
+
+func TestDeliveryRetryInterval(t *testing.T) {
+	tests := []struct {
+		retry DeliveryRetry
+		want  time.Duration
+	}{
+		{DeliveryRetry{Deadline: 5 * time.Minute, MaxAttempts: 3}, 100 * time.Second},
+		{DeliveryRetry{Deadline: time.Minute, MaxAttempts: 10}, minDeliveryRetrySpacing},
+		{DeliveryRetry{Deadline: time.Minute, MaxAttempts: 1}, time.Minute},
+	}
+	for _, tt := range tests {
+		if got := tt.retry.interval(); got != tt.want {
+			t.Errorf("%+v: interval = %s, want %s", tt.retry, got, tt.want)
+		}
+	}
+}
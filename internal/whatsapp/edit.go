@@ -0,0 +1,53 @@
+package whatsapp
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultEditWindow is how long after sending WhatsApp accepts edits.
+const DefaultEditWindow = 15 * time.Minute
+
+// ErrEditWindowExpired is returned when a message is too old to edit. The
+// server only answers such edits with a generic rejection, so it is checked
+// locally first.
+var ErrEditWindowExpired = errors.New("edit_window_expired")
+
+// checkEditWindow returns ErrEditWindowExpired, with the message's age, if a
+// message sent at sentAt can no longer be edited at now. A window of zero or
+// less uses DefaultEditWindow.
+func checkEditWindow(sentAt, now time.Time, window time.Duration) error {
+	if window <= 0 {
+		window = DefaultEditWindow
+	}
+	if age := now.Sub(sentAt); age > window {
+		return fmt.Errorf("%w: message was sent %s ago, WhatsApp only allows edits for %s",
+			ErrEditWindowExpired, age.Round(time.Second), window)
+	}
+	return nil
+}
+
+// editableMessageChat returns which of chatJIDs a stored message is in, after
+// checking that we sent it, that it is a text message and that it is still
+// within the edit window at now.
+func (c *Client) editableMessageChat(messageID string, now time.Time, chatJIDs ...string) (string, error) {
+	chatJID, err := c.ownMessageChat(messageID, chatJIDs...)
+	if err != nil {
+		return "", err
+	}
+
+	var mediaType string
+	var sentAt time.Time
+	row := c.Store.QueryRow(`SELECT COALESCE(media_type, ''), timestamp FROM messages WHERE id = ? AND chat_jid = ?`, messageID, chatJID)
+	if err := row.Scan(&mediaType, &sentAt); err != nil {
+		return "", err
+	}
+	if mediaType != "" {
+		return "", fmt.Errorf("message %s is a %s message; only text messages can be edited", messageID, mediaType)
+	}
+	if err := checkEditWindow(sentAt, now, c.EditWindow); err != nil {
+		return "", err
+	}
+	return chatJID, nil
+}
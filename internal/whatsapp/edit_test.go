@@ -0,0 +1,69 @@
+package whatsapp
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eddmann/whatsapp-cli/internal/store"
+)
+
+func TestCheckEditWindow(t *testing.T) {
+	now := time.Date(2026, 4, 24, 12, 0, 0, 0, time.UTC)
+
+	if err := checkEditWindow(now.Add(-time.Minute), now, 0); err != nil {
+		t.Fatalf("expected a recent message to be editable, got %v", err)
+	}
+
+	err := checkEditWindow(now.Add(-time.Hour), now, 0)
+	if !errors.Is(err, ErrEditWindowExpired) {
+		t.Fatalf("expected ErrEditWindowExpired, got %v", err)
+	}
+	if want := "edit_window_expired: message was sent 1h0m0s ago, WhatsApp only allows edits for 15m0s"; err.Error() != want {
+		t.Fatalf("unexpected error %q", err)
+	}
+
+	if err := checkEditWindow(now.Add(-time.Hour), now, 2*time.Hour); err != nil {
+		t.Fatalf("expected a configured window to be used, got %v", err)
+	}
+}
+
+func TestEditableMessageChat(t *testing.T) {
+	db, err := store.Open(filepath.Join(t.TempDir(), "messages.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.CloseQuietly()
+
+	chat := "12345@s.whatsapp.net"
+	sent := time.Date(2026, 4, 24, 12, 0, 0, 0, time.UTC)
+	if _, err := db.Exec(`INSERT INTO chats (jid, name) VALUES (?, 'Alice')`, chat); err != nil {
+		t.Fatalf("insert chat: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO messages (id, chat_jid, sender, content, timestamp, is_from_me, media_type) VALUES
+		('text', ?, 'me', 'helo', ?, 1, NULL),
+		('photo', ?, 'me', '', ?, 1, 'image')`, chat, sent, chat, sent); err != nil {
+		t.Fatalf("insert messages: %v", err)
+	}
+
+	c := &Client{Store: db}
+	if got, err := c.editableMessageChat("text", sent.Add(time.Minute), chat); err != nil || got != chat {
+		t.Fatalf("expected the text message to be editable, got %q, %v", got, err)
+	}
+	if _, err := c.editableMessageChat("photo", sent.Add(time.Minute), chat); err == nil || !strings.Contains(err.Error(), "only text messages") {
+		t.Fatalf("expected media to be rejected, got %v", err)
+	}
+	if _, err := c.editableMessageChat("text", sent.Add(time.Hour), chat); !errors.Is(err, ErrEditWindowExpired) {
+		t.Fatalf("expected ErrEditWindowExpired, got %v", err)
+	}
+
+	if err := db.UpdateMessageContent(chat, "text", "hello"); err != nil {
+		t.Fatalf("update content: %v", err)
+	}
+	found, err := db.SearchMessages(store.SearchMessagesOptions{Query: "hello"})
+	if err != nil || len(found) != 1 || found[0].ID != "text" {
+		t.Fatalf("expected the search index to have the edited text, got %+v, %v", found, err)
+	}
+}
@@ -0,0 +1,269 @@
+package whatsapp
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// reactionShortcodes maps names accepted by 'whatsapp react' to emoji. The first
+// six are WhatsApp's default reactions.
+var reactionShortcodes = map[string]string{
+	"thumbsup":   "\U0001F44D",
+	"+1":         "\U0001F44D",
+	"heart":      "\u2764\ufe0f",
+	"joy":        "\U0001F602",
+	"laugh":      "\U0001F602",
+	"wow":        "\U0001F62E",
+	"open_mouth": "\U0001F62E",
+	"cry":        "\U0001F622",
+	"sad":        "\U0001F622",
+	"pray":       "\U0001F64F",
+	"thanks":     "\U0001F64F",
+	"thumbsdown": "\U0001F44E",
+	"-1":         "\U0001F44E",
+	"fire":       "\U0001F525",
+	"tada":       "\U0001F389",
+	"clap":       "\U0001F44F",
+	"eyes":       "\U0001F440",
+	"100":        "\U0001F4AF",
+	"ok_hand":    "\U0001F44C",
+	"smile":      "\U0001F604",
+	"check":      "\u2705",
+}
+
+const (
+	zeroWidthJoiner  = '\u200d'
+	combiningKeycap  = '\u20e3'
+	cancelTag        = '\U000E007F'
+	regionalIndStart = '\U0001F1E6'
+	regionalIndEnd   = '\U0001F1FF'
+)
+
+// ValidateReaction resolves a reaction to the exact text to send. It accepts a
+// single emoji, including ZWJ sequences, flags and skin tones, or a shortcode
+// such as "thumbsup" or ":heart:". Words and multiple emoji are rejected.
+func ValidateReaction(reaction string) (string, error) {
+	trimmed := strings.TrimSpace(reaction)
+	if code := strings.ToLower(strings.Trim(trimmed, ":")); reactionShortcodes[code] != "" {
+		return reactionShortcodes[code], nil
+	}
+
+	text := normalizeEmoji(trimmed)
+	if text == "" {
+		return "", fmt.Errorf("reaction is empty (use --remove to remove a reaction)")
+	}
+
+	n, ok := countEmojiClusters(text)
+	switch {
+	case !ok:
+		return "", fmt.Errorf("reaction %q is not an emoji or a known shortcode", reaction)
+	case n > 1:
+		return "", fmt.Errorf("reaction %q has %d emoji, only one is allowed", reaction, n)
+	}
+	return text, nil
+}
+
+// countEmojiClusters counts the emoji grapheme clusters in s, treating ZWJ
+// sequences, flags, keycaps and modifier sequences as one. ok is false if s
+// contains anything that isn't part of an emoji.
+func countEmojiClusters(s string) (n int, ok bool) {
+	runes := []rune(s)
+	for i := 0; i < len(runes); {
+		next, valid := emojiClusterEnd(runes, i)
+		if !valid {
+			return n, false
+		}
+		n++
+		i = next
+	}
+	return n, true
+}
+
+// emojiClusterEnd returns the index just past the emoji cluster starting at i.
+func emojiClusterEnd(runes []rune, i int) (int, bool) {
+	r := runes[i]
+
+	// Flags are pairs of regional indicators
+	if isRegionalIndicator(r) {
+		if i+1 < len(runes) && isRegionalIndicator(runes[i+1]) {
+			return i + 2, true
+		}
+		return 0, false
+	}
+
+	// Keycaps: digit, # or *, optional VS16, then U+20E3
+	if (r >= '0' && r <= '9') || r == '#' || r == '*' {
+		j := i + 1
+		if j < len(runes) && runes[j] == emojiPresentationSelector {
+			j++
+		}
+		if j < len(runes) && runes[j] == combiningKeycap {
+			return j + 1, true
+		}
+		return 0, false
+	}
+
+	if !isEmojiBase(r) {
+		return 0, false
+	}
+
+	j := skipEmojiModifiers(runes, i+1)
+	for j+1 < len(runes) && runes[j] == zeroWidthJoiner && isEmojiBase(runes[j+1]) {
+		j = skipEmojiModifiers(runes, j+2)
+	}
+	return j, true
+}
+
+// skipEmojiModifiers skips variation selectors, skin tones and tag sequences.
+func skipEmojiModifiers(runes []rune, j int) int {
+	for j < len(runes) {
+		r := runes[j]
+		switch {
+		case r == emojiPresentationSelector, r >= 0x1F3FB && r <= 0x1F3FF:
+			j++
+		case r >= 0xE0020 && r <= cancelTag:
+			j++
+		default:
+			return j
+		}
+	}
+	return j
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= regionalIndStart && r <= regionalIndEnd
+}
+
+// isEmojiBase reports whether r can start an emoji. Above U+1F000 the check is
+// by block, which is enough to tell emoji apart from words and punctuation;
+// below it, where emoji share blocks with plain symbols, it uses the Unicode
+// Emoji property.
+func isEmojiBase(r rune) bool {
+	if r >= 0x1F000 && r <= 0x1FAFF {
+		return !isRegionalIndicator(r) && !(r >= 0x1F3FB && r <= 0x1F3FF)
+	}
+	return unicode.In(r, textDefaultEmoji, emojiPresentation)
+}
+
+// textDefaultEmoji is the Basic Multilingual Plane characters with the Unicode
+// Emoji property that default to text presentation, so they need U+FE0F to
+// show as emoji. From emoji-data.txt: Emoji=Yes minus Emoji_Presentation=Yes,
+// leaving out the keycap bases (digits, # and *).
+var textDefaultEmoji = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{Lo: 0x00A9, Hi: 0x00A9, Stride: 1},
+		{Lo: 0x00AE, Hi: 0x00AE, Stride: 1},
+		{Lo: 0x203C, Hi: 0x203C, Stride: 1},
+		{Lo: 0x2049, Hi: 0x2049, Stride: 1},
+		{Lo: 0x2122, Hi: 0x2122, Stride: 1},
+		{Lo: 0x2139, Hi: 0x2139, Stride: 1},
+		{Lo: 0x2194, Hi: 0x2199, Stride: 1},
+		{Lo: 0x21A9, Hi: 0x21AA, Stride: 1},
+		{Lo: 0x2328, Hi: 0x2328, Stride: 1},
+		{Lo: 0x23CF, Hi: 0x23CF, Stride: 1},
+		{Lo: 0x23ED, Hi: 0x23EF, Stride: 1},
+		{Lo: 0x23F1, Hi: 0x23F2, Stride: 1},
+		{Lo: 0x23F8, Hi: 0x23FA, Stride: 1},
+		{Lo: 0x24C2, Hi: 0x24C2, Stride: 1},
+		{Lo: 0x25AA, Hi: 0x25AB, Stride: 1},
+		{Lo: 0x25B6, Hi: 0x25B6, Stride: 1},
+		{Lo: 0x25C0, Hi: 0x25C0, Stride: 1},
+		{Lo: 0x25FB, Hi: 0x25FC, Stride: 1},
+		{Lo: 0x2600, Hi: 0x2604, Stride: 1},
+		{Lo: 0x260E, Hi: 0x260E, Stride: 1},
+		{Lo: 0x2611, Hi: 0x2611, Stride: 1},
+		{Lo: 0x2618, Hi: 0x2618, Stride: 1},
+		{Lo: 0x261D, Hi: 0x261D, Stride: 1},
+		{Lo: 0x2620, Hi: 0x2620, Stride: 1},
+		{Lo: 0x2622, Hi: 0x2623, Stride: 1},
+		{Lo: 0x2626, Hi: 0x2626, Stride: 1},
+		{Lo: 0x262A, Hi: 0x262A, Stride: 1},
+		{Lo: 0x262E, Hi: 0x262F, Stride: 1},
+		{Lo: 0x2638, Hi: 0x263A, Stride: 1},
+		{Lo: 0x2640, Hi: 0x2640, Stride: 1},
+		{Lo: 0x2642, Hi: 0x2642, Stride: 1},
+		{Lo: 0x265F, Hi: 0x2660, Stride: 1},
+		{Lo: 0x2663, Hi: 0x2663, Stride: 1},
+		{Lo: 0x2665, Hi: 0x2666, Stride: 1},
+		{Lo: 0x2668, Hi: 0x2668, Stride: 1},
+		{Lo: 0x267B, Hi: 0x267B, Stride: 1},
+		{Lo: 0x267E, Hi: 0x267E, Stride: 1},
+		{Lo: 0x2692, Hi: 0x2692, Stride: 1},
+		{Lo: 0x2694, Hi: 0x2697, Stride: 1},
+		{Lo: 0x2699, Hi: 0x2699, Stride: 1},
+		{Lo: 0x269B, Hi: 0x269C, Stride: 1},
+		{Lo: 0x26A0, Hi: 0x26A0, Stride: 1},
+		{Lo: 0x26A7, Hi: 0x26A7, Stride: 1},
+		{Lo: 0x26B0, Hi: 0x26B1, Stride: 1},
+		{Lo: 0x26C8, Hi: 0x26C8, Stride: 1},
+		{Lo: 0x26CF, Hi: 0x26CF, Stride: 1},
+		{Lo: 0x26D1, Hi: 0x26D1, Stride: 1},
+		{Lo: 0x26D3, Hi: 0x26D3, Stride: 1},
+		{Lo: 0x26E9, Hi: 0x26E9, Stride: 1},
+		{Lo: 0x26F0, Hi: 0x26F1, Stride: 1},
+		{Lo: 0x26F4, Hi: 0x26F4, Stride: 1},
+		{Lo: 0x26F7, Hi: 0x26F9, Stride: 1},
+		{Lo: 0x2702, Hi: 0x2702, Stride: 1},
+		{Lo: 0x2708, Hi: 0x2709, Stride: 1},
+		{Lo: 0x270C, Hi: 0x270D, Stride: 1},
+		{Lo: 0x270F, Hi: 0x270F, Stride: 1},
+		{Lo: 0x2712, Hi: 0x2712, Stride: 1},
+		{Lo: 0x2714, Hi: 0x2714, Stride: 1},
+		{Lo: 0x2716, Hi: 0x2716, Stride: 1},
+		{Lo: 0x271D, Hi: 0x271D, Stride: 1},
+		{Lo: 0x2721, Hi: 0x2721, Stride: 1},
+		{Lo: 0x2733, Hi: 0x2734, Stride: 1},
+		{Lo: 0x2744, Hi: 0x2744, Stride: 1},
+		{Lo: 0x2747, Hi: 0x2747, Stride: 1},
+		{Lo: 0x2763, Hi: 0x2764, Stride: 1},
+		{Lo: 0x27A1, Hi: 0x27A1, Stride: 1},
+		{Lo: 0x2934, Hi: 0x2935, Stride: 1},
+		{Lo: 0x2B05, Hi: 0x2B07, Stride: 1},
+		{Lo: 0x3030, Hi: 0x3030, Stride: 1},
+		{Lo: 0x303D, Hi: 0x303D, Stride: 1},
+		{Lo: 0x3297, Hi: 0x3297, Stride: 1},
+		{Lo: 0x3299, Hi: 0x3299, Stride: 1},
+	},
+	LatinOffset: 2,
+}
+
+// emojiPresentation is the Basic Multilingual Plane characters that default to
+// emoji presentation.
+var emojiPresentation = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{Lo: 0x231A, Hi: 0x231B, Stride: 1},
+		{Lo: 0x23E9, Hi: 0x23EC, Stride: 1},
+		{Lo: 0x23F0, Hi: 0x23F0, Stride: 1},
+		{Lo: 0x23F3, Hi: 0x23F3, Stride: 1},
+		{Lo: 0x25FD, Hi: 0x25FE, Stride: 1},
+		{Lo: 0x2614, Hi: 0x2615, Stride: 1},
+		{Lo: 0x2648, Hi: 0x2653, Stride: 1},
+		{Lo: 0x267F, Hi: 0x267F, Stride: 1},
+		{Lo: 0x2693, Hi: 0x2693, Stride: 1},
+		{Lo: 0x26A1, Hi: 0x26A1, Stride: 1},
+		{Lo: 0x26AA, Hi: 0x26AB, Stride: 1},
+		{Lo: 0x26BD, Hi: 0x26BE, Stride: 1},
+		{Lo: 0x26C4, Hi: 0x26C5, Stride: 1},
+		{Lo: 0x26CE, Hi: 0x26CE, Stride: 1},
+		{Lo: 0x26D4, Hi: 0x26D4, Stride: 1},
+		{Lo: 0x26EA, Hi: 0x26EA, Stride: 1},
+		{Lo: 0x26F2, Hi: 0x26F3, Stride: 1},
+		{Lo: 0x26F5, Hi: 0x26F5, Stride: 1},
+		{Lo: 0x26FA, Hi: 0x26FA, Stride: 1},
+		{Lo: 0x26FD, Hi: 0x26FD, Stride: 1},
+		{Lo: 0x2705, Hi: 0x2705, Stride: 1},
+		{Lo: 0x270A, Hi: 0x270B, Stride: 1},
+		{Lo: 0x2728, Hi: 0x2728, Stride: 1},
+		{Lo: 0x274C, Hi: 0x274C, Stride: 1},
+		{Lo: 0x274E, Hi: 0x274E, Stride: 1},
+		{Lo: 0x2753, Hi: 0x2755, Stride: 1},
+		{Lo: 0x2757, Hi: 0x2757, Stride: 1},
+		{Lo: 0x2795, Hi: 0x2797, Stride: 1},
+		{Lo: 0x27B0, Hi: 0x27B0, Stride: 1},
+		{Lo: 0x27BF, Hi: 0x27BF, Stride: 1},
+		{Lo: 0x2B1B, Hi: 0x2B1C, Stride: 1},
+		{Lo: 0x2B50, Hi: 0x2B50, Stride: 1},
+		{Lo: 0x2B55, Hi: 0x2B55, Stride: 1},
+	},
+}
@@ -0,0 +1,66 @@
+package whatsapp
+
+import "testing"
+
+func TestNormalizeEmoji(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"adds presentation selector to text-default heart", "❤", "❤️"},
+		{"keeps heart that already has selector", "❤️", "❤️"},
+		{"keeps skin tone modifier", "👍🏽", "👍🏽"},
+		{"keeps ZWJ sequence", "👨‍👩‍👧", "👨‍👩‍👧"},
+		{"composes combining marks", "e\u0301", "\u00e9"},
+		{"trims whitespace", " 👍 ", "👍"},
+		{"adds presentation selector to text-default copyright", "\u00a9", "\u00a9\ufe0f"},
+		{"leaves emoji-default watch alone", "\u231a", "\u231a"},
+		{"leaves non-emoji arrow alone", "\u2192", "\u2192"},
+		{"leaves non-emoji punctuation alone", "\u2026", "\u2026"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeEmoji(tt.in); got != tt.want {
+				t.Fatalf("normalizeEmoji(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateReaction(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"thumbs up", "\U0001F44D", "\U0001F44D", false},
+		{"ZWJ family", "\U0001F468\u200d\U0001F469\u200d\U0001F467", "\U0001F468\u200d\U0001F469\u200d\U0001F467", false},
+		{"skin tone", "\U0001F44D\U0001F3FD", "\U0001F44D\U0001F3FD", false},
+		{"flag", "\U0001F1EC\U0001F1E7", "\U0001F1EC\U0001F1E7", false},
+		{"keycap", "1\ufe0f\u20e3", "1\ufe0f\u20e3", false},
+		{"text-default heart", "\u2764", "\u2764\ufe0f", false},
+		{"shortcode", "thumbsup", "\U0001F44D", false},
+		{"shortcode with colons", ":Heart:", "\u2764\ufe0f", false},
+		{"emoji arrow", "\u2194", "\u2194\ufe0f", false},
+		{"plain arrow", "\u2192", "", true},
+		{"word", "ok", "", true},
+		{"two emoji", "\U0001F44D\U0001F44D", "", true},
+		{"emoji and text", "\U0001F44D yes", "", true},
+		{"empty", " ", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ValidateReaction(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateReaction(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("ValidateReaction(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
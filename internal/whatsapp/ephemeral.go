@@ -0,0 +1,57 @@
+package whatsapp
+
+import (
+	waE2E "go.mau.fi/whatsmeow/proto/waE2E"
+)
+
+// observedEphemeral returns the disappearing message timer, in seconds, that
+// a message reveals for its chat: either a change of the setting (0 when it is
+// turned off) or the expiration the message was sent with.
+func observedEphemeral(m *waE2E.Message) (seconds uint32, ok bool) {
+	if p := m.GetProtocolMessage(); p != nil && p.GetType() == waE2E.ProtocolMessage_EPHEMERAL_SETTING {
+		return p.GetEphemeralExpiration(), true
+	}
+	if exp := extractContextInfo(m).GetExpiration(); exp > 0 {
+		return exp, true
+	}
+	return 0, false
+}
+
+// recordEphemeral stores the chat's disappearing message timer if m reveals it.
+func (c *Client) recordEphemeral(chatJID string, m *waE2E.Message) {
+	seconds, ok := observedEphemeral(m)
+	if !ok {
+		return
+	}
+	if err := c.Store.SetChatEphemeral(chatJID, seconds); err != nil {
+		c.Logger.Warn("failed to store disappearing timer", "chat_jid", chatJID, "err", err)
+	}
+}
+
+// ephemeralExpiration returns the disappearing message timer to send with to
+// the first of chatJIDs that has one stored, or 0 when there is none or
+// IgnoreEphemeral is set.
+func (c *Client) ephemeralExpiration(chatJIDs ...string) uint32 {
+	if c.IgnoreEphemeral || c.Store == nil {
+		return 0
+	}
+	for _, jid := range chatJIDs {
+		if seconds, err := c.Store.GetChatEphemeral(jid); err == nil && seconds > 0 {
+			return seconds
+		}
+	}
+	return 0
+}
+
+// withExpiration sets a disappearing message timer on ctx, creating it if
+// needed. It returns ctx unchanged when seconds is 0.
+func withExpiration(ctx *waE2E.ContextInfo, seconds uint32) *waE2E.ContextInfo {
+	if seconds == 0 {
+		return ctx
+	}
+	if ctx == nil {
+		ctx = &waE2E.ContextInfo{}
+	}
+	ctx.Expiration = protoUint32(seconds)
+	return ctx
+}
@@ -0,0 +1,69 @@
+package whatsapp
+
+import (
+	"path/filepath"
+	"testing"
+
+	waE2E "go.mau.fi/whatsmeow/proto/waE2E"
+
+	"github.com/eddmann/whatsapp-cli/internal/store"
+)
+
+func TestObservedEphemeral(t *testing.T) {
+	setting := waE2E.ProtocolMessage_EPHEMERAL_SETTING
+	tests := []struct {
+		name    string
+		msg     *waE2E.Message
+		seconds uint32
+		ok      bool
+	}{
+		{"plain text", &waE2E.Message{Conversation: protoString("hi")}, 0, false},
+		{"sent with a timer", &waE2E.Message{ExtendedTextMessage: &waE2E.ExtendedTextMessage{
+			Text: protoString("hi"), ContextInfo: &waE2E.ContextInfo{Expiration: protoUint32(86400)},
+		}}, 86400, true},
+		{"timer turned off", &waE2E.Message{ProtocolMessage: &waE2E.ProtocolMessage{
+			Type: &setting, EphemeralExpiration: protoUint32(0),
+		}}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seconds, ok := observedEphemeral(tt.msg)
+			if seconds != tt.seconds || ok != tt.ok {
+				t.Fatalf("got (%d, %v), want (%d, %v)", seconds, ok, tt.seconds, tt.ok)
+			}
+		})
+	}
+}
+
+func TestEphemeralExpirationUsesStoredTimer(t *testing.T) {
+	db, err := store.Open(filepath.Join(t.TempDir(), "messages.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.CloseQuietly()
+
+	chat := "12345@s.whatsapp.net"
+	c := &Client{Store: db}
+	if got := c.ephemeralExpiration(chat); got != 0 {
+		t.Fatalf("expected no timer for an unseen chat, got %d", got)
+	}
+
+	if err := db.SetChatEphemeral(chat, 604800); err != nil {
+		t.Fatalf("set timer: %v", err)
+	}
+	if got := c.ephemeralExpiration("999@lid", chat); got != 604800 {
+		t.Fatalf("expected the stored timer, got %d", got)
+	}
+
+	c.IgnoreEphemeral = true
+	if got := c.ephemeralExpiration(chat); got != 0 {
+		t.Fatalf("expected IgnoreEphemeral to send without a timer, got %d", got)
+	}
+
+	if ctx := withExpiration(nil, 0); ctx != nil {
+		t.Fatalf("expected no context info without a timer, got %v", ctx)
+	}
+	if ctx := withExpiration(nil, 60); ctx.GetExpiration() != 60 {
+		t.Fatalf("expected the timer on a new context info, got %v", ctx)
+	}
+}
@@ -0,0 +1,72 @@
+package whatsapp
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// filenameFields are the values a download filename template can use.
+var filenameFields = []string{"id", "chat", "sender", "sender_name", "type", "timestamp", "date", "original", "ext"}
+
+// ParseFilenameTemplate parses a template for the names downloaded media is
+// saved under, such as "{{.timestamp}}_{{.sender}}_{{.original}}". It is tried
+// against sample values so an unknown field fails before anything downloads.
+func ParseFilenameTemplate(text string) (*template.Template, error) {
+	tpl, err := template.New("filename").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filename template: %w", err)
+	}
+
+	sample := make(map[string]string, len(filenameFields))
+	for _, field := range filenameFields {
+		sample[field] = field
+	}
+	if _, err := renderFilename(tpl, sample); err != nil {
+		return nil, fmt.Errorf("invalid filename template (fields: %s): %w", strings.Join(filenameFields, ", "), err)
+	}
+	return tpl, nil
+}
+
+// mediaFilenameFields returns the template values for a stored media message.
+func mediaFilenameFields(id, chatJID, sender, senderName, mediaType, original string, ts time.Time) map[string]string {
+	return map[string]string{
+		"id":          id,
+		"chat":        MediaDirName(chatJID),
+		"sender":      sender,
+		"sender_name": senderName,
+		"type":        mediaType,
+		"timestamp":   ts.Local().Format("20060102_150405"),
+		"date":        ts.Local().Format("2006-01-02"),
+		"original":    original,
+		"ext":         filepath.Ext(original),
+	}
+}
+
+// renderFilename renders a filename template and makes the result safe to use
+// as a single file name.
+func renderFilename(tpl *template.Template, fields map[string]string) (string, error) {
+	var b strings.Builder
+	if err := tpl.Execute(&b, fields); err != nil {
+		return "", err
+	}
+	name := sanitizeFilename(b.String())
+	if name == "" {
+		return "", fmt.Errorf("filename template rendered an empty name")
+	}
+	return name, nil
+}
+
+// sanitizeFilename replaces path separators and control characters, so a name
+// can't point outside its folder, and trims leading dots and spaces.
+func sanitizeFilename(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r < 0x20 || r == 0x7f {
+			return '_'
+		}
+		return r
+	}, name)
+	return strings.TrimLeft(strings.TrimSpace(name), ". ")
+}
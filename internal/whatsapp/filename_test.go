@@ -0,0 +1,114 @@
+package whatsapp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/eddmann/whatsapp-cli/internal/store"
+)
+
+func TestRenderFilename(t *testing.T) {
+	tpl, err := ParseFilenameTemplate("{{.timestamp}}_{{.sender_name}}_{{.original}}")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	ts := time.Date(2026, 4, 24, 12, 30, 5, 0, time.Local)
+	fields := mediaFilenameFields("ABC", "123-456@g.us", "447700900001", "Jane/../Doe", "image", "image_1.jpg", ts)
+	got, err := renderFilename(tpl, fields)
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if want := "20260424_123005_Jane_.._Doe_image_1.jpg"; got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+func TestParseFilenameTemplateRejectsUnknownFields(t *testing.T) {
+	if _, err := ParseFilenameTemplate("{{.nope}}"); err == nil {
+		t.Fatal("expected an unknown field to be rejected")
+	}
+	if _, err := ParseFilenameTemplate("{{.original"); err == nil {
+		t.Fatal("expected a malformed template to be rejected")
+	}
+}
+
+func TestSanitizeFilename(t *testing.T) {
+	tests := map[string]string{
+		"../../etc/passwd": "_.._etc_passwd",
+		"a\\b\nc.jpg":      "a_b_c.jpg",
+		"  .hidden":        "hidden",
+		"...":              "",
+	}
+	for in, want := range tests {
+		if got := sanitizeFilename(in); got != want {
+			t.Errorf("sanitizeFilename(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestDocumentFileName(t *testing.T) {
+	tests := []struct{ title, path, want string }{
+		{"Invoice.pdf", "/tmp/tmp123.pdf", "Invoice.pdf"},
+		{"March invoice", "/tmp/tmp123.pdf", "March invoice.pdf"},
+		{"Q1/Q2 report.xlsx", "report.xlsx", "Q1_Q2 report.xlsx"},
+	}
+	for _, tt := range tests {
+		if got := documentFileName(tt.title, tt.path); got != tt.want {
+			t.Errorf("documentFileName(%q, %q) = %q, want %q", tt.title, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestDownloadMediaReusesLocalPath(t *testing.T) {
+	dir := t.TempDir()
+	db, err := store.Open(filepath.Join(dir, "messages.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.CloseQuietly()
+
+	chatJID := "123@s.whatsapp.net"
+	saved := filepath.Join(dir, "photo.jpg")
+	if err := os.WriteFile(saved, []byte("jpeg"), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO chats (jid, name) VALUES (?, ?)", chatJID, "Alice"); err != nil {
+		t.Fatalf("insert chat: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO messages (id, chat_jid, sender, timestamp, is_from_me, media_type, filename,
+		url, media_key, file_sha256, file_enc_sha256, file_length)
+		VALUES ('IMG1', ?, '123', ?, 0, 'image', 'image_1.jpg', 'https://mmg.whatsapp.net/x', x'01', x'02', x'03', 4)`, chatJID, time.Now()); err != nil {
+		t.Fatalf("insert message: %v", err)
+	}
+	if err := db.SetMediaLocalPath(chatJID, "IMG1", saved); err != nil {
+		t.Fatalf("set local path: %v", err)
+	}
+
+	// No connection is needed while the earlier download is on disk
+	c := &Client{Store: db}
+	result, err := c.DownloadMedia("IMG1", chatJID, nil, false)
+	if err != nil {
+		t.Fatalf("download: %v", err)
+	}
+	if !result.Cached || result.Path != saved || result.Filename != "photo.jpg" {
+		t.Fatalf("expected the saved file to be reused, got %+v", result)
+	}
+
+	messages, err := db.ListMessages(store.ListMessagesOptions{ChatJID: chatJID})
+	if err != nil {
+		t.Fatalf("list messages: %v", err)
+	}
+	if len(messages) != 1 || messages[0].LocalPath == nil || *messages[0].LocalPath != saved {
+		t.Fatalf("expected local_path on the message, got %+v", messages)
+	}
+	known, err := db.MediaFilenames()
+	if err != nil {
+		t.Fatalf("media filenames: %v", err)
+	}
+	if !known[chatJID]["image_1.jpg"] || !known[chatJID]["photo.jpg"] {
+		t.Fatalf("expected both the stored and downloaded names to be known, got %v", known[chatJID])
+	}
+}
@@ -0,0 +1,587 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+
+	"github.com/eddmann/whatsapp-cli/internal/store"
+)
+
+// GetGroupInfo fetches a group's info from WhatsApp and caches it locally, so
+// 'whatsapp groups --local' can show it offline.
+func (c *Client) GetGroupInfo(ctx context.Context, jid types.JID) (*types.GroupInfo, error) {
+	info, err := c.WA.GetGroupInfo(ctx, jid)
+	if err != nil {
+		return nil, err
+	}
+	c.cacheGroup(info)
+	return info, nil
+}
+
+// cacheGroup saves a group's info to the local cache.
+func (c *Client) cacheGroup(info *types.GroupInfo) {
+	if c.Store == nil {
+		return
+	}
+	if err := c.Store.SaveGroup(groupRecord(info)); err != nil {
+		c.Logger.Warn("failed to cache group info", "chat_jid", info.JID.String(), "err", err)
+	}
+}
+
+// refreshGroup re-fetches a group's info after we changed it, so the local
+// cache doesn't keep what it was before.
+func (c *Client) refreshGroup(ctx context.Context, jid types.JID) {
+	if _, err := c.GetGroupInfo(ctx, jid); err != nil {
+		c.Logger.Warn("failed to refresh group info", "chat_jid", jid.String(), "err", err)
+	}
+}
+
+// groupInfoChange returns the name and topic a group info event changes, nil
+// for those it leaves alone.
+func groupInfoChange(evt *events.GroupInfo) (name, topic *string) {
+	if evt.Name != nil {
+		name = &evt.Name.Name
+	}
+	if evt.Topic != nil {
+		topic = &evt.Topic.Topic
+		if evt.Topic.TopicDeleted {
+			topic = new(string)
+		}
+	}
+	return name, topic
+}
+
+// groupRecord is the locally cached form of a group's info, without its
+// participants.
+func groupRecord(info *types.GroupInfo) store.GroupInfo {
+	return store.GroupInfo{
+		JID:              info.JID.String(),
+		Name:             info.Name,
+		Topic:            info.Topic,
+		Created:          info.GroupCreated,
+		CreatorJID:       info.OwnerJID.String(),
+		ParticipantCount: len(info.Participants),
+	}
+}
+
+// requireGroupAdmin returns an error unless we are an admin of the group.
+func (c *Client) requireGroupAdmin(ctx context.Context, jid types.JID) error {
+	if jid.Server != types.GroupServer {
+		return fmt.Errorf("%s is not a group JID", jid.String())
+	}
+
+	info, err := c.GetGroupInfo(ctx, jid)
+	if err != nil {
+		return fmt.Errorf("failed to get group info: %w", err)
+	}
+	if !c.isGroupAdmin(info) {
+		return fmt.Errorf("you must be an admin of %s", jid.String())
+	}
+	return nil
+}
+
+// isGroupAdmin reports whether we are an admin of the group.
+func (c *Client) isGroupAdmin(info *types.GroupInfo) bool {
+	for _, p := range info.Participants {
+		if c.isOwnJID(p.JID.String()) || (!p.PhoneNumber.IsEmpty() && c.isOwnJID(p.PhoneNumber.String())) {
+			return p.IsAdmin || p.IsSuperAdmin
+		}
+	}
+	return false
+}
+
+// groupMembers lists a group's members from the local participant cache, or
+// looks them up if none are cached.
+func (c *Client) groupMembers(jid types.JID) ([]store.Participant, error) {
+	if c.Store != nil {
+		cached, err := c.Store.GetGroupParticipants(jid.String())
+		if err != nil {
+			return nil, err
+		}
+		if len(cached) > 0 {
+			return cached, nil
+		}
+	}
+
+	info, err := c.GetGroupInfo(context.Background(), jid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get group info: %w", err)
+	}
+	members := make([]store.Participant, len(info.Participants))
+	for i, p := range info.Participants {
+		members[i].JID = p.JID.String()
+		if !p.LID.IsEmpty() {
+			members[i].LID = &p.LID.User
+		}
+		if !p.PhoneNumber.IsEmpty() {
+			members[i].Phone = &p.PhoneNumber.User
+		}
+	}
+	return members, nil
+}
+
+// maxGroupNameLength is the longest group name WhatsApp accepts, in characters.
+const maxGroupNameLength = 25
+
+// CreateGroup creates a group with the given participants, given as phone
+// numbers or JIDs, and records it as a chat. WhatsApp creates the group even
+// when some participants can't be added, such as those whose privacy settings
+// only allow invites; the result reports each participant's outcome.
+func (c *Client) CreateGroup(name string, participants []string) (store.GroupCreateResult, error) {
+	if !c.WA.IsConnected() {
+		return store.GroupCreateResult{}, ErrNotConnected
+	}
+	if name == "" {
+		return store.GroupCreateResult{}, fmt.Errorf("group name must not be empty")
+	}
+	if utf8.RuneCountInString(name) > maxGroupNameLength {
+		return store.GroupCreateResult{}, fmt.Errorf("group name must be at most %d characters", maxGroupNameLength)
+	}
+
+	jids, err := parseParticipants(participants)
+	if err != nil {
+		return store.GroupCreateResult{}, err
+	}
+
+	info, err := c.WA.CreateGroup(context.Background(), whatsmeow.ReqCreateGroup{
+		Name:         name,
+		Participants: jids,
+	})
+	if err != nil {
+		return store.GroupCreateResult{}, fmt.Errorf("failed to create group: %w", err)
+	}
+
+	if err := c.upsertChat(info.JID.String(), name, info.GroupCreated, true); err != nil {
+		c.Logger.Warn("failed to record new group", "chat_jid", info.JID.String(), "err", err)
+	}
+
+	return store.GroupCreateResult{
+		JID:          info.JID.String(),
+		Name:         info.Name,
+		Participants: participantResults(jids, info.Participants),
+	}, nil
+}
+
+// SetGroupTopic sets a group's description, or clears it if topic is empty.
+// Groups whose info only admins may edit need us to be an admin.
+func (c *Client) SetGroupTopic(groupJID, topic string) (store.GroupTopic, error) {
+	if !c.WA.IsConnected() {
+		return store.GroupTopic{}, ErrNotConnected
+	}
+	jid, err := parseJID(groupJID)
+	if err != nil {
+		return store.GroupTopic{}, fmt.Errorf("invalid group JID: %w", err)
+	}
+	if jid.Server != types.GroupServer {
+		return store.GroupTopic{}, fmt.Errorf("%s is not a group JID", jid.String())
+	}
+
+	ctx := context.Background()
+	info, err := c.GetGroupInfo(ctx, jid)
+	if err != nil {
+		return store.GroupTopic{}, fmt.Errorf("failed to get group info: %w", err)
+	}
+	if info.IsLocked && !c.isGroupAdmin(info) {
+		return store.GroupTopic{}, fmt.Errorf("only admins can change the description of %s", jid.String())
+	}
+
+	err = c.WA.SetGroupTopic(ctx, jid, info.TopicID, "", topic)
+	if errors.Is(err, whatsmeow.ErrIQForbidden) || errors.Is(err, whatsmeow.ErrIQNotAuthorized) {
+		return store.GroupTopic{}, fmt.Errorf("only admins can change the description of %s: %w", jid.String(), err)
+	}
+	if err != nil {
+		return store.GroupTopic{}, fmt.Errorf("failed to set group description: %w", err)
+	}
+	info.Topic = topic
+	c.cacheGroup(info)
+	return store.GroupTopic{JID: jid.String(), Topic: topic}, nil
+}
+
+// GetGroupSettings returns who may send messages in, and edit the info of, a
+// group.
+func (c *Client) GetGroupSettings(groupJID string) (store.GroupSettings, error) {
+	if !c.WA.IsConnected() {
+		return store.GroupSettings{}, ErrNotConnected
+	}
+	jid, err := parseJID(groupJID)
+	if err != nil {
+		return store.GroupSettings{}, fmt.Errorf("invalid group JID: %w", err)
+	}
+	if jid.Server != types.GroupServer {
+		return store.GroupSettings{}, fmt.Errorf("%s is not a group JID", jid.String())
+	}
+
+	info, err := c.GetGroupInfo(context.Background(), jid)
+	if err != nil {
+		return store.GroupSettings{}, fmt.Errorf("failed to get group info: %w", err)
+	}
+	return store.GroupSettings{JID: jid.String(), Announce: info.IsAnnounce, Locked: info.IsLocked}, nil
+}
+
+// SetGroupAnnounce sets whether only admins can send messages in a group we
+// are an admin of.
+func (c *Client) SetGroupAnnounce(groupJID string, adminsOnly bool) error {
+	return c.setGroupSetting(groupJID, "announce", func(ctx context.Context, jid types.JID) error {
+		return c.WA.SetGroupAnnounce(ctx, jid, adminsOnly)
+	})
+}
+
+// SetGroupLocked sets whether only admins can edit the info of a group we are
+// an admin of.
+func (c *Client) SetGroupLocked(groupJID string, locked bool) error {
+	return c.setGroupSetting(groupJID, "locked", func(ctx context.Context, jid types.JID) error {
+		return c.WA.SetGroupLocked(ctx, jid, locked)
+	})
+}
+
+// setGroupSetting changes the named setting of a group we are an admin of.
+func (c *Client) setGroupSetting(groupJID, name string, set func(context.Context, types.JID) error) error {
+	if !c.WA.IsConnected() {
+		return ErrNotConnected
+	}
+	jid, err := parseJID(groupJID)
+	if err != nil {
+		return fmt.Errorf("invalid group JID: %w", err)
+	}
+
+	ctx := context.Background()
+	if err := c.requireGroupAdmin(ctx, jid); err != nil {
+		return err
+	}
+
+	err = set(ctx, jid)
+	if errors.Is(err, whatsmeow.ErrIQForbidden) || errors.Is(err, whatsmeow.ErrIQNotAuthorized) {
+		return fmt.Errorf("you must be an admin of %s: %w", jid.String(), err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set %s: %w", name, err)
+	}
+	c.refreshGroup(ctx, jid)
+	return nil
+}
+
+// SetGroupPhoto sets a group's photo from a JPEG or PNG file, cropped square,
+// for a group we are an admin of. It returns the new picture ID.
+func (c *Client) SetGroupPhoto(groupJID, path string) (store.GroupPhoto, error) {
+	data, err := encodeGroupPhoto(path)
+	if err != nil {
+		return store.GroupPhoto{}, err
+	}
+	return c.setGroupPhoto(groupJID, data)
+}
+
+// RemoveGroupPhoto removes the photo of a group we are an admin of.
+func (c *Client) RemoveGroupPhoto(groupJID string) (store.GroupPhoto, error) {
+	return c.setGroupPhoto(groupJID, nil)
+}
+
+// setGroupPhoto sets a group's photo to a JPEG, or removes it if data is nil.
+func (c *Client) setGroupPhoto(groupJID string, data []byte) (store.GroupPhoto, error) {
+	if !c.WA.IsConnected() {
+		return store.GroupPhoto{}, ErrNotConnected
+	}
+	jid, err := parseJID(groupJID)
+	if err != nil {
+		return store.GroupPhoto{}, fmt.Errorf("invalid group JID: %w", err)
+	}
+
+	ctx := context.Background()
+	if err := c.requireGroupAdmin(ctx, jid); err != nil {
+		return store.GroupPhoto{}, err
+	}
+
+	pictureID, err := c.WA.SetGroupPhoto(ctx, jid, data)
+	switch {
+	case errors.Is(err, whatsmeow.ErrIQForbidden) || errors.Is(err, whatsmeow.ErrIQNotAuthorized):
+		return store.GroupPhoto{}, fmt.Errorf("you must be an admin of %s: %w", jid.String(), err)
+	case errors.Is(err, whatsmeow.ErrInvalidImageFormat):
+		return store.GroupPhoto{}, fmt.Errorf("WhatsApp rejected the photo: %w", err)
+	case err != nil:
+		return store.GroupPhoto{}, fmt.Errorf("failed to set group photo: %w", err)
+	}
+	c.refreshGroup(ctx, jid)
+	return store.GroupPhoto{JID: jid.String(), PictureID: pictureID, Removed: data == nil}, nil
+}
+
+// GetGroupInviteLink returns the https://chat.whatsapp.com/ invite link for a
+// group we are an admin of. With reset, the current link is revoked first, so
+// it stops working, and the new one is returned.
+func (c *Client) GetGroupInviteLink(groupJID string, reset bool) (store.GroupInviteLink, error) {
+	if !c.WA.IsConnected() {
+		return store.GroupInviteLink{}, ErrNotConnected
+	}
+	jid, err := parseJID(groupJID)
+	if err != nil {
+		return store.GroupInviteLink{}, fmt.Errorf("invalid group JID: %w", err)
+	}
+
+	ctx := context.Background()
+	if err := c.requireGroupAdmin(ctx, jid); err != nil {
+		return store.GroupInviteLink{}, err
+	}
+
+	link, err := c.WA.GetGroupInviteLink(ctx, jid, reset)
+	if errors.Is(err, whatsmeow.ErrGroupInviteLinkUnauthorized) {
+		return store.GroupInviteLink{}, fmt.Errorf("you must be an admin of %s: %w", jid.String(), err)
+	}
+	if err != nil {
+		return store.GroupInviteLink{}, fmt.Errorf("failed to get invite link: %w", err)
+	}
+	return store.GroupInviteLink{JID: jid.String(), Link: link, Reset: reset}, nil
+}
+
+// Participant changes accepted by UpdateGroupParticipants.
+const (
+	GroupParticipantAdd    = "add"
+	GroupParticipantRemove = "remove"
+)
+
+// UpdateGroupParticipants adds participants to, or removes them from, a group
+// we are an admin of. Participants may be given as phone numbers or JIDs; the
+// result reports whether each change was made.
+func (c *Client) UpdateGroupParticipants(groupJID string, participants []string, action string) ([]store.GroupMemberResult, error) {
+	if !c.WA.IsConnected() {
+		return nil, ErrNotConnected
+	}
+	var change whatsmeow.ParticipantChange
+	switch action {
+	case GroupParticipantAdd:
+		change = whatsmeow.ParticipantChangeAdd
+	case GroupParticipantRemove:
+		change = whatsmeow.ParticipantChangeRemove
+	default:
+		return nil, fmt.Errorf("invalid participant change %q: use %s or %s", action, GroupParticipantAdd, GroupParticipantRemove)
+	}
+	jid, err := parseJID(groupJID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid group JID: %w", err)
+	}
+	jids, err := parseParticipants(participants)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	if err := c.requireGroupAdmin(ctx, jid); err != nil {
+		return nil, err
+	}
+
+	updated, err := c.WA.UpdateGroupParticipants(ctx, jid, jids, change)
+	if errors.Is(err, whatsmeow.ErrIQForbidden) {
+		// Admin rights can be revoked between the check and the change
+		return nil, fmt.Errorf("you must be an admin of %s: %w", jid.String(), err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s participants: %w", action, err)
+	}
+	return participantResults(jids, updated), nil
+}
+
+// parseParticipants parses participants given as phone numbers or JIDs,
+// dropping repeats.
+func parseParticipants(participants []string) ([]types.JID, error) {
+	jids := make([]types.JID, 0, len(participants))
+	seen := make(map[types.JID]bool, len(participants))
+	for _, p := range participants {
+		jid, err := parseRecipient(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid participant %q: %w", p, err)
+		}
+		if !seen[jid] {
+			seen[jid] = true
+			jids = append(jids, jid)
+		}
+	}
+	return jids, nil
+}
+
+// participantResults matches the participants of a group change against those
+// the server reports, which may be identified by LID rather than phone number,
+// and records why any change wasn't made.
+func participantResults(requested []types.JID, got []types.GroupParticipant) []store.GroupMemberResult {
+	byUser := make(map[string]types.GroupParticipant, len(got)*2)
+	for _, p := range got {
+		byUser[p.JID.User] = p
+		if !p.PhoneNumber.IsEmpty() {
+			byUser[p.PhoneNumber.User] = p
+		}
+		if !p.LID.IsEmpty() {
+			byUser[p.LID.User] = p
+		}
+	}
+
+	results := make([]store.GroupMemberResult, len(requested))
+	for i, jid := range requested {
+		results[i].JID = jid.String()
+		p, ok := byUser[jid.User]
+		switch {
+		case !ok:
+			results[i].Error = "no response from server"
+		case p.AddRequest != nil:
+			results[i].Error = "not added: their privacy settings only allow an invite"
+		case p.Error != 0:
+			results[i].Error = fmt.Sprintf("rejected by server (code %d)", p.Error)
+		default:
+			results[i].Success = true
+		}
+	}
+	return results
+}
+
+// GroupJoinRequests lists the pending requests to join a group. Only admins can
+// see them.
+func (c *Client) GroupJoinRequests(groupJID string) ([]store.GroupJoinRequest, error) {
+	if !c.WA.IsConnected() {
+		return nil, ErrNotConnected
+	}
+	jid, err := parseJID(groupJID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid group JID: %w", err)
+	}
+
+	ctx := context.Background()
+	if err := c.requireGroupAdmin(ctx, jid); err != nil {
+		return nil, err
+	}
+
+	pending, err := c.WA.GetGroupRequestParticipants(ctx, jid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get join requests: %w", err)
+	}
+
+	requests := make([]store.GroupJoinRequest, 0, len(pending))
+	for _, p := range pending {
+		req := store.GroupJoinRequest{
+			JID:         p.JID.String(),
+			RequestedAt: p.RequestedAt,
+		}
+		if p.JID.Server == types.DefaultUserServer {
+			req.Phone = p.JID.User
+		} else if phone, _, found := c.Store.GetLIDMapping(p.JID.User); found {
+			req.Phone = phone
+		}
+		if name := c.resolvePreferredName(p.JID); name != p.JID.User {
+			req.Name = name
+		}
+		requests = append(requests, req)
+	}
+	return requests, nil
+}
+
+// UpdateGroupJoinRequests approves or rejects pending join requests. Members may
+// be given as phone numbers or JIDs; each is matched against the pending
+// requests, so a phone number also matches a request made from its LID.
+func (c *Client) UpdateGroupJoinRequests(groupJID string, members []string, approve bool) ([]store.GroupMemberResult, error) {
+	if !c.WA.IsConnected() {
+		return nil, ErrNotConnected
+	}
+	jid, err := parseJID(groupJID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid group JID: %w", err)
+	}
+
+	ctx := context.Background()
+	if err := c.requireGroupAdmin(ctx, jid); err != nil {
+		return nil, err
+	}
+
+	pending, err := c.WA.GetGroupRequestParticipants(ctx, jid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get join requests: %w", err)
+	}
+
+	// Index pending requests by JID, user part and, for LIDs, mapped phone
+	byKey := make(map[string]types.JID, len(pending)*3)
+	for _, p := range pending {
+		byKey[p.JID.String()] = p.JID
+		byKey[p.JID.User] = p.JID
+		if p.JID.Server == types.HiddenUserServer {
+			if phone, _, found := c.Store.GetLIDMapping(p.JID.User); found && phone != "" {
+				byKey[phone] = p.JID
+			}
+		}
+	}
+
+	results, targets, targetIndex, duplicates := matchJoinRequests(members, byKey)
+	if len(targets) == 0 {
+		return results, nil
+	}
+
+	action := whatsmeow.ParticipantChangeReject
+	if approve {
+		action = whatsmeow.ParticipantChangeApprove
+	}
+	updated, err := c.WA.UpdateGroupRequestParticipants(ctx, jid, targets, action)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update join requests: %w", err)
+	}
+
+	answered := make(map[int]bool, len(updated))
+	for _, p := range updated {
+		i, ok := targetIndex[p.JID.User]
+		if !ok && !p.LID.IsEmpty() {
+			i, ok = targetIndex[p.LID.User]
+		}
+		if !ok && !p.PhoneNumber.IsEmpty() {
+			i, ok = targetIndex[p.PhoneNumber.User]
+		}
+		if !ok {
+			continue
+		}
+		answered[i] = true
+		if p.Error != 0 {
+			results[i].Error = fmt.Sprintf("rejected by server (code %d)", p.Error)
+		} else {
+			results[i].Success = true
+		}
+	}
+	for _, i := range targetIndex {
+		if !answered[i] {
+			results[i].Error = "no response from server"
+		}
+	}
+	for dup, first := range duplicates {
+		results[dup].Success, results[dup].Error = results[first].Success, results[first].Error
+	}
+	return results, nil
+}
+
+// matchJoinRequests matches members against the pending join requests indexed
+// by byKey. It returns a result per member, the request JIDs to act on, and the
+// member each target's user part came from. A member naming a request already
+// matched isn't sent twice; duplicates maps its index to the first match.
+func matchJoinRequests(members []string, byKey map[string]types.JID) (results []store.GroupMemberResult, targets []types.JID, targetIndex map[string]int, duplicates map[int]int) {
+	results = make([]store.GroupMemberResult, len(members))
+	targetIndex = make(map[string]int)
+	duplicates = make(map[int]int)
+	for i, member := range members {
+		results[i].JID = member
+		target, err := parseRecipient(member)
+		if err != nil {
+			results[i].Error = fmt.Sprintf("invalid member: %v", err)
+			continue
+		}
+		requestJID, ok := byKey[target.String()]
+		if !ok {
+			requestJID, ok = byKey[target.User]
+		}
+		if !ok {
+			results[i].Error = "no pending join request"
+			continue
+		}
+		results[i].JID = requestJID.String()
+		if first, seen := targetIndex[requestJID.User]; seen {
+			duplicates[i] = first
+			continue
+		}
+		targetIndex[requestJID.User] = i
+		targets = append(targets, requestJID)
+	}
+	return results, targets, targetIndex, duplicates
+}
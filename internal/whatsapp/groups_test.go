@@ -0,0 +1,96 @@
+package whatsapp
+
+import (
+	"strings"
+	"testing"
+
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+func TestGroupInfoChange(t *testing.T) {
+	name, topic := groupInfoChange(&events.GroupInfo{Name: &types.GroupName{Name: "Book Club"}})
+	if name == nil || *name != "Book Club" || topic != nil {
+		t.Fatalf("expected only the name changed, got %v, %v", name, topic)
+	}
+
+	name, topic = groupInfoChange(&events.GroupInfo{Topic: &types.GroupTopic{Topic: "old", TopicDeleted: true}})
+	if name != nil || topic == nil || *topic != "" {
+		t.Fatalf("expected a deleted topic cleared, got %v, %v", name, topic)
+	}
+
+	if name, topic := groupInfoChange(&events.GroupInfo{Announce: &types.GroupAnnounce{IsAnnounce: true}}); name != nil || topic != nil {
+		t.Fatalf("expected no name or topic change, got %v, %v", name, topic)
+	}
+}
+
+func TestMatchJoinRequestsDedupesMembers(t *testing.T) {
+	lid := types.NewJID("98765", types.HiddenUserServer)
+	byKey := map[string]types.JID{
+		lid.String():   lid,
+		lid.User:       lid,
+		"447700900000": lid,
+	}
+
+	results, targets, targetIndex, duplicates := matchJoinRequests([]string{"+447700900000", "98765@lid", "447700900999"}, byKey)
+	if len(targets) != 1 || targets[0] != lid {
+		t.Fatalf("expected the request to be targeted once, got %v", targets)
+	}
+	if targetIndex[lid.User] != 0 {
+		t.Fatalf("expected the target to map to the first member, got %v", targetIndex)
+	}
+	if first, ok := duplicates[1]; !ok || first != 0 {
+		t.Fatalf("expected the second member to be a duplicate of the first, got %v", duplicates)
+	}
+	if results[1].JID != lid.String() {
+		t.Fatalf("expected the duplicate to resolve to %s, got %s", lid, results[1].JID)
+	}
+	if results[2].Error != "no pending join request" {
+		t.Fatalf("expected an unmatched member error, got %+v", results[2])
+	}
+}
+
+func TestParticipantResultsReportsFailedParticipants(t *testing.T) {
+	added := types.NewJID("447700900001", types.DefaultUserServer)
+	private := types.NewJID("447700900002", types.DefaultUserServer)
+	refused := types.NewJID("447700900003", types.DefaultUserServer)
+	missing := types.NewJID("447700900004", types.DefaultUserServer)
+
+	got := []types.GroupParticipant{
+		// The server may answer with the participant's LID
+		{JID: types.NewJID("111", types.HiddenUserServer), PhoneNumber: added},
+		{JID: private, Error: 403, AddRequest: &types.GroupParticipantAddRequest{Code: "abc"}},
+		{JID: refused, Error: 409},
+	}
+
+	results := participantResults([]types.JID{added, private, refused, missing}, got)
+	if len(results) != 4 {
+		t.Fatalf("expected 4 results, got %+v", results)
+	}
+	if !results[0].Success || results[0].JID != added.String() {
+		t.Fatalf("expected %s to be added, got %+v", added, results[0])
+	}
+	if results[1].Success || !strings.Contains(results[1].Error, "invite") {
+		t.Fatalf("expected %s to need an invite, got %+v", private, results[1])
+	}
+	if results[2].Success || results[2].Error != "rejected by server (code 409)" {
+		t.Fatalf("expected %s to be refused, got %+v", refused, results[2])
+	}
+	if results[3].Success || results[3].Error != "no response from server" {
+		t.Fatalf("expected %s to be reported missing, got %+v", missing, results[3])
+	}
+}
+
+func TestParseParticipantsDropsRepeats(t *testing.T) {
+	jids, err := parseParticipants([]string{"+447700900001", "447700900001@s.whatsapp.net", "447700900002"})
+	if err != nil {
+		t.Fatalf("parseParticipants failed: %v", err)
+	}
+	if len(jids) != 2 || jids[0].User != "447700900001" || jids[1].User != "447700900002" {
+		t.Fatalf("expected 2 distinct participants in order, got %v", jids)
+	}
+
+	if _, err := parseParticipants([]string{"447700900001", "@lid"}); err == nil {
+		t.Fatal("expected an invalid participant to be rejected")
+	}
+}
@@ -2,19 +2,299 @@ package whatsapp
 
 import (
 	"fmt"
+	"regexp"
+	"slices"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"go.mau.fi/whatsmeow"
 	waE2E "go.mau.fi/whatsmeow/proto/waE2E"
+	waWeb "go.mau.fi/whatsmeow/proto/waWeb"
 	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/eddmann/whatsapp-cli/internal/store"
 )
 
+const emojiPresentationSelector = '\uFE0F'
+
+// normalizeEmoji normalizes reaction text so the same emoji is always sent the same way.
+// Single symbols that default to text presentation (e.g. ❤ U+2764) get the emoji
+// variation selector appended, matching what the WhatsApp emoji picker sends.
+func normalizeEmoji(s string) string {
+	s = store.NormalizeText(strings.TrimSpace(s))
+
+	runes := []rune(s)
+	if len(runes) == 1 && unicode.Is(textDefaultEmoji, runes[0]) {
+		return s + string(emojiPresentationSelector)
+	}
+	return s
+}
+
+// SplitText splits text into chunks of at most limit characters, breaking at a line
+// boundary when one falls in the second half of the chunk, otherwise at the last space
+// or newline. Runs without any whitespace are cut at the limit.
+func SplitText(text string, limit int) []string {
+	if limit <= 0 || utf8.RuneCountInString(text) <= limit {
+		return []string{text}
+	}
+
+	var chunks []string
+	rest := text
+	for utf8.RuneCountInString(rest) > limit {
+		cut := 0
+		for n := 0; n < limit; n++ {
+			_, size := utf8.DecodeRuneInString(rest[cut:])
+			cut += size
+		}
+
+		window := rest[:cut]
+		if i := strings.LastIndex(window, "\n"); i > len(window)/2 {
+			cut = i
+		} else if i := strings.LastIndexAny(window, " \n"); i > 0 {
+			cut = i
+		}
+
+		if chunk := strings.TrimRight(rest[:cut], " \n"); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+		rest = strings.TrimLeft(rest[cut:], " \n")
+	}
+	if rest != "" {
+		chunks = append(chunks, rest)
+	}
+
+	return chunks
+}
+
 // parseJID parses a JID string into a types.JID.
 func parseJID(jid string) (types.JID, error) {
 	return types.ParseJID(jid)
 }
 
+// AppendMentionTokens appends an @token for each mentioned JID to the text,
+// which is what WhatsApp clients highlight as a mention. Tokens already in the
+// text are not repeated.
+func AppendMentionTokens(text string, jids []string) string {
+	var tokens []string
+	for _, jid := range jids {
+		user := mentionUser(jid)
+		if !hasMentionToken(text, user) {
+			tokens = append(tokens, "@"+user)
+		}
+	}
+	if len(tokens) == 0 {
+		return text
+	}
+
+	if text == "" {
+		return strings.Join(tokens, " ")
+	}
+	return text + "\n" + strings.Join(tokens, " ")
+}
+
+// MentionsInText returns the JIDs in jids whose @token appears in text, so
+// each part of a split message mentions only the people it names.
+func MentionsInText(text string, jids []string) []string {
+	var found []string
+	for _, jid := range jids {
+		if hasMentionToken(text, mentionUser(jid)) {
+			found = append(found, jid)
+		}
+	}
+	return found
+}
+
+// mentionUser returns the user part of jid, which is its @token in text.
+func mentionUser(jid string) string {
+	if i := strings.Index(jid, "@"); i >= 0 {
+		return jid[:i]
+	}
+	return jid
+}
+
+// mentionTokenPattern matches an @phone-number mention in message text.
+var mentionTokenPattern = regexp.MustCompile(`@(\d{7,15})\b`)
+
+// hasMentionToken reports whether text contains @user as a whole token.
+func hasMentionToken(text, user string) bool {
+	for _, m := range mentionTokenPattern.FindAllStringSubmatch(text, -1) {
+		if m[1] == user {
+			return true
+		}
+	}
+	return false
+}
+
+// MentionJIDs returns the phone JIDs to mention for the given numbers (with or
+// without a leading + or @) and for each @number token in text, without
+// duplicates.
+func MentionJIDs(text string, numbers []string) ([]string, error) {
+	var jids []string
+	add := func(number string) {
+		jid := number + "@" + types.DefaultUserServer
+		if !slices.Contains(jids, jid) {
+			jids = append(jids, jid)
+		}
+	}
+
+	for _, n := range numbers {
+		number := strings.TrimLeft(strings.TrimSpace(n), "+@")
+		if len(number) < 7 || len(number) > 15 || strings.Trim(number, "0123456789") != "" {
+			return nil, fmt.Errorf("invalid mention %q: expected a phone number with country code", n)
+		}
+		add(number)
+	}
+	for _, m := range mentionTokenPattern.FindAllStringSubmatch(text, -1) {
+		add(m[1])
+	}
+	return jids, nil
+}
+
+// matchMentionTokens matches each @number token in text against the group
+// members' phone numbers and JIDs. Tokens are rewritten to the user of the
+// matched member's JID, so a member known by LID is highlighted. It returns
+// the rewritten text, the member JIDs and the numbers that matched no member,
+// each without duplicates.
+func matchMentionTokens(text string, members []store.Participant) (string, []string, []string) {
+	byNumber := make(map[string]types.JID, len(members)*2)
+	for _, m := range members {
+		jid, err := parseJID(m.JID)
+		if err != nil {
+			continue
+		}
+		byNumber[jid.User] = jid
+		if m.Phone != nil && *m.Phone != "" {
+			byNumber[*m.Phone] = jid
+		}
+		if m.LID != nil && *m.LID != "" {
+			byNumber[*m.LID] = jid
+		}
+	}
+
+	var jids, unmatched []string
+	text = mentionTokenPattern.ReplaceAllStringFunc(text, func(token string) string {
+		number := token[1:]
+		jid, ok := byNumber[number]
+		if !ok {
+			if !slices.Contains(unmatched, number) {
+				unmatched = append(unmatched, number)
+			}
+			return token
+		}
+		if !slices.Contains(jids, jid.String()) {
+			jids = append(jids, jid.String())
+		}
+		return "@" + jid.User
+	})
+	return text, jids, unmatched
+}
+
+// describeSystemMessage returns a short description for a message with no text or
+// media, such as protocol messages and encryption notices. Reactions return "" since
+// they aren't standalone messages.
+func describeSystemMessage(m *waE2E.Message) string {
+	if m == nil || m.GetReactionMessage() != nil {
+		return ""
+	}
+
+	if pm := m.GetProtocolMessage(); pm != nil {
+		if !visibleProtocolMessages[pm.GetType()] {
+			return ""
+		}
+		return "Protocol message: " + humanizeEnum(pm.GetType().String())
+	}
+
+	// Sender keys are re-shared all the time and mean nothing to the user
+	if m.GetSenderKeyDistributionMessage() != nil {
+		return ""
+	}
+
+	var field string
+	m.ProtoReflect().Range(func(fd protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+		if fd.Name() == "messageContextInfo" {
+			return true
+		}
+		field = string(fd.Name())
+		return false
+	})
+	if field == "" {
+		return ""
+	}
+	return "Unsupported message: " + field
+}
+
+// visibleProtocolMessages are the protocol messages that record something the
+// user would see in the chat. The rest, like history sync notifications and
+// app state key shares, are housekeeping between devices.
+var visibleProtocolMessages = map[waE2E.ProtocolMessage_Type]bool{
+	waE2E.ProtocolMessage_REVOKE:                    true,
+	waE2E.ProtocolMessage_EPHEMERAL_SETTING:         true,
+	waE2E.ProtocolMessage_MESSAGE_EDIT:              true,
+	waE2E.ProtocolMessage_SHARE_PHONE_NUMBER:        true,
+	waE2E.ProtocolMessage_LIMIT_SHARING:             true,
+	waE2E.ProtocolMessage_GROUP_MEMBER_LABEL_CHANGE: true,
+	waE2E.ProtocolMessage_CHAT_THEME_SETTING:        true,
+}
+
+// describeHistorySystemMessage describes a history sync message with no text or media,
+// including group events (participant changes, subject changes) sent as stubs.
+func describeHistorySystemMessage(info *waWeb.WebMessageInfo) string {
+	if stub := info.GetMessageStubType(); stub != waWeb.WebMessageInfo_UNKNOWN {
+		desc := humanizeEnum(stub.String())
+		if params := info.GetMessageStubParameters(); len(params) > 0 {
+			desc += ": " + strings.Join(params, ", ")
+		}
+		return desc
+	}
+	return describeSystemMessage(info.GetMessage())
+}
+
+// humanizeEnum turns a proto enum name like GROUP_PARTICIPANT_ADD into "group participant add".
+func humanizeEnum(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "_", " "))
+}
+
+// extractContextInfo returns the context info (quote, mentions, forwarding) of a
+// text or media message, or nil if it has none.
+func extractContextInfo(m *waE2E.Message) *waE2E.ContextInfo {
+	switch {
+	case m == nil:
+		return nil
+	case m.GetExtendedTextMessage() != nil:
+		return m.GetExtendedTextMessage().GetContextInfo()
+	case m.GetImageMessage() != nil:
+		return m.GetImageMessage().GetContextInfo()
+	case m.GetVideoMessage() != nil:
+		return m.GetVideoMessage().GetContextInfo()
+	case m.GetAudioMessage() != nil:
+		return m.GetAudioMessage().GetContextInfo()
+	case m.GetDocumentMessage() != nil:
+		return m.GetDocumentMessage().GetContextInfo()
+	case m.GetStickerMessage() != nil:
+		return m.GetStickerMessage().GetContextInfo()
+	case m.GetLocationMessage() != nil:
+		return m.GetLocationMessage().GetContextInfo()
+	case m.GetContactMessage() != nil:
+		return m.GetContactMessage().GetContextInfo()
+	}
+	return nil
+}
+
+// forwardingScore returns how many times a message was forwarded, from its
+// context info. Forwarded messages without a score count as forwarded once.
+func forwardingScore(ci *waE2E.ContextInfo) uint32 {
+	if score := ci.GetForwardingScore(); score > 0 {
+		return score
+	}
+	if ci.GetIsForwarded() {
+		return 1
+	}
+	return 0
+}
+
 // extractTextContent extracts text content from a WhatsApp message.
 func extractTextContent(m *waE2E.Message) string {
 	if m == nil {
@@ -57,6 +337,22 @@ func extractTextContent(m *waE2E.Message) string {
 		return fmt.Sprintf("😊 Reaction: %s", reaction.GetText())
 	}
 
+	if buttons := m.GetButtonsMessage(); buttons != nil {
+		labels := make([]string, 0, len(buttons.GetButtons()))
+		for _, b := range buttons.GetButtons() {
+			labels = append(labels, "["+b.GetButtonText().GetDisplayText()+"]")
+		}
+		return strings.TrimSpace(buttons.GetContentText() + "\n" + strings.Join(labels, " "))
+	}
+
+	if resp := m.GetButtonsResponseMessage(); resp != nil {
+		return fmt.Sprintf("🔘 %s [%s]", resp.GetSelectedDisplayText(), resp.GetSelectedButtonID())
+	}
+
+	if reply := m.GetTemplateButtonReplyMessage(); reply != nil {
+		return fmt.Sprintf("🔘 %s [%s]", reply.GetSelectedDisplayText(), reply.GetSelectedID())
+	}
+
 	if m.GetProtocolMessage() != nil {
 		return "🔧 System Message"
 	}
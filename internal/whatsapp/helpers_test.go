@@ -0,0 +1,180 @@
+package whatsapp
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	waE2E "go.mau.fi/whatsmeow/proto/waE2E"
+	waWeb "go.mau.fi/whatsmeow/proto/waWeb"
+
+	"github.com/eddmann/whatsapp-cli/internal/store"
+)
+
+func TestAppendMentionTokensAddsTokenPerJID(t *testing.T) {
+	got := AppendMentionTokens("Standup in 5", []string{"447700900001@s.whatsapp.net", "233564700451061@lid"})
+	want := "Standup in 5\n@447700900001 @233564700451061"
+	if got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+func TestAppendMentionTokensLeavesTextWithoutMentions(t *testing.T) {
+	if got := AppendMentionTokens("hello", nil); got != "hello" {
+		t.Fatalf("expected text unchanged, got %q", got)
+	}
+}
+
+func TestAppendMentionTokensSkipsTokensInText(t *testing.T) {
+	got := AppendMentionTokens("Thanks @447700900001", []string{"447700900001@s.whatsapp.net", "4477009000012@s.whatsapp.net"})
+	want := "Thanks @447700900001\n@4477009000012"
+	if got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+func TestMentionsInTextMatchesSplitParts(t *testing.T) {
+	jids := []string{"447700900001@s.whatsapp.net", "447700900002@s.whatsapp.net"}
+	text := AppendMentionTokens("Thanks @447700900001 for the notes", jids)
+	parts := SplitText(text, 36)
+	if len(parts) != 2 {
+		t.Fatalf("expected two parts, got %q", parts)
+	}
+
+	if got := MentionsInText(parts[0], jids); !slices.Equal(got, jids[:1]) {
+		t.Fatalf("expected the first part to mention %v, got %v", jids[:1], got)
+	}
+	if got := MentionsInText(parts[1], jids); !slices.Equal(got, jids[1:]) {
+		t.Fatalf("expected the last part to mention %v, got %v", jids[1:], got)
+	}
+}
+
+func TestMentionJIDs(t *testing.T) {
+	got, err := MentionJIDs("Ping @447700900001 and @447700900002, not @2pm", []string{"+447700900003", "447700900001"})
+	if err != nil {
+		t.Fatalf("mention jids: %v", err)
+	}
+	want := []string{"447700900003@s.whatsapp.net", "447700900001@s.whatsapp.net", "447700900002@s.whatsapp.net"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	if _, err := MentionJIDs("", []string{"alice"}); err == nil {
+		t.Fatal("expected an error for a mention that isn't a number")
+	}
+}
+
+func TestSplitText(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		want  []string
+	}{
+		{"short", "hello world", 20, []string{"hello world"}},
+		{"disabled", "hello world", 0, []string{"hello world"}},
+		{"words", "one two three four", 9, []string{"one two", "three", "four"}},
+		{"lines", "first line here\nsecond line", 20, []string{"first line here", "second line"}},
+		{"hard cut", "abcdefghij", 4, []string{"abcd", "efgh", "ij"}},
+		{"runes", "日本語日本語", 4, []string{"日本語日", "本語"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SplitText(tt.text, tt.limit)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("SplitText(%q, %d) = %q, want %q", tt.text, tt.limit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDescribeHistorySystemMessage(t *testing.T) {
+	stub := waWeb.WebMessageInfo_GROUP_PARTICIPANT_ADD
+	info := &waWeb.WebMessageInfo{
+		MessageStubType:       &stub,
+		MessageStubParameters: []string{"447700900001@s.whatsapp.net"},
+	}
+	if got := describeHistorySystemMessage(info); got != "group participant add: 447700900001@s.whatsapp.net" {
+		t.Fatalf("unexpected stub description %q", got)
+	}
+
+	revoke := waE2E.ProtocolMessage_REVOKE
+	info = &waWeb.WebMessageInfo{Message: &waE2E.Message{ProtocolMessage: &waE2E.ProtocolMessage{Type: &revoke}}}
+	if got := describeHistorySystemMessage(info); got != "Protocol message: revoke" {
+		t.Fatalf("unexpected protocol description %q", got)
+	}
+
+	reaction := &waE2E.Message{ReactionMessage: &waE2E.ReactionMessage{}}
+	if got := describeSystemMessage(reaction); got != "" {
+		t.Fatalf("expected reactions to be skipped, got %q", got)
+	}
+
+	historyNotice := waE2E.ProtocolMessage_HISTORY_SYNC_NOTIFICATION
+	housekeeping := []*waE2E.Message{
+		{ProtocolMessage: &waE2E.ProtocolMessage{Type: &historyNotice}},
+		{SenderKeyDistributionMessage: &waE2E.SenderKeyDistributionMessage{}},
+	}
+	for _, m := range housekeeping {
+		if got := describeSystemMessage(m); got != "" {
+			t.Fatalf("expected housekeeping message to be skipped, got %q", got)
+		}
+	}
+}
+
+func TestMatchMentionTokensResolvesMembers(t *testing.T) {
+	phone, lid := "447700900002", "98765432101234"
+	members := []store.Participant{
+		{JID: "447700900001@s.whatsapp.net"},
+		{JID: lid + "@lid", LID: &lid, Phone: &phone},
+	}
+
+	text, jids, unmatched := matchMentionTokens("@447700900001 and @447700900002, not @447700900999 or @447700900001", members)
+	if text != "@447700900001 and @"+lid+", not @447700900999 or @447700900001" {
+		t.Fatalf("expected the LID member's token to be rewritten, got %q", text)
+	}
+	if len(jids) != 2 || jids[0] != "447700900001@s.whatsapp.net" || jids[1] != lid+"@lid" {
+		t.Fatalf("expected both members once each, got %v", jids)
+	}
+	if len(unmatched) != 1 || unmatched[0] != "447700900999" {
+		t.Fatalf("expected the non-member to be unmatched, got %v", unmatched)
+	}
+}
+
+func TestExtractTextContentButtons(t *testing.T) {
+	sent := &waE2E.Message{ButtonsMessage: &waE2E.ButtonsMessage{
+		ContentText: protoString("Choose:"),
+		Buttons: []*waE2E.ButtonsMessage_Button{
+			{ButtonID: protoString("id1"), ButtonText: &waE2E.ButtonsMessage_Button_ButtonText{DisplayText: protoString("Yes")}},
+			{ButtonID: protoString("id2"), ButtonText: &waE2E.ButtonsMessage_Button_ButtonText{DisplayText: protoString("No")}},
+		},
+	}}
+	if got := extractTextContent(sent); got != "Choose:\n[Yes] [No]" {
+		t.Errorf("expected the text and button labels, got %q", got)
+	}
+
+	reply := &waE2E.Message{ButtonsResponseMessage: &waE2E.ButtonsResponseMessage{
+		SelectedButtonID: protoString("id2"),
+		Response:         &waE2E.ButtonsResponseMessage_SelectedDisplayText{SelectedDisplayText: "No"},
+	}}
+	if got := extractTextContent(reply); got != "🔘 No [id2]" {
+		t.Errorf("expected the chosen button and its ID, got %q", got)
+	}
+}
+
+func TestForwardingScore(t *testing.T) {
+	tests := []struct {
+		ci   *waE2E.ContextInfo
+		want uint32
+	}{
+		{nil, 0},
+		{&waE2E.ContextInfo{}, 0},
+		{&waE2E.ContextInfo{IsForwarded: protoBool(true)}, 1},
+		{&waE2E.ContextInfo{IsForwarded: protoBool(true), ForwardingScore: protoUint32(7)}, 7},
+	}
+	for _, tt := range tests {
+		if got := forwardingScore(tt.ci); got != tt.want {
+			t.Errorf("forwardingScore(%v) = %d, want %d", tt.ci, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package whatsapp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	_ "image/gif" // Register GIF decoding for thumbnails
+	"image/jpeg"
+	_ "image/png" // Register PNG decoding for thumbnails
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	linkPreviewTimeout   = 10 * time.Second
+	linkPreviewPageLimit = 512 << 10 // Only the <head> is needed
+	linkPreviewImageCap  = 4 << 20
+	linkPreviewThumbSize = 192 // Longest edge of the JPEG thumbnail, in pixels
+
+	// linkPreviewMaxPixels caps the decoded image size. A small, highly
+	// compressed file can declare huge dimensions and exhaust memory.
+	linkPreviewMaxPixels = 25_000_000
+)
+
+var urlPattern = regexp.MustCompile(`https?://[^\s<>"]+`)
+
+// LinkPreview is the OpenGraph summary of a URL, shown under a text message.
+type LinkPreview struct {
+	URL         string
+	Title       string
+	Description string
+	Thumbnail   []byte // JPEG
+	ThumbWidth  int
+	ThumbHeight int
+}
+
+// FirstURL returns the first http(s) URL in text, or "" if there is none.
+func FirstURL(text string) string {
+	return strings.TrimRight(urlPattern.FindString(text), ".,;:!?)]}'")
+}
+
+// FetchLinkPreview fetches rawURL and builds a preview from its OpenGraph tags,
+// falling back to <title> and <meta name="description">. The thumbnail is
+// best-effort; a preview is returned without one if the image can't be used.
+func FetchLinkPreview(ctx context.Context, rawURL string) (*LinkPreview, error) {
+	ctx, cancel := context.WithTimeout(ctx, linkPreviewTimeout)
+	defer cancel()
+
+	body, err := fetchLimited(ctx, rawURL, linkPreviewPageLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	preview, imageURL := parseOpenGraph(body, rawURL)
+	if preview.Title == "" {
+		return nil, fmt.Errorf("no title found at %s", rawURL)
+	}
+
+	if imageURL != "" {
+		if img, err := fetchLimited(ctx, imageURL, linkPreviewImageCap); err == nil {
+			preview.Thumbnail, preview.ThumbWidth, preview.ThumbHeight, _ = makeThumbnail(img)
+		}
+	}
+
+	return preview, nil
+}
+
+func fetchLimited(ctx context.Context, rawURL string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; whatsapp-cli link preview)")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch %s: %s", rawURL, resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, limit))
+}
+
+// parseOpenGraph extracts the preview text and the absolute og:image URL from a page.
+func parseOpenGraph(page []byte, pageURL string) (*LinkPreview, string) {
+	preview := &LinkPreview{URL: pageURL}
+	var title, description, imageURL string
+
+	z := html.NewTokenizer(bytes.NewReader(page))
+	inTitle := false
+scan:
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			break scan
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+			switch tok.Data {
+			case "title":
+				inTitle = true
+			case "meta":
+				key, content := metaAttrs(tok)
+				switch key {
+				case "og:title":
+					preview.Title = content
+				case "og:description":
+					preview.Description = content
+				case "og:image", "og:image:url", "og:image:secure_url":
+					if imageURL == "" {
+						imageURL = content
+					}
+				case "description":
+					description = content
+				}
+			case "body":
+				break scan
+			}
+		case html.TextToken:
+			if inTitle && title == "" {
+				title = strings.TrimSpace(string(z.Text()))
+			}
+		case html.EndTagToken:
+			if tok := z.Token(); tok.Data == "title" {
+				inTitle = false
+			} else if tok.Data == "head" {
+				break scan
+			}
+		}
+	}
+
+	if preview.Title == "" {
+		preview.Title = title
+	}
+	if preview.Description == "" {
+		preview.Description = description
+	}
+	return preview, resolveURL(pageURL, imageURL)
+}
+
+// metaAttrs returns the property/name and content of a <meta> tag.
+func metaAttrs(tok html.Token) (key, content string) {
+	for _, a := range tok.Attr {
+		switch strings.ToLower(a.Key) {
+		case "property", "name":
+			key = strings.ToLower(a.Val)
+		case "content":
+			content = strings.TrimSpace(a.Val)
+		}
+	}
+	return key, content
+}
+
+func resolveURL(base, ref string) string {
+	if ref == "" {
+		return ""
+	}
+	b, err := url.Parse(base)
+	if err != nil {
+		return ""
+	}
+	r, err := url.Parse(ref)
+	if err != nil {
+		return ""
+	}
+	return b.ResolveReference(r).String()
+}
+
+// makeThumbnail scales an image down to linkPreviewThumbSize and encodes it as JPEG.
+func makeThumbnail(data []byte) ([]byte, int, int, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, 0, 0, fmt.Errorf("empty image")
+	}
+	if cfg.Width > linkPreviewMaxPixels/cfg.Height {
+		return nil, 0, 0, fmt.Errorf("image too large (%dx%d)", cfg.Width, cfg.Height)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w == 0 || h == 0 {
+		return nil, 0, 0, fmt.Errorf("empty image")
+	}
+	if w > linkPreviewThumbSize || h > linkPreviewThumbSize {
+		if w >= h {
+			w, h = linkPreviewThumbSize, max(1, h*linkPreviewThumbSize/w)
+		} else {
+			w, h = max(1, w*linkPreviewThumbSize/h), linkPreviewThumbSize
+		}
+	}
+
+	// Nearest-neighbour is plenty for a thumbnail this small
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			dst.Set(x, y, src.At(b.Min.X+x*b.Dx()/w, b.Min.Y+y*b.Dy()/h))
+		}
+	}
+
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, dst, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, 0, 0, err
+	}
+	return out.Bytes(), w, h, nil
+}
@@ -0,0 +1,83 @@
+package whatsapp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestFirstURL(t *testing.T) {
+	cases := map[string]string{
+		"see https://example.com/a?b=1.":          "https://example.com/a?b=1",
+		"(http://example.com) and https://x.test": "http://example.com",
+		"no links here":                           "",
+	}
+	for text, want := range cases {
+		if got := FirstURL(text); got != want {
+			t.Errorf("FirstURL(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestParseOpenGraph(t *testing.T) {
+	page := []byte(`<!doctype html><html><head>
+		<title>Fallback title</title>
+		<meta property="og:title" content="Release 2.0">
+		<meta name="description" content="Plain description">
+		<meta property="og:image" content="/img/cover.png">
+	</head><body><meta property="og:title" content="ignored"></body></html>`)
+
+	preview, imageURL := parseOpenGraph(page, "https://example.com/blog/post")
+	if preview.Title != "Release 2.0" {
+		t.Errorf("expected og:title, got %q", preview.Title)
+	}
+	if preview.Description != "Plain description" {
+		t.Errorf("expected description fallback, got %q", preview.Description)
+	}
+	if imageURL != "https://example.com/img/cover.png" {
+		t.Errorf("expected resolved image URL, got %q", imageURL)
+	}
+
+	preview, _ = parseOpenGraph([]byte(`<html><head><title> Just a title </title></head></html>`), "https://example.com")
+	if preview.Title != "Just a title" {
+		t.Errorf("expected <title> fallback, got %q", preview.Title)
+	}
+}
+
+func TestMakeThumbnailScalesDown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 800, 400))); err != nil {
+		t.Fatal(err)
+	}
+
+	thumb, w, h, err := makeThumbnail(buf.Bytes())
+	if err != nil {
+		t.Fatalf("makeThumbnail: %v", err)
+	}
+	if w != linkPreviewThumbSize || h != linkPreviewThumbSize/2 {
+		t.Fatalf("expected %dx%d, got %dx%d", linkPreviewThumbSize, linkPreviewThumbSize/2, w, h)
+	}
+	if _, format, err := image.DecodeConfig(bytes.NewReader(thumb)); err != nil || format != "jpeg" {
+		t.Fatalf("expected a JPEG thumbnail, got %q (%v)", format, err)
+	}
+}
+
+func TestMakeThumbnailRejectsHugeImages(t *testing.T) {
+	// A PNG header is enough for DecodeConfig to read the dimensions
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	binary.BigEndian.PutUint32(data[16:], 100000) // IHDR width
+	binary.BigEndian.PutUint32(data[20:], 100000) // IHDR height
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+
+	if _, _, _, err := makeThumbnail(data); err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Fatalf("expected the image to be rejected as too large, got %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package whatsapp
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// lockFileName is the advisory lock guarding the session store.
+	lockFileName = "session.lock"
+
+	// lockWaitTimeout is how long New waits for another instance to release the lock.
+	// The kernel drops flock locks when a process exits, so a crashed instance can't
+	// leave a stale lock behind; the wait only covers short overlaps between commands.
+	lockWaitTimeout = 3 * time.Second
+	lockPollDelay   = 100 * time.Millisecond
+)
+
+// ErrSessionLocked is returned when another process holds the session lock.
+var ErrSessionLocked = errors.New("another instance is using this session")
+
+// sessionLock is an exclusive flock on the store directory's lock file.
+type sessionLock struct {
+	file *os.File
+}
+
+// acquireSessionLock takes the session lock in baseDir, waiting up to timeout.
+func acquireSessionLock(baseDir string, timeout time.Duration) (*sessionLock, error) {
+	path := filepath.Join(baseDir, lockFileName)
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, syscall.EWOULDBLOCK) {
+			_ = f.Close()
+			return nil, fmt.Errorf("failed to lock session: %w", err)
+		}
+		if time.Now().After(deadline) {
+			holder := readLockHolder(f)
+			_ = f.Close()
+			if holder != "" {
+				return nil, fmt.Errorf("%w (pid %s)", ErrSessionLocked, holder)
+			}
+			return nil, ErrSessionLocked
+		}
+		time.Sleep(lockPollDelay)
+	}
+
+	// Record our PID so a blocked instance can say who holds the lock.
+	if err := f.Truncate(0); err == nil {
+		_, _ = f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+
+	return &sessionLock{file: f}, nil
+}
+
+// LockSession takes the session lock without creating a client, for commands
+// that change the session files directly. The returned func releases it.
+func LockSession(baseDir string) (func(), error) {
+	l, err := acquireSessionLock(baseDir, lockWaitTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return l.release, nil
+}
+
+// release drops the lock. It is safe to call more than once.
+func (l *sessionLock) release() {
+	if l == nil || l.file == nil {
+		return
+	}
+	_ = syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN)
+	_ = l.file.Close()
+	l.file = nil
+}
+
+func readLockHolder(f *os.File) string {
+	buf := make([]byte, 32)
+	n, _ := f.ReadAt(buf, 0)
+	return strings.TrimSpace(string(buf[:n]))
+}
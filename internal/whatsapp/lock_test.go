@@ -0,0 +1,64 @@
+package whatsapp
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSessionLockIsExclusive(t *testing.T) {
+	dir := t.TempDir()
+
+	first, err := acquireSessionLock(dir, 0)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+
+	if _, err := acquireSessionLock(dir, 2*lockPollDelay); !errors.Is(err, ErrSessionLocked) {
+		t.Fatalf("expected ErrSessionLocked, got %v", err)
+	}
+
+	first.release()
+	first.release()
+
+	second, err := acquireSessionLock(dir, 0)
+	if err != nil {
+		t.Fatalf("acquire after release: %v", err)
+	}
+	second.release()
+}
+
+func TestSessionLockWaitsForRelease(t *testing.T) {
+	dir := t.TempDir()
+
+	first, err := acquireSessionLock(dir, 0)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	time.AfterFunc(2*lockPollDelay, first.release)
+
+	second, err := acquireSessionLock(dir, time.Second)
+	if err != nil {
+		t.Fatalf("expected lock after release, got %v", err)
+	}
+	second.release()
+}
+
+func TestLockSessionBlocksClients(t *testing.T) {
+	dir := t.TempDir()
+
+	unlock, err := LockSession(dir)
+	if err != nil {
+		t.Fatalf("lock: %v", err)
+	}
+	if _, err := acquireSessionLock(dir, 0); !errors.Is(err, ErrSessionLocked) {
+		t.Fatalf("expected ErrSessionLocked, got %v", err)
+	}
+
+	unlock()
+	l, err := acquireSessionLock(dir, 0)
+	if err != nil {
+		t.Fatalf("acquire after unlock: %v", err)
+	}
+	l.release()
+}
@@ -5,18 +5,126 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	_ "image/png" // Register the PNG decoder for compressImage
+	"io"
 	"math"
 	"math/rand"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
+	"strings"
 )
 
 var ffmpegBin = "ffmpeg"
 
+// ErrFFmpegNotFound is returned when audio needs converting but ffmpeg isn't installed.
+var ErrFFmpegNotFound = errors.New("ffmpeg not found in PATH; install it to send audio")
+
+// FFmpegPath returns where ffmpeg is installed, or ErrFFmpegNotFound.
+func FFmpegPath() (string, error) {
+	path, err := exec.LookPath(ffmpegBin)
+	if err != nil {
+		return "", ErrFFmpegNotFound
+	}
+	return path, nil
+}
+
+// OpusOptions are the libopus settings ffmpeg converts audio with.
+type OpusOptions struct {
+	Bitrate     int    // Bits per second
+	SampleRate  int    // Hz
+	Application string // voip, audio or lowdelay
+}
+
+// MediaOptions controls how PrepareMedia converts and sends a file.
+type MediaOptions struct {
+	Voice        bool // Send audio as a push-to-talk voice note
+	Opus         OpusOptions
+	ImageQuality int    // Re-encode JPEG/PNG images as JPEG at this quality; 0 sends them as-is
+	ImageMaxDim  int    // Longest side of re-encoded images; 0 for no limit
+	DocTitle     string // Name a document is shown and saved as; the file's own name if empty
+	AsDocument   bool   // Send any file as a document, with its original bytes
+}
+
+// Opus encoder defaults: voice notes are tuned for speech, other audio for music.
+var (
+	voiceOpus = OpusOptions{Bitrate: 32000, SampleRate: 24000, Application: "voip"}
+	musicOpus = OpusOptions{Bitrate: 128000, SampleRate: 48000, Application: "audio"}
+)
+
+// DefaultMediaOptions returns the options for sending audio as a voice note
+// or as a regular attachment. WHATSAPP_OPUS_BITRATE (e.g. 64k),
+// WHATSAPP_OPUS_SAMPLERATE and WHATSAPP_OPUS_APPLICATION override the
+// encoder defaults.
+func DefaultMediaOptions(voice bool) (MediaOptions, error) {
+	opts := MediaOptions{Voice: voice, Opus: musicOpus}
+	if voice {
+		opts.Opus = voiceOpus
+	}
+
+	if v := os.Getenv("WHATSAPP_OPUS_BITRATE"); v != "" {
+		bitrate, err := parseBitrate(v)
+		if err != nil {
+			return opts, fmt.Errorf("invalid WHATSAPP_OPUS_BITRATE: %w", err)
+		}
+		opts.Opus.Bitrate = bitrate
+	}
+	if v := os.Getenv("WHATSAPP_OPUS_SAMPLERATE"); v != "" {
+		rate, err := strconv.Atoi(v)
+		if err != nil {
+			return opts, fmt.Errorf("invalid WHATSAPP_OPUS_SAMPLERATE: %q is not a number", v)
+		}
+		opts.Opus.SampleRate = rate
+	}
+	if v := os.Getenv("WHATSAPP_OPUS_APPLICATION"); v != "" {
+		opts.Opus.Application = v
+	}
+
+	return opts, opts.Opus.Validate()
+}
+
+// Validate checks the options are within what libopus supports.
+func (o OpusOptions) Validate() error {
+	if o.Bitrate < 6000 || o.Bitrate > 510000 {
+		return fmt.Errorf("opus bitrate must be between 6k and 510k, got %d", o.Bitrate)
+	}
+	switch o.SampleRate {
+	case 8000, 12000, 16000, 24000, 48000:
+	default:
+		return fmt.Errorf("opus sample rate must be 8000, 12000, 16000, 24000 or 48000, got %d", o.SampleRate)
+	}
+	switch o.Application {
+	case "voip", "audio", "lowdelay":
+	default:
+		return fmt.Errorf("opus application must be voip, audio or lowdelay, got %q", o.Application)
+	}
+	return nil
+}
+
+// parseBitrate parses bits per second, with an optional k suffix for kilobits.
+func parseBitrate(s string) (int, error) {
+	num, mult := strings.ToLower(strings.TrimSpace(s)), 1
+	if strings.HasSuffix(num, "k") {
+		num, mult = strings.TrimSuffix(num, "k"), 1000
+	}
+	n, err := strconv.Atoi(num)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a bitrate", s)
+	}
+	return n * mult, nil
+}
+
 // ConvertToOpusOgg converts an input audio file to .ogg (Opus) using ffmpeg.
 // Returns the output path (temporary next to input) without removing the input.
-func ConvertToOpusOgg(inputPath string) (string, error) {
+func ConvertToOpusOgg(inputPath string, opts OpusOptions) (string, error) {
+	if err := opts.Validate(); err != nil {
+		return "", err
+	}
 	if _, err := os.Stat(inputPath); err != nil {
 		return "", fmt.Errorf("input missing: %w", err)
 	}
@@ -24,25 +132,176 @@ func ConvertToOpusOgg(inputPath string) (string, error) {
 	base := filepath.Base(inputPath)
 	out := filepath.Join(dir, base+".converted.ogg")
 	cmd := exec.Command(ffmpegBin,
+		"-hide_banner",
+		"-loglevel", "error",
 		"-i", inputPath,
 		"-c:a", "libopus",
-		"-b:a", "32k",
-		"-ar", "24000",
-		"-application", "voip",
+		"-b:a", strconv.Itoa(opts.Bitrate),
+		"-ar", strconv.Itoa(opts.SampleRate),
+		"-application", opts.Application,
 		"-vbr", "on",
 		"-compression_level", "10",
 		"-frame_duration", "60",
 		"-y",
 		out,
 	)
-	cmd.Stdout = nil
-	cmd.Stderr = nil
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
 	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("ffmpeg failed: %w: %s", err, msg)
+		}
 		return "", fmt.Errorf("ffmpeg failed: %w", err)
 	}
 	return out, nil
 }
 
+// compressSkipBytes is the size below which images that already fit the
+// dimension limit are sent as-is; re-encoding them saves little.
+const compressSkipBytes = 200 << 10
+
+// compressImage re-encodes a JPEG or PNG as a JPEG at quality (1-100), scaled
+// down so neither side exceeds maxDim (0 for no limit). It returns the path of
+// a temporary file, or path itself when the image is already small or
+// re-encoding wouldn't make it smaller.
+func compressImage(path string, quality, maxDim int) (string, error) {
+	if quality < 1 || quality > 100 {
+		return "", fmt.Errorf("quality must be between 1 and 100, got %d", quality)
+	}
+	if maxDim < 0 {
+		return "", fmt.Errorf("max dimension must not be negative, got %d", maxDim)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return "", fmt.Errorf("failed to read image: %w", err)
+	}
+	fits := maxDim == 0 || (cfg.Width <= maxDim && cfg.Height <= maxDim)
+	if fits && info.Size() < compressSkipBytes {
+		return path, nil
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	out, err := os.CreateTemp("", "whatsapp-*.jpg")
+	if err != nil {
+		return "", err
+	}
+	if err := jpeg.Encode(out, scaleToFit(img, maxDim), &jpeg.Options{Quality: quality}); err != nil {
+		_ = out.Close()
+		_ = os.Remove(out.Name())
+		return "", fmt.Errorf("failed to encode image: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		_ = os.Remove(out.Name())
+		return "", err
+	}
+
+	if compressed, err := os.Stat(out.Name()); err != nil || compressed.Size() >= info.Size() {
+		_ = os.Remove(out.Name())
+		return path, nil
+	}
+	return out.Name(), nil
+}
+
+// groupPhotoSize is the side of the square JPEG that group photos are sent as.
+const groupPhotoSize = 640
+
+// encodeGroupPhoto reads a JPEG or PNG and returns it as the square JPEG
+// WhatsApp expects for a group photo, cropped to its centre and scaled down to
+// groupPhotoSize.
+func encodeGroupPhoto(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	b := img.Bounds()
+	side := min(b.Dx(), b.Dy())
+	if side == 0 {
+		return nil, fmt.Errorf("image is empty")
+	}
+	crop := image.Rect(0, 0, side, side).Add(b.Min).Add(image.Pt((b.Dx()-side)/2, (b.Dy()-side)/2))
+	if sub, ok := img.(interface {
+		SubImage(image.Rectangle) image.Image
+	}); ok {
+		img = sub.SubImage(crop)
+	}
+
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, scaleToFit(img, groupPhotoSize), &jpeg.Options{Quality: 85}); err != nil {
+		return nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// scaleToFit returns img flattened onto white (JPEG has no transparency) and,
+// if either side exceeds maxDim, shrunk to fit with each pixel averaging the
+// source pixels it covers.
+func scaleToFit(img image.Image, maxDim int) *image.RGBA {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if maxDim > 0 && (w > maxDim || h > maxDim) {
+		if w >= h {
+			w, h = maxDim, max(1, h*maxDim/w)
+		} else {
+			w, h = max(1, w*maxDim/h), maxDim
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	if w == b.Dx() && h == b.Dy() {
+		draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+		draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Over)
+		return dst
+	}
+
+	for y := 0; y < h; y++ {
+		y0, y1 := b.Min.Y+y*b.Dy()/h, b.Min.Y+(y+1)*b.Dy()/h
+		for x := 0; x < w; x++ {
+			x0, x1 := b.Min.X+x*b.Dx()/w, b.Min.X+(x+1)*b.Dx()/w
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r, g, bl, a, n = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca), n+1
+				}
+			}
+			// Colours are alpha-premultiplied, so adding the missing
+			// coverage as white composites the pixel over a white background
+			white := 0xffff - a/n
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8((r/n + white) >> 8),
+				G: uint8((g/n + white) >> 8),
+				B: uint8((bl/n + white) >> 8),
+				A: 0xff,
+			})
+		}
+	}
+	return dst
+}
+
 // AnalyzeOggOpus computes duration seconds and a 64-byte waveform for WhatsApp PTT.
 func AnalyzeOggOpus(data []byte) (uint32, []byte, error) {
 	if len(data) < 4 || string(data[0:4]) != "OggS" {
@@ -103,7 +362,73 @@ func AnalyzeOggOpus(data []byte) (uint32, []byte, error) {
 	if duration > 300 {
 		duration = 300
 	}
-	return duration, placeholderWaveform(duration), nil
+	return duration, audioWaveform(data, duration), nil
+}
+
+// waveformSampleRate is the rate audio is decoded at for the waveform; the
+// envelope only needs loudness, not fidelity.
+const waveformSampleRate = 8000
+
+// audioWaveform returns the voice-note waveform of Ogg/Opus data, decoded with
+// ffmpeg. If it can't be decoded it falls back to a placeholder, so sending
+// never fails because of the waveform.
+func audioWaveform(data []byte, duration uint32) []byte {
+	samples, err := decodePCM(data)
+	if err != nil || len(samples) == 0 {
+		return placeholderWaveform(duration)
+	}
+	return pcmWaveform(samples)
+}
+
+// decodePCM decodes audio to mono 16-bit samples by piping it through ffmpeg.
+func decodePCM(data []byte) ([]int16, error) {
+	cmd := exec.Command(ffmpegBin,
+		"-i", "pipe:0",
+		"-f", "s16le",
+		"-ac", "1",
+		"-ar", fmt.Sprint(waveformSampleRate),
+		"pipe:1",
+	)
+	cmd.Stdin = bytes.NewReader(data)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg failed: %w", err)
+	}
+
+	samples := make([]int16, len(out)/2)
+	for i := range samples {
+		samples[i] = int16(binary.LittleEndian.Uint16(out[i*2:]))
+	}
+	return samples, nil
+}
+
+// pcmWaveform computes WhatsApp's 64-value waveform: the RMS loudness of 64
+// equal slices of the audio, scaled so the loudest slice is 100.
+func pcmWaveform(samples []int16) []byte {
+	const n = 64
+	levels := make([]float64, n)
+	var peak float64
+	for i := range levels {
+		slice := samples[i*len(samples)/n : (i+1)*len(samples)/n]
+		if len(slice) == 0 {
+			continue
+		}
+		var sum float64
+		for _, s := range slice {
+			sum += float64(s) * float64(s)
+		}
+		levels[i] = math.Sqrt(sum / float64(len(slice)))
+		peak = math.Max(peak, levels[i])
+	}
+
+	wf := make([]byte, n)
+	if peak == 0 {
+		return wf
+	}
+	for i, level := range levels {
+		wf[i] = byte(math.Round(level / peak * 100))
+	}
+	return wf
 }
 
 func placeholderWaveform(duration uint32) []byte {
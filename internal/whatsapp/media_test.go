@@ -0,0 +1,239 @@
+package whatsapp
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestPCMWaveformFollowsLoudness(t *testing.T) {
+	// Silent first half, then a tone at full and half volume
+	samples := make([]int16, 6400)
+	for i := 3200; i < len(samples); i++ {
+		amp := 20000.0
+		if i >= 4800 {
+			amp = 10000
+		}
+		samples[i] = int16(amp * math.Sin(float64(i)*0.3))
+	}
+
+	wf := pcmWaveform(samples)
+	if len(wf) != 64 {
+		t.Fatalf("expected 64 values, got %d", len(wf))
+	}
+	if wf[0] != 0 || wf[31] != 0 {
+		t.Fatalf("expected silence to be 0, got %v", wf)
+	}
+	if wf[40] < 95 || wf[60] < 45 || wf[60] > 55 {
+		t.Fatalf("expected loud slices near 100 and half-volume ones near 50, got %v", wf)
+	}
+	if got := pcmWaveform(make([]int16, 100)); !bytes.Equal(got, make([]byte, 64)) {
+		t.Fatalf("expected all-silent audio to give a flat waveform, got %v", got)
+	}
+}
+
+func TestAudioWaveformFallsBackWithoutFFmpeg(t *testing.T) {
+	orig := ffmpegBin
+	ffmpegBin = filepath.Join(t.TempDir(), "no-ffmpeg")
+	t.Cleanup(func() { ffmpegBin = orig })
+
+	if got, want := audioWaveform([]byte("OggS not really"), 5), placeholderWaveform(5); !bytes.Equal(got, want) {
+		t.Fatalf("expected the placeholder waveform, got %v", got)
+	}
+}
+
+func TestDefaultMediaOptions(t *testing.T) {
+	t.Setenv("WHATSAPP_OPUS_BITRATE", "")
+	t.Setenv("WHATSAPP_OPUS_SAMPLERATE", "")
+	t.Setenv("WHATSAPP_OPUS_APPLICATION", "")
+
+	voice, err := DefaultMediaOptions(true)
+	if err != nil || !voice.Voice || voice.Opus != voiceOpus {
+		t.Fatalf("expected speech-tuned voice note options, got %+v (%v)", voice, err)
+	}
+	music, err := DefaultMediaOptions(false)
+	if err != nil || music.Voice || music.Opus.Application != "audio" {
+		t.Fatalf("expected music options, got %+v (%v)", music, err)
+	}
+
+	t.Setenv("WHATSAPP_OPUS_BITRATE", "64k")
+	t.Setenv("WHATSAPP_OPUS_SAMPLERATE", "48000")
+	opts, err := DefaultMediaOptions(true)
+	if err != nil || opts.Opus.Bitrate != 64000 || opts.Opus.SampleRate != 48000 || opts.Opus.Application != "voip" {
+		t.Fatalf("expected env overrides to apply, got %+v (%v)", opts, err)
+	}
+
+	for _, bitrate := range []string{"0", "-32k", "fast", "900k"} {
+		t.Setenv("WHATSAPP_OPUS_BITRATE", bitrate)
+		if _, err := DefaultMediaOptions(true); err == nil {
+			t.Errorf("expected bitrate %q to be rejected", bitrate)
+		}
+	}
+	t.Setenv("WHATSAPP_OPUS_BITRATE", "")
+
+	t.Setenv("WHATSAPP_OPUS_SAMPLERATE", "44100")
+	if _, err := DefaultMediaOptions(false); err == nil {
+		t.Error("expected a sample rate opus doesn't support to be rejected")
+	}
+	t.Setenv("WHATSAPP_OPUS_SAMPLERATE", "")
+
+	t.Setenv("WHATSAPP_OPUS_APPLICATION", "music")
+	if _, err := DefaultMediaOptions(false); err == nil {
+		t.Error("expected an unknown application to be rejected")
+	}
+}
+
+func TestCompressImage(t *testing.T) {
+	dir := t.TempDir()
+	writePNG := func(name string, w, h int) string {
+		img := image.NewNRGBA(image.Rect(0, 0, w, h))
+		r := rand.New(rand.NewSource(1))
+		for i := range img.Pix {
+			img.Pix[i] = byte(r.Intn(256))
+		}
+		path := filepath.Join(dir, name)
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = f.Close() }()
+		if err := png.Encode(f, img); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	small := writePNG("small.png", 20, 10)
+	if got, err := compressImage(small, 80, 1600); err != nil || got != small {
+		t.Fatalf("expected a small image to be left as-is, got %q (%v)", got, err)
+	}
+
+	large := writePNG("large.png", 600, 300)
+	got, err := compressImage(large, 60, 200)
+	if err != nil {
+		t.Fatalf("compressImage: %v", err)
+	}
+	if got == large {
+		t.Fatal("expected a large image to be re-encoded")
+	}
+	t.Cleanup(func() { _ = os.Remove(got) })
+
+	f, err := os.Open(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	cfg, format, err := image.DecodeConfig(f)
+	if err != nil || format != "jpeg" || cfg.Width != 200 || cfg.Height != 100 {
+		t.Fatalf("expected a 200x100 jpeg, got %s %dx%d (%v)", format, cfg.Width, cfg.Height, err)
+	}
+
+	if _, err := compressImage(large, 0, 200); err == nil {
+		t.Error("expected quality 0 to be rejected")
+	}
+}
+
+func TestEncodeGroupPhotoCropsSquare(t *testing.T) {
+	// A wide image, red on the left and blue on the right of a green centre
+	img := image.NewNRGBA(image.Rect(0, 0, 900, 300))
+	for y := 0; y < 300; y++ {
+		for x := 0; x < 900; x++ {
+			c := color.NRGBA{G: 255, A: 255}
+			if x < 300 {
+				c = color.NRGBA{R: 255, A: 255}
+			} else if x >= 600 {
+				c = color.NRGBA{B: 255, A: 255}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "wide.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	_ = f.Close()
+
+	data, err := encodeGroupPhoto(path)
+	if err != nil {
+		t.Fatalf("encodeGroupPhoto: %v", err)
+	}
+	photo, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil || format != "jpeg" {
+		t.Fatalf("expected a jpeg, got %q (%v)", format, err)
+	}
+	if b := photo.Bounds(); b.Dx() != 300 || b.Dy() != 300 {
+		t.Fatalf("expected the 300x300 centre, got %v", b)
+	}
+	for _, x := range []int{5, 295} {
+		if r, g, _, _ := photo.At(x, 150).RGBA(); g>>8 < 200 || r>>8 > 60 {
+			t.Fatalf("expected only the green centre at x=%d, got %v", x, photo.At(x, 150))
+		}
+	}
+}
+
+func TestScaleToFitFlattensTransparency(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+	img.SetNRGBA(0, 0, color.NRGBA{R: 255, A: 255})
+
+	full := scaleToFit(img, 0)
+	if full.Bounds().Dx() != 4 || full.RGBAAt(0, 0) != (color.RGBA{R: 255, A: 255}) || full.RGBAAt(3, 1) != (color.RGBA{R: 255, G: 255, B: 255, A: 255}) {
+		t.Fatalf("expected opaque red kept and transparency turned white, got %v and %v", full.RGBAAt(0, 0), full.RGBAAt(3, 1))
+	}
+
+	half := scaleToFit(img, 2)
+	if b := half.Bounds(); b.Dx() != 2 || b.Dy() != 1 {
+		t.Fatalf("expected 2x1, got %v", b)
+	}
+	// One red pixel averaged with three transparent (white) ones
+	if c := half.RGBAAt(0, 0); c.R != 255 || c.G < 180 || c.G > 200 {
+		t.Fatalf("expected a light pink average, got %v", c)
+	}
+}
+
+func TestFFmpegMissing(t *testing.T) {
+	orig := ffmpegBin
+	ffmpegBin = filepath.Join(t.TempDir(), "no-ffmpeg")
+	t.Cleanup(func() { ffmpegBin = orig })
+
+	if _, err := FFmpegPath(); !errors.Is(err, ErrFFmpegNotFound) {
+		t.Fatalf("expected ErrFFmpegNotFound, got %v", err)
+	}
+}
+
+func TestConvertToOpusOggReportsFFmpegError(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as a stand-in for ffmpeg")
+	}
+	dir := t.TempDir()
+	fake := filepath.Join(dir, "ffmpeg")
+	script := "#!/bin/sh\necho 'song.mp3: Invalid data found when processing input' >&2\nexit 1\n"
+	if err := os.WriteFile(fake, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	input := filepath.Join(dir, "song.mp3")
+	if err := os.WriteFile(input, []byte("not audio"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	orig := ffmpegBin
+	ffmpegBin = fake
+	t.Cleanup(func() { ffmpegBin = orig })
+
+	_, err := ConvertToOpusOgg(input, voiceOpus)
+	if err == nil || !strings.Contains(err.Error(), "Invalid data found") {
+		t.Fatalf("expected ffmpeg's stderr in the error, got %v", err)
+	}
+}
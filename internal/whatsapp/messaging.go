@@ -2,16 +2,25 @@ package whatsapp
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"io/fs"
+	"math"
 	"os"
 	"path/filepath"
 	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
 
 	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/appstate"
 	waCommon "go.mau.fi/whatsmeow/proto/waCommon"
 	waE2E "go.mau.fi/whatsmeow/proto/waE2E"
 	"go.mau.fi/whatsmeow/types"
+
+	"github.com/eddmann/whatsapp-cli/internal/store"
 )
 
 // SendMessageResult represents the result of sending a WhatsApp message.
@@ -29,37 +38,99 @@ type DownloadMediaResult struct {
 	MediaType string
 	Filename  string
 	Path      string
+	Cached    bool // The file from an earlier download was reused
+}
+
+// SendOptions contains optional settings for outgoing text messages.
+type SendOptions struct {
+	ReplyTo     string       // Message ID to quote
+	QuoteFrom   string       // Chat the quoted message is in, if not the destination
+	Mentions    []string     // JIDs to mention (the text should contain matching @tokens)
+	LinkPreview *LinkPreview // Preview of a URL in the text
+	LowPriority bool         // Ask for no notification; unsupported, see CheckSendOptions
+	MessageID   string       // ID to send with (empty = generate one)
+}
+
+// ErrLowPriorityUnsupported is returned for silent/low-priority sends. WhatsApp
+// has no message flag that suppresses the recipient's notification; whether it
+// buzzes depends only on their mute settings. (Edits of an already-sent message
+// don't notify again, but there is no way to send a new message quietly.)
+var ErrLowPriorityUnsupported = errors.New("WhatsApp does not support silent or low-priority sends; recipients are notified unless they have muted the chat")
+
+// CheckSendOptions reports whether opts can be honoured, so callers can fail
+// before connecting instead of sending with an option silently ignored.
+func CheckSendOptions(opts SendOptions) error {
+	if opts.LowPriority {
+		return ErrLowPriorityUnsupported
+	}
+	return nil
 }
 
 // SendText sends a text message to a JID or phone number string (without +) or group JID.
-// If replyToMessageID is provided, sends as a quoted reply.
-func (c *Client) SendText(recipient, text, replyToMessageID string) (*SendMessageResult, error) {
+// If opts.ReplyTo is provided, sends as a quoted reply.
+func (c *Client) SendText(recipient, text string, opts SendOptions) (*SendMessageResult, error) {
+	if err := CheckSendOptions(opts); err != nil {
+		return &SendMessageResult{Success: false, Message: err.Error()}, err
+	}
 	if !c.WA.IsConnected() {
-		return &SendMessageResult{Success: false, Message: "not connected"}, fmt.Errorf("not connected")
+		return &SendMessageResult{Success: false, Message: "not connected"}, ErrNotConnected
 	}
 
-	jid, err := parseRecipient(recipient)
+	jid, err := c.resolveRecipient(recipient)
 	if err != nil {
 		return &SendMessageResult{Success: false, Message: "invalid recipient"}, err
 	}
 
 	msg := &waE2E.Message{}
 
-	if replyToMessageID != "" {
-		quotedMsg, err := c.buildQuotedMessage(replyToMessageID, jid.String())
+	var ctxInfo *waE2E.ContextInfo
+	if opts.ReplyTo != "" {
+		source := jid.String()
+		if opts.QuoteFrom != "" {
+			sourceJID, err := c.resolveRecipient(opts.QuoteFrom)
+			if err != nil {
+				return &SendMessageResult{Success: false, Message: "invalid quote source"}, err
+			}
+			source = sourceJID.String()
+		}
+		ctxInfo, err = c.buildQuotedMessage(opts.ReplyTo, source, jid.String())
 		if err != nil {
 			return &SendMessageResult{Success: false, Message: "failed to build quote"}, err
 		}
+	}
+
+	if len(opts.Mentions) > 0 {
+		if ctxInfo == nil {
+			ctxInfo = &waE2E.ContextInfo{}
+		}
+		ctxInfo.MentionedJID = opts.Mentions
+	}
+	ctxInfo = withExpiration(ctxInfo, c.ephemeralExpiration(jid.String(), recipient))
 
+	if ctxInfo != nil || opts.LinkPreview != nil {
 		msg.ExtendedTextMessage = &waE2E.ExtendedTextMessage{
 			Text:        protoString(text),
-			ContextInfo: quotedMsg,
+			ContextInfo: ctxInfo,
+		}
+		if p := opts.LinkPreview; p != nil {
+			ext := msg.ExtendedTextMessage
+			ext.MatchedText = protoString(p.URL)
+			ext.Title = protoString(p.Title)
+			ext.PreviewType = waE2E.ExtendedTextMessage_NONE.Enum()
+			if p.Description != "" {
+				ext.Description = protoString(p.Description)
+			}
+			if len(p.Thumbnail) > 0 {
+				ext.JPEGThumbnail = p.Thumbnail
+				ext.ThumbnailWidth = protoUint32(uint32(p.ThumbWidth))
+				ext.ThumbnailHeight = protoUint32(uint32(p.ThumbHeight))
+			}
 		}
 	} else {
 		msg.Conversation = protoString(text)
 	}
 
-	resp, err := c.WA.SendMessage(context.Background(), jid, msg)
+	resp, err := c.WA.SendMessage(context.Background(), jid, msg, whatsmeow.SendRequestExtra{ID: opts.MessageID})
 	if err != nil {
 		return &SendMessageResult{Success: false, Message: err.Error()}, err
 	}
@@ -73,45 +144,138 @@ func (c *Client) SendText(recipient, text, replyToMessageID string) (*SendMessag
 	}, nil
 }
 
-// SendMedia sends an image/video/document/audio with optional caption.
-// If replyToMessageID is provided, sends as a quoted reply.
-func (c *Client) SendMedia(recipient, path, caption, replyToMessageID string) (*SendMessageResult, error) {
+// PreparedMedia is a file uploaded to WhatsApp's media servers, ready to be
+// sent to any number of recipients without uploading it again.
+type PreparedMedia struct {
+	Type     whatsmeow.MediaType
+	Mimetype string
+	FileName string
+	Title    string // Document title shown in the chat
+	Upload   whatsmeow.UploadResponse
+	Seconds  uint32 // Audio duration
+	Waveform []byte // Audio waveform
+	Voice    bool   // Audio is a push-to-talk voice note
+}
+
+// PrepareMedia reads and uploads a file for SendPreparedMedia. Audio that
+// isn't Ogg/Opus is converted first with opts.Opus, and is sent as a voice
+// note when opts.Voice is set. JPEG and PNG images are re-encoded when
+// opts.ImageQuality is set. With opts.AsDocument the file is sent untouched as
+// a document, whatever its type.
+func (c *Client) PrepareMedia(path string, opts MediaOptions) (*PreparedMedia, error) {
 	if !c.WA.IsConnected() {
-		return &SendMessageResult{Success: false, Message: "not connected"}, fmt.Errorf("not connected")
+		return nil, ErrNotConnected
 	}
 
-	jid, err := parseRecipient(recipient)
-	if err != nil {
-		return &SendMessageResult{Success: false, Message: "invalid recipient"}, err
+	mediaType, mime := classify(path)
+	if opts.AsDocument {
+		mediaType = whatsmeow.MediaDocument
+	}
+	media := &PreparedMedia{Type: mediaType, Mimetype: mime, FileName: filepath.Base(path)}
+	media.Title = media.FileName
+	if opts.DocTitle != "" {
+		if mediaType != whatsmeow.MediaDocument {
+			return nil, fmt.Errorf("a document title only applies to documents, not %s", filepath.Base(path))
+		}
+		media.Title = opts.DocTitle
+		media.FileName = documentFileName(opts.DocTitle, path)
+	}
+	media.Voice = mediaType == whatsmeow.MediaAudio && opts.Voice
+
+	if mediaType == whatsmeow.MediaAudio && !isOgg(path) {
+		if _, err := FFmpegPath(); err != nil {
+			return nil, err
+		}
+		cpath, err := ConvertToOpusOgg(path, opts.Opus)
+		if err != nil {
+			return nil, fmt.Errorf("conversion failed: %w", err)
+		}
+		defer func() { _ = os.Remove(cpath) }()
+		path = cpath
+		media.Mimetype = "audio/ogg; codecs=opus"
+	}
+
+	if opts.ImageQuality > 0 && (mime == "image/jpeg" || mime == "image/png") {
+		cpath, err := compressImage(path, opts.ImageQuality, opts.ImageMaxDim)
+		if err != nil {
+			return nil, fmt.Errorf("compression failed: %w", err)
+		}
+		if cpath != path {
+			defer func() { _ = os.Remove(cpath) }()
+			path = cpath
+			media.Mimetype = "image/jpeg"
+		}
 	}
 
 	b, err := os.ReadFile(path)
 	if err != nil {
-		return &SendMessageResult{Success: false, Message: "read error"}, err
+		return nil, err
+	}
+	if mediaType == whatsmeow.MediaAudio {
+		media.Seconds, media.Waveform, _ = AnalyzeOggOpus(b)
 	}
 
-	mediaType, mime := classify(path)
-	up, err := c.WA.Upload(context.Background(), b, mediaType)
+	if media.Upload, err = c.WA.Upload(context.Background(), b, mediaType); err != nil {
+		return nil, fmt.Errorf("upload failed: %w", err)
+	}
+	return media, nil
+}
+
+// documentFileName is the file name a document titled title downloads as,
+// keeping path's extension if the title has none.
+func documentFileName(title, path string) string {
+	name := sanitizeFilename(title)
+	if filepath.Ext(name) == "" {
+		name += filepath.Ext(path)
+	}
+	return name
+}
+
+// SendMedia sends an image/video/document/audio with optional caption.
+// If replyToMessageID is provided, sends as a quoted reply.
+func (c *Client) SendMedia(recipient, path, caption, replyToMessageID string, opts MediaOptions) (*SendMessageResult, error) {
+	if !c.WA.IsConnected() {
+		return &SendMessageResult{Success: false, Message: "not connected"}, ErrNotConnected
+	}
+	if _, err := c.resolveRecipient(recipient); err != nil {
+		return &SendMessageResult{Success: false, Message: "invalid recipient"}, err
+	}
+
+	media, err := c.PrepareMedia(path, opts)
 	if err != nil {
-		return &SendMessageResult{Success: false, Message: "upload failed"}, err
+		return &SendMessageResult{Success: false, Message: err.Error()}, err
+	}
+	return c.SendPreparedMedia(recipient, media, caption, replyToMessageID)
+}
+
+// SendPreparedMedia sends media uploaded with PrepareMedia, with an optional
+// caption. If replyToMessageID is provided, sends as a quoted reply.
+func (c *Client) SendPreparedMedia(recipient string, media *PreparedMedia, caption, replyToMessageID string) (*SendMessageResult, error) {
+	if !c.WA.IsConnected() {
+		return &SendMessageResult{Success: false, Message: "not connected"}, ErrNotConnected
 	}
 
-	m := &waE2E.Message{}
-	base := filepath.Base(path)
+	jid, err := c.resolveRecipient(recipient)
+	if err != nil {
+		return &SendMessageResult{Success: false, Message: "invalid recipient"}, err
+	}
 
 	var quotedCtx *waE2E.ContextInfo
 	if replyToMessageID != "" {
-		quotedCtx, err = c.buildQuotedMessage(replyToMessageID, jid.String())
+		quotedCtx, err = c.buildQuotedMessage(replyToMessageID, jid.String(), jid.String())
 		if err != nil {
 			return &SendMessageResult{Success: false, Message: "failed to build quote"}, err
 		}
 	}
+	quotedCtx = withExpiration(quotedCtx, c.ephemeralExpiration(jid.String(), recipient))
 
-	switch mediaType {
+	m := &waE2E.Message{}
+	up := media.Upload
+	switch media.Type {
 	case whatsmeow.MediaImage:
 		m.ImageMessage = &waE2E.ImageMessage{
 			Caption:       protoString(caption),
-			Mimetype:      protoString(mime),
+			Mimetype:      protoString(media.Mimetype),
 			URL:           &up.URL,
 			DirectPath:    &up.DirectPath,
 			MediaKey:      up.MediaKey,
@@ -123,7 +287,7 @@ func (c *Client) SendMedia(recipient, path, caption, replyToMessageID string) (*
 	case whatsmeow.MediaVideo:
 		m.VideoMessage = &waE2E.VideoMessage{
 			Caption:       protoString(caption),
-			Mimetype:      protoString(mime),
+			Mimetype:      protoString(media.Mimetype),
 			URL:           &up.URL,
 			DirectPath:    &up.DirectPath,
 			MediaKey:      up.MediaKey,
@@ -134,9 +298,10 @@ func (c *Client) SendMedia(recipient, path, caption, replyToMessageID string) (*
 		}
 	case whatsmeow.MediaDocument:
 		m.DocumentMessage = &waE2E.DocumentMessage{
-			Title:         protoString(base),
+			Title:         protoString(media.Title),
+			FileName:      protoString(media.FileName),
 			Caption:       protoString(caption),
-			Mimetype:      protoString(mime),
+			Mimetype:      protoString(media.Mimetype),
 			URL:           &up.URL,
 			DirectPath:    &up.DirectPath,
 			MediaKey:      up.MediaKey,
@@ -146,83 +311,333 @@ func (c *Client) SendMedia(recipient, path, caption, replyToMessageID string) (*
 			ContextInfo:   quotedCtx,
 		}
 	case whatsmeow.MediaAudio:
-		if !isOgg(path) {
-			cpath, err := ConvertToOpusOgg(path)
-			if err != nil {
-				return &SendMessageResult{Success: false, Message: "conversion failed"}, err
-			}
-			defer func() { _ = os.Remove(cpath) }()
+		m.AudioMessage = &waE2E.AudioMessage{
+			Mimetype:      protoString(media.Mimetype),
+			URL:           &up.URL,
+			DirectPath:    &up.DirectPath,
+			MediaKey:      up.MediaKey,
+			FileEncSHA256: up.FileEncSHA256,
+			FileSHA256:    up.FileSHA256,
+			FileLength:    &up.FileLength,
+			Seconds:       protoUint32(media.Seconds),
+			PTT:           protoBool(media.Voice),
+			Waveform:      media.Waveform,
+			ContextInfo:   quotedCtx,
+		}
+	}
 
-			b2, err := os.ReadFile(cpath)
-			if err != nil {
-				return &SendMessageResult{Success: false, Message: "read converted"}, err
-			}
+	resp, err := c.WA.SendMessage(context.Background(), jid, m)
+	if err != nil {
+		return &SendMessageResult{Success: false, Message: err.Error()}, err
+	}
 
-			up2, err := c.WA.Upload(context.Background(), b2, whatsmeow.MediaAudio)
-			if err != nil {
-				return &SendMessageResult{Success: false, Message: "upload converted"}, err
-			}
+	return &SendMessageResult{
+		Success:   true,
+		Message:   fmt.Sprintf("sent media to %s", recipient),
+		MessageID: resp.ID,
+		ChatJID:   jid.String(),
+		Timestamp: resp.Timestamp.Format("2006-01-02T15:04:05Z07:00"),
+	}, nil
+}
 
-			dur, waveform, _ := AnalyzeOggOpus(b2)
-			m.AudioMessage = &waE2E.AudioMessage{
-				Mimetype:      protoString("audio/ogg; codecs=opus"),
-				URL:           &up2.URL,
-				DirectPath:    &up2.DirectPath,
-				MediaKey:      up2.MediaKey,
-				FileEncSHA256: up2.FileEncSHA256,
-				FileSHA256:    up2.FileSHA256,
-				FileLength:    &up2.FileLength,
-				Seconds:       protoUint32(dur),
-				PTT:           protoBool(true),
-				Waveform:      waveform,
-				ContextInfo:   quotedCtx,
-			}
-		} else {
-			dur, waveform, _ := AnalyzeOggOpus(b)
-			m.AudioMessage = &waE2E.AudioMessage{
-				Mimetype:      protoString(mime),
-				URL:           &up.URL,
-				DirectPath:    &up.DirectPath,
-				MediaKey:      up.MediaKey,
-				FileEncSHA256: up.FileEncSHA256,
-				FileSHA256:    up.FileSHA256,
-				FileLength:    &up.FileLength,
-				Seconds:       protoUint32(dur),
-				PTT:           protoBool(true),
-				Waveform:      waveform,
-				ContextInfo:   quotedCtx,
+// ValidateCoordinates reports whether lat and lon are a valid location.
+func ValidateCoordinates(lat, lon float64) error {
+	if math.IsNaN(lat) || lat < -90 || lat > 90 {
+		return fmt.Errorf("latitude %v out of range (-90 to 90)", lat)
+	}
+	if math.IsNaN(lon) || lon < -180 || lon > 180 {
+		return fmt.Errorf("longitude %v out of range (-180 to 180)", lon)
+	}
+	return nil
+}
+
+// SendLocation sends a location pin with an optional place name and address.
+// If replyToMessageID is provided, sends as a quoted reply.
+func (c *Client) SendLocation(recipient string, lat, lon float64, name, address, replyToMessageID string) (*SendMessageResult, error) {
+	if err := ValidateCoordinates(lat, lon); err != nil {
+		return &SendMessageResult{Success: false, Message: "invalid location"}, err
+	}
+	if !c.WA.IsConnected() {
+		return &SendMessageResult{Success: false, Message: "not connected"}, ErrNotConnected
+	}
+
+	jid, err := c.resolveRecipient(recipient)
+	if err != nil {
+		return &SendMessageResult{Success: false, Message: "invalid recipient"}, err
+	}
+
+	loc := &waE2E.LocationMessage{
+		DegreesLatitude:  &lat,
+		DegreesLongitude: &lon,
+	}
+	if name != "" {
+		loc.Name = protoString(name)
+	}
+	if address != "" {
+		loc.Address = protoString(address)
+	}
+	if replyToMessageID != "" {
+		loc.ContextInfo, err = c.buildQuotedMessage(replyToMessageID, jid.String(), jid.String())
+		if err != nil {
+			return &SendMessageResult{Success: false, Message: "failed to build quote"}, err
+		}
+	}
+	loc.ContextInfo = withExpiration(loc.ContextInfo, c.ephemeralExpiration(jid.String(), recipient))
+
+	resp, err := c.WA.SendMessage(context.Background(), jid, &waE2E.Message{LocationMessage: loc})
+	if err != nil {
+		return &SendMessageResult{Success: false, Message: err.Error()}, err
+	}
+
+	return &SendMessageResult{
+		Success:   true,
+		Message:   fmt.Sprintf("sent location to %s", recipient),
+		MessageID: resp.ID,
+		ChatJID:   jid.String(),
+		Timestamp: resp.Timestamp.Format("2006-01-02T15:04:05Z07:00"),
+	}, nil
+}
+
+// Button is a reply button on a message sent with SendButtons. The recipient
+// sees Text; ID comes back in their response.
+type Button struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+}
+
+const (
+	// maxButtons is the most reply buttons WhatsApp shows on a message.
+	maxButtons = 3
+	// maxButtonTextLength is the longest button label WhatsApp shows, in characters.
+	maxButtonTextLength = 20
+)
+
+// ErrButtonsNeedBusiness is returned by SendButtons when the session isn't a
+// WhatsApp Business account. WhatsApp drops button messages from personal
+// accounts, and even business accounts' buttons may not be shown on every
+// device, as WhatsApp is phasing them out in favour of its Business API.
+var ErrButtonsNeedBusiness = errors.New("button messages can only be sent from a WhatsApp Business account, and this session isn't one")
+
+// ParseButton parses a button given as "Text:id". Without an ID, the text is
+// used as the ID.
+func ParseButton(spec string) (Button, error) {
+	text, id := spec, spec
+	if i := strings.LastIndex(spec, ":"); i >= 0 {
+		text, id = spec[:i], spec[i+1:]
+	}
+	b := Button{ID: strings.TrimSpace(id), Text: strings.TrimSpace(text)}
+	if b.Text == "" || b.ID == "" {
+		return Button{}, fmt.Errorf("invalid button %q: use Text:id", spec)
+	}
+	if n := utf8.RuneCountInString(b.Text); n > maxButtonTextLength {
+		return Button{}, fmt.Errorf("button text %q is %d characters, WhatsApp allows %d", b.Text, n, maxButtonTextLength)
+	}
+	return b, nil
+}
+
+// ValidateButtons reports whether buttons can be sent together: there must be
+// one to maxButtons of them, with different IDs.
+func ValidateButtons(buttons []Button) error {
+	if len(buttons) == 0 || len(buttons) > maxButtons {
+		return fmt.Errorf("a message can have 1 to %d buttons, got %d", maxButtons, len(buttons))
+	}
+	for i, b := range buttons {
+		for _, other := range buttons[:i] {
+			if other.ID == b.ID {
+				return fmt.Errorf("button ID %q is used more than once", b.ID)
 			}
 		}
 	}
+	return nil
+}
 
-	resp, err := c.WA.SendMessage(context.Background(), jid, m)
+// SendButtons sends body with reply buttons, which needs a WhatsApp Business
+// account. Responses are stored like other messages, as the chosen button's
+// text and ID.
+func (c *Client) SendButtons(recipient, body string, buttons []Button) (*SendMessageResult, error) {
+	if strings.TrimSpace(body) == "" {
+		return &SendMessageResult{Success: false, Message: "empty message"}, fmt.Errorf("a button message needs text")
+	}
+	if err := ValidateButtons(buttons); err != nil {
+		return &SendMessageResult{Success: false, Message: "invalid buttons"}, err
+	}
+	if !c.WA.IsConnected() {
+		return &SendMessageResult{Success: false, Message: "not connected"}, ErrNotConnected
+	}
+	if c.WA.Store.BusinessName == "" {
+		return &SendMessageResult{Success: false, Message: "not a business account"}, ErrButtonsNeedBusiness
+	}
+
+	jid, err := c.resolveRecipient(recipient)
+	if err != nil {
+		return &SendMessageResult{Success: false, Message: "invalid recipient"}, err
+	}
+
+	msg := &waE2E.ButtonsMessage{
+		ContentText: protoString(body),
+		HeaderType:  waE2E.ButtonsMessage_EMPTY.Enum(),
+		ContextInfo: withExpiration(nil, c.ephemeralExpiration(jid.String(), recipient)),
+	}
+	for _, b := range buttons {
+		msg.Buttons = append(msg.Buttons, &waE2E.ButtonsMessage_Button{
+			ButtonID:   protoString(b.ID),
+			ButtonText: &waE2E.ButtonsMessage_Button_ButtonText{DisplayText: protoString(b.Text)},
+			Type:       waE2E.ButtonsMessage_Button_RESPONSE.Enum(),
+		})
+	}
+
+	resp, err := c.WA.SendMessage(context.Background(), jid, &waE2E.Message{ButtonsMessage: msg})
 	if err != nil {
 		return &SendMessageResult{Success: false, Message: err.Error()}, err
 	}
 
 	return &SendMessageResult{
 		Success:   true,
-		Message:   fmt.Sprintf("sent media to %s", recipient),
+		Message:   fmt.Sprintf("sent buttons to %s", recipient),
 		MessageID: resp.ID,
 		ChatJID:   jid.String(),
 		Timestamp: resp.Timestamp.Format("2006-01-02T15:04:05Z07:00"),
 	}, nil
 }
 
+// Chat presence states accepted by SendChatPresence.
+const (
+	PresenceComposing = "composing" // Typing...
+	PresenceRecording = "recording" // Recording audio...
+	PresencePaused    = "paused"    // Stopped typing or recording
+)
+
+// chatPresence maps a presence state to WhatsApp's chat state and media.
+func chatPresence(state string) (types.ChatPresence, types.ChatPresenceMedia, error) {
+	switch state {
+	case PresenceComposing:
+		return types.ChatPresenceComposing, types.ChatPresenceMediaText, nil
+	case PresenceRecording:
+		return types.ChatPresenceComposing, types.ChatPresenceMediaAudio, nil
+	case PresencePaused:
+		return types.ChatPresencePaused, types.ChatPresenceMediaText, nil
+	}
+	return "", "", fmt.Errorf("invalid presence %q (use composing, recording or paused)", state)
+}
+
+// ValidateChatPresence reports whether state can be sent with SendChatPresence.
+func ValidateChatPresence(state string) error {
+	_, _, err := chatPresence(state)
+	return err
+}
+
+// SendChatPresence shows a typing or recording indicator in a chat, or clears
+// it with paused.
+func (c *Client) SendChatPresence(chatJID, state string) error {
+	presence, media, err := chatPresence(state)
+	if err != nil {
+		return err
+	}
+	if !c.WA.IsConnected() {
+		return ErrNotConnected
+	}
+
+	jid, err := c.resolveRecipient(chatJID)
+	if err != nil {
+		return fmt.Errorf("invalid chat JID: %w", err)
+	}
+	return c.WA.SendChatPresence(context.Background(), jid, presence, media)
+}
+
+// SetOnline marks the account as online (available) or offline to contacts.
+// While online, the phone may hold back notifications for new messages.
+func (c *Client) SetOnline(online bool) error {
+	if !c.WA.IsConnected() {
+		return ErrNotConnected
+	}
+	state := types.PresenceUnavailable
+	if online {
+		state = types.PresenceAvailable
+	}
+	err := c.WA.SendPresence(context.Background(), state)
+	if errors.Is(err, whatsmeow.ErrNoPushName) {
+		return fmt.Errorf("WhatsApp hasn't sent your profile name yet, which presence needs; try again once a sync has finished: %w", err)
+	}
+	return err
+}
+
+// GroupMentions returns the JIDs of every member of a group except ourselves,
+// preferring the local participant cache and falling back to a live lookup.
+func (c *Client) GroupMentions(groupJID string) ([]string, error) {
+	jid, err := parseJID(groupJID)
+	if err != nil {
+		return nil, err
+	}
+	if jid.Server != types.GroupServer {
+		return nil, fmt.Errorf("%s is not a group JID", groupJID)
+	}
+
+	members, err := c.groupMembers(jid)
+	if err != nil {
+		return nil, err
+	}
+
+	var mentions []string
+	for _, m := range members {
+		if c.isOwnJID(m.JID) {
+			continue
+		}
+		mentions = append(mentions, m.JID)
+	}
+	return mentions, nil
+}
+
+// MentionsFromText resolves each @number token in text to the member of the
+// group with that phone number, preferring the local participant cache. Since
+// WhatsApp highlights a mention by the mentioned JID's user, tokens for members
+// known by their LID are rewritten to it. It returns the rewritten text, the
+// JIDs to mention and the numbers that matched no member.
+func (c *Client) MentionsFromText(groupJID, text string) (string, []string, []string, error) {
+	jid, err := parseJID(groupJID)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	if jid.Server != types.GroupServer {
+		return "", nil, nil, fmt.Errorf("%s is not a group JID", groupJID)
+	}
+
+	members, err := c.groupMembers(jid)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	text, mentions, unmatched := matchMentionTokens(text, members)
+	return text, mentions, unmatched, nil
+}
+
+// isOwnJID reports whether a JID refers to the logged-in account (phone or LID).
+func (c *Client) isOwnJID(jid string) bool {
+	if c.WA == nil || c.WA.Store == nil {
+		return false
+	}
+	parsed, err := parseJID(jid)
+	if err != nil {
+		return false
+	}
+	if c.WA.Store.ID != nil && parsed.User == c.WA.Store.ID.User {
+		return true
+	}
+	return !c.WA.Store.LID.IsEmpty() && parsed.User == c.WA.Store.LID.User
+}
+
 // ForwardMessage forwards a message to a recipient.
 func (c *Client) ForwardMessage(recipient, messageID, fromChatJID string) (*SendMessageResult, error) {
 	if !c.WA.IsConnected() {
-		return &SendMessageResult{Success: false, Message: "not connected"}, fmt.Errorf("not connected")
+		return &SendMessageResult{Success: false, Message: "not connected"}, ErrNotConnected
 	}
 
-	toJID, err := parseRecipient(recipient)
+	toJID, err := c.resolveRecipient(recipient)
 	if err != nil {
 		return &SendMessageResult{Success: false, Message: "invalid recipient"}, err
 	}
 
 	// Query original message content
 	var content, mediaType string
-	row := c.Store.Messages.QueryRow(`
+	row := c.Store.QueryRow(`
 		SELECT content, COALESCE(media_type, '') FROM messages WHERE id = ? AND chat_jid = ?
 	`, messageID, fromChatJID)
 	if err := row.Scan(&content, &mediaType); err != nil {
@@ -234,9 +649,7 @@ func (c *Client) ForwardMessage(recipient, messageID, fromChatJID string) (*Send
 		return &SendMessageResult{Success: false, Message: "media forwarding not supported"}, fmt.Errorf("media forwarding not yet supported")
 	}
 
-	msg := &waE2E.Message{
-		Conversation: protoString(content),
-	}
+	msg := forwardedText(content, withExpiration(nil, c.ephemeralExpiration(toJID.String(), recipient)))
 
 	resp, err := c.WA.SendMessage(context.Background(), toJID, msg)
 	if err != nil {
@@ -252,34 +665,49 @@ func (c *Client) ForwardMessage(recipient, messageID, fromChatJID string) (*Send
 	}, nil
 }
 
+// forwardedText builds the message ForwardMessage sends, as extended text
+// when it needs a ContextInfo.
+func forwardedText(content string, ctxInfo *waE2E.ContextInfo) *waE2E.Message {
+	if ctxInfo == nil {
+		return &waE2E.Message{Conversation: protoString(content)}
+	}
+	return &waE2E.Message{ExtendedTextMessage: &waE2E.ExtendedTextMessage{
+		Text:        protoString(content),
+		ContextInfo: ctxInfo,
+	}}
+}
+
 // SendReaction sends a reaction to a message.
 func (c *Client) SendReaction(chatJID, messageID, emoji string, remove bool) (*SendMessageResult, error) {
 	if !c.WA.IsConnected() {
-		return &SendMessageResult{Success: false, Message: "not connected"}, fmt.Errorf("not connected")
+		return &SendMessageResult{Success: false, Message: "not connected"}, ErrNotConnected
 	}
 
-	jid, err := parseRecipient(chatJID)
+	jid, err := c.resolveRecipient(chatJID)
 	if err != nil {
 		return &SendMessageResult{Success: false, Message: "invalid chat JID"}, err
 	}
 
-	// Get the sender of the original message for the reaction target
-	var sender string
+	// Get the sender of the original message for the reaction target. The
+	// message may be stored under the LID chat or its phone JID.
+	var sender, storedChat string
 	var isFromMe bool
-	row := c.Store.Messages.QueryRow(`SELECT sender, is_from_me FROM messages WHERE id = ? AND chat_jid = ?`, messageID, chatJID)
-	if err := row.Scan(&sender, &isFromMe); err != nil {
+	row := c.Store.QueryRow(`SELECT sender, is_from_me, chat_jid FROM messages WHERE id = ? AND chat_jid IN (?, ?)`, messageID, chatJID, jid.String())
+	if err := row.Scan(&sender, &isFromMe, &storedChat); err != nil {
 		return &SendMessageResult{Success: false, Message: "message not found"}, err
 	}
 
-	reactionText := emoji
-	if remove {
-		reactionText = ""
+	reactionText := ""
+	if !remove {
+		if reactionText, err = ValidateReaction(emoji); err != nil {
+			return &SendMessageResult{Success: false, Message: "invalid reaction"}, err
+		}
 	}
 
 	msg := &waE2E.Message{
 		ReactionMessage: &waE2E.ReactionMessage{
 			Key: &waCommon.MessageKey{
-				RemoteJID: protoString(chatJID),
+				RemoteJID: protoString(jid.String()),
 				FromMe:    protoBool(isFromMe),
 				ID:        protoString(messageID),
 			},
@@ -292,6 +720,12 @@ func (c *Client) SendReaction(chatJID, messageID, emoji string, remove bool) (*S
 		return &SendMessageResult{Success: false, Message: err.Error()}, err
 	}
 
+	if own := c.WA.Store.ID; own != nil {
+		if err := c.Store.SetReaction(storedChat, messageID, own.User, reactionText, resp.Timestamp); err != nil {
+			c.Logger.Warn("failed to store reaction", "id", messageID, "chat_jid", storedChat, "err", err)
+		}
+	}
+
 	action := "reacted"
 	if remove {
 		action = "removed reaction"
@@ -306,17 +740,164 @@ func (c *Client) SendReaction(chatJID, messageID, emoji string, remove bool) (*S
 	}, nil
 }
 
-// DownloadMedia looks up media from DB and downloads via whatsmeow.
-func (c *Client) DownloadMedia(messageID, chatJID string) (*DownloadMediaResult, error) {
-	var mediaType, filename, url string
+// RevokeMessage deletes a message we sent for everyone in the chat and removes
+// it from the local store.
+func (c *Client) RevokeMessage(chatJID, messageID string) (*SendMessageResult, error) {
+	if !c.WA.IsConnected() {
+		return &SendMessageResult{Success: false, Message: "not connected"}, ErrNotConnected
+	}
+
+	jid, err := c.resolveRecipient(chatJID)
+	if err != nil {
+		return &SendMessageResult{Success: false, Message: "invalid chat JID"}, err
+	}
+
+	storedChat, err := c.ownMessageChat(messageID, chatJID, jid.String())
+	if err != nil {
+		return &SendMessageResult{Success: false, Message: "cannot revoke"}, err
+	}
+
+	resp, err := c.WA.SendMessage(context.Background(), jid, c.WA.BuildRevoke(jid, types.EmptyJID, messageID))
+	if err != nil {
+		return &SendMessageResult{Success: false, Message: err.Error()}, err
+	}
+
+	if err := c.Store.DeleteMessage(storedChat, messageID); err != nil {
+		return &SendMessageResult{Success: false, Message: "revoked but not removed locally"}, fmt.Errorf("failed to delete revoked message: %w", err)
+	}
+
+	return &SendMessageResult{
+		Success:   true,
+		Message:   fmt.Sprintf("revoked message %s", messageID),
+		MessageID: resp.ID,
+		ChatJID:   jid.String(),
+		Timestamp: resp.Timestamp.Format("2006-01-02T15:04:05Z07:00"),
+	}, nil
+}
+
+// EditMessage replaces the text of a message we sent and updates the stored
+// copy. Only text messages within the edit window (c.EditWindow) can be edited.
+func (c *Client) EditMessage(chatJID, messageID, newText string) (*SendMessageResult, error) {
+	if !c.WA.IsConnected() {
+		return &SendMessageResult{Success: false, Message: "not connected"}, ErrNotConnected
+	}
+
+	jid, err := c.resolveRecipient(chatJID)
+	if err != nil {
+		return &SendMessageResult{Success: false, Message: "invalid chat JID"}, err
+	}
+
+	storedChat, err := c.editableMessageChat(messageID, time.Now(), chatJID, jid.String())
+	if err != nil {
+		return &SendMessageResult{Success: false, Message: "cannot edit"}, err
+	}
+
+	msg := c.WA.BuildEdit(jid, messageID, &waE2E.Message{Conversation: protoString(newText)})
+	resp, err := c.WA.SendMessage(context.Background(), jid, msg)
+	if err != nil {
+		return &SendMessageResult{Success: false, Message: err.Error()}, err
+	}
+
+	if err := c.Store.UpdateMessageContent(storedChat, messageID, newText); err != nil {
+		return &SendMessageResult{Success: false, Message: "edited but not updated locally"}, fmt.Errorf("failed to update edited message: %w", err)
+	}
+
+	return &SendMessageResult{
+		Success:   true,
+		Message:   fmt.Sprintf("edited message %s", messageID),
+		MessageID: resp.ID,
+		ChatJID:   jid.String(),
+		Timestamp: resp.Timestamp.Format("2006-01-02T15:04:05Z07:00"),
+	}, nil
+}
+
+// ownMessageChat returns which of chatJIDs a stored message is in, after
+// checking that it exists and that we sent it. The message may be stored under
+// the LID chat or its phone JID.
+func (c *Client) ownMessageChat(messageID string, chatJIDs ...string) (string, error) {
+	args := []any{messageID}
+	for _, jid := range chatJIDs {
+		args = append(args, jid)
+	}
+
+	var chatJID string
+	var isFromMe bool
+	row := c.Store.QueryRow(`SELECT chat_jid, is_from_me FROM messages WHERE id = ? AND chat_jid IN (`+store.Placeholders(len(chatJIDs))+`)`, args...)
+	if err := row.Scan(&chatJID, &isFromMe); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("message %s not found in %s", messageID, chatJIDs[0])
+		}
+		return "", err
+	}
+	if !isFromMe {
+		return "", fmt.Errorf("message %s was not sent by you; only your own messages can be changed", messageID)
+	}
+	return chatJID, nil
+}
+
+// StarMessage stars or unstars a message via an app state patch and records it locally.
+func (c *Client) StarMessage(chatJID, messageID string, starred bool) error {
+	if !c.WA.IsConnected() {
+		return ErrNotConnected
+	}
+
+	chat, err := parseJID(chatJID)
+	if err != nil {
+		return fmt.Errorf("invalid chat JID: %w", err)
+	}
+
+	var sender string
+	var isFromMe bool
+	row := c.Store.QueryRow(`SELECT sender, is_from_me FROM messages WHERE id = ? AND chat_jid = ?`, messageID, chatJID)
+	if err := row.Scan(&sender, &isFromMe); err != nil {
+		return fmt.Errorf("message not found: %w", err)
+	}
+
+	// The sender is only meaningful for other people's messages in groups
+	senderJID := chat
+	if chat.Server == types.GroupServer && !isFromMe {
+		if senderJID, err = parseJID(c.resolveParticipantJIDForGroup(sender, chatJID)); err != nil {
+			return fmt.Errorf("invalid sender JID: %w", err)
+		}
+	}
+
+	patch := appstate.BuildStar(chat, senderJID, messageID, isFromMe, starred)
+	if err := c.WA.SendAppState(context.Background(), patch); err != nil {
+		return err
+	}
+
+	return c.Store.SetMessageStarred(chatJID, messageID, starred)
+}
+
+// DownloadMedia looks up media from DB and downloads via whatsmeow. The file
+// is saved under its stored filename, or the name nameTemplate renders (see
+// ParseFilenameTemplate) when it isn't nil, and its path is recorded as the
+// message's local_path. If that file is still on disk it is returned without
+// downloading again, unless force is set.
+func (c *Client) DownloadMedia(messageID, chatJID string, nameTemplate *template.Template, force bool) (*DownloadMediaResult, error) {
+	var mediaType, filename, url, sender, senderName, localPath string
 	var mediaKey, fileSHA256, fileEncSHA256 []byte
 	var fileLength uint64
+	var ts time.Time
 
-	row := c.Store.Messages.QueryRow("SELECT media_type, filename, url, media_key, file_sha256, file_enc_sha256, file_length FROM messages WHERE id = ? AND chat_jid = ?", messageID, chatJID)
-	if err := row.Scan(&mediaType, &filename, &url, &mediaKey, &fileSHA256, &fileEncSHA256, &fileLength); err != nil {
+	row := c.Store.QueryRow(`SELECT media_type, filename, url, media_key, file_sha256, file_enc_sha256, file_length,
+		sender, COALESCE(sender_name, ''), timestamp, COALESCE(local_path, '') FROM messages WHERE id = ? AND chat_jid = ?`, messageID, chatJID)
+	if err := row.Scan(&mediaType, &filename, &url, &mediaKey, &fileSHA256, &fileEncSHA256, &fileLength, &sender, &senderName, &ts, &localPath); err != nil {
 		return &DownloadMediaResult{Success: false}, err
 	}
 
+	if localPath != "" && !force {
+		if info, err := os.Stat(localPath); err == nil && info.Mode().IsRegular() {
+			return &DownloadMediaResult{
+				Success:   true,
+				MediaType: mediaType,
+				Filename:  filepath.Base(localPath),
+				Path:      localPath,
+				Cached:    true,
+			}, nil
+		}
+	}
+
 	if mediaType == "" || url == "" || len(mediaKey) == 0 || len(fileSHA256) == 0 || len(fileEncSHA256) == 0 || fileLength == 0 {
 		return &DownloadMediaResult{Success: false}, fmt.Errorf("incomplete media info")
 	}
@@ -337,17 +918,27 @@ func (c *Client) DownloadMedia(messageID, chatJID string) (*DownloadMediaResult,
 		return &DownloadMediaResult{Success: false}, err
 	}
 
-	outDir := filepath.Join(c.BaseDir, strings.ReplaceAll(chatJID, ":", "_"))
+	outDir := filepath.Join(c.BaseDir, MediaDirName(chatJID))
 	if err := os.MkdirAll(outDir, 0755); err != nil {
 		return &DownloadMediaResult{Success: false}, err
 	}
 
+	if nameTemplate != nil {
+		fields := mediaFilenameFields(messageID, chatJID, sender, senderName, mediaType, filename, ts)
+		if filename, err = renderFilename(nameTemplate, fields); err != nil {
+			return &DownloadMediaResult{Success: false}, err
+		}
+	}
+
 	out := filepath.Join(outDir, filename)
 	if err := os.WriteFile(out, data, fs.FileMode(0644)); err != nil {
 		return &DownloadMediaResult{Success: false}, err
 	}
 
 	abs, _ := filepath.Abs(out)
+	if err := c.Store.SetMediaLocalPath(chatJID, messageID, abs); err != nil {
+		c.Logger.Warn("failed to record media path", "id", messageID, "chat_jid", chatJID, "err", err)
+	}
 	return &DownloadMediaResult{
 		Success:   true,
 		MediaType: mediaType,
@@ -356,6 +947,12 @@ func (c *Client) DownloadMedia(messageID, chatJID string) (*DownloadMediaResult,
 	}, nil
 }
 
+// MediaDirName returns the name of the folder under the store directory that
+// media downloaded from chatJID is saved in.
+func MediaDirName(chatJID string) string {
+	return strings.ReplaceAll(chatJID, ":", "_")
+}
+
 // protoString returns a pointer to a string (for protobuf).
 func protoString(s string) *string { return &s }
 
@@ -366,36 +963,85 @@ func protoBool(b bool) *bool { return &b }
 func protoUint32(u uint32) *uint32 { return &u }
 
 // parseRecipient parses a recipient string (phone or JID) into a types.JID.
+// A device suffix (as in "12345:7@lid" from group listings) is dropped, since
+// messages go to the account rather than one device.
 func parseRecipient(recipient string) (types.JID, error) {
+	recipient = strings.TrimSpace(recipient)
 	if strings.Contains(recipient, "@") {
-		return types.ParseJID(recipient)
+		jid, err := types.ParseJID(recipient)
+		if err != nil {
+			return jid, err
+		}
+		if jid.User == "" {
+			return jid, fmt.Errorf("invalid JID %q: missing user", recipient)
+		}
+		if jid.Server == types.DefaultUserServer || jid.Server == types.HiddenUserServer {
+			jid = jid.ToNonAD()
+		}
+		return jid, nil
 	}
-	return types.JID{User: recipient, Server: "s.whatsapp.net"}, nil
+	return types.JID{User: strings.TrimPrefix(recipient, "+"), Server: "s.whatsapp.net"}, nil
 }
 
-// buildQuotedMessage fetches the message being replied to and constructs a ContextInfo.
-func (c *Client) buildQuotedMessage(messageID, chatJID string) (*waE2E.ContextInfo, error) {
+// resolveRecipient parses a recipient like parseRecipient and maps a LID JID to
+// its phone JID when the mapping is known, from lid_mappings or the session
+// store. Chats are stored under the phone JID, and some sends (such as to
+// contacts we have no session with yet) fail when addressed to the LID. An
+// unmapped LID is returned unchanged, which WhatsApp also accepts.
+func (c *Client) resolveRecipient(recipient string) (types.JID, error) {
+	jid, err := parseRecipient(recipient)
+	if err != nil || jid.Server != types.HiddenUserServer {
+		return jid, err
+	}
+
+	if c.Store != nil {
+		if phone, _, found := c.Store.GetLIDMapping(jid.User); found && phone != "" {
+			return parseRecipient(phone)
+		}
+	}
+	if c.WA != nil && c.WA.Store != nil && c.WA.Store.LIDs != nil {
+		if pn, err := c.WA.Store.LIDs.GetPNForLID(context.Background(), jid); err == nil && !pn.IsEmpty() {
+			return pn.ToNonAD(), nil
+		}
+	}
+	return jid, nil
+}
+
+// buildQuotedMessage fetches the message being replied to from sourceChatJID
+// and constructs a ContextInfo for a message sent to destChatJID. When the two
+// differ the quote carries its source chat, and the quoted sender is always set
+// so WhatsApp can attribute it outside its own chat.
+func (c *Client) buildQuotedMessage(messageID, sourceChatJID, destChatJID string) (*waE2E.ContextInfo, error) {
 	var sender, content string
 	var isFromMe bool
 	var mediaType *string
 
-	row := c.Store.Messages.QueryRow(`
+	row := c.Store.QueryRow(`
 		SELECT sender, content, is_from_me, media_type
 		FROM messages
 		WHERE id = ? AND chat_jid = ?
-	`, messageID, chatJID)
+	`, messageID, sourceChatJID)
 
 	err := row.Scan(&sender, &content, &isFromMe, &mediaType)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) && sourceChatJID != destChatJID {
+			return nil, fmt.Errorf("message %s not found in %s", messageID, sourceChatJID)
+		}
 		return nil, fmt.Errorf("failed to find quoted message: %w", err)
 	}
 
+	crossChat := sourceChatJID != destChatJID
+
 	participantJID := ""
-	if strings.HasSuffix(chatJID, "@g.us") {
-		resolved := c.resolveParticipantJIDForGroup(sender, chatJID)
-		if resolved != "" {
-			participantJID = resolved
+	switch {
+	case strings.HasSuffix(sourceChatJID, "@g.us"):
+		participantJID = c.resolveParticipantJIDForGroup(sender, sourceChatJID)
+	case crossChat && isFromMe:
+		if c.WA.Store.ID != nil {
+			participantJID = c.WA.Store.ID.ToNonAD().String()
 		}
+	case crossChat:
+		participantJID = sourceChatJID
 	}
 
 	quotedMsg := &waE2E.Message{}
@@ -413,6 +1059,9 @@ func (c *Client) buildQuotedMessage(messageID, chatJID string) (*waE2E.ContextIn
 	if participantJID != "" {
 		ctx.Participant = protoString(participantJID)
 	}
+	if crossChat {
+		ctx.RemoteJID = protoString(sourceChatJID)
+	}
 
 	return ctx, nil
 }
@@ -462,7 +1111,7 @@ func (c *Client) resolveParticipantJIDForGroup(sender, groupJID string) string {
 		// Not found in LID mappings. Fetch group info to populate them.
 		groupParsed, err := types.ParseJID(groupJID)
 		if err == nil {
-			if info, err := c.WA.GetGroupInfo(context.Background(), groupParsed); err == nil {
+			if info, err := c.GetGroupInfo(context.Background(), groupParsed); err == nil {
 				for _, p := range info.Participants {
 					if !p.LID.IsEmpty() {
 						if !p.PhoneNumber.IsEmpty() {
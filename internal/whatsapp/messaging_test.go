@@ -0,0 +1,254 @@
+package whatsapp
+
+import (
+	"errors"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+
+	"github.com/eddmann/whatsapp-cli/internal/store"
+)
+
+func TestForwardedTextCarriesExpiration(t *testing.T) {
+	if msg := forwardedText("hi", withExpiration(nil, 0)); msg.GetConversation() != "hi" || msg.ExtendedTextMessage != nil {
+		t.Fatalf("expected plain text without a timer, got %v", msg)
+	}
+
+	msg := forwardedText("hi", withExpiration(nil, 86400))
+	ext := msg.GetExtendedTextMessage()
+	if ext.GetText() != "hi" || ext.GetContextInfo().GetExpiration() != 86400 || msg.Conversation != nil {
+		t.Fatalf("expected extended text with the chat's timer, got %v", msg)
+	}
+}
+
+func TestParseRecipient(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"447700900000", "447700900000@s.whatsapp.net", false},
+		{"+447700900000", "447700900000@s.whatsapp.net", false},
+		{"12345@lid", "12345@lid", false},
+		{"12345:7@lid", "12345@lid", false},
+		{"447700900000:3@s.whatsapp.net", "447700900000@s.whatsapp.net", false},
+		{"123456789-987654321@g.us", "123456789-987654321@g.us", false},
+		{"@lid", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseRecipient(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("parseRecipient(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+		}
+		if err == nil && got.String() != tt.want {
+			t.Errorf("parseRecipient(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestResolveRecipientMapsLID(t *testing.T) {
+	db, err := store.Open(filepath.Join(t.TempDir(), "messages.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.CloseQuietly()
+	if err := db.StoreLIDMapping("12345", "447700900000", "Alice"); err != nil {
+		t.Fatalf("store mapping: %v", err)
+	}
+
+	c := &Client{Store: db}
+	got, err := c.resolveRecipient("12345@lid")
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if got.String() != "447700900000@s.whatsapp.net" {
+		t.Fatalf("expected mapped phone JID, got %s", got)
+	}
+
+	// Unmapped LIDs are sent to as-is
+	got, err = c.resolveRecipient("67890@lid")
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if got.String() != "67890@lid" {
+		t.Fatalf("expected unmapped LID unchanged, got %s", got)
+	}
+}
+
+func TestBuildQuotedMessageFromAnotherChat(t *testing.T) {
+	db, err := store.Open(filepath.Join(t.TempDir(), "messages.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.CloseQuietly()
+
+	source, dest := "111@s.whatsapp.net", "222@s.whatsapp.net"
+	if _, err := db.Messages.Exec(`INSERT INTO chats (jid, name) VALUES (?, 'Source')`, source); err != nil {
+		t.Fatalf("insert chat: %v", err)
+	}
+	if _, err := db.Messages.Exec(`INSERT INTO messages (id, chat_jid, sender, content, timestamp, is_from_me) VALUES ('Q1', ?, '111', 'lunch at 1?', CURRENT_TIMESTAMP, 0)`, source); err != nil {
+		t.Fatalf("insert message: %v", err)
+	}
+
+	c := &Client{Store: db}
+	ctx, err := c.buildQuotedMessage("Q1", source, dest)
+	if err != nil {
+		t.Fatalf("build quote: %v", err)
+	}
+	if ctx.GetRemoteJID() != source || ctx.GetParticipant() != source {
+		t.Fatalf("expected quote attributed to %s, got remote %q participant %q", source, ctx.GetRemoteJID(), ctx.GetParticipant())
+	}
+	if ctx.GetStanzaID() != "Q1" || ctx.GetQuotedMessage().GetConversation() != "lunch at 1?" {
+		t.Fatalf("unexpected quote %+v", ctx)
+	}
+
+	// Quoting within the same chat doesn't name a source
+	ctx, err = c.buildQuotedMessage("Q1", source, source)
+	if err != nil {
+		t.Fatalf("build quote: %v", err)
+	}
+	if ctx.RemoteJID != nil || ctx.Participant != nil {
+		t.Fatalf("expected no remote JID or participant for a same-chat quote, got %+v", ctx)
+	}
+
+	if _, err := c.buildQuotedMessage("Q1", dest, source); err == nil {
+		t.Fatal("expected an error for a message missing from the source chat")
+	}
+}
+
+func TestParseButton(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Button
+		wantErr bool
+	}{
+		{spec: "Yes:id1", want: Button{ID: "id1", Text: "Yes"}},
+		{spec: "Time: 10:30:slot1", want: Button{ID: "slot1", Text: "Time: 10:30"}},
+		{spec: "Maybe", want: Button{ID: "Maybe", Text: "Maybe"}},
+		{spec: ":id1", wantErr: true},
+		{spec: "Yes:", wantErr: true},
+		{spec: "A label that is far too long:id", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseButton(tt.spec)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseButton(%q) = %+v, %v; want %+v (error %v)", tt.spec, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	if err := ValidateButtons([]Button{{ID: "a", Text: "A"}, {ID: "a", Text: "B"}}); err == nil {
+		t.Error("expected repeated button IDs to be rejected")
+	}
+	if err := ValidateButtons(make([]Button, 4)); err == nil {
+		t.Error("expected more than three buttons to be rejected")
+	}
+}
+
+func TestValidateCoordinates(t *testing.T) {
+	tests := []struct {
+		lat, lon float64
+		ok       bool
+	}{
+		{51.5, -0.12, true},
+		{-90, 180, true},
+		{90.1, 0, false},
+		{0, -180.5, false},
+		{math.NaN(), 0, false},
+	}
+	for _, tt := range tests {
+		err := ValidateCoordinates(tt.lat, tt.lon)
+		if (err == nil) != tt.ok {
+			t.Errorf("ValidateCoordinates(%v, %v) = %v, want ok=%v", tt.lat, tt.lon, err, tt.ok)
+		}
+	}
+}
+
+func TestChatPresence(t *testing.T) {
+	tests := []struct {
+		state    string
+		presence types.ChatPresence
+		media    types.ChatPresenceMedia
+		ok       bool
+	}{
+		{PresenceComposing, types.ChatPresenceComposing, types.ChatPresenceMediaText, true},
+		{PresenceRecording, types.ChatPresenceComposing, types.ChatPresenceMediaAudio, true},
+		{PresencePaused, types.ChatPresencePaused, types.ChatPresenceMediaText, true},
+		{"typing", "", "", false},
+	}
+	for _, tt := range tests {
+		presence, media, err := chatPresence(tt.state)
+		if (err == nil) != tt.ok || presence != tt.presence || media != tt.media {
+			t.Errorf("chatPresence(%q) = %q, %q, %v", tt.state, presence, media, err)
+		}
+	}
+}
+
+func TestOwnMessageChat(t *testing.T) {
+	db, err := store.Open(filepath.Join(t.TempDir(), "messages.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.CloseQuietly()
+
+	phone, lid := "12345@s.whatsapp.net", "999@lid"
+	if _, err := db.Exec(`INSERT INTO chats (jid, name) VALUES (?, 'Alice')`, phone); err != nil {
+		t.Fatalf("insert chat: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO messages (id, chat_jid, sender, content, timestamp, is_from_me) VALUES
+		('mine', ?, 'me', 'hi', CURRENT_TIMESTAMP, 1),
+		('theirs', ?, '12345', 'hello', CURRENT_TIMESTAMP, 0)`, phone, phone); err != nil {
+		t.Fatalf("insert messages: %v", err)
+	}
+
+	c := &Client{Store: db}
+	if chat, err := c.ownMessageChat("mine", lid, phone); err != nil || chat != phone {
+		t.Fatalf("expected own message in %s, got %q, %v", phone, chat, err)
+	}
+	if _, err := c.ownMessageChat("theirs", phone); err == nil || !strings.Contains(err.Error(), "not sent by you") {
+		t.Fatalf("expected a not-sent-by-you error, got %v", err)
+	}
+	if _, err := c.ownMessageChat("missing", phone); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected a not-found error, got %v", err)
+	}
+
+	if err := db.DeleteMessage(phone, "mine"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := c.ownMessageChat("mine", phone); err == nil {
+		t.Fatal("expected the deleted message to be gone")
+	}
+}
+
+func TestSetOnlineNeedsConnection(t *testing.T) {
+	c := &Client{WA: &whatsmeow.Client{}}
+	for _, online := range []bool{true, false} {
+		if err := c.SetOnline(online); !errors.Is(err, ErrNotConnected) {
+			t.Fatalf("SetOnline(%v): expected ErrNotConnected, got %v", online, err)
+		}
+	}
+}
+
+func TestLowPrioritySendFailsBeforeConnecting(t *testing.T) {
+	if err := CheckSendOptions(SendOptions{}); err != nil {
+		t.Fatalf("plain send rejected: %v", err)
+	}
+	if err := CheckSendOptions(SendOptions{LowPriority: true}); !errors.Is(err, ErrLowPriorityUnsupported) {
+		t.Fatalf("expected ErrLowPriorityUnsupported, got %v", err)
+	}
+
+	// The capability check runs before the connection check, so a disconnected
+	// client still reports the unsupported option rather than ErrNotConnected
+	c := &Client{WA: &whatsmeow.Client{}}
+	result, err := c.SendText("123", "hello", SendOptions{LowPriority: true})
+	if !errors.Is(err, ErrLowPriorityUnsupported) {
+		t.Fatalf("expected ErrLowPriorityUnsupported, got %v", err)
+	}
+	if result == nil || result.Success || result.Message != ErrLowPriorityUnsupported.Error() {
+		t.Fatalf("unexpected result %+v", result)
+	}
+}
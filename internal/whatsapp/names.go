@@ -0,0 +1,63 @@
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+
+	"go.mau.fi/whatsmeow/types"
+
+	"github.com/eddmann/whatsapp-cli/internal/store"
+)
+
+// ResolveName reports what each name source returns for a JID and which one
+// wins, in the order sender names are resolved: the LID mapping, then the
+// stored chat name, then resolvePreferredName's group subject or contact
+// names, then the number. Local aliases aren't shown as names, so they are
+// listed but never win.
+func (c *Client) ResolveName(jid string) (*store.NameResolution, error) {
+	parsed, err := parseRecipient(jid)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JID: %w", err)
+	}
+	res := &store.NameResolution{JID: parsed.String()}
+
+	// Chats are stored under the phone JID when the LID's phone is known
+	chatJID := parsed
+	switch parsed.Server {
+	case types.HiddenUserServer:
+		if phone, name, found := c.Store.GetLIDMapping(parsed.User); found {
+			res.LIDName = name
+			if phone != "" {
+				chatJID = types.NewJID(phone, types.DefaultUserServer)
+			}
+		}
+	case types.DefaultUserServer:
+		if _, name, found := c.Store.GetLIDMappingByPhone(parsed.User); found {
+			res.LIDName = name
+		}
+	}
+	res.ChatName = c.Store.GetChatName(chatJID.String())
+
+	ctx := context.Background()
+	if parsed.Server == types.GroupServer {
+		if c.WA.IsConnected() {
+			if info, err := c.GetGroupInfo(ctx, parsed); err == nil {
+				res.GroupName = info.Name
+			}
+		}
+	} else if contact, err := c.WA.Store.Contacts.GetContact(ctx, chatJID); err == nil {
+		res.FullName = contact.FullName
+		res.BusinessName = contact.BusinessName
+		res.PushName = contact.PushName
+	}
+
+	switch {
+	case res.LIDName != "":
+		res.Resolved, res.Source = res.LIDName, "lid_name"
+	case res.ChatName != "":
+		res.Resolved, res.Source = res.ChatName, "chat_name"
+	default:
+		res.Resolved, res.Source = c.preferredName(chatJID)
+	}
+	return res, nil
+}
@@ -0,0 +1,74 @@
+package whatsapp
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"go.mau.fi/whatsmeow"
+	wastore "go.mau.fi/whatsmeow/store"
+	"go.mau.fi/whatsmeow/types"
+
+	"github.com/eddmann/whatsapp-cli/internal/store"
+)
+
+// fakeContacts serves GetContact from a map; other ContactStore methods are
+// not used.
+type fakeContacts struct {
+	wastore.ContactStore
+	contacts map[types.JID]types.ContactInfo
+}
+
+func (f fakeContacts) GetContact(_ context.Context, jid types.JID) (types.ContactInfo, error) {
+	return f.contacts[jid], nil
+}
+
+func TestResolveNameMatchesPreferredName(t *testing.T) {
+	db, err := store.Open(filepath.Join(t.TempDir(), "messages.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.CloseQuietly()
+
+	phone := types.NewJID("447700900001", types.DefaultUserServer)
+	other := types.NewJID("447700900002", types.DefaultUserServer)
+	contacts := fakeContacts{contacts: map[types.JID]types.ContactInfo{
+		phone: {FullName: "Alice Smith", PushName: "Ali"},
+		other: {PushName: "Bob"},
+	}}
+	c := &Client{Store: db, WA: &whatsmeow.Client{Store: &wastore.Device{Contacts: contacts}}}
+
+	if err := db.StoreLIDMapping("233564700451061", phone.User, "Alice (LID)"); err != nil {
+		t.Fatalf("store lid mapping: %v", err)
+	}
+
+	tests := []struct {
+		jid, resolved, source string
+	}{
+		// The LID mapping's name wins over the contact, as for senders
+		{"233564700451061@lid", "Alice (LID)", "lid_name"},
+		{phone.String(), "Alice (LID)", "lid_name"},
+		{other.String(), "Bob", "push_name"},
+		{"447700900003@s.whatsapp.net", "447700900003", "jid"},
+	}
+	for _, tt := range tests {
+		res, err := c.ResolveName(tt.jid)
+		if err != nil {
+			t.Fatalf("resolve %s: %v", tt.jid, err)
+		}
+		if res.Resolved != tt.resolved || res.Source != tt.source {
+			t.Errorf("%s: expected %q from %s, got %q from %s", tt.jid, tt.resolved, tt.source, res.Resolved, res.Source)
+		}
+	}
+
+	res, err := c.ResolveName(phone.String())
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if res.FullName != "Alice Smith" || res.PushName != "Ali" {
+		t.Fatalf("expected every source listed, got %+v", res)
+	}
+	if got := c.resolvePreferredName(other.String()); got != "Bob" {
+		t.Fatalf("expected resolvePreferredName to agree, got %q", got)
+	}
+}
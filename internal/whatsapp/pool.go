@@ -0,0 +1,54 @@
+package whatsapp
+
+import (
+	"context"
+	"sync"
+)
+
+// Result is the outcome of one item processed by RunBounded.
+type Result[R any] struct {
+	Value R
+	Err   error
+}
+
+// RunBounded calls fn for each item with at most n calls in flight, and returns
+// the results in item order. A failing item doesn't stop the others. Once ctx
+// is cancelled no new items are started, and those left over get ctx's error.
+// n below 1 is treated as 1.
+func RunBounded[T, R any](ctx context.Context, items []T, n int, fn func(context.Context, T) (R, error)) []Result[R] {
+	n = max(1, min(n, len(items)))
+	results := make([]Result[R], len(items))
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				results[i].Value, results[i].Err = fn(ctx, items[i])
+			}
+		}()
+	}
+
+	i := 0
+feed:
+	for ; i < len(items); i++ {
+		// Check first so a cancelled context always wins over a free worker
+		if ctx.Err() != nil {
+			break
+		}
+		select {
+		case next <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(next)
+	wg.Wait()
+
+	for ; i < len(items); i++ {
+		results[i].Err = ctx.Err()
+	}
+	return results
+}
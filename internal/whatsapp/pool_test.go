@@ -0,0 +1,100 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunBoundedLimitsConcurrencyAndCollectsErrors(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5, 6, 7, 8}
+	var inFlight, peak atomic.Int32
+
+	results := RunBounded(context.Background(), items, 3, func(_ context.Context, n int) (int, error) {
+		cur := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if cur <= p || peak.CompareAndSwap(p, cur) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		if n%3 == 0 {
+			return 0, fmt.Errorf("item %d failed", n)
+		}
+		return n * 10, nil
+	})
+
+	if peak.Load() > 3 {
+		t.Fatalf("expected at most 3 concurrent calls, saw %d", peak.Load())
+	}
+	for i, r := range results {
+		n := items[i]
+		if n%3 == 0 {
+			if r.Err == nil {
+				t.Errorf("item %d: expected an error", n)
+			}
+			continue
+		}
+		if r.Err != nil || r.Value != n*10 {
+			t.Errorf("item %d: got %+v", n, r)
+		}
+	}
+}
+
+func TestRunBoundedStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Both workers block until cancelled, which happens once they're both busy
+	items := make([]int, 20)
+	var started atomic.Int32
+	results := RunBounded(ctx, items, 2, func(ctx context.Context, _ int) (struct{}, error) {
+		if started.Add(1) == 2 {
+			cancel()
+		}
+		<-ctx.Done()
+		return struct{}{}, nil
+	})
+
+	assertCancelledAfter(t, results, int(started.Load()))
+}
+
+func TestRunBoundedStopsOnCancelWithFastItems(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Items return straight away; cancelling mid-run still stops new work
+	items := make([]int, 50)
+	var started atomic.Int32
+	results := RunBounded(ctx, items, 3, func(_ context.Context, _ int) (int, error) {
+		if started.Add(1) == 5 {
+			cancel()
+		}
+		return 1, nil
+	})
+
+	assertCancelledAfter(t, results, int(started.Load()))
+}
+
+// assertCancelledAfter checks that only a few items ran once the context was
+// cancelled and every item that didn't run reports the cancellation.
+func assertCancelledAfter[R any](t *testing.T, results []Result[R], started int) {
+	t.Helper()
+	if started >= len(results) {
+		t.Fatalf("expected cancellation to stop new work, all %d items started", started)
+	}
+	cancelled := 0
+	for _, r := range results {
+		if errors.Is(r.Err, context.Canceled) {
+			cancelled++
+		}
+	}
+	if cancelled != len(results)-started {
+		t.Fatalf("expected %d unstarted items to report cancellation, got %d", len(results)-started, cancelled)
+	}
+}
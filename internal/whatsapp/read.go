@@ -0,0 +1,160 @@
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+
+	"github.com/eddmann/whatsapp-cli/internal/store"
+)
+
+// readTarget is a stored message to send a read receipt for.
+type readTarget struct {
+	ID     string
+	Sender string
+}
+
+// readBatch is a set of messages from one sender, which WhatsApp accepts in a
+// single read receipt.
+type readBatch struct {
+	Sender string
+	IDs    []types.MessageID
+}
+
+// MarkRead sends read receipts for messages in a chat. With no messageIDs the
+// latest limit messages from others in the chat are marked read. Our own
+// messages are skipped.
+func (c *Client) MarkRead(chatJID string, messageIDs []string, limit int) (store.ReadResult, error) {
+	if !c.WA.IsConnected() {
+		return store.ReadResult{}, ErrNotConnected
+	}
+
+	jid, err := c.resolveRecipient(chatJID)
+	if err != nil {
+		return store.ReadResult{}, fmt.Errorf("invalid chat JID: %w", err)
+	}
+
+	targets, err := c.readTargets(messageIDs, limit, chatJID, jid.String())
+	if err != nil {
+		return store.ReadResult{}, err
+	}
+
+	result := store.ReadResult{ChatJID: jid.String(), MessageIDs: []string{}}
+	now := time.Now()
+	for _, batch := range batchReadReceipts(targets) {
+		var sender types.JID
+		if jid.Server == types.GroupServer {
+			sender = c.senderJID(batch.Sender)
+		}
+		if err := c.WA.MarkRead(context.Background(), batch.IDs, now, jid, sender); err != nil {
+			return result, fmt.Errorf("failed to mark %d messages from %s read: %w", len(batch.IDs), batch.Sender, err)
+		}
+		result.MessageIDs = append(result.MessageIDs, batch.IDs...)
+		result.Receipts++
+	}
+
+	if err := c.Store.AdvanceReadWatermark(result.MessageIDs, chatJID, jid.String()); err != nil {
+		c.Logger.Warn("failed to store read watermark", "chat_jid", chatJID, "err", err)
+	}
+	return result, nil
+}
+
+// recordOwnRead advances a chat's read watermark when we read messages on
+// another device, such as the phone.
+func (c *Client) recordOwnRead(evt *events.Receipt) {
+	if !evt.IsFromMe || (evt.Type != types.ReceiptTypeRead && evt.Type != types.ReceiptTypeReadSelf) {
+		return
+	}
+	if err := c.Store.AdvanceReadWatermark(evt.MessageIDs, evt.Chat.String()); err != nil {
+		c.Logger.Warn("failed to store read watermark", "chat_jid", evt.Chat.String(), "err", err)
+	}
+}
+
+// readTargets loads the messages to mark read from whichever of chatJIDs they
+// are stored under: messageIDs, or the latest limit messages when none are
+// given. Messages we sent are left out.
+func (c *Client) readTargets(messageIDs []string, limit int, chatJIDs ...string) ([]readTarget, error) {
+	var args []any
+	for _, jid := range chatJIDs {
+		args = append(args, jid)
+	}
+	args = append(args, store.SystemMessageType)
+
+	query := `SELECT id, sender, is_from_me FROM messages WHERE chat_jid IN (` + store.Placeholders(len(chatJIDs)) + `) AND COALESCE(media_type, '') != ?`
+	if len(messageIDs) > 0 {
+		query += ` AND id IN (` + store.Placeholders(len(messageIDs)) + `)`
+		for _, id := range messageIDs {
+			args = append(args, id)
+		}
+	} else {
+		query += ` AND is_from_me = 0 ORDER BY timestamp DESC LIMIT ?`
+		args = append(args, limit)
+	}
+
+	rows, err := c.Store.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load messages: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	found := make(map[string]bool)
+	var targets []readTarget
+	for rows.Next() {
+		var t readTarget
+		var fromMe bool
+		if err := rows.Scan(&t.ID, &t.Sender, &fromMe); err != nil {
+			return nil, err
+		}
+		found[t.ID] = true
+		if !fromMe {
+			targets = append(targets, t)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, id := range messageIDs {
+		if !found[id] {
+			return nil, fmt.Errorf("message %s not found in %s", id, chatJIDs[0])
+		}
+	}
+	return targets, nil
+}
+
+// batchReadReceipts groups messages by sender, in order of first appearance.
+func batchReadReceipts(targets []readTarget) []readBatch {
+	var batches []readBatch
+	index := make(map[string]int)
+	for _, t := range targets {
+		i, ok := index[t.Sender]
+		if !ok {
+			i = len(batches)
+			index[t.Sender] = i
+			batches = append(batches, readBatch{Sender: t.Sender})
+		}
+		batches[i].IDs = append(batches[i].IDs, t.ID)
+	}
+	return batches
+}
+
+// senderJID turns a stored sender (the user part only) back into the JID the
+// message came from: a LID when lid_mappings knows it as one, otherwise a phone
+// JID. Group receipts must name the sender as it was addressed.
+func (c *Client) senderJID(sender string) types.JID {
+	if strings.Contains(sender, "@") {
+		if jid, err := types.ParseJID(sender); err == nil {
+			return jid.ToNonAD()
+		}
+	}
+	if c.Store != nil {
+		if _, _, found := c.Store.GetLIDMapping(sender); found {
+			return types.JID{User: sender, Server: types.HiddenUserServer}
+		}
+	}
+	return types.JID{User: sender, Server: types.DefaultUserServer}
+}
@@ -0,0 +1,72 @@
+package whatsapp
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+
+	"github.com/eddmann/whatsapp-cli/internal/store"
+)
+
+func TestReadTargetsAndBatches(t *testing.T) {
+	db, err := store.Open(filepath.Join(t.TempDir(), "messages.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.CloseQuietly()
+
+	group := "123-456@g.us"
+	ts := time.Date(2026, 4, 24, 12, 0, 0, 0, time.UTC)
+	if _, err := db.Exec(`INSERT INTO chats (jid, name) VALUES (?, 'Team')`, group); err != nil {
+		t.Fatalf("insert chat: %v", err)
+	}
+	for i, m := range []struct {
+		id, sender string
+		fromMe     bool
+	}{
+		{"a1", "111", false},
+		{"b1", "222", false},
+		{"me", "999", true},
+		{"a2", "111", false},
+	} {
+		if _, err := db.Exec(`INSERT INTO messages (id, chat_jid, sender, content, timestamp, is_from_me) VALUES (?, ?, ?, 'hi', ?, ?)`,
+			m.id, group, m.sender, ts.Add(time.Duration(i)*time.Minute), m.fromMe); err != nil {
+			t.Fatalf("insert message: %v", err)
+		}
+	}
+
+	c := &Client{Store: db}
+	targets, err := c.readTargets(nil, 2, group)
+	if err != nil {
+		t.Fatalf("latest targets: %v", err)
+	}
+	if want := []readTarget{{"a2", "111"}, {"b1", "222"}}; !reflect.DeepEqual(targets, want) {
+		t.Fatalf("expected the latest messages from others, got %+v", targets)
+	}
+
+	targets, err = c.readTargets([]string{"a1", "me", "a2", "b1"}, 0, group)
+	if err != nil {
+		t.Fatalf("targets by id: %v", err)
+	}
+	batches := batchReadReceipts(targets)
+	if len(batches) != 2 || len(batches[0].IDs)+len(batches[1].IDs) != 3 {
+		t.Fatalf("expected one batch per sender without our own message, got %+v", batches)
+	}
+
+	if _, err := c.readTargets([]string{"missing"}, 0, group); err == nil {
+		t.Fatal("expected an error for an unknown message")
+	}
+
+	if err := db.StoreLIDMapping("222", "447700900001", ""); err != nil {
+		t.Fatalf("store mapping: %v", err)
+	}
+	if got := c.senderJID("222"); got.Server != types.HiddenUserServer {
+		t.Fatalf("expected a known LID to stay a LID, got %s", got)
+	}
+	if got := c.senderJID("111"); got.String() != "111@s.whatsapp.net" {
+		t.Fatalf("expected a phone JID, got %s", got)
+	}
+}
@@ -2,23 +2,161 @@ package whatsapp
 
 import (
 	"database/sql"
+	"fmt"
 	"strings"
 	"time"
 
 	waHistorySync "go.mau.fi/whatsmeow/proto/waHistorySync"
 	"go.mau.fi/whatsmeow/types"
 	"go.mau.fi/whatsmeow/types/events"
+
+	"github.com/eddmann/whatsapp-cli/internal/store"
 )
 
+// maxSyncErrorSamples is how many persistence error messages SyncStats keeps.
+const maxSyncErrorSamples = 5
+
+// SyncStats counts messages newly persisted since the client was created,
+// along with any persistence errors hit while storing chats and messages.
+type SyncStats struct {
+	NewMessages  int      `json:"new_messages"`
+	Images       int      `json:"images"`
+	Videos       int      `json:"videos"`
+	Audio        int      `json:"audio"`
+	Documents    int      `json:"documents"`
+	Errors       int      `json:"errors"`
+	ErrorSamples []string `json:"error_samples,omitempty"`
+}
+
+// storedMessage holds the columns persisted for each message.
+type storedMessage struct {
+	ID              string
+	ChatJID         string
+	Sender          string
+	SenderName      string
+	Content         string
+	Timestamp       time.Time
+	IsFromMe        bool
+	MediaType       string
+	Filename        string
+	URL             string
+	MediaKey        []byte
+	FileSHA256      []byte
+	FileEncSHA256   []byte
+	FileLength      uint64
+	ReplyToID       string
+	ForwardingScore uint32
+}
+
+// SyncStats returns a snapshot of the messages persisted so far.
+func (c *Client) SyncStats() SyncStats {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+	stats := c.stats
+	stats.ErrorSamples = append([]string(nil), c.stats.ErrorSamples...)
+	return stats
+}
+
+// recordSyncError counts a persistence error, keeping the first few messages.
+func (c *Client) recordSyncError(format string, args ...any) {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+	c.stats.Errors++
+	if len(c.stats.ErrorSamples) < maxSyncErrorSamples {
+		c.stats.ErrorSamples = append(c.stats.ErrorSamples, fmt.Sprintf(format, args...))
+	}
+}
+
+// persistMessage upserts a message and counts it in the sync stats if it
+// wasn't stored before, reporting whether it was new.
+func (c *Client) persistMessage(m storedMessage) (bool, error) {
+	var exists int
+	isNew := c.Store.QueryRow("SELECT 1 FROM messages WHERE id = ? AND chat_jid = ?", m.ID, m.ChatJID).Scan(&exists) == sql.ErrNoRows
+
+	// Update in place rather than REPLACE, which would delete the row: that
+	// resets local columns like starred and skips the search index's delete trigger
+	if _, err := c.Store.Exec(`INSERT INTO messages
+		(id, chat_jid, sender, sender_name, content, timestamp, is_from_me, media_type, filename, url, media_key, file_sha256, file_enc_sha256, file_length, reply_to_id, forwarding_score)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id, chat_jid) DO UPDATE SET
+			sender = excluded.sender,
+			sender_name = excluded.sender_name,
+			content = excluded.content,
+			timestamp = excluded.timestamp,
+			is_from_me = excluded.is_from_me,
+			media_type = excluded.media_type,
+			filename = excluded.filename,
+			url = excluded.url,
+			media_key = excluded.media_key,
+			file_sha256 = excluded.file_sha256,
+			file_enc_sha256 = excluded.file_enc_sha256,
+			file_length = excluded.file_length,
+			reply_to_id = excluded.reply_to_id,
+			forwarding_score = excluded.forwarding_score`,
+		m.ID, m.ChatJID, m.Sender, m.SenderName, m.Content, m.Timestamp, m.IsFromMe, m.MediaType, m.Filename, m.URL, m.MediaKey, m.FileSHA256, m.FileEncSHA256, m.FileLength, m.ReplyToID, m.ForwardingScore,
+	); err != nil {
+		return false, err
+	}
+	c.syncState.activity()
+
+	if isNew {
+		c.statsMu.Lock()
+		c.stats.NewMessages++
+		switch m.MediaType {
+		case "image":
+			c.stats.Images++
+		case "video":
+			c.stats.Videos++
+		case "audio":
+			c.stats.Audio++
+		case "document":
+			c.stats.Documents++
+		}
+		c.statsMu.Unlock()
+	}
+
+	return isNew, nil
+}
+
+// upsertChat records a chat and the time of its latest message. System rows
+// don't count as activity, so they leave an existing chat's time alone.
+func (c *Client) upsertChat(chatJID, name string, at time.Time, system bool) error {
+	query := "INSERT OR REPLACE INTO chats (jid, name, last_message_time) VALUES (?, ?, ?)"
+	if system {
+		query = "INSERT INTO chats (jid, name, last_message_time) VALUES (?, ?, ?) ON CONFLICT(jid) DO UPDATE SET name = excluded.name"
+	}
+	_, err := c.Store.Exec(query, chatJID, name, at)
+	return err
+}
+
 // handleMessage processes real-time incoming messages and persists them.
 func (c *Client) handleMessage(msg *events.Message) {
+	c.dumpProto(msg)
+
 	chatJID := msg.Info.Chat.String()
+	c.recordEphemeral(chatJID, msg.Message)
+
+	// Reactions are stored against the message they react to, not as messages
+	if reaction := msg.Message.GetReactionMessage(); reaction != nil {
+		target := reaction.GetKey().GetID()
+		if err := c.Store.SetReaction(chatJID, target, msg.Info.Sender.User, reaction.GetText(), msg.Info.Timestamp); err != nil {
+			c.Logger.Warn("failed to store reaction", "id", target, "chat_jid", chatJID, "err", err)
+			c.recordSyncError("store reaction to %s in %s: %v", target, chatJID, err)
+		}
+		return
+	}
 	sender := msg.Info.Sender.User
-	content := extractTextContent(msg.Message)
+	content := store.NormalizeText(extractTextContent(msg.Message))
 	mediaType, filename, url, mediaKey, fileSHA256, fileEncSHA256, fileLength := extractMediaInfo(msg.Message)
 
 	if content == "" && mediaType == "" {
-		return
+		if !c.IncludeSystem {
+			return
+		}
+		if content = describeSystemMessage(msg.Message); content == "" {
+			return
+		}
+		mediaType = store.SystemMessageType
 	}
 
 	// Resolve sender name
@@ -28,29 +166,97 @@ func (c *Client) handleMessage(msg *events.Message) {
 	if sender != "" {
 		indiv := types.JID{User: sender, Server: "s.whatsapp.net"}
 		var existing sql.NullString
-		_ = c.Store.Messages.QueryRow("SELECT name FROM chats WHERE jid = ?", indiv.String()).Scan(&existing)
+		_ = c.Store.QueryRow("SELECT name FROM chats WHERE jid = ?", indiv.String()).Scan(&existing)
 		if !existing.Valid {
 			resolved := c.resolvePreferredName(indiv.String())
-			_, _ = c.Store.Messages.Exec("INSERT INTO chats (jid, name) VALUES (?, ?)", indiv.String(), resolved)
+			_, _ = c.Store.Exec("INSERT INTO chats (jid, name) VALUES (?, ?)", indiv.String(), resolved)
 		} else if existing.String == "" {
 			resolved := c.resolvePreferredName(indiv.String())
 			if resolved != "" {
-				_, _ = c.Store.Messages.Exec("UPDATE chats SET name = ? WHERE jid = ?", resolved, indiv.String())
+				_, _ = c.Store.Exec("UPDATE chats SET name = ? WHERE jid = ?", resolved, indiv.String())
 			}
 		}
 	}
 
 	name := c.getChatName(msg.Info.Chat.String(), chatJID, nil, sender)
-	if _, err := c.Store.Messages.Exec("INSERT OR REPLACE INTO chats (jid, name, last_message_time) VALUES (?, ?, ?)", chatJID, name, msg.Info.Timestamp); err != nil {
+	if err := c.upsertChat(chatJID, name, msg.Info.Timestamp, mediaType == store.SystemMessageType); err != nil {
 		c.Logger.Warn("failed to upsert chat", "jid", chatJID, "err", err)
+		c.recordSyncError("upsert chat %s: %v", chatJID, err)
 	}
 
-	if _, err := c.Store.Messages.Exec(`INSERT OR REPLACE INTO messages
-		(id, chat_jid, sender, sender_name, content, timestamp, is_from_me, media_type, filename, url, media_key, file_sha256, file_enc_sha256, file_length)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		msg.Info.ID, chatJID, sender, senderName, content, msg.Info.Timestamp, msg.Info.IsFromMe, mediaType, filename, url, mediaKey, fileSHA256, fileEncSHA256, fileLength,
-	); err != nil {
+	isNew, err := c.persistMessage(storedMessage{
+		ID:              msg.Info.ID,
+		ChatJID:         chatJID,
+		Sender:          sender,
+		SenderName:      senderName,
+		Content:         content,
+		Timestamp:       msg.Info.Timestamp,
+		IsFromMe:        msg.Info.IsFromMe,
+		MediaType:       mediaType,
+		Filename:        filename,
+		URL:             url,
+		MediaKey:        mediaKey,
+		FileSHA256:      fileSHA256,
+		FileEncSHA256:   fileEncSHA256,
+		FileLength:      fileLength,
+		ReplyToID:       extractContextInfo(msg.Message).GetStanzaID(),
+		ForwardingScore: forwardingScore(extractContextInfo(msg.Message)),
+	})
+	if err != nil {
 		c.Logger.Warn("failed to store message", "id", msg.Info.ID, "chat_jid", chatJID, "err", err)
+		c.recordSyncError("store message %s in %s: %v", msg.Info.ID, chatJID, err)
+		return
+	}
+
+	// Redelivered messages were counted the first time
+	if isNew && !msg.Info.IsFromMe && mediaType != store.SystemMessageType {
+		if err := c.Store.IncrementUnread(chatJID); err != nil {
+			c.Logger.Warn("failed to count unread message", "chat_jid", chatJID, "err", err)
+		}
+	}
+}
+
+// cacheSyncedGroup caches the group info carried by a history sync
+// conversation, and its participants if none are cached. Groups already cached
+// are left alone, as their info may be newer than the sync's.
+func (c *Client) cacheSyncedGroup(jid, name string, conv *waHistorySync.Conversation) {
+	if conv.GetName() != "" {
+		name = conv.GetName()
+	}
+	g := store.GroupInfo{
+		JID:              jid,
+		Name:             name,
+		Topic:            conv.GetDescription(),
+		CreatorJID:       conv.GetCreatedBy(),
+		ParticipantCount: len(conv.GetParticipant()),
+	}
+	if ts := conv.GetCreatedAt(); ts != 0 {
+		g.Created = time.Unix(int64(ts), 0)
+	}
+	added, err := c.Store.AddGroup(g)
+	if err != nil {
+		c.Logger.Warn("history sync: failed to cache group info", "jid", jid, "err", err)
+		return
+	}
+	if !added || len(conv.GetParticipant()) == 0 {
+		return
+	}
+
+	if cached, err := c.Store.GetGroupParticipants(jid); err != nil || len(cached) > 0 {
+		return
+	}
+	participants := make([]store.Participant, 0, len(conv.GetParticipant()))
+	for _, p := range conv.GetParticipant() {
+		if p.GetUserJID() == "" {
+			continue
+		}
+		participants = append(participants, store.Participant{
+			JID:     p.GetUserJID(),
+			IsAdmin: p.GetRank() != waHistorySync.GroupParticipant_REGULAR,
+		})
+	}
+	if err := c.Store.ReplaceGroupParticipants(jid, participants); err != nil {
+		c.Logger.Warn("history sync: failed to cache group participants", "jid", jid, "err", err)
 	}
 }
 
@@ -79,6 +285,19 @@ func (c *Client) handleHistorySync(hs *events.HistorySync) HistorySyncResult {
 		}
 
 		name := c.getChatName(jid.String(), chatJID, conv, "")
+		if jid.Server == types.GroupServer {
+			c.cacheSyncedGroup(chatJID, name, conv)
+		}
+		if conv.UnreadCount != nil {
+			if err := c.Store.SetUnread(chatJID, int(conv.GetUnreadCount())); err != nil {
+				c.Logger.Warn("history sync: failed to store unread count", "jid", chatJID, "err", err)
+			}
+		}
+		if conv.EphemeralExpiration != nil {
+			if err := c.Store.SetChatEphemeral(chatJID, conv.GetEphemeralExpiration()); err != nil {
+				c.Logger.Warn("history sync: failed to store disappearing timer", "jid", chatJID, "err", err)
+			}
+		}
 		endType := conv.GetEndOfHistoryTransferType()
 		if endType == waHistorySync.Conversation_COMPLETE_BUT_MORE_MESSAGES_REMAIN_ON_PRIMARY ||
 			endType == waHistorySync.Conversation_COMPLETE_ON_DEMAND_SYNC_BUT_MORE_MSG_REMAIN_ON_PRIMARY {
@@ -89,8 +308,9 @@ func (c *Client) handleHistorySync(hs *events.HistorySync) HistorySyncResult {
 			ts := conv.Messages[0].Message.GetMessageTimestamp()
 			if ts != 0 {
 				t := time.Unix(int64(ts), 0)
-				if _, err := c.Store.Messages.Exec("INSERT OR REPLACE INTO chats (jid, name, last_message_time) VALUES (?, ?, ?)", chatJID, name, t); err != nil {
+				if _, err := c.Store.Exec("INSERT OR REPLACE INTO chats (jid, name, last_message_time) VALUES (?, ?, ?)", chatJID, name, t); err != nil {
 					c.Logger.Warn("history sync: failed to upsert chat", "jid", chatJID, "err", err)
+					c.recordSyncError("history sync: upsert chat %s: %v", chatJID, err)
 				}
 			}
 		}
@@ -102,7 +322,7 @@ func (c *Client) handleHistorySync(hs *events.HistorySync) HistorySyncResult {
 
 			var text string
 			if m.Message.Message != nil {
-				text = extractTextContent(m.Message.Message)
+				text = store.NormalizeText(extractTextContent(m.Message.Message))
 			}
 
 			mt, fn, u, mk, sha, enc, fl := "", "", "", ([]byte)(nil), ([]byte)(nil), ([]byte)(nil), uint64(0)
@@ -111,7 +331,13 @@ func (c *Client) handleHistorySync(hs *events.HistorySync) HistorySyncResult {
 			}
 
 			if text == "" && mt == "" {
-				continue
+				if !c.IncludeSystem {
+					continue
+				}
+				if text = describeHistorySystemMessage(m.Message); text == "" {
+					continue
+				}
+				mt = store.SystemMessageType
 			}
 
 			fromMe := false
@@ -142,14 +368,14 @@ func (c *Client) handleHistorySync(hs *events.HistorySync) HistorySyncResult {
 			if !fromMe && snd != "" {
 				indiv := types.JID{User: snd, Server: "s.whatsapp.net"}
 				var existing sql.NullString
-				_ = c.Store.Messages.QueryRow("SELECT name FROM chats WHERE jid = ?", indiv.String()).Scan(&existing)
+				_ = c.Store.QueryRow("SELECT name FROM chats WHERE jid = ?", indiv.String()).Scan(&existing)
 				if !existing.Valid {
 					resolved := c.resolvePreferredName(indiv.String())
-					_, _ = c.Store.Messages.Exec("INSERT INTO chats (jid, name) VALUES (?, ?)", indiv.String(), resolved)
+					_, _ = c.Store.Exec("INSERT INTO chats (jid, name) VALUES (?, ?)", indiv.String(), resolved)
 				} else if existing.String == "" {
 					resolved := c.resolvePreferredName(indiv.String())
 					if resolved != "" {
-						_, _ = c.Store.Messages.Exec("UPDATE chats SET name = ? WHERE jid = ?", resolved, indiv.String())
+						_, _ = c.Store.Exec("UPDATE chats SET name = ? WHERE jid = ?", resolved, indiv.String())
 					}
 				}
 			}
@@ -173,10 +399,26 @@ func (c *Client) handleHistorySync(hs *events.HistorySync) HistorySyncResult {
 				senderName = c.resolvePreferredName(phoneJID.String())
 			}
 
-			if _, err := c.Store.Messages.Exec(`INSERT OR REPLACE INTO messages
-				(id, chat_jid, sender, sender_name, content, timestamp, is_from_me, media_type, filename, url, media_key, file_sha256, file_enc_sha256, file_length)
-				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, id, chatJID, snd, senderName, text, t, fromMe, mt, fn, u, mk, sha, enc, fl); err != nil {
+			if _, err := c.persistMessage(storedMessage{
+				ID:              id,
+				ChatJID:         chatJID,
+				Sender:          snd,
+				SenderName:      senderName,
+				Content:         text,
+				Timestamp:       t,
+				IsFromMe:        fromMe,
+				MediaType:       mt,
+				Filename:        fn,
+				URL:             u,
+				MediaKey:        mk,
+				FileSHA256:      sha,
+				FileEncSHA256:   enc,
+				FileLength:      fl,
+				ReplyToID:       extractContextInfo(m.Message.GetMessage()).GetStanzaID(),
+				ForwardingScore: forwardingScore(extractContextInfo(m.Message.GetMessage())),
+			}); err != nil {
 				c.Logger.Warn("history sync: failed to store message", "id", id, "chat_jid", chatJID, "err", err)
+				c.recordSyncError("history sync: store message %s in %s: %v", id, chatJID, err)
 				continue
 			}
 			synced++
@@ -187,16 +429,16 @@ func (c *Client) handleHistorySync(hs *events.HistorySync) HistorySyncResult {
 	return HistorySyncResult{MessagesSynced: synced, MoreAvailable: moreAvailable}
 }
 
-// backfillChatNames finds chats without a proper name and updates them.
-func (c *Client) backfillChatNames() {
+// BackfillChatNames re-resolves chats stored without a proper name (empty, a
+// bare phone number or a placeholder group name) and returns how many changed.
+func (c *Client) BackfillChatNames() (int, error) {
 	if c.Store == nil || c.Store.Messages == nil {
-		return
+		return 0, nil
 	}
 
-	rows, err := c.Store.Messages.Query(`SELECT jid, COALESCE(name, '') FROM chats`)
+	rows, err := c.Store.Query(`SELECT jid, COALESCE(name, '') FROM chats`)
 	if err != nil {
-		c.Logger.Warn("backfill: query chats failed", "err", err)
-		return
+		return 0, fmt.Errorf("failed to query chats: %w", err)
 	}
 	defer func() { _ = rows.Close() }()
 
@@ -238,7 +480,7 @@ func (c *Client) backfillChatNames() {
 			continue
 		}
 
-		if _, err := c.Store.Messages.Exec(`UPDATE chats SET name = ? WHERE jid = ?`, resolved, r.jid); err != nil {
+		if _, err := c.Store.Exec(`UPDATE chats SET name = ? WHERE jid = ?`, resolved, r.jid); err != nil {
 			c.Logger.Warn("backfill: update failed", "jid", r.jid, "err", err)
 			continue
 		}
@@ -248,4 +490,5 @@ func (c *Client) backfillChatNames() {
 	if updated > 0 {
 		c.Logger.Info("backfill: updated chat names", "count", updated)
 	}
+	return updated, nil
 }
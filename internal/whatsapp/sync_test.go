@@ -0,0 +1,287 @@
+package whatsapp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	wastore "go.mau.fi/whatsmeow/store"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+
+	"github.com/eddmann/whatsapp-cli/internal/store"
+)
+
+func TestRecordSyncErrorKeepsFirstSamples(t *testing.T) {
+	c := &Client{}
+	for i := 0; i < maxSyncErrorSamples+2; i++ {
+		c.recordSyncError("store message %d: %v", i, errors.New("disk I/O error"))
+	}
+
+	stats := c.SyncStats()
+	if stats.Errors != maxSyncErrorSamples+2 {
+		t.Fatalf("expected %d errors, got %d", maxSyncErrorSamples+2, stats.Errors)
+	}
+	if len(stats.ErrorSamples) != maxSyncErrorSamples {
+		t.Fatalf("expected %d samples, got %d", maxSyncErrorSamples, len(stats.ErrorSamples))
+	}
+	if stats.ErrorSamples[0] != "store message 0: disk I/O error" {
+		t.Fatalf("unexpected first sample %q", stats.ErrorSamples[0])
+	}
+}
+
+func TestDumpProtoWritesJSONLine(t *testing.T) {
+	var buf bytes.Buffer
+	c := &Client{ProtoDump: &buf}
+
+	text := "hello"
+	c.dumpProto(&events.Message{
+		Info: types.MessageInfo{
+			MessageSource: types.MessageSource{
+				Chat:   types.NewJID("123", types.DefaultUserServer),
+				Sender: types.NewJID("123", types.DefaultUserServer),
+			},
+			ID:        "ABC",
+			Timestamp: time.Date(2026, 4, 24, 12, 0, 0, 0, time.UTC),
+		},
+		Message: &waE2E.Message{Conversation: &text},
+	})
+
+	var entry struct {
+		ID      string `json:"id"`
+		ChatJID string `json:"chat_jid"`
+		Message struct {
+			Conversation string `json:"conversation"`
+		} `json:"message"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("expected a JSON line, got %q: %v", buf.String(), err)
+	}
+	if entry.ID != "ABC" || entry.ChatJID != "123@s.whatsapp.net" || entry.Message.Conversation != "hello" {
+		t.Fatalf("unexpected entry %+v", entry)
+	}
+}
+
+func TestPersistMessageKeepsStarredFlag(t *testing.T) {
+	db, err := store.Open(filepath.Join(t.TempDir(), "messages.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.CloseQuietly()
+
+	c := &Client{Store: db}
+	msg := storedMessage{
+		ID:        "MSG1",
+		ChatJID:   "123@s.whatsapp.net",
+		Sender:    "123",
+		Content:   "hello",
+		Timestamp: time.Date(2026, 4, 24, 12, 0, 0, 0, time.UTC),
+	}
+	if _, err := db.Exec("INSERT INTO chats (jid, name) VALUES (?, ?)", msg.ChatJID, "Alice"); err != nil {
+		t.Fatalf("insert chat: %v", err)
+	}
+	if isNew, err := c.persistMessage(msg); err != nil || !isNew {
+		t.Fatalf("persist: new=%v err=%v", isNew, err)
+	}
+	if err := db.SetMessageStarred(msg.ChatJID, msg.ID, true); err != nil {
+		t.Fatalf("star: %v", err)
+	}
+
+	msg.Content = "hello (edited)"
+	if isNew, err := c.persistMessage(msg); err != nil || isNew {
+		t.Fatalf("persist again: new=%v err=%v", isNew, err)
+	}
+
+	var content string
+	var starred bool
+	if err := db.Messages.QueryRow("SELECT content, starred FROM messages WHERE id = ? AND chat_jid = ?", msg.ID, msg.ChatJID).Scan(&content, &starred); err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if !starred {
+		t.Fatal("expected the message to stay starred")
+	}
+	if content != "hello (edited)" {
+		t.Fatalf("expected updated content, got %q", content)
+	}
+	if stats := c.SyncStats(); stats.NewMessages != 1 {
+		t.Fatalf("expected 1 new message, got %d", stats.NewMessages)
+	}
+}
+
+func TestPersistMessageAgainKeepsSearchIndexInSync(t *testing.T) {
+	db, err := store.Open(filepath.Join(t.TempDir(), "messages.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.CloseQuietly()
+	if !db.HasFTS() {
+		t.Skip("full-text search not available")
+	}
+
+	c := &Client{Store: db}
+	msg := storedMessage{
+		ID:        "MSG1",
+		ChatJID:   "123@s.whatsapp.net",
+		Sender:    "123",
+		Content:   "hello",
+		Timestamp: time.Date(2026, 4, 24, 12, 0, 0, 0, time.UTC),
+	}
+	if _, err := db.Exec("INSERT INTO chats (jid, name) VALUES (?, ?)", msg.ChatJID, "Alice"); err != nil {
+		t.Fatalf("insert chat: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := c.persistMessage(msg); err != nil {
+			t.Fatalf("persist %d: %v", i, err)
+		}
+	}
+
+	status, err := db.CheckFTS()
+	if err != nil {
+		t.Fatalf("check fts: %v", err)
+	}
+	if status.Drift() != 0 {
+		t.Fatalf("expected no drift, got %+v", status)
+	}
+}
+
+func TestSystemMessageKeepsChatLastMessageTime(t *testing.T) {
+	db, err := store.Open(filepath.Join(t.TempDir(), "messages.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.CloseQuietly()
+
+	c := &Client{Store: db}
+	chatJID := "123@s.whatsapp.net"
+	sent := time.Date(2026, 4, 24, 12, 0, 0, 0, time.UTC)
+	if err := c.upsertChat(chatJID, "Alice", sent, false); err != nil {
+		t.Fatalf("upsert chat: %v", err)
+	}
+	if err := c.upsertChat(chatJID, "Alice B", sent.Add(time.Hour), true); err != nil {
+		t.Fatalf("upsert chat for system message: %v", err)
+	}
+
+	var name string
+	var last time.Time
+	if err := db.QueryRow("SELECT name, last_message_time FROM chats WHERE jid = ?", chatJID).Scan(&name, &last); err != nil {
+		t.Fatalf("query chat: %v", err)
+	}
+	if !last.Equal(sent) {
+		t.Fatalf("expected last message time %v, got %v", sent, last)
+	}
+	if name != "Alice B" {
+		t.Fatalf("expected name to be updated, got %q", name)
+	}
+}
+
+func TestPersistMessageCountsMediaTypes(t *testing.T) {
+	db, err := store.Open(filepath.Join(t.TempDir(), "messages.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.CloseQuietly()
+
+	chatJID := "123@s.whatsapp.net"
+	if _, err := db.Exec("INSERT INTO chats (jid, name) VALUES (?, ?)", chatJID, "Alice"); err != nil {
+		t.Fatalf("insert chat: %v", err)
+	}
+
+	c := &Client{Store: db}
+	ts := time.Date(2026, 4, 24, 12, 0, 0, 0, time.UTC)
+	for i, mediaType := range []string{"", "image", "image", "video", "audio", "document", "sticker"} {
+		msg := storedMessage{
+			ID:        fmt.Sprintf("MSG%d", i),
+			ChatJID:   chatJID,
+			Sender:    "123",
+			Timestamp: ts.Add(time.Duration(i) * time.Minute),
+			MediaType: mediaType,
+		}
+		if _, err := c.persistMessage(msg); err != nil {
+			t.Fatalf("persist %s: %v", msg.ID, err)
+		}
+	}
+	// Seeing a message again, e.g. in a later history chunk, isn't new
+	if isNew, err := c.persistMessage(storedMessage{ID: "MSG1", ChatJID: chatJID, Sender: "123", Timestamp: ts, MediaType: "image"}); err != nil || isNew {
+		t.Fatalf("re-persist: new=%v err=%v", isNew, err)
+	}
+
+	want := SyncStats{NewMessages: 7, Images: 2, Videos: 1, Audio: 1, Documents: 1}
+	if got := c.SyncStats(); got.NewMessages != want.NewMessages || got.Images != want.Images ||
+		got.Videos != want.Videos || got.Audio != want.Audio || got.Documents != want.Documents || got.Errors != 0 {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestBackfillChatNamesUpdatesBareNumbers(t *testing.T) {
+	db, err := store.Open(filepath.Join(t.TempDir(), "messages.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.CloseQuietly()
+
+	alice := types.NewJID("447700900001", types.DefaultUserServer)
+	bob := types.NewJID("447700900002", types.DefaultUserServer)
+	carol := types.NewJID("447700900005", types.DefaultUserServer)
+	contacts := fakeContacts{contacts: map[types.JID]types.ContactInfo{
+		alice: {FullName: "Alice Smith"},
+		bob:   {PushName: "Bob"},
+		carol: {FullName: "Caroline"},
+	}}
+	c := &Client{
+		Store:  db,
+		WA:     &whatsmeow.Client{Store: &wastore.Device{Contacts: contacts}},
+		Logger: slog.New(slog.DiscardHandler),
+	}
+
+	chats := map[string]string{
+		alice.String():                "",
+		bob.String():                  bob.User,
+		"447700900003@s.whatsapp.net": "447700900003@s.whatsapp.net",
+		"447700900004@s.whatsapp.net": "447700900004", // No better name, so unchanged
+		carol.String():                "Carol",        // Already named, left alone
+		"120363000000000001@g.us":     "Book club",
+	}
+	for jid, name := range chats {
+		if _, err := db.Exec("INSERT INTO chats (jid, name) VALUES (?, ?)", jid, name); err != nil {
+			t.Fatalf("insert chat %s: %v", jid, err)
+		}
+	}
+
+	updated, err := c.BackfillChatNames()
+	if err != nil {
+		t.Fatalf("backfill: %v", err)
+	}
+	if updated != 3 {
+		t.Fatalf("expected 3 names updated, got %d", updated)
+	}
+
+	want := map[string]string{
+		alice.String():                "Alice Smith",
+		bob.String():                  "Bob",
+		"447700900003@s.whatsapp.net": "447700900003",
+		"447700900004@s.whatsapp.net": "447700900004",
+		carol.String():                "Carol",
+		"120363000000000001@g.us":     "Book club",
+	}
+	for jid, name := range want {
+		var got string
+		if err := db.QueryRow("SELECT name FROM chats WHERE jid = ?", jid).Scan(&got); err != nil {
+			t.Fatalf("query %s: %v", jid, err)
+		}
+		if got != name {
+			t.Errorf("%s: expected %q, got %q", jid, name, got)
+		}
+	}
+
+	// A second pass has nothing left to fix
+	if updated, err := c.BackfillChatNames(); err != nil || updated != 0 {
+		t.Fatalf("second backfill: updated=%d err=%v", updated, err)
+	}
+}
@@ -0,0 +1,145 @@
+package whatsapp
+
+import (
+	"sync"
+	"time"
+)
+
+// syncTracker decides when the initial sync has settled.
+//
+// WhatsApp delivers data in several phases: the offline backlog (ending with
+// OfflineSyncCompleted) and, after pairing, history sync (ending at progress
+// 100). The sync is ready once the phases we need are done: history for a fresh
+// store, otherwise just the offline backlog. It settles after a further quiet
+// period with no new messages, so late chunks aren't missed. done is closed
+// exactly once, so every waiter sees it and no signal is lost or repeated.
+//
+// With the quiet-period fallback enabled, the sync also settles once the
+// connection has gone a quiet period without storing a message, for accounts
+// where the phase events never arrive.
+type syncTracker struct {
+	isConnected func() bool
+	done        chan struct{}
+
+	mu          sync.Mutex
+	quiet       time.Duration
+	fallback    bool
+	historyDone bool
+	offlineDone bool
+	needHistory bool
+	settled     bool
+	timer       *time.Timer
+}
+
+func newSyncTracker(quiet time.Duration, isConnected func() bool) *syncTracker {
+	return &syncTracker{
+		quiet:       quiet,
+		isConnected: isConnected,
+		done:        make(chan struct{}),
+	}
+}
+
+// historyComplete records that history sync reached 100%.
+func (s *syncTracker) historyComplete() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.historyDone = true
+	s.armLocked()
+}
+
+// offlineComplete records the end of the offline backlog. needHistory means the
+// store was empty, so history sync must finish too before settling.
+func (s *syncTracker) offlineComplete(needHistory bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.offlineDone = true
+	s.needHistory = needHistory
+	s.armLocked()
+}
+
+// setQuietPeriod changes the quiet period and enables the fallback.
+func (s *syncTracker) setQuietPeriod(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.quiet = d
+	s.fallback = true
+}
+
+// connected starts the quiet-period fallback, if enabled.
+func (s *syncTracker) connected() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fallback {
+		s.startTimerLocked()
+	}
+}
+
+// activity postpones settling while messages are still arriving.
+func (s *syncTracker) activity() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.timer != nil && !s.settled {
+		s.timer.Reset(s.quiet)
+	}
+}
+
+// disconnected pauses settling. A reconnect replays the offline backlog, so
+// that phase has to complete again.
+func (s *syncTracker) disconnected() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.offlineDone = false
+	s.stopLocked()
+}
+
+// stop cancels any pending settle.
+func (s *syncTracker) stop() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopLocked()
+}
+
+func (s *syncTracker) stopLocked() {
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+}
+
+func (s *syncTracker) readyLocked() bool {
+	return s.historyDone || (s.offlineDone && !s.needHistory)
+}
+
+func (s *syncTracker) armLocked() {
+	if s.readyLocked() {
+		s.startTimerLocked()
+	}
+}
+
+func (s *syncTracker) startTimerLocked() {
+	if s.settled {
+		return
+	}
+	if s.timer != nil {
+		s.timer.Reset(s.quiet)
+		return
+	}
+	s.timer = time.AfterFunc(s.quiet, s.settle)
+}
+
+func (s *syncTracker) settle() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.settled || !(s.fallback || s.readyLocked()) || !s.isConnected() {
+		return
+	}
+	s.settled = true
+	s.timer = nil
+	close(s.done)
+}
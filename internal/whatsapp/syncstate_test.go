@@ -0,0 +1,94 @@
+package whatsapp
+
+import (
+	"testing"
+	"time"
+)
+
+const testQuiet = 30 * time.Millisecond
+
+func connected() bool { return true }
+
+func settled(s *syncTracker, within time.Duration) bool {
+	select {
+	case <-s.done:
+		return true
+	case <-time.After(within):
+		return false
+	}
+}
+
+func TestSyncTrackerWaitsForHistoryOnFreshStore(t *testing.T) {
+	s := newSyncTracker(testQuiet, connected)
+
+	s.offlineComplete(true)
+	if settled(s, 3*testQuiet) {
+		t.Fatal("settled before history sync finished")
+	}
+
+	s.historyComplete()
+	if !settled(s, 10*testQuiet) {
+		t.Fatal("expected to settle after history sync")
+	}
+}
+
+func TestSyncTrackerWaitsForQuietPeriod(t *testing.T) {
+	s := newSyncTracker(testQuiet, connected)
+	s.offlineComplete(false)
+
+	// Keep messages arriving for longer than the quiet period
+	for i := 0; i < 4; i++ {
+		time.Sleep(testQuiet / 2)
+		s.activity()
+		select {
+		case <-s.done:
+			t.Fatal("settled while messages were still arriving")
+		default:
+		}
+	}
+
+	if !settled(s, 10*testQuiet) {
+		t.Fatal("expected to settle once messages stopped")
+	}
+
+	// Further phases after settling must not panic on a closed channel
+	s.historyComplete()
+	s.offlineComplete(false)
+	time.Sleep(2 * testQuiet)
+}
+
+func TestSyncTrackerRestartsAfterDisconnect(t *testing.T) {
+	s := newSyncTracker(testQuiet, connected)
+	s.offlineComplete(false)
+	s.disconnected()
+
+	if settled(s, 3*testQuiet) {
+		t.Fatal("settled after a disconnect without a new offline sync")
+	}
+
+	s.offlineComplete(false)
+	if !settled(s, 10*testQuiet) {
+		t.Fatal("expected to settle after the reconnect's offline sync")
+	}
+}
+
+func TestSyncTrackerQuietPeriodFallback(t *testing.T) {
+	s := newSyncTracker(time.Hour, connected)
+	s.setQuietPeriod(testQuiet)
+	s.connected()
+
+	// No phase events at all: settles on quiet alone
+	if !settled(s, 10*testQuiet) {
+		t.Fatal("expected the quiet-period fallback to settle")
+	}
+}
+
+func TestSyncTrackerNoFallbackByDefault(t *testing.T) {
+	s := newSyncTracker(testQuiet, connected)
+	s.connected()
+	s.activity()
+
+	if settled(s, 3*testQuiet) {
+		t.Fatal("settled without phase events or the fallback enabled")
+	}
+}
@@ -0,0 +1,162 @@
+package whatsapp
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// vcardLineLimit is the longest a vCard line may be, in octets, before folding.
+const vcardLineLimit = 75
+
+// VCardContact is a contact to write as a vCard.
+type VCardContact struct {
+	Name  string
+	Phone string // Digits only, without +
+}
+
+// FormatVCard renders a contact as a vCard 3.0 entry in the form WhatsApp uses
+// for shared contacts: the phone carries a waid parameter so the app links it
+// to the account. Lines end in CRLF and are folded as RFC 2426 requires.
+func FormatVCard(c VCardContact) string {
+	name := c.Name
+	if name == "" {
+		name = "+" + c.Phone
+	}
+
+	var b strings.Builder
+	writeVCardLine(&b, "BEGIN:VCARD")
+	writeVCardLine(&b, "VERSION:3.0")
+	writeVCardLine(&b, "N:;"+escapeVCardText(name)+";;;")
+	writeVCardLine(&b, "FN:"+escapeVCardText(name))
+	writeVCardLine(&b, "TEL;type=CELL;type=VOICE;waid="+c.Phone+":+"+c.Phone)
+	writeVCardLine(&b, "END:VCARD")
+	return b.String()
+}
+
+// escapeVCardText escapes the characters with special meaning in vCard values.
+func escapeVCardText(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, ",", `\,`)
+	s = strings.ReplaceAll(s, ";", `\;`)
+	s = strings.ReplaceAll(s, "\r\n", `\n`)
+	return strings.ReplaceAll(s, "\n", `\n`)
+}
+
+// writeVCardLine writes line folded at vcardLineLimit octets, continuation lines
+// starting with a space. Folds never split a UTF-8 sequence.
+func writeVCardLine(b *strings.Builder, line string) {
+	limit := vcardLineLimit
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		limit = vcardLineLimit - 1 // The leading space counts
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
+
+// ParseVCards reads the contacts in a vCard file. Each contact's phone is taken
+// from the waid parameter WhatsApp adds to TEL entries, or else from the first
+// number in international (+) format; contacts with neither get an empty Phone.
+func ParseVCards(data string) []VCardContact {
+	// Unfold continuation lines, which start with a space or tab
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	data = strings.ReplaceAll(data, "\n ", "")
+	data = strings.ReplaceAll(data, "\n\t", "")
+
+	var contacts []VCardContact
+	var current *VCardContact
+	var fallback, structured string
+	for _, line := range strings.Split(data, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		params := strings.Split(key, ";")
+		name := strings.ToUpper(params[0])
+		if i := strings.LastIndex(name, "."); i >= 0 {
+			name = name[i+1:] // Drop group prefixes like "item1."
+		}
+
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VCARD"):
+			current = &VCardContact{}
+			fallback, structured = "", ""
+		case current == nil:
+			continue
+		case name == "END" && strings.EqualFold(value, "VCARD"):
+			if current.Phone == "" {
+				current.Phone = fallback
+			}
+			if current.Name == "" {
+				current.Name = structured
+			}
+			contacts = append(contacts, *current)
+			current = nil
+		case name == "FN":
+			current.Name = strings.TrimSpace(unescapeVCardText(value))
+		case name == "N":
+			structured = structuredVCardName(value)
+		case name == "TEL":
+			if waid := vcardParam(params[1:], "waid"); waid != "" && current.Phone == "" {
+				current.Phone = digitsOnly(waid)
+			} else if fallback == "" && strings.HasPrefix(strings.TrimSpace(value), "+") {
+				fallback = digitsOnly(value)
+			}
+		}
+	}
+	return contacts
+}
+
+// vcardParam returns the value of a named parameter, such as waid=123.
+func vcardParam(params []string, name string) string {
+	for _, p := range params {
+		if k, v, ok := strings.Cut(p, "="); ok && strings.EqualFold(k, name) {
+			return strings.Trim(v, `"`)
+		}
+	}
+	return ""
+}
+
+// structuredVCardName turns an N value (family;given;middle;prefix;suffix)
+// into "given family".
+func structuredVCardName(value string) string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++ // Skip the escaped character
+		case ';':
+			parts = append(parts, value[start:i])
+			start = i + 1
+		}
+	}
+	parts = append(parts, value[start:])
+
+	family := unescapeVCardText(parts[0])
+	given := ""
+	if len(parts) > 1 {
+		given = unescapeVCardText(parts[1])
+	}
+	return strings.TrimSpace(given + " " + family)
+}
+
+func unescapeVCardText(s string) string {
+	r := strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`)
+	return r.Replace(s)
+}
+
+func digitsOnly(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+}
@@ -0,0 +1,48 @@
+package whatsapp
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestFormatVCard(t *testing.T) {
+	got := FormatVCard(VCardContact{Name: "Smith, Jane; Work", Phone: "447700900000"})
+	want := "BEGIN:VCARD\r\n" +
+		"VERSION:3.0\r\n" +
+		"N:;Smith\\, Jane\\; Work;;;\r\n" +
+		"FN:Smith\\, Jane\\; Work\r\n" +
+		"TEL;type=CELL;type=VOICE;waid=447700900000:+447700900000\r\n" +
+		"END:VCARD\r\n"
+	if got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	// Unnamed contacts fall back to their number
+	if got := FormatVCard(VCardContact{Phone: "15550100"}); !strings.Contains(got, "FN:+15550100\r\n") {
+		t.Fatalf("expected number as name, got %q", got)
+	}
+}
+
+func TestFormatVCardFoldsLongLines(t *testing.T) {
+	name := strings.Repeat("\u00e9", 60) // 120 octets
+	got := FormatVCard(VCardContact{Name: name, Phone: "447700900000"})
+
+	var unfolded strings.Builder
+	for _, line := range strings.Split(strings.TrimSuffix(got, "\r\n"), "\r\n") {
+		if len(line) > vcardLineLimit {
+			t.Fatalf("line longer than %d octets: %q", vcardLineLimit, line)
+		}
+		if !utf8.ValidString(line) {
+			t.Fatalf("fold split a UTF-8 sequence: %q", line)
+		}
+		if strings.HasPrefix(line, " ") {
+			unfolded.WriteString(line[1:])
+		} else {
+			unfolded.WriteString("\n" + line)
+		}
+	}
+	if !strings.Contains(unfolded.String(), "\nFN:"+name+"\n") {
+		t.Fatalf("unfolded card lost the name: %q", unfolded.String())
+	}
+}